@@ -0,0 +1,203 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+//
+// This file implements the anchor listener. It follows the chain head, reads
+// the Anchored events of the CopyrightRegistry from the receipts of new
+// blocks and marks the matching local batches as anchored, so they are not
+// injected again.
+
+package ots
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	otsmetrics "github.com/ethereum/go-ethereum/ots/metrics"
+	"github.com/ethereum/go-ethereum/ots/storage"
+	"github.com/ethereum/go-ethereum/ots/systx"
+)
+
+const (
+	// anchorHeadChanSize is the size of the chain head event channel
+	anchorHeadChanSize = 16
+
+	// anchorInclusionBlocks is the number of blocks an injected anchor may
+	// take to be included before its batches are injected again
+	anchorInclusionBlocks = 20
+
+	// anchorReorgDepth is the number of blocks rescanned after a reorg
+	anchorReorgDepth = 128
+)
+
+// runAnchorListener scans every new chain head for anchored batches
+func (m *Module) runAnchorListener() {
+	heads := make(chan core.ChainHeadEvent, anchorHeadChanSize)
+	sub := m.blockchain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	log.Info("OTS: Anchor listener started")
+	defer log.Info("OTS: Anchor listener stopped")
+
+	// Catch up with anchors included while the node was down
+	if head := m.blockchain.CurrentHeader(); head != nil {
+		m.scanAnchors(head)
+	}
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case err := <-sub.Err():
+			if err != nil {
+				log.Warn("OTS: Chain head subscription failed", "err", err)
+			}
+			return
+		case ev := <-heads:
+			if ev.Block != nil {
+				m.scanAnchors(ev.Block.Header())
+			}
+		}
+	}
+}
+
+// scanAnchors processes the anchors of the canonical blocks from the last
+// scanned block up to head. Only the listener goroutine calls it.
+func (m *Module) scanAnchors(head *types.Header) {
+	number := head.Number.Uint64()
+	from, ok := m.anchorScanStart(number)
+	if !ok {
+		return
+	}
+	for n := from; n <= number; n++ {
+		if m.ctx.Err() != nil {
+			return
+		}
+		header := m.blockchain.GetHeaderByNumber(n)
+		if header == nil {
+			return
+		}
+		m.processAnchors(header)
+		m.lastAnchorScan, m.lastAnchorHash = n, header.Hash()
+	}
+}
+
+// anchorScanStart returns the first block to scan for anchors, and false if
+// there is nothing to scan
+func (m *Module) anchorScanStart(head uint64) (uint64, bool) {
+	indexMgr := storage.NewIndexManager(m.store)
+
+	// On the first scan no anchor can predate the oldest unanchored batch
+	if m.lastAnchorScan == 0 {
+		from, ok := m.oldestUnanchoredBlock(indexMgr)
+		if !ok {
+			if header := m.blockchain.GetHeaderByNumber(head); header != nil {
+				m.lastAnchorScan, m.lastAnchorHash = head, header.Hash()
+			}
+			return 0, false
+		}
+		return from, true
+	}
+
+	// After a reorg, forget the anchors of the dropped blocks and rescan
+	if header := m.blockchain.GetHeaderByNumber(m.lastAnchorScan); header == nil || header.Hash() != m.lastAnchorHash {
+		fork := uint64(1)
+		if m.lastAnchorScan > anchorReorgDepth {
+			fork = m.lastAnchorScan - anchorReorgDepth + 1
+		}
+		reverted, err := indexMgr.UnmarkAnchoredSince(fork)
+		if err != nil {
+			log.Error("OTS: Failed to revert reorged anchors", "from", fork, "err", err)
+			otsmetrics.IncStorageError()
+		}
+		log.Info("OTS: Rescanning anchors after reorg", "from", fork, "head", head, "reverted", len(reverted))
+		return fork, true
+	}
+	return m.lastAnchorScan + 1, m.lastAnchorScan < head
+}
+
+// oldestUnanchoredBlock returns the block after the end of the oldest batch
+// that is not anchored yet, the first block its anchor can be in
+func (m *Module) oldestUnanchoredBlock(indexMgr *storage.IndexManager) (uint64, bool) {
+	pending, _, err := indexMgr.GetPendingBatches()
+	if err != nil {
+		log.Warn("OTS: Failed to load unanchored batches", "err", err)
+		return 0, false
+	}
+	confirmed, _, err := indexMgr.GetConfirmedBatches()
+	if err != nil {
+		log.Warn("OTS: Failed to load unanchored batches", "err", err)
+		return 0, false
+	}
+	var (
+		oldest uint64
+		found  bool
+	)
+	for _, meta := range append(pending, confirmed...) {
+		if !found || meta.EndBlock < oldest {
+			oldest, found = meta.EndBlock, true
+		}
+	}
+	return oldest + 1, found
+}
+
+// processAnchors marks the batches anchored in the block as anchored
+func (m *Module) processAnchors(header *types.Header) {
+	if !types.BloomLookup(header.Bloom, m.config.ContractAddress) || !types.BloomLookup(header.Bloom, systx.AnchoredEventTopic) {
+		return
+	}
+	receipts := m.blockchain.GetReceiptsByHash(header.Hash())
+	events := systx.AnchoredEvents(header.Number.Uint64(), receipts, m.config.ContractAddress)
+
+	indexMgr := storage.NewIndexManager(m.store)
+	for _, ev := range events {
+		batchID, anchored, err := indexMgr.MarkAnchored(ev.StartBlock, ev.EndBlock, ev.RootHash, ev.TxHash, ev.BlockNumber)
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Debug("OTS: Anchored batch not tracked locally", "startBlock", ev.StartBlock, "endBlock", ev.EndBlock, "rootHash", ev.RootHash.Hex())
+			continue
+		}
+		if err != nil {
+			log.Error("OTS: Failed to mark batch anchored", "batchID", batchID, "err", err)
+			otsmetrics.IncStorageError()
+			continue
+		}
+
+		m.anchorMu.Lock()
+		delete(m.injected, batchID)
+		m.anchorMu.Unlock()
+
+		if anchored {
+			otsmetrics.IncBatchAnchored()
+			log.Info("OTS: Batch anchored on-chain",
+				"batchID", batchID,
+				"block", ev.BlockNumber,
+				"txHash", ev.TxHash.Hex(),
+			)
+		}
+	}
+}
+
+// recentlyInjected reports whether an anchor of the batch was injected
+// recently enough that it may still be included
+func (m *Module) recentlyInjected(batchID string, number uint64) bool {
+	m.anchorMu.Lock()
+	defer m.anchorMu.Unlock()
+
+	injectedAt, ok := m.injected[batchID]
+	return ok && number < injectedAt+anchorInclusionBlocks && number > injectedAt
+}
+
+// markInjected records that anchors of the batches were injected in the
+// block with the given number
+func (m *Module) markInjected(batches []*CandidateBatch, number uint64) {
+	m.anchorMu.Lock()
+	defer m.anchorMu.Unlock()
+
+	if m.injected == nil {
+		m.injected = make(map[string]uint64)
+	}
+	for _, batch := range batches {
+		m.injected[batch.BatchID] = number
+	}
+}
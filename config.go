@@ -9,9 +9,12 @@
 package ots
 
 import (
+	"encoding/hex"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ots/opentimestamps"
 )
 
 // Mode defines the running mode of OTS module
@@ -43,7 +46,7 @@ type Config struct {
 
 	// TriggerHour is the UTC hour for daily batch trigger (0-23)
 	// Note: This is for reference only. Actual triggering is done by consensus layer
-	// (BreatheBlock mechanism) based on block timestamps crossing this hour.
+	// according to the consensus.TriggerSchedule in the chain config.
 	TriggerHour uint8
 
 	// Confirmations is the number of block confirmations before processing
@@ -64,7 +67,11 @@ type Config struct {
 
 // OTSConfig holds OpenTimestamps specific configuration
 type OTSConfig struct {
-	// BinaryPath is the path to the ots CLI binary
+	// Client selects the OpenTimestamps implementation: "native" (default)
+	// or "cli" to shell out to the Python ots binary
+	Client string
+
+	// BinaryPath is the path to the ots CLI binary (Client = "cli")
 	BinaryPath string
 
 	// CalendarServers is the list of calendar server URLs
@@ -79,8 +86,167 @@ type OTSConfig struct {
 	// CalendarPollInterval is the interval for polling calendar status
 	CalendarPollInterval time.Duration
 
+	// MinCalendarResponses is how many calendar servers must accept a
+	// digest for a submission to succeed
+	MinCalendarResponses int
+
 	// BTCConfirmations is the number of BTC confirmations required
 	BTCConfirmations uint8
+
+	// BitcoinExplorer selects the backend used to verify Bitcoin attestations:
+	// "blockstream" (default), "mempool", "esplora", "bitcoind", "local" or
+	// "quorum"
+	BitcoinExplorer string
+
+	// EsploraURL is the base URL of an Esplora-compatible API
+	// (BitcoinExplorer = "esplora")
+	EsploraURL string
+
+	// Bitcoind holds the Bitcoin Core RPC settings (BitcoinExplorer = "bitcoind")
+	Bitcoind BitcoindConfig
+
+	// HeaderFile is a JSON dump of bitcoind getblockheader results, or raw
+	// 80-byte headers starting at genesis or at the header checkpoint
+	// (BitcoinExplorer = "local")
+	HeaderFile string
+
+	// HeaderCheckpointHeight and HeaderCheckpointHash name a trusted block
+	// the header file starts at instead of genesis. The height must be a
+	// multiple of 2016 (BitcoinExplorer = "local").
+	HeaderCheckpointHeight uint64
+	HeaderCheckpointHash   string
+
+	// ExplorerBackends are queried together when BitcoinExplorer = "quorum"
+	ExplorerBackends []ExplorerBackend
+
+	// QuorumThreshold is how many ExplorerBackends must agree on a block's
+	// hash and merkle root before an attestation is accepted
+	QuorumThreshold int
+
+	// ProofPeers are JSON-RPC endpoints of nodes serving ots_getProofSidecar,
+	// asked for committed proofs missing from the local sidecar store
+	ProofPeers []string
+}
+
+// OpenTimestamps client implementations
+const (
+	OTSClientNative = "native"
+	OTSClientCLI    = "cli"
+)
+
+// Bitcoin explorer backends
+const (
+	ExplorerBlockstream = "blockstream"
+	ExplorerMempool     = "mempool"
+	ExplorerEsplora     = "esplora"
+	ExplorerBitcoind    = "bitcoind"
+	ExplorerLocal       = "local"
+	ExplorerQuorum      = "quorum"
+)
+
+// ExplorerBackend configures one member of a quorum explorer
+type ExplorerBackend struct {
+	// Name identifies the backend in logs and health reports
+	Name string
+
+	// Type is "blockstream", "mempool", "esplora", "bitcoind" or "local"
+	Type string
+
+	// URL is the base URL of an Esplora-compatible API (Type = "esplora")
+	URL string
+
+	// Bitcoind holds the Bitcoin Core RPC settings (Type = "bitcoind")
+	Bitcoind BitcoindConfig
+
+	// HeaderFile is the header dump to load (Type = "local")
+	HeaderFile string
+
+	// HeaderCheckpointHeight and HeaderCheckpointHash name the trusted block
+	// the header dump starts at, genesis if unset (Type = "local")
+	HeaderCheckpointHeight uint64
+	HeaderCheckpointHash   string
+}
+
+// validate checks the settings required by the backend type
+func (b *ExplorerBackend) validate() error {
+	switch b.Type {
+	case "", ExplorerBlockstream, ExplorerMempool:
+	case ExplorerEsplora:
+		if b.URL == "" {
+			return ErrInvalidEsploraURL
+		}
+	case ExplorerBitcoind:
+		if b.Bitcoind.URL == "" || (b.Bitcoind.CookieFile == "" && b.Bitcoind.User == "") {
+			return ErrInvalidBitcoindConfig
+		}
+	case ExplorerLocal:
+		if b.HeaderFile == "" {
+			return ErrInvalidHeaderFile
+		}
+		if _, err := b.headerCheckpoint(); err != nil {
+			return err
+		}
+	default:
+		return ErrInvalidExplorer
+	}
+	return nil
+}
+
+// headerCheckpoint returns the checkpoint the header dump starts at, nil for
+// genesis
+func (b *ExplorerBackend) headerCheckpoint() (*opentimestamps.HeaderCheckpoint, error) {
+	if b.HeaderCheckpointHash == "" {
+		if b.HeaderCheckpointHeight != 0 {
+			return nil, ErrInvalidHeaderCheckpoint
+		}
+		return nil, nil
+	}
+	hash, err := hex.DecodeString(strings.TrimPrefix(b.HeaderCheckpointHash, "0x"))
+	if err != nil || len(hash) != 32 || b.HeaderCheckpointHeight%2016 != 0 {
+		return nil, ErrInvalidHeaderCheckpoint
+	}
+	// Block hashes are configured in display byte order
+	for i, j := 0, len(hash)-1; i < j; i, j = i+1, j-1 {
+		hash[i], hash[j] = hash[j], hash[i]
+	}
+	return &opentimestamps.HeaderCheckpoint{Height: b.HeaderCheckpointHeight, Hash: hash}, nil
+}
+
+// singleExplorer returns the backend selected when no quorum is configured
+func (c *OTSConfig) singleExplorer() ExplorerBackend {
+	return ExplorerBackend{
+		Name:       c.BitcoinExplorer,
+		Type:       c.BitcoinExplorer,
+		URL:        c.EsploraURL,
+		Bitcoind:   c.Bitcoind,
+		HeaderFile: c.HeaderFile,
+
+		HeaderCheckpointHeight: c.HeaderCheckpointHeight,
+		HeaderCheckpointHash:   c.HeaderCheckpointHash,
+	}
+}
+
+// calendarServers returns the calendar servers submissions go to, the
+// default servers if none are configured
+func (c *OTSConfig) calendarServers() []string {
+	if len(c.CalendarServers) == 0 {
+		return opentimestamps.DefaultCalendarServers
+	}
+	return c.CalendarServers
+}
+
+// BitcoindConfig holds Bitcoin Core JSON-RPC settings
+type BitcoindConfig struct {
+	// URL is the JSON-RPC endpoint, e.g. http://127.0.0.1:8332
+	URL string
+
+	// User and Password are the rpcuser/rpcpassword credentials
+	User     string
+	Password string
+
+	// CookieFile is the path to bitcoind's .cookie file, used instead of
+	// User/Password when set
+	CookieFile string
 }
 
 // StorageConfig holds storage layer configuration
@@ -115,6 +281,7 @@ func DefaultConfig() *Config {
 		Confirmations:   15,
 		SystemTxGasLimit: 500000, // 500k gas for system transactions
 		OTS: OTSConfig{
+			Client:     OTSClientNative,
 			BinaryPath: "ots",
 			CalendarServers: []string{
 				"https://alice.btc.calendar.opentimestamps.org",
@@ -124,7 +291,9 @@ func DefaultConfig() *Config {
 			Timeout:              30 * time.Second,
 			CalendarTimeout:      30 * time.Second,
 			CalendarPollInterval: 5 * time.Minute,
+			MinCalendarResponses: 1,
 			BTCConfirmations:     6,
+			BitcoinExplorer:      ExplorerBlockstream,
 		},
 		Storage: StorageConfig{
 			CacheSize:   128,
@@ -160,5 +329,35 @@ func (c *Config) Validate() error {
 		return ErrInvalidContractAddress
 	}
 
+	switch c.OTS.Client {
+	case "", OTSClientNative:
+	case OTSClientCLI:
+		if c.OTS.BinaryPath == "" {
+			return ErrInvalidOTSBinary
+		}
+	default:
+		return ErrInvalidOTSClient
+	}
+
+	if c.OTS.MinCalendarResponses < 0 || c.OTS.MinCalendarResponses > len(c.OTS.calendarServers()) {
+		return ErrInvalidCalendarQuorum
+	}
+
+	if c.OTS.BitcoinExplorer == ExplorerQuorum {
+		if c.OTS.QuorumThreshold < 1 || c.OTS.QuorumThreshold > len(c.OTS.ExplorerBackends) {
+			return ErrInvalidExplorerQuorum
+		}
+		for i := range c.OTS.ExplorerBackends {
+			if err := c.OTS.ExplorerBackends[i].validate(); err != nil {
+				return err
+			}
+		}
+	} else {
+		single := c.OTS.singleExplorer()
+		if err := single.validate(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
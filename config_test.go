@@ -0,0 +1,83 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package ots
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ots/opentimestamps"
+)
+
+func TestConfigValidate_Explorer(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*OTSConfig)
+		want   error
+	}{
+		{"default", func(*OTSConfig) {}, nil},
+		{"esplora", func(c *OTSConfig) {
+			c.BitcoinExplorer = ExplorerEsplora
+			c.EsploraURL = "https://esplora.example/api"
+		}, nil},
+		{"esplora without url", func(c *OTSConfig) { c.BitcoinExplorer = ExplorerEsplora }, ErrInvalidEsploraURL},
+		{"unknown", func(c *OTSConfig) { c.BitcoinExplorer = "electrum" }, ErrInvalidExplorer},
+		{"local checkpoint", func(c *OTSConfig) {
+			c.BitcoinExplorer = ExplorerLocal
+			c.HeaderFile = "headers.bin"
+			c.HeaderCheckpointHash = "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+		}, nil},
+		{"local checkpoint off a retarget height", func(c *OTSConfig) {
+			c.BitcoinExplorer = ExplorerLocal
+			c.HeaderFile = "headers.bin"
+			c.HeaderCheckpointHeight = 1
+			c.HeaderCheckpointHash = "00000000839a8e6886ab5951d76f411475428afc90947ee320161bbf18eb6048"
+		}, ErrInvalidHeaderCheckpoint},
+		{"local checkpoint without hash", func(c *OTSConfig) {
+			c.BitcoinExplorer = ExplorerLocal
+			c.HeaderFile = "headers.bin"
+			c.HeaderCheckpointHeight = 32256
+		}, ErrInvalidHeaderCheckpoint},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.Enabled = true
+		tt.modify(&config.OTS)
+		if err := config.Validate(); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Validate() = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	config := DefaultConfig()
+	config.OTS.BitcoinExplorer = ExplorerEsplora
+	config.OTS.EsploraURL = "https://esplora.example/api"
+	if single := config.OTS.singleExplorer(); single.URL != config.OTS.EsploraURL {
+		t.Errorf("esplora URL not passed to the explorer: %q", single.URL)
+	}
+}
+
+func TestConfigValidate_CalendarQuorum(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers []string
+		quorum  int
+		want    error
+	}{
+		{"default servers", nil, 1, nil},
+		{"all default servers", nil, len(opentimestamps.DefaultCalendarServers), nil},
+		{"above default servers", nil, len(opentimestamps.DefaultCalendarServers) + 1, ErrInvalidCalendarQuorum},
+		{"configured servers", []string{"https://a.example", "https://b.example"}, 2, nil},
+		{"above configured servers", []string{"https://a.example"}, 2, ErrInvalidCalendarQuorum},
+		{"negative", nil, -1, ErrInvalidCalendarQuorum},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.Enabled = true
+		config.OTS.CalendarServers = tt.servers
+		config.OTS.MinCalendarResponses = tt.quorum
+		if err := config.Validate(); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Validate() = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+//
+// This file implements the OTS consensus activation fork. Networks that
+// predate OTS switch it on at a scheduled block, optionally also gated on a
+// block timestamp, like the hard forks of the chain config. Before the fork
+// the OTS state passes through blocks unchanged.
+
+package consensus
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/ots/merkle"
+)
+
+var (
+	ErrInvalidForkActivation = errors.New("invalid OTS fork activation")
+)
+
+// ForkActivation schedules the first block OTS consensus applies to
+type ForkActivation struct {
+	// Block is the first block number OTS may apply to
+	Block uint64 `json:"block"`
+
+	// Time optionally delays activation to the first block at or after Block
+	// whose timestamp is at least Time
+	Time *uint64 `json:"time,omitempty"`
+
+	// LastAnchoredBlock is the LastAnchoredBlock of the state the first OTS
+	// block builds on, so the first batch starts after it. Zero selects
+	// Block-1, so batching starts at the fork instead of walking the whole
+	// pre-fork chain in the first trigger.
+	LastAnchoredBlock uint64 `json:"lastAnchoredBlock,omitempty"`
+
+	// MerkleV1Block is the first block whose triggers build batch roots with
+	// merkle.SchemeV1. Earlier triggers, and all triggers if it is unset,
+	// use merkle.SchemeLegacy.
+	MerkleV1Block *uint64 `json:"merkleV1Block,omitempty"`
+}
+
+// Validate checks that the activation state does not anchor blocks from
+// after the fork
+func (f *ForkActivation) Validate() error {
+	if f.LastAnchoredBlock > 0 && f.LastAnchoredBlock >= f.Block {
+		return fmt.Errorf("%w: last anchored block %d not before fork block %d", ErrInvalidForkActivation, f.LastAnchoredBlock, f.Block)
+	}
+	return nil
+}
+
+// IsActive reports whether OTS applies to the block with the given number
+// and timestamp. A nil fork activates OTS at genesis.
+func (f *ForkActivation) IsActive(number, time uint64) bool {
+	if f == nil {
+		return true
+	}
+	return number >= f.Block && (f.Time == nil || time >= *f.Time)
+}
+
+// MerkleScheme returns the scheme of the batch roots triggered at the block
+// with the given number. A nil fork keeps merkle.SchemeLegacy.
+func (f *ForkActivation) MerkleScheme(number uint64) merkle.Scheme {
+	if f == nil || f.MerkleV1Block == nil || number < *f.MerkleV1Block {
+		return merkle.SchemeLegacy
+	}
+	return merkle.SchemeV1
+}
+
+// lastAnchoredBlock returns the LastAnchoredBlock of the activation state
+func (f *ForkActivation) lastAnchoredBlock() uint64 {
+	switch {
+	case f == nil:
+		return 0
+	case f.LastAnchoredBlock == 0 && f.Block > 1:
+		return f.Block - 1
+	default:
+		return f.LastAnchoredBlock
+	}
+}
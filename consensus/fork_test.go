@@ -0,0 +1,216 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ots/systx"
+)
+
+// newForkChain returns a chain with a claim in every block whose daily
+// trigger fires at blocks 13, 37 and 61
+func newForkChain() *testChain {
+	registry := common.HexToAddress(CopyrightRegistryAddress)
+	claims := make([][]*types.Log, 60)
+	for i := range claims {
+		claims[i] = []*types.Log{claimLog(registry, common.BigToHash(big.NewInt(int64(i+1))))}
+	}
+	return newTestChain(registry, claims)
+}
+
+// newForkManager returns a manager with the given activation fork
+func newForkManager(t *testing.T, chain *testChain, fork *ForkActivation) *OTSConsensusManager {
+	t.Helper()
+	m, err := NewOTSConsensusManager(rawdb.NewMemoryDatabase(), &OTSManagerConfig{Enabled: true, Fork: fork})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetChainAccessors(chain.getReceipts, chain.getHeader, chain.getHeaderByNumber)
+	return m
+}
+
+// syncChain processes the chain block by block like a syncing node and
+// returns the number of the first block OTS applied to
+func syncChain(t *testing.T, m *OTSConsensusManager, chain *testChain) (uint64, *Snapshot) {
+	t.Helper()
+	var (
+		first uint64
+		snap  *Snapshot
+	)
+	for _, header := range chain.headers[1:] {
+		next, err := m.ProcessBlock(header, header.ParentHash)
+		if err != nil {
+			t.Fatalf("ProcessBlock %d failed: %v", header.Number, err)
+		}
+		if next == nil {
+			if first != 0 {
+				t.Fatalf("block %d not processed after activation at %d", header.Number, first)
+			}
+			if m.snapshots.HasSnapshot(header.Hash()) {
+				t.Fatalf("snapshot stored for pre-fork block %d", header.Number)
+			}
+			continue
+		}
+		if first == 0 {
+			first = header.Number.Uint64()
+		}
+		snap = next
+	}
+	return first, snap
+}
+
+func TestForkActivation_Sync(t *testing.T) {
+	chain := newForkChain()
+	forkTime := chain.headers[33].Time
+
+	tests := []struct {
+		name      string
+		fork      *ForkActivation
+		activates uint64
+		batches   [][2]uint64 // start and end of the in-flight batches
+	}{
+		{"genesis", nil, 1, [][2]uint64{{1, 12}, {13, 36}, {37, 60}}},
+		{"fork block", &ForkActivation{Block: 30, LastAnchoredBlock: 20}, 30, [][2]uint64{{21, 36}, {37, 60}}},
+		{"fork block without anchored block", &ForkActivation{Block: 30}, 30, [][2]uint64{{30, 36}, {37, 60}}},
+		{"fork time", &ForkActivation{Block: 30, Time: &forkTime, LastAnchoredBlock: 20}, 33, [][2]uint64{{21, 36}, {37, 60}}},
+		{"fork after trigger", &ForkActivation{Block: 40, LastAnchoredBlock: 39}, 40, [][2]uint64{{40, 60}}},
+	}
+	for _, tt := range tests {
+		m := newForkManager(t, chain, tt.fork)
+		first, head := syncChain(t, m, chain)
+		if first != tt.activates {
+			t.Errorf("%s: activated at block %d, want %d", tt.name, first, tt.activates)
+		}
+		if want := tt.fork.lastAnchoredBlock(); head.State.LastAnchoredBlock != want {
+			t.Errorf("%s: LastAnchoredBlock = %d, want %d", tt.name, head.State.LastAnchoredBlock, want)
+		}
+		var batches [][2]uint64
+		for _, batch := range head.State.Batches {
+			batches = append(batches, [2]uint64{batch.StartBlock, batch.EndBlock})
+		}
+		if len(batches) != len(tt.batches) {
+			t.Errorf("%s: batches = %v, want %v", tt.name, batches, tt.batches)
+			continue
+		}
+		for i := range batches {
+			if batches[i] != tt.batches[i] {
+				t.Errorf("%s: batches = %v, want %v", tt.name, batches, tt.batches)
+				break
+			}
+		}
+
+		// A node rebuilding from genesis derives the same state
+		rebuilt := newForkManager(t, chain, tt.fork)
+		if err := rebuilt.RebuildFromChain(0, head.Number); err != nil {
+			t.Fatalf("%s: RebuildFromChain failed: %v", tt.name, err)
+		}
+		if snap, err := rebuilt.GetSnapshot(head.Hash); err != nil || snap.State.Hash() != head.State.Hash() {
+			t.Errorf("%s: rebuilt state differs from synced state: %v", tt.name, err)
+		}
+	}
+}
+
+func TestForkActivation_SystemTxs(t *testing.T) {
+	chain := newForkChain()
+	m := newForkManager(t, chain, &ForkActivation{Block: 30, LastAnchoredBlock: 20})
+	syncChain(t, m, chain)
+
+	tx, err := systx.NewBuilder(copyrightRegistryAddr).BuildOTSSubmittedTx(&systx.OTSSubmittedParams{RootHash: common.Hash{0x01}, OTSDigest: [32]byte{0x01}}, common.Address{}, 0, 1000000)
+	if err != nil {
+		t.Fatalf("BuildOTSSubmittedTx failed: %v", err)
+	}
+	noNonce := func(common.Address) uint64 { return 0 }
+
+	// Before the fork OTS transactions are not checked or built
+	if err := m.ValidateOTSSystemTx(tx, chain.headers[29]); err != nil {
+		t.Errorf("pre-fork validation failed: %v", err)
+	}
+	if txs, err := m.GetSystemTransactions(chain.headers[29], chain.headers[28].Hash(), common.Address{}, noNonce); txs != nil || err != nil {
+		t.Errorf("pre-fork system transactions built: %v, %v", txs, err)
+	}
+
+	// The fork block builds on the base state, later blocks on snapshots
+	for _, number := range []int{30, 40} {
+		if err := m.ValidateOTSSystemTx(tx, chain.headers[number]); !errors.Is(err, ErrBatchNotFound) {
+			t.Errorf("block %d: expected ErrBatchNotFound, got %v", number, err)
+		}
+	}
+}
+
+func TestForkActivation_Validate(t *testing.T) {
+	tests := []struct {
+		fork  ForkActivation
+		valid bool
+	}{
+		{ForkActivation{}, true},
+		{ForkActivation{Block: 100}, true},
+		{ForkActivation{Block: 100, LastAnchoredBlock: 99}, true},
+		{ForkActivation{Block: 100, LastAnchoredBlock: 100}, false},
+		{ForkActivation{LastAnchoredBlock: 1}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.fork.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%+v: Validate() = %v, want valid %v", tt.fork, err, tt.valid)
+		}
+	}
+	if _, err := NewOTSConsensusManager(rawdb.NewMemoryDatabase(), &OTSManagerConfig{Enabled: true, Fork: &ForkActivation{Block: 10, LastAnchoredBlock: 10}}); !errors.Is(err, ErrInvalidForkActivation) {
+		t.Errorf("expected ErrInvalidForkActivation, got %v", err)
+	}
+}
+
+func TestForkActivation_LargeForkHeight(t *testing.T) {
+	const fork = 50_000_000
+
+	// Only the blocks around the fork are available, so a trigger reading
+	// the pre-fork chain fails
+	chain := &testChain{receipts: make(map[common.Hash]types.Receipts)}
+	byNumber := make(map[uint64]*types.Header)
+	parent := common.Hash{0x01}
+	for number := uint64(fork - 2); number <= fork+10; number++ {
+		header := &types.Header{ParentHash: parent, Number: new(big.Int).SetUint64(number), ReceiptHash: types.EmptyReceiptsHash}
+		byNumber[number] = header
+		parent = header.Hash()
+	}
+	getHeader := func(hash common.Hash, number uint64) *types.Header {
+		if header := byNumber[number]; header != nil && header.Hash() == hash {
+			return header
+		}
+		return nil
+	}
+	getHeaderByNumber := func(number uint64) *types.Header { return byNumber[number] }
+
+	m, err := NewOTSConsensusManager(rawdb.NewMemoryDatabase(), &OTSManagerConfig{
+		Enabled:         true,
+		Fork:            &ForkActivation{Block: fork},
+		TriggerSchedule: TriggerSchedule{{Blocks: 5}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetChainAccessors(chain.getReceipts, getHeader, getHeaderByNumber)
+
+	var snap *Snapshot
+	for number := uint64(fork - 1); number <= fork+10; number++ {
+		header := byNumber[number]
+		next, err := m.ProcessBlock(header, header.ParentHash)
+		if err != nil {
+			t.Fatalf("ProcessBlock %d failed: %v", number, err)
+		}
+		if next != nil {
+			snap = next
+		}
+	}
+	if snap.State.LastAnchoredBlock != fork-1 {
+		t.Errorf("LastAnchoredBlock = %d, want %d", snap.State.LastAnchoredBlock, fork-1)
+	}
+	if b := snap.State.OldestBatch(); b == nil || b.StartBlock != fork || b.EndBlock != fork+4 {
+		t.Errorf("expected the first batch to cover [%d, %d], got %+v", fork, fork+4, b)
+	}
+}
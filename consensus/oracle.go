@@ -0,0 +1,132 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+//
+// This file implements verification of otsConfirmed claims against the
+// node's own view of Bitcoin. Views differ between nodes, so a claim is only
+// rejected when it is provably false beyond a tolerance window, and accepted
+// when the node cannot check it.
+
+package consensus
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/log"
+	otsmetrics "github.com/ethereum/go-ethereum/ots/metrics"
+	"github.com/ethereum/go-ethereum/ots/systx"
+)
+
+var (
+	ErrBTCClaimMismatch = errors.New("otsConfirmed claim contradicts the local Bitcoin view")
+)
+
+// BTCHeaderOracle is the node's view of the Bitcoin header chain
+type BTCHeaderOracle interface {
+	// BTCTipHeight returns the height of the best known Bitcoin block
+	BTCTipHeight() (uint64, error)
+	// BTCBlockTime returns the timestamp of the Bitcoin block at height
+	BTCBlockTime(height uint64) (uint64, error)
+}
+
+// ConfirmationTolerance bounds how far an otsConfirmed claim may differ from
+// the local view before it is rejected
+type ConfirmationTolerance struct {
+	// Heights is the allowed Bitcoin block height difference from a local
+	// proof, covering calendars attesting in different blocks
+	Heights uint64
+	// Seconds is the allowed Bitcoin block timestamp difference
+	Seconds uint64
+}
+
+// DefaultConfirmationTolerance allows six Bitcoin blocks and two hours, the
+// Bitcoin limit on block timestamps running ahead
+var DefaultConfirmationTolerance = ConfirmationTolerance{
+	Heights: 6,
+	Seconds: 2 * 60 * 60,
+}
+
+// claimViews are the views of Bitcoin an otsConfirmed claim is checked
+// against. They are copied from the manager under its lock, so their lookups
+// run without holding it.
+type claimViews struct {
+	oracle    BTCHeaderOracle
+	client    OTSClientInterface
+	tolerance ConfirmationTolerance
+}
+
+// claimViews returns the manager's views of Bitcoin. The caller must hold m.mu.
+func (m *OTSConsensusManager) claimViews() *claimViews {
+	return &claimViews{oracle: m.btcOracle, client: m.otsClient, tolerance: m.tolerance}
+}
+
+// verifyConfirmationClaim checks an otsConfirmed claim for the batch against
+// the header oracle and the OTS client. It returns whether any view could
+// check the claim, or ErrBTCClaimMismatch if a view contradicts it.
+func (v *claimViews) verifyConfirmationClaim(batch *BatchState, params *systx.OTSConfirmedParams) (bool, error) {
+	tol := v.tolerance
+	verified := false
+
+	if v.oracle != nil {
+		// A block above the local tip only shows the local view is behind,
+		// so the claim is left unverified
+		if tip, err := v.oracle.BTCTipHeight(); err != nil {
+			log.Debug("OTS: Bitcoin header oracle unavailable", "err", err)
+		} else if params.BTCBlockHeight > tip {
+			log.Debug("OTS: Claimed Bitcoin block beyond the local tip", "height", params.BTCBlockHeight, "tip", tip)
+		} else {
+			blockTime, err := v.oracle.BTCBlockTime(params.BTCBlockHeight)
+			if err != nil {
+				log.Debug("OTS: Bitcoin header oracle unavailable", "height", params.BTCBlockHeight, "err", err)
+			} else if absDiff(blockTime, params.BTCTimestamp) > tol.Seconds {
+				return false, fmt.Errorf("%w: block %d time %d, claimed %d", ErrBTCClaimMismatch, params.BTCBlockHeight, blockTime, params.BTCTimestamp)
+			} else {
+				verified = true
+			}
+		}
+	}
+
+	if v.client != nil {
+		result, err := v.client.CheckConfirmation(batch.OTSDigest)
+		if err != nil {
+			log.Debug("OTS: Cannot check confirmation locally", "rootHash", batch.RootHash.Hex(), "err", err)
+		} else if result != nil && result.Confirmed {
+			// A pending local proof proves nothing, a confirmed one bounds the
+			// height and names the Bitcoin transaction
+			if absDiff(result.BTCBlockHeight, params.BTCBlockHeight) > tol.Heights {
+				return false, fmt.Errorf("%w: proof attests block %d, claimed %d", ErrBTCClaimMismatch, result.BTCBlockHeight, params.BTCBlockHeight)
+			}
+			if result.BTCTxID != "" {
+				if txID := systx.BTCTxIDToBytes32(result.BTCTxID); txID != params.BTCTxID {
+					return false, fmt.Errorf("%w: proof attests tx %x, claimed %x", ErrBTCClaimMismatch, txID, params.BTCTxID)
+				}
+			}
+			verified = true
+		}
+	}
+	return verified, nil
+}
+
+// absDiff returns |a - b|
+func absDiff(a, b uint64) uint64 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// checkConfirmationClaim applies the fail-open policy: a contradicted claim is
+// rejected, an unverifiable one is accepted and counted
+func (v *claimViews) checkConfirmationClaim(batch *BatchState, params *systx.OTSConfirmedParams) error {
+	verified, err := v.verifyConfirmationClaim(batch, params)
+	if err != nil {
+		otsmetrics.IncConfirmationRejected()
+		log.Warn("OTS: Rejecting otsConfirmed claim", "rootHash", batch.RootHash.Hex(), "err", err)
+		return err
+	}
+	if !verified {
+		otsmetrics.IncConfirmationUnverified()
+		log.Debug("OTS: Accepting unverifiable otsConfirmed claim", "rootHash", batch.RootHash.Hex(), "btcBlock", params.BTCBlockHeight)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package consensus
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ots/systx"
+)
+
+var errUnavailable = errors.New("unavailable")
+
+// testOracle is a Bitcoin header view with blocks 0..tip every 600 seconds
+type testOracle struct {
+	tip uint64
+	err error
+}
+
+func (o *testOracle) BTCTipHeight() (uint64, error) {
+	return o.tip, o.err
+}
+
+func (o *testOracle) BTCBlockTime(height uint64) (uint64, error) {
+	return 1700000000 + height*600, o.err
+}
+
+// testOTSClient reports a fixed confirmation result
+type testOTSClient struct {
+	result *BTCConfirmationResult
+	err    error
+}
+
+func (c *testOTSClient) Stamp(digest common.Hash) ([]byte, [32]byte, error) {
+	return nil, [32]byte{}, errUnavailable
+}
+
+func (c *testOTSClient) CheckConfirmation(digest [32]byte) (*BTCConfirmationResult, error) {
+	return c.result, c.err
+}
+
+func TestVerifyConfirmationClaim(t *testing.T) {
+	claim := func(height, timestamp uint64) *systx.OTSConfirmedParams {
+		return &systx.OTSConfirmedParams{RootHash: common.Hash{0x01}, BTCBlockHeight: height, BTCTimestamp: timestamp}
+	}
+	at := func(height uint64) uint64 { return 1700000000 + height*600 }
+	confirmed := func(height uint64) *testOTSClient {
+		return &testOTSClient{result: &BTCConfirmationResult{Confirmed: true, BTCBlockHeight: height}}
+	}
+	const txID = "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"
+	withTx := func(params *systx.OTSConfirmedParams, txID string) *systx.OTSConfirmedParams {
+		params.BTCTxID = systx.BTCTxIDToBytes32(txID)
+		return params
+	}
+	attestsTx := &testOTSClient{result: &BTCConfirmationResult{Confirmed: true, BTCBlockHeight: 799990, BTCTxID: txID}}
+
+	tests := []struct {
+		name     string
+		oracle   BTCHeaderOracle
+		client   OTSClientInterface
+		params   *systx.OTSConfirmedParams
+		verified bool
+		err      error
+	}{
+		{"matching header", &testOracle{tip: 800000}, nil, claim(799990, at(799990)), true, nil},
+		{"time within tolerance", &testOracle{tip: 800000}, nil, claim(799990, at(799990)+7200), true, nil},
+		{"height ahead of lagging tip", &testOracle{tip: 800000}, nil, claim(800006, at(800006)), false, nil},
+		{"height far beyond stale tip", &testOracle{tip: 800000}, nil, claim(801000, at(801000)), false, nil},
+		{"stale tip, proof agrees", &testOracle{tip: 800000}, confirmed(801000), claim(801000, at(801000)), true, nil},
+		{"time mismatch", &testOracle{tip: 800000}, nil, claim(799990, at(799990)+7201), false, ErrBTCClaimMismatch},
+		{"matching proof", nil, confirmed(799990), claim(799990, 0), true, nil},
+		{"proof within tolerance", nil, confirmed(799996), claim(799990, 0), true, nil},
+		{"proof height mismatch", nil, confirmed(799997), claim(799990, 0), false, ErrBTCClaimMismatch},
+		{"matching tx", nil, attestsTx, withTx(claim(799990, 0), txID), true, nil},
+		{"tx mismatch", nil, attestsTx, withTx(claim(799990, 0), "0x01"), false, ErrBTCClaimMismatch},
+		{"tx missing from claim", nil, attestsTx, claim(799990, 0), false, ErrBTCClaimMismatch},
+		{"oracle and proof agree", &testOracle{tip: 800000}, confirmed(799990), claim(799990, at(799990)), true, nil},
+		{"proof contradicts oracle", &testOracle{tip: 800000}, confirmed(799900), claim(799990, at(799990)), false, ErrBTCClaimMismatch},
+		{"no views", nil, nil, claim(799990, at(799990)), false, nil},
+		{"oracle unavailable", &testOracle{err: errUnavailable}, nil, claim(799990, at(799990)), false, nil},
+		{"client unavailable", nil, &testOTSClient{err: errUnavailable}, claim(799990, 0), false, nil},
+		{"proof pending", nil, &testOTSClient{result: &BTCConfirmationResult{}}, claim(799990, 0), false, nil},
+	}
+	for _, tt := range tests {
+		views := &claimViews{oracle: tt.oracle, client: tt.client, tolerance: DefaultConfirmationTolerance}
+		verified, err := views.verifyConfirmationClaim(&BatchState{RootHash: common.Hash{0x01}}, tt.params)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+		if verified != tt.verified {
+			t.Errorf("%s: verified = %v, want %v", tt.name, verified, tt.verified)
+		}
+	}
+}
+
+// lockProbeOracle reports whether the manager lock could be taken while
+// the oracle was queried
+type lockProbeOracle struct {
+	m        *OTSConsensusManager
+	lockable bool
+}
+
+func (o *lockProbeOracle) BTCTipHeight() (uint64, error) {
+	locked := make(chan struct{})
+	go func() {
+		o.m.mu.Lock()
+		o.m.mu.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		o.lockable = true
+	case <-time.After(time.Second):
+	}
+	return 800000, nil
+}
+
+func (o *lockProbeOracle) BTCBlockTime(height uint64) (uint64, error) {
+	return 1700000000, nil
+}
+
+func TestValidateOTSSystemTx_NoLockDuringLookup(t *testing.T) {
+	chain := newForkChain()
+	m := newForkManager(t, chain, nil)
+	oracle := &lockProbeOracle{m: m}
+	m.SetBTCHeaderOracle(oracle)
+
+	state := NewOTSState(true)
+	rootHash := common.Hash{0x01}
+	_ = state.Trigger(1, 1, 2, common.Address{}, rootHash)
+	_ = state.MarkSubmitted(rootHash, [32]byte{0x01}, 2, common.Address{})
+	parent := chain.headers[2]
+	if err := m.snapshots.StoreSnapshot(NewSnapshot(2, parent.Hash(), state)); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := systx.NewBuilder(copyrightRegistryAddr).BuildOTSConfirmedTx(&systx.OTSConfirmedParams{
+		RootHash:       rootHash,
+		BTCBlockHeight: 799990,
+		BTCTimestamp:   1700000000,
+	}, common.Address{}, 0, 1000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ValidateOTSSystemTx(tx, chain.headers[3]); err != nil {
+		t.Fatalf("ValidateOTSSystemTx failed: %v", err)
+	}
+	if !oracle.lockable {
+		t.Error("manager lock held while querying the Bitcoin oracle")
+	}
+}
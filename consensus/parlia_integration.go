@@ -13,6 +13,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/ots/merkle"
 	"github.com/ethereum/go-ethereum/ots/systx"
 )
 
@@ -30,6 +31,8 @@ type OTSConsensusManager struct {
 	enabled         bool
 	contractAddress common.Address
 	systemTxGasLimit uint64
+	triggerSchedule TriggerSchedule
+	fork            *ForkActivation
 
 	// Chain access functions (set during initialization)
 	getReceipts func(hash common.Hash, number uint64) types.Receipts
@@ -38,6 +41,17 @@ type OTSConsensusManager struct {
 
 	// OTS client for background operations (optional)
 	otsClient OTSClientInterface
+
+	// Bitcoin header view for checking otsConfirmed claims (optional)
+	btcOracle BTCHeaderOracle
+	tolerance ConfirmationTolerance
+
+	// Proof sidecar for publishing upgraded OTS proofs (optional)
+	proofSidecar ProofSidecar
+
+	// Snapshot rebuild job, if one is running, and the last one that ended
+	rebuild     *rebuildJob
+	lastRebuild *rebuildJob
 }
 
 // OTSClientInterface defines the interface for OTS client operations
@@ -54,6 +68,14 @@ type BTCConfirmationResult struct {
 	BTCBlockHeight uint64
 	BTCTxID        string
 	BTCTimestamp   uint64
+	// Proof is the upgraded .ots proof, if the client provides it
+	Proof []byte
+}
+
+// ProofSidecar stores upgraded OTS proofs under their keccak256 hash, so
+// peers can fetch the proof an otsConfirmed transaction commits to
+type ProofSidecar interface {
+	SaveProofSidecar(proof []byte) (common.Hash, error)
 }
 
 // OTSManagerConfig contains configuration for OTS consensus manager
@@ -62,14 +84,46 @@ type OTSManagerConfig struct {
 	ContractAddress  common.Address
 	SystemTxGasLimit uint64
 	DataDir          string
+
+	// TriggerSchedule is the batch trigger policy from the chain config.
+	// Nil selects DefaultTriggerSchedule.
+	TriggerSchedule TriggerSchedule
+
+	// ConfirmationTolerance bounds otsConfirmed claims against the local
+	// Bitcoin view. The zero value selects DefaultConfirmationTolerance.
+	ConfirmationTolerance ConfirmationTolerance
+
+	// Fork is the OTS activation fork from the chain config. Nil applies OTS
+	// from genesis.
+	Fork *ForkActivation
 }
 
 // NewOTSConsensusManager creates a new OTS consensus manager
 func NewOTSConsensusManager(db ethdb.Database, config *OTSManagerConfig) (*OTSConsensusManager, error) {
+	schedule := config.TriggerSchedule
+	if schedule == nil {
+		schedule = DefaultTriggerSchedule
+	}
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+
+	if config.Fork != nil {
+		if err := config.Fork.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	tolerance := config.ConfirmationTolerance
+	if tolerance == (ConfirmationTolerance{}) {
+		tolerance = DefaultConfirmationTolerance
+	}
+
 	snapshots, err := NewSnapshotManager(db, config.Enabled)
 	if err != nil {
 		return nil, err
 	}
+	snapshots.SetForkActivation(config.Fork)
 
 	manager := &OTSConsensusManager{
 		db:               db,
@@ -77,6 +131,9 @@ func NewOTSConsensusManager(db ethdb.Database, config *OTSManagerConfig) (*OTSCo
 		enabled:          config.Enabled,
 		contractAddress:  config.ContractAddress,
 		systemTxGasLimit: config.SystemTxGasLimit,
+		triggerSchedule:  schedule,
+		fork:             config.Fork,
+		tolerance:        tolerance,
 		txBuilder:        systx.NewBuilder(config.ContractAddress),
 	}
 
@@ -98,6 +155,11 @@ func (m *OTSConsensusManager) SetChainAccessors(
 
 	// Create transition engine with chain accessors
 	m.engine = NewTransitionEngine(m.snapshots, getReceipts, getHeader)
+	if m.contractAddress != (common.Address{}) {
+		m.engine.SetRegistryAddress(m.contractAddress)
+	}
+	m.engine.SetTriggerSchedule(m.triggerSchedule)
+	m.engine.SetForkActivation(m.fork)
 }
 
 // SetOTSClient sets the OTS client for background operations
@@ -107,11 +169,68 @@ func (m *OTSConsensusManager) SetOTSClient(client OTSClientInterface) {
 	m.otsClient = client
 }
 
+// SetBTCHeaderOracle sets the Bitcoin header view used to check otsConfirmed
+// claims. Without one, claims are only checked against the OTS client.
+func (m *OTSConsensusManager) SetBTCHeaderOracle(oracle BTCHeaderOracle) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.btcOracle = oracle
+}
+
+// SetProofSidecar sets the store that upgraded proofs are published to.
+// Without one, otsConfirmed transactions do not commit to a proof.
+func (m *OTSConsensusManager) SetProofSidecar(sidecar ProofSidecar) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.proofSidecar = sidecar
+}
+
 // IsEnabled returns whether OTS is enabled
 func (m *OTSConsensusManager) IsEnabled() bool {
 	return m.enabled
 }
 
+// IsActive returns whether OTS applies to the given block, that is OTS is
+// enabled and the block is past the activation fork
+func (m *OTSConsensusManager) IsActive(header *types.Header) bool {
+	return m.enabled && m.fork.IsActive(header.Number.Uint64(), header.Time)
+}
+
+// parentSnapshot returns the snapshot of parentHash, which the given block
+// builds on. The parent of the first OTS block has none, its state is the
+// base state. The caller must hold m.mu.
+func (m *OTSConsensusManager) parentSnapshot(header *types.Header, parentHash common.Hash) (*Snapshot, error) {
+	snap, err := m.snapshots.GetSnapshot(parentHash)
+	if err == nil {
+		return snap, nil
+	}
+	if !m.activatesAt(header) {
+		return nil, err
+	}
+	return m.snapshots.BaseSnapshot(header.Number.Uint64()-1, parentHash), nil
+}
+
+// activatesAt reports whether header is the first block OTS applies to. The
+// caller must hold m.mu.
+func (m *OTSConsensusManager) activatesAt(header *types.Header) bool {
+	number := header.Number.Uint64()
+	switch {
+	case number == 0:
+		return false
+	case number == 1:
+		// Genesis is never processed
+		return true
+	case m.fork == nil:
+		return false
+	case m.fork.Time == nil:
+		return number == m.fork.Block
+	case m.getHeader == nil:
+		return false
+	}
+	parent := m.getHeader(header.ParentHash, number-1)
+	return parent != nil && !m.fork.IsActive(parent.Number.Uint64(), parent.Time)
+}
+
 // GetSnapshot returns the OTS snapshot for a given block
 func (m *OTSConsensusManager) GetSnapshot(hash common.Hash) (*Snapshot, error) {
 	return m.snapshots.GetSnapshot(hash)
@@ -132,19 +251,15 @@ func (m *OTSConsensusManager) ProcessBlock(header *types.Header, parentHash comm
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if !m.enabled || m.engine == nil {
+	// Blocks before the activation fork have no snapshots
+	if !m.IsActive(header) || m.engine == nil {
 		return nil, nil
 	}
 
 	// Get parent snapshot
-	parentSnap, err := m.snapshots.GetSnapshot(parentHash)
+	parentSnap, err := m.parentSnapshot(header, parentHash)
 	if err != nil {
-		// If no parent snapshot, try to get genesis
-		if header.Number.Uint64() == 1 {
-			parentSnap = m.snapshots.GetGenesisSnapshot(parentHash)
-		} else {
-			return nil, err
-		}
+		return nil, err
 	}
 
 	// Process the block
@@ -162,7 +277,7 @@ func (m *OTSConsensusManager) GetSystemTransactions(
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	if !m.enabled {
+	if !m.IsActive(header) {
 		return nil, nil
 	}
 
@@ -174,46 +289,48 @@ func (m *OTSConsensusManager) GetSystemTransactions(
 	}
 
 	state := snap.State
-	if state == nil || state.CurrentBatch == nil {
+	if state == nil || !state.HasActiveBatch() {
 		return nil, nil
 	}
 
 	var txs []*types.Transaction
 	nonce := getNonce(coinbase)
 
-	switch state.CurrentBatch.Status {
-	case BatchStatusTriggered:
-		// Any validator with otsClient can submit to OTS calendar
-		// The first one to successfully submit and include OTSSubmitted tx wins
-		// Duplicate OTS submissions are harmless (same digest goes to same calendar entry)
-		// Consensus layer validates that rootHash matches, regardless of who submitted
-		if m.otsClient != nil {
-			tx, err := m.tryBuildOTSSubmittedTx(state, coinbase, nonce)
-			if err != nil {
-				log.Debug("OTS: Failed to build otsSubmitted tx", "err", err)
-			} else if tx != nil {
-				txs = append(txs, tx)
+	// Each in-flight batch advances independently, oldest first
+	for _, batch := range state.Batches {
+		var (
+			tx  *types.Transaction
+			err error
+		)
+		switch batch.Status {
+		case BatchStatusTriggered:
+			// Any validator with otsClient can submit to OTS calendar
+			// The first one to successfully submit and include OTSSubmitted tx wins
+			// Duplicate OTS submissions are harmless (same digest goes to same calendar entry)
+			// Consensus layer validates that rootHash matches, regardless of who submitted
+			if m.otsClient != nil {
+				if tx, err = m.tryBuildOTSSubmittedTx(batch, coinbase, nonce); err != nil {
+					log.Debug("OTS: Failed to build otsSubmitted tx", "rootHash", batch.RootHash.Hex(), "err", err)
+				}
 			}
-		}
 
-	case BatchStatusSubmitted:
-		// We need to check for BTC confirmation
-		if m.otsClient != nil {
-			tx, err := m.tryBuildOTSConfirmedTx(state, coinbase, nonce)
-			if err != nil {
-				log.Debug("OTS: Failed to build otsConfirmed tx", "err", err)
-			} else if tx != nil {
-				txs = append(txs, tx)
+		case BatchStatusSubmitted:
+			// We need to check for BTC confirmation
+			if m.otsClient != nil {
+				if tx, err = m.tryBuildOTSConfirmedTx(batch, coinbase, nonce); err != nil {
+					log.Debug("OTS: Failed to build otsConfirmed tx", "rootHash", batch.RootHash.Hex(), "err", err)
+				}
 			}
-		}
 
-	case BatchStatusConfirmed:
-		// We need to anchor on-chain
-		tx, err := m.buildAnchorTx(state, coinbase, nonce)
-		if err != nil {
-			log.Debug("OTS: Failed to build anchor tx", "err", err)
-		} else if tx != nil {
+		case BatchStatusConfirmed:
+			// We need to anchor on-chain
+			if tx, err = m.buildAnchorTx(batch, coinbase, nonce); err != nil {
+				log.Debug("OTS: Failed to build anchor tx", "rootHash", batch.RootHash.Hex(), "err", err)
+			}
+		}
+		if err == nil && tx != nil {
 			txs = append(txs, tx)
+			nonce++
 		}
 	}
 
@@ -221,20 +338,20 @@ func (m *OTSConsensusManager) GetSystemTransactions(
 }
 
 // tryBuildOTSSubmittedTx attempts to submit to OTS and build the submission tx
-func (m *OTSConsensusManager) tryBuildOTSSubmittedTx(state *OTSState, coinbase common.Address, nonce uint64) (*types.Transaction, error) {
-	if state.CurrentBatch == nil || state.CurrentBatch.Status != BatchStatusTriggered {
+func (m *OTSConsensusManager) tryBuildOTSSubmittedTx(batch *BatchState, coinbase common.Address, nonce uint64) (*types.Transaction, error) {
+	if batch.Status != BatchStatusTriggered {
 		return nil, nil
 	}
 
 	// Submit to OTS calendar
-	_, digest, err := m.otsClient.Stamp(state.CurrentBatch.RootHash)
+	_, digest, err := m.otsClient.Stamp(batch.RootHash)
 	if err != nil {
 		return nil, err
 	}
 
 	// Build otsSubmitted transaction
 	params := &systx.OTSSubmittedParams{
-		RootHash:  state.CurrentBatch.RootHash,
+		RootHash:  batch.RootHash,
 		OTSDigest: digest,
 	}
 
@@ -242,42 +359,46 @@ func (m *OTSConsensusManager) tryBuildOTSSubmittedTx(state *OTSState, coinbase c
 }
 
 // tryBuildOTSConfirmedTx checks for BTC confirmation and builds the confirmation tx
-func (m *OTSConsensusManager) tryBuildOTSConfirmedTx(state *OTSState, coinbase common.Address, nonce uint64) (*types.Transaction, error) {
-	if state.CurrentBatch == nil || state.CurrentBatch.Status != BatchStatusSubmitted {
+func (m *OTSConsensusManager) tryBuildOTSConfirmedTx(batch *BatchState, coinbase common.Address, nonce uint64) (*types.Transaction, error) {
+	if batch.Status != BatchStatusSubmitted {
 		return nil, nil
 	}
 
 	// Check for BTC confirmation
-	result, err := m.otsClient.CheckConfirmation(state.CurrentBatch.OTSDigest)
+	result, err := m.otsClient.CheckConfirmation(batch.OTSDigest)
 	if err != nil || !result.Confirmed {
 		return nil, err
 	}
 
-	// Convert BTCTxID to bytes32
-	var btcTxID [32]byte
-	copy(btcTxID[:], []byte(result.BTCTxID))
-
 	// Build otsConfirmed transaction
 	params := &systx.OTSConfirmedParams{
-		RootHash:       state.CurrentBatch.RootHash,
+		RootHash:       batch.RootHash,
 		BTCBlockHeight: result.BTCBlockHeight,
-		BTCTxID:        btcTxID,
+		BTCTxID:        systx.BTCTxIDToBytes32(result.BTCTxID),
 		BTCTimestamp:   result.BTCTimestamp,
 	}
 
+	// Commit to the proof once it is published for peers to fetch
+	if len(result.Proof) > 0 && m.proofSidecar != nil {
+		proofHash, err := m.proofSidecar.SaveProofSidecar(result.Proof)
+		if err != nil {
+			log.Warn("OTS: Failed to publish proof sidecar", "rootHash", batch.RootHash.Hex(), "err", err)
+		} else {
+			params.ProofHash = proofHash
+		}
+	}
+
 	return m.txBuilder.BuildOTSConfirmedTx(params, coinbase, nonce, m.systemTxGasLimit)
 }
 
 // buildAnchorTx builds the final anchor transaction
-func (m *OTSConsensusManager) buildAnchorTx(state *OTSState, coinbase common.Address, nonce uint64) (*types.Transaction, error) {
-	if state.CurrentBatch == nil || state.CurrentBatch.Status != BatchStatusConfirmed {
+func (m *OTSConsensusManager) buildAnchorTx(batch *BatchState, coinbase common.Address, nonce uint64) (*types.Transaction, error) {
+	if batch.Status != BatchStatusConfirmed {
 		return nil, nil
 	}
 
-	batch := state.CurrentBatch
-
 	// Get RUIDs for the batch (we need to collect them from chain)
-	ruids := m.collectRUIDsForBatch(batch.StartBlock, batch.EndBlock)
+	ruids := m.collectRUIDsForBatch(batch.StartBlock, batch.EndBlock, batch.MerkleScheme)
 
 	// Build candidate batch for anchor
 	candidate := &systx.CandidateBatch{
@@ -293,8 +414,9 @@ func (m *OTSConsensusManager) buildAnchorTx(state *OTSState, coinbase common.Add
 	return m.txBuilder.BuildAnchorTx(candidate, coinbase, nonce, m.systemTxGasLimit)
 }
 
-// collectRUIDsForBatch collects RUIDs from chain events
-func (m *OTSConsensusManager) collectRUIDsForBatch(startBlock, endBlock uint64) []common.Hash {
+// collectRUIDsForBatch collects RUIDs from chain events, in the leaf order of
+// the batch Merkle tree built with scheme
+func (m *OTSConsensusManager) collectRUIDsForBatch(startBlock, endBlock uint64, scheme merkle.Scheme) []common.Hash {
 	var ruids []common.Hash
 
 	for blockNum := startBlock; blockNum <= endBlock; blockNum++ {
@@ -309,31 +431,25 @@ func (m *OTSConsensusManager) collectRUIDsForBatch(startBlock, endBlock uint64)
 		}
 
 		// Extract RUIDs from CopyrightClaimed events
-		for _, receipt := range receipts {
-			for _, log := range receipt.Logs {
-				if log.Address == common.HexToAddress(CopyrightRegistryAddress) {
-					if len(log.Topics) >= 2 && log.Topics[0] == CopyrightClaimedEventSig {
-						ruids = append(ruids, log.Topics[1]) // RUID is indexed
-					}
-				}
-			}
-		}
+		ruids = append(ruids, ruidsFromReceipts(receipts, m.engine.registry)...)
 	}
 
-	return ruids
+	return scheme.LeafRUIDs(ruids)
 }
 
-// ValidateOTSSystemTx validates an OTS system transaction
-func (m *OTSConsensusManager) ValidateOTSSystemTx(tx *types.Transaction, parentHash common.Hash) error {
+// ValidateOTSSystemTx validates an OTS system transaction included in the
+// block with the given header
+func (m *OTSConsensusManager) ValidateOTSSystemTx(tx *types.Transaction, header *types.Header) error {
+	// Checking a confirmation may query explorers, which must not block the
+	// manager, so only the state and views are read under the lock
 	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	if !m.enabled {
+	if !m.IsActive(header) {
+		m.mu.RUnlock()
 		return nil
 	}
-
-	// Get current state
-	snap, err := m.snapshots.GetSnapshot(parentHash)
+	snap, err := m.parentSnapshot(header, header.ParentHash)
+	views := m.claimViews()
+	m.mu.RUnlock()
 	if err != nil {
 		return err
 	}
@@ -343,132 +459,82 @@ func (m *OTSConsensusManager) ValidateOTSSystemTx(tx *types.Transaction, parentH
 		return ErrInvalidState
 	}
 
-	// Validate based on transaction type
-	if systx.IsOTSSubmittedTx(tx) {
-		return m.validateOTSSubmittedTx(tx, state)
+	// Decode with the checks the system transaction validator applies
+	if !systx.IsOTSSystemTx(tx) {
+		return nil
 	}
-
-	if systx.IsOTSConfirmedTx(tx) {
-		return m.validateOTSConfirmedTx(tx, state)
+	call, err := systx.DecodeSystemCall(tx.Data())
+	if err != nil {
+		return err
 	}
 
-	if systx.IsAnchorTx(tx) {
-		return m.validateAnchorTx(tx, state)
+	switch call.Method {
+	case systx.MethodOTSSubmitted:
+		return m.validateOTSSubmittedTx(call.Submitted, state)
+	case systx.MethodOTSConfirmed:
+		return m.validateOTSConfirmedTx(call.Confirmed, state, views)
+	case systx.MethodAnchor:
+		return validateAnchorEntry(call.Anchor, state)
+	case systx.MethodAnchorBatch:
+		return m.validateAnchorBatchTx(call.AnchorBatch, state)
 	}
-
 	return nil
 }
 
 // validateOTSSubmittedTx validates an otsSubmitted transaction
-func (m *OTSConsensusManager) validateOTSSubmittedTx(tx *types.Transaction, state *OTSState) error {
-	// Must have active batch in Triggered status
-	if state.CurrentBatch == nil || state.CurrentBatch.Status != BatchStatusTriggered {
-		return ErrInvalidTransition
-	}
-
-	// Decode and verify rootHash matches
-	params, err := systx.DecodeOTSSubmittedTx(tx)
-	if err != nil {
-		return err
-	}
-
-	if params.RootHash != state.CurrentBatch.RootHash {
-		return ErrInvalidState
-	}
-
-	return nil
+func (m *OTSConsensusManager) validateOTSSubmittedTx(params *systx.OTSSubmittedParams, state *OTSState) error {
+	// Must address an in-flight batch in Triggered status
+	_, err := state.batchInStatus(params.RootHash, BatchStatusTriggered, ErrInvalidTransition)
+	return err
 }
 
 // validateOTSConfirmedTx validates an otsConfirmed transaction
-func (m *OTSConsensusManager) validateOTSConfirmedTx(tx *types.Transaction, state *OTSState) error {
-	// Must have active batch in Submitted status
-	if state.CurrentBatch == nil || state.CurrentBatch.Status != BatchStatusSubmitted {
-		return ErrInvalidTransition
-	}
-
-	// Decode and verify rootHash matches
-	params, err := systx.DecodeOTSConfirmedTx(tx)
+func (m *OTSConsensusManager) validateOTSConfirmedTx(params *systx.OTSConfirmedParams, state *OTSState, views *claimViews) error {
+	// Must address an in-flight batch in Submitted status
+	batch, err := state.batchInStatus(params.RootHash, BatchStatusSubmitted, ErrInvalidTransition)
 	if err != nil {
 		return err
 	}
 
-	if params.RootHash != state.CurrentBatch.RootHash {
-		return ErrInvalidState
-	}
-
-	return nil
+	// The claimed Bitcoin block must agree with our view of Bitcoin
+	return views.checkConfirmationClaim(batch, params)
 }
 
-// validateAnchorTx validates an anchor transaction
-func (m *OTSConsensusManager) validateAnchorTx(tx *types.Transaction, state *OTSState) error {
-	// Must have active batch in Confirmed status
-	if state.CurrentBatch == nil || state.CurrentBatch.Status != BatchStatusConfirmed {
-		return ErrInvalidTransition
-	}
-
-	// Decode and verify rootHash matches
-	decoded, err := systx.DecodeCalldata(tx.Data())
-	if err != nil {
-		return err
-	}
-
-	// Verify rootHash
-	if decoded.RootHash != state.CurrentBatch.RootHash {
-		return ErrInvalidState
-	}
-
-	// Verify block range
-	if decoded.StartBlock != state.CurrentBatch.StartBlock || decoded.EndBlock != state.CurrentBatch.EndBlock {
-		return ErrInvalidState
+// validateAnchorBatchTx validates an anchorBatch transaction. Every entry
+// must anchor a Confirmed batch; the decoder checked that they are in block
+// order like the builder packs them.
+func (m *OTSConsensusManager) validateAnchorBatchTx(entries []*systx.DecodedAnchorCalldata, state *OTSState) error {
+	for _, entry := range entries {
+		if err := validateAnchorEntry(entry, state); err != nil {
+			return err
+		}
 	}
-
-	// Note: BTCBlockHeight is not included in anchor calldata, it's stored in consensus state
-	// from the otsConfirmed transaction
-
 	return nil
 }
 
-// RebuildFromChain rebuilds OTS state from chain data
-func (m *OTSConsensusManager) RebuildFromChain(fromBlock, toBlock uint64) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	if m.engine == nil || m.getHeaderByNumber == nil {
-		return ErrInvalidState
+// validateAnchorEntry checks that an anchored root and block range name an
+// in-flight batch in Confirmed status
+func validateAnchorEntry(decoded *systx.DecodedAnchorCalldata, state *OTSState) error {
+	// Must address an in-flight batch, matched by range as empty batches
+	// share a root hash
+	if state.Batch(decoded.RootHash) == nil {
+		return ErrBatchNotFound
 	}
-
-	// Find nearest snapshot
-	fromHash := common.Hash{}
-	if fromBlock > 0 {
-		header := m.getHeaderByNumber(fromBlock)
-		if header != nil {
-			fromHash = header.Hash()
+	for _, batch := range state.Batches {
+		if batch.RootHash != decoded.RootHash || batch.StartBlock != decoded.StartBlock || batch.EndBlock != decoded.EndBlock {
+			continue
 		}
-	}
-
-	snap, err := m.snapshots.FindNearestSnapshot(fromBlock, func(num uint64) common.Hash {
-		header := m.getHeaderByNumber(num)
-		if header != nil {
-			return header.Hash()
+		if batch.Status != BatchStatusConfirmed {
+			return ErrInvalidTransition
 		}
-		return common.Hash{}
-	})
-	if err != nil {
-		// Start from genesis
-		snap = m.snapshots.GetGenesisSnapshot(fromHash)
-	}
-
-	// Rebuild state
-	newSnap, err := m.engine.RebuildState(snap, toBlock, m.getHeaderByNumber)
-	if err != nil {
-		return err
+		return nil
 	}
 
-	// Force store the final snapshot
-	return m.snapshots.ForceStore(newSnap)
+	// Verify block range
+	return ErrInvalidState
 }
 
-// GetBatchState returns the current batch state for RPC queries
+// GetBatchState returns the oldest in-flight batch for RPC queries
 func (m *OTSConsensusManager) GetBatchState(blockHash common.Hash) *BatchState {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -477,7 +543,19 @@ func (m *OTSConsensusManager) GetBatchState(blockHash common.Hash) *BatchState {
 	if err != nil || snap.State == nil {
 		return nil
 	}
-	return snap.State.CurrentBatch
+	return snap.State.OldestBatch()
+}
+
+// GetBatches returns all in-flight batches in trigger order for RPC queries
+func (m *OTSConsensusManager) GetBatches(blockHash common.Hash) []*BatchState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snap, err := m.snapshots.GetSnapshot(blockHash)
+	if err != nil || snap.State == nil {
+		return nil
+	}
+	return snap.State.Batches
 }
 
 // GetStats returns OTS consensus statistics
@@ -491,14 +569,23 @@ func (m *OTSConsensusManager) GetStats(blockHash common.Hash) map[string]interfa
 	snap, err := m.snapshots.GetSnapshot(blockHash)
 	if err == nil && snap.State != nil {
 		stats["lastAnchoredBlock"] = snap.State.LastAnchoredBlock
-		if snap.State.CurrentBatch != nil {
-			stats["currentBatch"] = map[string]interface{}{
-				"startBlock":   snap.State.CurrentBatch.StartBlock,
-				"endBlock":     snap.State.CurrentBatch.EndBlock,
-				"status":       snap.State.CurrentBatch.Status.String(),
-				"rootHash":     snap.State.CurrentBatch.RootHash.Hex(),
-				"triggerBlock": snap.State.CurrentBatch.TriggerBlock,
+		batches := make([]map[string]interface{}, 0, len(snap.State.Batches))
+		for _, batch := range snap.State.Batches {
+			batches = append(batches, map[string]interface{}{
+				"startBlock":   batch.StartBlock,
+				"endBlock":     batch.EndBlock,
+				"status":       batch.Status.String(),
+				"rootHash":     batch.RootHash.Hex(),
+				"triggerBlock": batch.TriggerBlock,
+			})
+		}
+		stats["batches"] = batches
+		if len(snap.State.Expired) > 0 {
+			expired := make([]string, len(snap.State.Expired))
+			for i, batch := range snap.State.Expired {
+				expired[i] = batch.RootHash.Hex()
 			}
+			stats["expired"] = expired
 		}
 	}
 
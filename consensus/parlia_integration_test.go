@@ -0,0 +1,112 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ots/systx"
+	otstypes "github.com/ethereum/go-ethereum/ots/types"
+)
+
+func TestValidateOTSSystemTx_AnchorBatch(t *testing.T) {
+	chain := newForkChain()
+	m, err := NewOTSConsensusManager(rawdb.NewMemoryDatabase(), &OTSManagerConfig{Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetChainAccessors(chain.getReceipts, chain.getHeader, chain.getHeaderByNumber)
+
+	// Blocks 1-10 and 21-30 are confirmed, 11-20 only submitted
+	node := common.Address{}
+	rootA, rootB, rootC := common.Hash{0x0a}, common.Hash{0x0b}, common.Hash{0x0c}
+	state := NewOTSState(true)
+	_ = state.Trigger(1, 10, 11, node, rootA)
+	_ = state.Trigger(11, 20, 21, node, rootB)
+	_ = state.Trigger(21, 30, 31, node, rootC)
+	for i, root := range []common.Hash{rootA, rootB, rootC} {
+		_ = state.MarkSubmitted(root, [32]byte{byte(i + 1)}, 32, node)
+	}
+	_ = state.MarkConfirmed(rootA, 800000, "txa", 1700000000, common.Hash{}, 33, node)
+	_ = state.MarkConfirmed(rootC, 800000, "txc", 1700000000, common.Hash{}, 33, node)
+	parent := chain.headers[33]
+	if err := m.snapshots.StoreSnapshot(NewSnapshot(33, parent.Hash(), state)); err != nil {
+		t.Fatal(err)
+	}
+
+	entry := func(start, end uint64, root common.Hash) *otstypes.CandidateBatch {
+		return &otstypes.CandidateBatch{BatchMeta: &otstypes.BatchMeta{StartBlock: start, EndBlock: end, RootHash: root}}
+	}
+	tests := []struct {
+		name    string
+		entries []*otstypes.CandidateBatch
+		err     error
+	}{
+		{"confirmed batches", []*otstypes.CandidateBatch{entry(1, 10, rootA), entry(21, 30, rootC)}, nil},
+		{"unconfirmed batch", []*otstypes.CandidateBatch{entry(1, 10, rootA), entry(11, 20, rootB)}, ErrInvalidTransition},
+		{"wrong range", []*otstypes.CandidateBatch{entry(1, 9, rootA)}, ErrInvalidState},
+		{"unknown root", []*otstypes.CandidateBatch{entry(1, 10, common.Hash{0xff})}, ErrBatchNotFound},
+	}
+	builder := systx.NewBuilder(copyrightRegistryAddr)
+	for _, tt := range tests {
+		tx, err := builder.BuildAnchorBatchTx(tt.entries, node, 0, 1000000)
+		if err != nil {
+			t.Fatalf("%s: BuildAnchorBatchTx failed: %v", tt.name, err)
+		}
+		if err := m.ValidateOTSSystemTx(tx, chain.headers[34]); !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestValidateOTSSystemTx_StrictCalldata(t *testing.T) {
+	chain := newForkChain()
+	m, err := NewOTSConsensusManager(rawdb.NewMemoryDatabase(), &OTSManagerConfig{Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetChainAccessors(chain.getReceipts, chain.getHeader, chain.getHeaderByNumber)
+
+	// Blocks 1-10 are an empty batch, 11-20 hold claims
+	node := common.Address{}
+	root := common.Hash{0x0a}
+	state := NewOTSState(true)
+	_ = state.Trigger(1, 10, 11, node, common.Hash{})
+	_ = state.Trigger(11, 20, 21, node, root)
+	parent := chain.headers[33]
+	if err := m.snapshots.StoreSnapshot(NewSnapshot(33, parent.Hash(), state)); err != nil {
+		t.Fatal(err)
+	}
+
+	submitted := func(params *systx.OTSSubmittedParams) *types.Transaction {
+		tx, err := systx.NewBuilder(copyrightRegistryAddr).BuildOTSSubmittedTx(params, node, 0, 1000000)
+		if err != nil {
+			t.Fatalf("BuildOTSSubmittedTx failed: %v", err)
+		}
+		return tx
+	}
+	valid := submitted(&systx.OTSSubmittedParams{RootHash: root, OTSDigest: root})
+	trailing := types.NewTransaction(0, copyrightRegistryAddr, big.NewInt(0), 1000000, big.NewInt(0), append(common.CopyBytes(valid.Data()), root[:]...))
+
+	tests := []struct {
+		name string
+		tx   *types.Transaction
+		err  error
+	}{
+		{"submitted", valid, nil},
+		{"empty batch submitted", submitted(&systx.OTSSubmittedParams{}), nil},
+		{"trailing data", trailing, systx.ErrCalldataLength},
+		{"zero digest", submitted(&systx.OTSSubmittedParams{RootHash: root}), systx.ErrInvalidArgument},
+	}
+	for _, tt := range tests {
+		if err := m.ValidateOTSSystemTx(tt.tx, chain.headers[34]); !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
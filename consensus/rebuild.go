@@ -0,0 +1,316 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+//
+// This file implements the OTS snapshot rebuild job, which regenerates
+// snapshots by replaying canonical headers and receipts. It lets a node with
+// corrupted snapshots recover without a full chain resync.
+//
+// The job writes a progress marker at every checkpoint. A rebuild of the same
+// range started after an interruption continues from the marker.
+
+package consensus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	otsmetrics "github.com/ethereum/go-ethereum/ots/metrics"
+)
+
+// rebuildCommitInterval is the block interval at which the rebuild persists
+// its progress marker
+const rebuildCommitInterval = snapshotCheckpointInterval
+
+var (
+	ErrRebuildRunning      = errors.New("OTS snapshot rebuild already running")
+	ErrRebuildNotRunning   = errors.New("no OTS snapshot rebuild running")
+	ErrRebuildStopped      = errors.New("OTS snapshot rebuild stopped")
+	ErrInvalidRebuildRange = errors.New("invalid OTS snapshot rebuild range")
+	ErrRebuildReorg        = errors.New("chain reorganised during OTS snapshot rebuild")
+
+	// rebuildProgressKey holds the progress marker of an unfinished rebuild
+	rebuildProgressKey = []byte("ots-rebuild-progress")
+)
+
+// RebuildProgress is the progress of a snapshot rebuild
+type RebuildProgress struct {
+	From   uint64      `json:"from"`   // First block requested
+	To     uint64      `json:"to"`     // Last block to rebuild
+	Number uint64      `json:"number"` // Last block whose snapshot is rebuilt
+	Hash   common.Hash `json:"hash"`   // Hash of that block
+
+	// Running and Error describe the job in this process and are not persisted
+	Running bool   `json:"-"`
+	Error   string `json:"-"`
+}
+
+// rebuildJob is a running snapshot rebuild
+type rebuildJob struct {
+	quit     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+
+	// progress is updated by the job goroutine after every block
+	mu       sync.Mutex
+	progress RebuildProgress
+
+	// err is the result of the job, set before done is closed
+	err error
+}
+
+// stop asks the job to stop after the current block
+func (job *rebuildJob) stop() {
+	job.stopOnce.Do(func() { close(job.quit) })
+}
+
+// RebuildFromChain regenerates the snapshots of the canonical blocks up to
+// toBlock, replaying from the nearest stored snapshot at or before fromBlock,
+// and blocks until done. It is the entry point for one-shot re-sync commands.
+func (m *OTSConsensusManager) RebuildFromChain(fromBlock, toBlock uint64) error {
+	job, err := m.startRebuild(fromBlock, toBlock)
+	if err != nil {
+		return err
+	}
+	<-job.done
+	return job.err
+}
+
+// StartRebuild starts regenerating the snapshots of fromBlock..toBlock in the
+// background, as RebuildFromChain does
+func (m *OTSConsensusManager) StartRebuild(fromBlock, toBlock uint64) error {
+	_, err := m.startRebuild(fromBlock, toBlock)
+	return err
+}
+
+// StopRebuild stops the running rebuild and waits for it to persist its
+// progress. Starting a rebuild of the same range later resumes it.
+func (m *OTSConsensusManager) StopRebuild() error {
+	m.mu.RLock()
+	job := m.rebuild
+	m.mu.RUnlock()
+
+	if job == nil {
+		return ErrRebuildNotRunning
+	}
+	job.stop()
+	<-job.done
+	return nil
+}
+
+// RebuildStatus returns the progress of the running rebuild or, if none is
+// running, of the unfinished rebuild recorded in the database. It returns nil
+// if there is neither.
+func (m *OTSConsensusManager) RebuildStatus() *RebuildProgress {
+	m.mu.RLock()
+	job, last := m.rebuild, m.lastRebuild
+	m.mu.RUnlock()
+
+	if job != nil {
+		job.mu.Lock()
+		progress := job.progress
+		job.mu.Unlock()
+		progress.Running = true
+		return &progress
+	}
+
+	progress := m.readRebuildProgress()
+	if progress == nil {
+		return nil
+	}
+	if last != nil && last.err != nil && !errors.Is(last.err, ErrRebuildStopped) && last.progress.From == progress.From && last.progress.To == progress.To {
+		progress.Error = last.err.Error()
+	}
+	return progress
+}
+
+// startRebuild validates the request and starts the job goroutine
+func (m *OTSConsensusManager) startRebuild(fromBlock, toBlock uint64) (*rebuildJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.engine == nil || m.getHeaderByNumber == nil {
+		return nil, ErrInvalidState
+	}
+	if fromBlock > toBlock {
+		return nil, ErrInvalidRebuildRange
+	}
+	if m.rebuild != nil {
+		return nil, ErrRebuildRunning
+	}
+
+	snap, err := m.rebuildBase(fromBlock, toBlock)
+	if err != nil {
+		return nil, err
+	}
+	job := &rebuildJob{
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+		progress: RebuildProgress{
+			From:   fromBlock,
+			To:     toBlock,
+			Number: snap.Number,
+			Hash:   snap.Hash,
+		},
+	}
+	if err := m.writeRebuildProgress(&job.progress); err != nil {
+		return nil, err
+	}
+	m.rebuild = job
+
+	log.Info("OTS: Starting snapshot rebuild", "from", fromBlock, "to", toBlock, "base", snap.Number)
+	go m.runRebuild(job, m.engine, m.getHeaderByNumber, snap)
+	return job, nil
+}
+
+// rebuildBase returns the snapshot a rebuild of fromBlock..toBlock starts
+// from: the snapshot of its progress marker if it was interrupted and the
+// marked block is still canonical, else the nearest stored snapshot. The
+// caller must hold m.mu.
+func (m *OTSConsensusManager) rebuildBase(fromBlock, toBlock uint64) (*Snapshot, error) {
+	if progress := m.readRebuildProgress(); progress != nil && progress.From == fromBlock && progress.To == toBlock {
+		if m.canonicalHash(progress.Number) == progress.Hash {
+			if snap, err := m.snapshots.GetSnapshot(progress.Hash); err == nil {
+				log.Info("OTS: Resuming snapshot rebuild", "from", fromBlock, "to", toBlock, "number", progress.Number)
+				return snap, nil
+			}
+		}
+		log.Warn("OTS: Discarding stale snapshot rebuild marker", "number", progress.Number, "hash", progress.Hash.Hex())
+	}
+	return m.snapshots.FindNearestSnapshot(fromBlock, m.canonicalHash)
+}
+
+// canonicalHash returns the hash of the canonical block with the given
+// number, or the zero hash if it is unknown. The caller must hold m.mu.
+func (m *OTSConsensusManager) canonicalHash(number uint64) common.Hash {
+	if header := m.getHeaderByNumber(number); header != nil {
+		return header.Hash()
+	}
+	return common.Hash{}
+}
+
+// runRebuild replays the blocks after snap up to the job's target. It does
+// not hold m.mu, so block processing continues while it runs.
+func (m *OTSConsensusManager) runRebuild(job *rebuildJob, engine *TransitionEngine, getHeader func(uint64) *types.Header, snap *Snapshot) {
+	defer close(job.done)
+
+	to := job.progress.To
+	otsmetrics.UpdateRebuildTarget(to)
+
+	start, base := time.Now(), snap.Number
+	committed := snap.Number
+	err := func() error {
+		for snap.Number < to {
+			select {
+			case <-job.quit:
+				return ErrRebuildStopped
+			default:
+			}
+
+			number := snap.Number + 1
+			header := getHeader(number)
+			if header == nil {
+				return fmt.Errorf("%w: header %d", errMissingChainData, number)
+			}
+			if header.ParentHash != snap.Hash {
+				return fmt.Errorf("%w: block %d does not extend %s", ErrRebuildReorg, number, snap.Hash.Hex())
+			}
+			next, err := engine.ProcessBlock(header, snap)
+			if err != nil {
+				return err
+			}
+			snap = next
+
+			job.mu.Lock()
+			job.progress.Number, job.progress.Hash = snap.Number, snap.Hash
+			job.mu.Unlock()
+			otsmetrics.MarkBlockRebuilt(number)
+
+			if number%rebuildCommitInterval == 0 {
+				if err := m.commitRebuild(job, snap); err != nil {
+					return err
+				}
+				committed = number
+				log.Info("OTS: Rebuilding snapshots", "number", number, "to", to,
+					"blocksPerSec", rebuildRate(number-base, time.Since(start)))
+			}
+		}
+		return nil
+	}()
+
+	switch {
+	case err == nil:
+		if err = m.snapshots.ForceStore(snap); err == nil {
+			err = m.db.Delete(rebuildProgressKey)
+		}
+	case snap.Number > committed:
+		// Keep what was replayed since the last checkpoint
+		if err := m.commitRebuild(job, snap); err != nil {
+			log.Error("OTS: Failed to persist snapshot rebuild progress", "number", snap.Number, "err", err)
+		}
+	}
+
+	if err == nil {
+		log.Info("OTS: Snapshot rebuild complete", "from", job.progress.From, "to", to,
+			"blocks", snap.Number-base, "elapsed", time.Since(start))
+	} else if errors.Is(err, ErrRebuildStopped) {
+		log.Info("OTS: Snapshot rebuild stopped", "number", snap.Number, "to", to)
+	} else {
+		log.Error("OTS: Snapshot rebuild failed", "number", snap.Number, "to", to, "err", err)
+	}
+
+	job.err = err
+	m.mu.Lock()
+	m.rebuild, m.lastRebuild = nil, job
+	m.mu.Unlock()
+}
+
+// commitRebuild persists the job's last rebuilt snapshot and marks it as the
+// point to resume from. Block processing only warns on failed stores, so the
+// snapshot is stored again here.
+func (m *OTSConsensusManager) commitRebuild(job *rebuildJob, snap *Snapshot) error {
+	if err := m.snapshots.ForceStore(snap); err != nil {
+		return err
+	}
+	job.mu.Lock()
+	progress := job.progress
+	job.mu.Unlock()
+	return m.writeRebuildProgress(&progress)
+}
+
+// readRebuildProgress returns the stored progress marker, or nil if there is
+// no unfinished rebuild
+func (m *OTSConsensusManager) readRebuildProgress() *RebuildProgress {
+	data, err := m.db.Get(rebuildProgressKey)
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	var progress RebuildProgress
+	if err := json.Unmarshal(data, &progress); err != nil {
+		log.Warn("OTS: Invalid snapshot rebuild marker", "err", err)
+		return nil
+	}
+	return &progress
+}
+
+// writeRebuildProgress stores the progress marker
+func (m *OTSConsensusManager) writeRebuildProgress(progress *RebuildProgress) error {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	return m.db.Put(rebuildProgressKey, data)
+}
+
+// rebuildRate returns the replay throughput in blocks per second
+func rebuildRate(blocks uint64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(blocks) / elapsed.Seconds()
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// newRebuildChain returns a chain spanning several checkpoints with a claim
+// every 50 blocks
+func newRebuildChain() *testChain {
+	registry := common.HexToAddress(CopyrightRegistryAddress)
+	claims := make([][]*types.Log, 2*snapshotCheckpointInterval+100)
+	for i := range claims {
+		if i%50 == 0 {
+			claims[i] = []*types.Log{claimLog(registry, common.BigToHash(big.NewInt(int64(i+1))))}
+		}
+	}
+	return newTestChain(registry, claims)
+}
+
+// newRebuildManager returns a manager on db reading headers by number through
+// getHeaderByNumber
+func newRebuildManager(t *testing.T, db ethdb.Database, chain *testChain, getHeaderByNumber func(uint64) *types.Header) *OTSConsensusManager {
+	t.Helper()
+	m, err := NewOTSConsensusManager(db, &OTSManagerConfig{Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetChainAccessors(chain.getReceipts, chain.getHeader, getHeaderByNumber)
+	return m
+}
+
+func (c *testChain) getHeaderByNumber(number uint64) *types.Header {
+	if number < uint64(len(c.headers)) {
+		return c.headers[number]
+	}
+	return nil
+}
+
+func TestRebuildFromChain(t *testing.T) {
+	chain := newRebuildChain()
+	head := chain.headers[len(chain.headers)-1]
+	want := replay(t, chain)
+
+	m := newRebuildManager(t, rawdb.NewMemoryDatabase(), chain, chain.getHeaderByNumber)
+	if err := m.RebuildFromChain(0, head.Number.Uint64()); err != nil {
+		t.Fatalf("RebuildFromChain failed: %v", err)
+	}
+	snap, err := m.GetSnapshot(head.Hash())
+	if err != nil {
+		t.Fatalf("head snapshot not rebuilt: %v", err)
+	}
+	if snap.State.Hash() != want.Hash() {
+		t.Error("rebuilt state differs from processed state")
+	}
+	if status := m.RebuildStatus(); status != nil {
+		t.Errorf("progress marker left after rebuild: %+v", status)
+	}
+
+	if err := m.RebuildFromChain(10, 5); !errors.Is(err, ErrInvalidRebuildRange) {
+		t.Errorf("expected ErrInvalidRebuildRange, got %v", err)
+	}
+	if err := m.RebuildFromChain(0, head.Number.Uint64()+1); !errors.Is(err, errMissingChainData) {
+		t.Errorf("expected errMissingChainData, got %v", err)
+	}
+	if status := m.RebuildStatus(); status == nil || status.Error == "" || status.Number != head.Number.Uint64() {
+		t.Errorf("failed rebuild not reported: %+v", status)
+	}
+}
+
+func TestRebuildFromChain_Resume(t *testing.T) {
+	chain := newRebuildChain()
+	head := chain.headers[len(chain.headers)-1].Number.Uint64()
+	const stopAt = snapshotCheckpointInterval + 500
+	want := replay(t, chain)
+	db := rawdb.NewMemoryDatabase()
+
+	// Stop the first run while it waits for header stopAt
+	reached, gate := make(chan struct{}), make(chan struct{})
+	m := newRebuildManager(t, db, chain, func(number uint64) *types.Header {
+		if number == stopAt {
+			close(reached)
+			<-gate
+		}
+		return chain.getHeaderByNumber(number)
+	})
+	if err := m.StartRebuild(0, head); err != nil {
+		t.Fatalf("StartRebuild failed: %v", err)
+	}
+	<-reached
+	if err := m.StartRebuild(0, head); !errors.Is(err, ErrRebuildRunning) {
+		t.Errorf("expected ErrRebuildRunning, got %v", err)
+	}
+	if status := m.RebuildStatus(); status == nil || !status.Running || status.Number != stopAt-1 {
+		t.Errorf("unexpected running status: %+v", status)
+	}
+	// Ask the job to stop before releasing it, so it stops right after stopAt
+	m.rebuild.stop()
+	close(gate)
+	if err := m.StopRebuild(); err != nil && !errors.Is(err, ErrRebuildNotRunning) {
+		t.Fatalf("StopRebuild failed: %v", err)
+	}
+	if status := m.RebuildStatus(); status == nil || status.Running || status.Error != "" || status.Number != stopAt {
+		t.Errorf("unexpected stopped status: %+v", status)
+	}
+
+	// After a restart the rebuild continues from the marker
+	var (
+		mu        sync.Mutex
+		requested []uint64
+	)
+	m = newRebuildManager(t, db, chain, func(number uint64) *types.Header {
+		mu.Lock()
+		requested = append(requested, number)
+		mu.Unlock()
+		return chain.getHeaderByNumber(number)
+	})
+	if err := m.RebuildFromChain(0, head); err != nil {
+		t.Fatalf("resumed RebuildFromChain failed: %v", err)
+	}
+	for _, number := range requested {
+		if number < stopAt {
+			t.Fatalf("resumed rebuild replayed block %d before the marker at %d", number, stopAt)
+		}
+	}
+	snap, err := m.GetSnapshot(chain.headers[head].Hash())
+	if err != nil || snap.State.Hash() != want.Hash() {
+		t.Errorf("resumed rebuild produced a different state: %v", err)
+	}
+	if err := m.StopRebuild(); !errors.Is(err, ErrRebuildNotRunning) {
+		t.Errorf("expected ErrRebuildNotRunning, got %v", err)
+	}
+}
@@ -3,16 +3,24 @@
 //
 // This file implements OTS snapshot management, similar to Parlia's validator snapshots.
 // Snapshots are stored locally and can be rebuilt from chain data.
+//
+// Every snapshot is persisted so that block processing can resume after a
+// restart. Snapshots at multiples of snapshotCheckpointInterval are kept as
+// checkpoints; all others are pruned at the first checkpoint that is
+// snapshotFinalityDepth blocks ahead of them.
 
 package consensus
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"errors"
+	"sort"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
 	lru "github.com/hashicorp/golang-lru"
 )
 
@@ -20,8 +28,14 @@ const (
 	// snapshotCacheSize is the number of snapshots to keep in memory
 	snapshotCacheSize = 128
 
-	// snapshotPersistInterval is the block interval for persisting snapshots
-	snapshotPersistInterval = 1024
+	// snapshotCheckpointInterval is the block interval of checkpoint
+	// snapshots, which are never pruned
+	snapshotCheckpointInterval = 1024
+
+	// snapshotFinalityDepth is how many blocks behind the newest snapshot
+	// other snapshots are kept, bounding the reorgs that can be processed
+	// without rebuilding from a checkpoint
+	snapshotFinalityDepth = 2048
 )
 
 var (
@@ -29,7 +43,9 @@ var (
 	ErrInvalidSnapshot  = errors.New("invalid OTS snapshot")
 
 	// Database key prefixes
-	snapshotPrefix = []byte("ots-snapshot-")
+	snapshotPrefix   = []byte("ots-snapshot-")   // hash -> snapshot
+	checkpointPrefix = []byte("ots-checkpoint-") // number + hash -> nil
+	snapIndexPrefix  = []byte("ots-snapindex-")  // number + hash -> nil, non-checkpoints
 )
 
 // Snapshot represents an OTS state snapshot at a specific block
@@ -79,6 +95,13 @@ type SnapshotManager struct {
 
 	// Configuration
 	otsEnabled bool
+	fork       *ForkActivation
+
+	// checkpoints holds the sorted numbers of stored checkpoints
+	checkpoints []uint64
+
+	// prunedTo is the highest block whose non-checkpoint snapshots are pruned
+	prunedTo uint64
 }
 
 // NewSnapshotManager creates a new snapshot manager
@@ -88,11 +111,29 @@ func NewSnapshotManager(db ethdb.Database, otsEnabled bool) (*SnapshotManager, e
 		return nil, err
 	}
 
-	return &SnapshotManager{
+	sm := &SnapshotManager{
 		db:         db,
 		cache:      cache,
 		otsEnabled: otsEnabled,
-	}, nil
+	}
+	if err := sm.loadCheckpoints(); err != nil {
+		return nil, err
+	}
+	return sm, nil
+}
+
+// loadCheckpoints reads the numbers of the stored checkpoints
+func (sm *SnapshotManager) loadCheckpoints() error {
+	iter := sm.db.NewIterator(checkpointPrefix, nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		number := binary.BigEndian.Uint64(iter.Key()[len(checkpointPrefix):])
+		if n := len(sm.checkpoints); n == 0 || sm.checkpoints[n-1] != number {
+			sm.checkpoints = append(sm.checkpoints, number)
+		}
+	}
+	return iter.Error()
 }
 
 // GetSnapshot retrieves a snapshot for the given block hash
@@ -123,7 +164,8 @@ func (sm *SnapshotManager) GetSnapshotByNumber(number uint64, hash common.Hash)
 	return sm.GetSnapshot(hash)
 }
 
-// StoreSnapshot stores a snapshot both in cache and database
+// StoreSnapshot stores a snapshot both in cache and database. Each checkpoint
+// prunes the non-checkpoint snapshots that fell behind the finality depth.
 func (sm *SnapshotManager) StoreSnapshot(snap *Snapshot) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
@@ -131,15 +173,16 @@ func (sm *SnapshotManager) StoreSnapshot(snap *Snapshot) error {
 	// Add to cache
 	sm.cache.Add(snap.Hash, snap.Copy())
 
-	// Persist to database at intervals
-	if snap.Number%snapshotPersistInterval == 0 {
-		return sm.saveToDB(snap)
+	if err := sm.saveToDB(snap); err != nil {
+		return err
+	}
+	if snap.Number%snapshotCheckpointInterval == 0 && snap.Number > snapshotFinalityDepth {
+		return sm.prune(snap.Number - snapshotFinalityDepth)
 	}
-
 	return nil
 }
 
-// ForceStore forces storage of a snapshot to database regardless of interval
+// ForceStore stores a snapshot in cache and database without pruning
 func (sm *SnapshotManager) ForceStore(snap *Snapshot) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
@@ -148,6 +191,44 @@ func (sm *SnapshotManager) ForceStore(snap *Snapshot) error {
 	return sm.saveToDB(snap)
 }
 
+// prune deletes the non-checkpoint snapshots of blocks up to number
+func (sm *SnapshotManager) prune(number uint64) error {
+	if number <= sm.prunedTo {
+		return nil
+	}
+	iter := sm.db.NewIterator(snapIndexPrefix, nil)
+	defer iter.Release()
+
+	batch := sm.db.NewBatch()
+	pruned := 0
+	for iter.Next() {
+		key := iter.Key()
+		if binary.BigEndian.Uint64(key[len(snapIndexPrefix):]) > number {
+			break
+		}
+		hash := common.BytesToHash(key[len(snapIndexPrefix)+8:])
+		sm.cache.Remove(hash)
+		if err := batch.Delete(append(append([]byte{}, snapshotPrefix...), hash.Bytes()...)); err != nil {
+			return err
+		}
+		if err := batch.Delete(common.CopyBytes(key)); err != nil {
+			return err
+		}
+		pruned++
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	if pruned > 0 {
+		log.Debug("OTS: Pruned snapshots", "upTo", number, "count", pruned)
+	}
+	sm.prunedTo = number
+	return nil
+}
+
 // loadFromDB loads a snapshot from the database
 func (sm *SnapshotManager) loadFromDB(hash common.Hash) (*Snapshot, error) {
 	key := append(snapshotPrefix, hash.Bytes()...)
@@ -158,14 +239,54 @@ func (sm *SnapshotManager) loadFromDB(hash common.Hash) (*Snapshot, error) {
 	return DecodeSnapshot(data)
 }
 
-// saveToDB saves a snapshot to the database
+// saveToDB saves a snapshot to the database, indexed by number as a
+// checkpoint or as a prunable snapshot
 func (sm *SnapshotManager) saveToDB(snap *Snapshot) error {
 	key := append(snapshotPrefix, snap.Hash.Bytes()...)
 	data, err := snap.Encode()
 	if err != nil {
 		return err
 	}
-	return sm.db.Put(key, data)
+
+	batch := sm.db.NewBatch()
+	if err := batch.Put(key, data); err != nil {
+		return err
+	}
+	checkpoint := snap.Number%snapshotCheckpointInterval == 0
+	if err := batch.Put(snapshotIndexKey(snap.Number, snap.Hash, checkpoint), nil); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	if checkpoint {
+		sm.addCheckpoint(snap.Number)
+	}
+	return nil
+}
+
+// addCheckpoint records the number of a stored checkpoint
+func (sm *SnapshotManager) addCheckpoint(number uint64) {
+	i := sort.Search(len(sm.checkpoints), func(i int) bool { return sm.checkpoints[i] >= number })
+	if i < len(sm.checkpoints) && sm.checkpoints[i] == number {
+		return
+	}
+	sm.checkpoints = append(sm.checkpoints, 0)
+	copy(sm.checkpoints[i+1:], sm.checkpoints[i:])
+	sm.checkpoints[i] = number
+}
+
+// snapshotIndexKey returns the number index key of a snapshot
+func snapshotIndexKey(number uint64, hash common.Hash, checkpoint bool) []byte {
+	prefix := snapIndexPrefix
+	if checkpoint {
+		prefix = checkpointPrefix
+	}
+	key := make([]byte, len(prefix)+8+common.HashLength)
+	copy(key, prefix)
+	binary.BigEndian.PutUint64(key[len(prefix):], number)
+	copy(key[len(prefix)+8:], hash.Bytes())
+	return key
 }
 
 // DeleteSnapshot removes a snapshot from cache and database
@@ -173,14 +294,40 @@ func (sm *SnapshotManager) DeleteSnapshot(hash common.Hash) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
+	// The number is needed to remove the index entry
+	if snap, err := sm.loadFromDB(hash); err == nil {
+		checkpoint := snap.Number%snapshotCheckpointInterval == 0
+		if err := sm.db.Delete(snapshotIndexKey(snap.Number, hash, checkpoint)); err != nil {
+			return err
+		}
+	}
+
 	sm.cache.Remove(hash)
 	key := append(snapshotPrefix, hash.Bytes()...)
 	return sm.db.Delete(key)
 }
 
+// SetForkActivation sets the OTS activation fork, whose activation state
+// replaces the empty genesis state. It must be called before the manager is
+// used.
+func (sm *SnapshotManager) SetForkActivation(fork *ForkActivation) {
+	sm.fork = fork
+}
+
 // GetGenesisSnapshot returns the genesis OTS snapshot
 func (sm *SnapshotManager) GetGenesisSnapshot(genesisHash common.Hash) *Snapshot {
-	return NewSnapshot(0, genesisHash, NewOTSState(sm.otsEnabled))
+	return sm.BaseSnapshot(0, genesisHash)
+}
+
+// BaseSnapshot returns the snapshot of a block that OTS has not applied to
+// yet. Blocks before the activation fork leave the genesis state unchanged,
+// so the first OTS block builds on it.
+func (sm *SnapshotManager) BaseSnapshot(number uint64, hash common.Hash) *Snapshot {
+	state := NewOTSState(sm.otsEnabled)
+	if sm.otsEnabled {
+		state.LastAnchoredBlock = sm.fork.lastAnchoredBlock()
+	}
+	return NewSnapshot(number, hash, state)
 }
 
 // HasSnapshot checks if a snapshot exists for the given hash
@@ -209,29 +356,29 @@ func (sm *SnapshotManager) Clear() {
 	sm.cache.Purge()
 }
 
-// FindNearestSnapshot finds the nearest stored snapshot before the given block number
+// FindNearestSnapshot finds the nearest stored snapshot at or before the given block number
 // This is used when rebuilding state from chain data
 func (sm *SnapshotManager) FindNearestSnapshot(targetNumber uint64, getHash func(uint64) common.Hash) (*Snapshot, error) {
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 
-	// Search backwards from target in steps of persist interval
-	for num := (targetNumber / snapshotPersistInterval) * snapshotPersistInterval; num > 0; num -= snapshotPersistInterval {
-		hash := getHash(num)
-		if hash == (common.Hash{}) {
-			continue
-		}
-
-		// Check cache
-		if snap, ok := sm.cache.Get(hash); ok {
-			return snap.(*Snapshot).Copy(), nil
+	// Fast path: the target itself, then the stored checkpoints from the
+	// nearest one down. Checkpoints of other forks do not match getHash.
+	if snap := sm.lookup(getHash(targetNumber)); snap != nil {
+		return snap, nil
+	}
+	i := sort.Search(len(sm.checkpoints), func(i int) bool { return sm.checkpoints[i] > targetNumber })
+	for i--; i >= 0; i-- {
+		if snap := sm.lookup(getHash(sm.checkpoints[i])); snap != nil {
+			return snap, nil
 		}
+	}
 
-		// Check database
-		snap, err := sm.loadFromDB(hash)
-		if err == nil {
-			sm.cache.Add(hash, snap)
-			return snap.Copy(), nil
+	// Search backwards from target in steps of checkpoint interval, for
+	// snapshots stored before checkpoints were indexed
+	for num := (targetNumber / snapshotCheckpointInterval) * snapshotCheckpointInterval; num > 0; num -= snapshotCheckpointInterval {
+		if snap := sm.lookup(getHash(num)); snap != nil {
+			return snap, nil
 		}
 	}
 
@@ -239,3 +386,20 @@ func (sm *SnapshotManager) FindNearestSnapshot(targetNumber uint64, getHash func
 	genesisHash := getHash(0)
 	return sm.GetGenesisSnapshot(genesisHash), nil
 }
+
+// lookup returns a copy of the snapshot with the given hash from cache or
+// database, or nil if it is not stored
+func (sm *SnapshotManager) lookup(hash common.Hash) *Snapshot {
+	if hash == (common.Hash{}) {
+		return nil
+	}
+	if snap, ok := sm.cache.Get(hash); ok {
+		return snap.(*Snapshot).Copy()
+	}
+	snap, err := sm.loadFromDB(hash)
+	if err != nil {
+		return nil
+	}
+	sm.cache.Add(hash, snap)
+	return snap.Copy()
+}
@@ -4,11 +4,15 @@
 package consensus
 
 import (
+	"bytes"
 	"math/big"
+	"math/rand"
+	"sync"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
 )
 
 func TestNewSnapshot(t *testing.T) {
@@ -63,9 +67,9 @@ func TestSnapshot_EncodeDecode(t *testing.T) {
 	state := NewOTSState(true)
 	state.LastAnchoredBlock = 100
 	triggerNode := common.HexToAddress("0xabcd")
-	_ = state.Trigger(1, 50, 51, triggerNode, common.HexToHash("0xffff"))
+	_ = state.Trigger(101, 150, 151, triggerNode, common.HexToHash("0xffff"))
 
-	snap := NewSnapshot(51, hash, state)
+	snap := NewSnapshot(151, hash, state)
 
 	// Encode
 	data, err := snap.Encode()
@@ -89,10 +93,10 @@ func TestSnapshot_EncodeDecode(t *testing.T) {
 	if decoded.State.LastAnchoredBlock != snap.State.LastAnchoredBlock {
 		t.Error("LastAnchoredBlock mismatch")
 	}
-	if decoded.State.CurrentBatch == nil {
-		t.Fatal("CurrentBatch should not be nil")
+	if len(decoded.State.Batches) != 1 {
+		t.Fatal("Batches should hold the triggered batch")
 	}
-	if decoded.State.CurrentBatch.StartBlock != snap.State.CurrentBatch.StartBlock {
+	if decoded.State.Batches[0].StartBlock != snap.State.Batches[0].StartBlock {
 		t.Error("StartBlock mismatch")
 	}
 }
@@ -313,7 +317,7 @@ func TestSnapshotManager_FindNearestSnapshot(t *testing.T) {
 
 	// Store snapshots at persistence intervals
 	for i := uint64(1); i <= 5; i++ {
-		blockNum := i * snapshotPersistInterval
+		blockNum := i * snapshotCheckpointInterval
 		hash := common.BigToHash(big.NewInt(int64(blockNum)))
 		state := NewOTSState(true)
 		state.LastAnchoredBlock = blockNum - 100
@@ -323,7 +327,7 @@ func TestSnapshotManager_FindNearestSnapshot(t *testing.T) {
 
 	// Mock getHash function
 	getHash := func(num uint64) common.Hash {
-		if num%snapshotPersistInterval == 0 && num <= 5*snapshotPersistInterval {
+		if num%snapshotCheckpointInterval == 0 && num <= 5*snapshotCheckpointInterval {
 			return common.BigToHash(big.NewInt(int64(num)))
 		}
 		return common.Hash{}
@@ -343,3 +347,156 @@ func TestSnapshotManager_FindNearestSnapshot(t *testing.T) {
 	}
 }
 
+// storeChain stores snapshots for blocks 1..n, with block i hashed as i
+func storeChain(t testing.TB, sm *SnapshotManager, n uint64) {
+	state := NewOTSState(true)
+	_ = state.Trigger(1, 100, 101, common.Address{}, common.HexToHash("0xabcd"))
+	for i := uint64(1); i <= n; i++ {
+		state.LastAnchoredBlock = i
+		if err := sm.StoreSnapshot(NewSnapshot(i, common.BigToHash(new(big.Int).SetUint64(i)), state)); err != nil {
+			t.Fatalf("StoreSnapshot %d failed: %v", i, err)
+		}
+	}
+}
+
+// canonicalHash returns the hash of block i as stored by storeChain
+func canonicalHash(num uint64) common.Hash {
+	return common.BigToHash(new(big.Int).SetUint64(num))
+}
+
+// snapshotDiskUsage returns the number of persisted snapshots and the size
+// of all snapshot keys and values
+func snapshotDiskUsage(t testing.TB, db ethdb.Database) (count int, size int) {
+	for _, prefix := range [][]byte{snapshotPrefix, checkpointPrefix, snapIndexPrefix} {
+		iter := db.NewIterator(prefix, nil)
+		for iter.Next() {
+			if bytes.Equal(prefix, snapshotPrefix) {
+				count++
+			}
+			size += len(iter.Key()) + len(iter.Value())
+		}
+		iter.Release()
+		if err := iter.Error(); err != nil {
+			t.Fatalf("iteration failed: %v", err)
+		}
+	}
+	return count, size
+}
+
+func TestSnapshotManager_Pruning(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	sm, _ := NewSnapshotManager(db, true)
+
+	head := uint64(4*snapshotCheckpointInterval + 10)
+	storeChain(t, sm, head)
+	sm.Clear()
+
+	// The last checkpoint pruned up to the finality depth below it
+	cutoff := head - head%snapshotCheckpointInterval - snapshotFinalityDepth
+	for _, num := range []uint64{snapshotCheckpointInterval, 2 * snapshotCheckpointInterval, cutoff + 1, head} {
+		if !sm.HasSnapshot(canonicalHash(num)) {
+			t.Errorf("snapshot %d pruned", num)
+		}
+	}
+	for _, num := range []uint64{1, snapshotCheckpointInterval + 1, cutoff - 1} {
+		if sm.HasSnapshot(canonicalHash(num)) {
+			t.Errorf("snapshot %d not pruned", num)
+		}
+	}
+	if count, _ := snapshotDiskUsage(t, db); count != int(head-cutoff)+2 {
+		t.Errorf("expected %d persisted snapshots, got %d", head-cutoff+2, count)
+	}
+}
+
+func TestSnapshotManager_FindNearestCheckpoint(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	sm, _ := NewSnapshotManager(db, true)
+	head := uint64(4*snapshotCheckpointInterval + 10)
+	storeChain(t, sm, head)
+
+	// Checkpoints are found again after a restart
+	sm, err := NewSnapshotManager(db, true)
+	if err != nil {
+		t.Fatalf("NewSnapshotManager failed: %v", err)
+	}
+	tests := []struct {
+		target uint64
+		want   uint64
+	}{
+		{head, head},         // recent snapshot
+		{2100, 2100},         // recent snapshot
+		{100, 0},             // before the first checkpoint
+		{1500, 1024},         // pruned, nearest checkpoint
+		{2 * 1024, 2 * 1024}, // checkpoint itself
+		{2047, 1024},         // pruned, checkpoint just below
+	}
+	for _, tt := range tests {
+		snap, err := sm.FindNearestSnapshot(tt.target, canonicalHash)
+		if err != nil {
+			t.Fatalf("FindNearestSnapshot(%d) failed: %v", tt.target, err)
+		}
+		if snap.Number != tt.want {
+			t.Errorf("FindNearestSnapshot(%d) = %d, want %d", tt.target, snap.Number, tt.want)
+		}
+	}
+
+	// A checkpoint of another fork is skipped
+	fork := func(num uint64) common.Hash {
+		if num == 2*snapshotCheckpointInterval {
+			return common.HexToHash("0xf0f0")
+		}
+		return canonicalHash(num)
+	}
+	if snap, _ := sm.FindNearestSnapshot(2500, fork); snap.Number != 2500 {
+		t.Errorf("expected recent snapshot 2500, got %d", snap.Number)
+	}
+	if snap, _ := sm.FindNearestSnapshot(2048, fork); snap.Number != snapshotCheckpointInterval {
+		t.Errorf("expected checkpoint %d, got %d", snapshotCheckpointInterval, snap.Number)
+	}
+}
+
+var (
+	millionOnce sync.Once
+	millionDB   ethdb.Database
+)
+
+// millionBlockSnapshots stores snapshots of a million blocks once per run
+func millionBlockSnapshots(b *testing.B) (ethdb.Database, uint64) {
+	const head = 1_000_000
+	millionOnce.Do(func() {
+		millionDB = rawdb.NewMemoryDatabase()
+		sm, _ := NewSnapshotManager(millionDB, true)
+		storeChain(b, sm, head)
+	})
+	return millionDB, head
+}
+
+// BenchmarkSnapshotManager_FindNearest measures lookups of random blocks of a
+// million-block chain from a fresh cache, and reports what is kept on disk
+func BenchmarkSnapshotManager_FindNearest(b *testing.B) {
+	db, head := millionBlockSnapshots(b)
+	sm, _ := NewSnapshotManager(db, true)
+	rng := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		target := uint64(rng.Int63n(int64(head)))
+		snap, err := sm.FindNearestSnapshot(target, canonicalHash)
+		if err != nil || target-snap.Number >= snapshotCheckpointInterval {
+			b.Fatalf("FindNearestSnapshot(%d) = %v, %v", target, snap, err)
+		}
+		sm.Clear()
+	}
+	b.StopTimer()
+
+	count, size := snapshotDiskUsage(b, db)
+	b.ReportMetric(float64(count), "snapshots")
+	b.ReportMetric(float64(size), "disk-bytes")
+}
+
+// BenchmarkSnapshotManager_Store measures storing one block's snapshot,
+// including pruning
+func BenchmarkSnapshotManager_Store(b *testing.B) {
+	sm, _ := NewSnapshotManager(rawdb.NewMemoryDatabase(), true)
+	b.ResetTimer()
+	storeChain(b, sm, uint64(b.N))
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ots/merkle"
 )
 
 var (
@@ -24,8 +25,13 @@ var (
 	ErrNotSubmitted      = errors.New("batch not yet submitted")
 	ErrNotConfirmed      = errors.New("batch not yet confirmed")
 	ErrAlreadyAnchored   = errors.New("batch already anchored")
+	ErrTooManyBatches    = errors.New("too many in-flight batches")
 )
 
+// MaxInFlightBatches bounds the batches tracked in OTS state. Triggering
+// pauses while the queue is full.
+const MaxInFlightBatches = 8
+
 // BatchStatus represents the status of an OTS batch in consensus
 type BatchStatus uint8
 
@@ -40,6 +46,9 @@ const (
 	BatchStatusConfirmed BatchStatus = 3
 	// BatchStatusAnchored indicates batch is anchored on-chain (terminal state)
 	BatchStatusAnchored BatchStatus = 4
+	// BatchStatusFailed indicates batch timed out before submission or
+	// confirmation (terminal state), its range is rolled into the next trigger
+	BatchStatusFailed BatchStatus = 5
 )
 
 func (s BatchStatus) String() string {
@@ -54,6 +63,8 @@ func (s BatchStatus) String() string {
 		return "confirmed"
 	case BatchStatusAnchored:
 		return "anchored"
+	case BatchStatusFailed:
+		return "failed"
 	default:
 		return "unknown"
 	}
@@ -65,12 +76,12 @@ func (s BatchStatus) CanTransitionTo(target BatchStatus) bool {
 	case BatchStatusNone:
 		return target == BatchStatusTriggered
 	case BatchStatusTriggered:
-		return target == BatchStatusSubmitted
+		return target == BatchStatusSubmitted || target == BatchStatusFailed
 	case BatchStatusSubmitted:
-		return target == BatchStatusConfirmed
+		return target == BatchStatusConfirmed || target == BatchStatusFailed
 	case BatchStatusConfirmed:
 		return target == BatchStatusAnchored || target == BatchStatusNone
-	case BatchStatusAnchored:
+	case BatchStatusAnchored, BatchStatusFailed:
 		return target == BatchStatusNone
 	default:
 		return false
@@ -83,11 +94,23 @@ type OTSState struct {
 	// Enabled indicates if OTS is enabled for this chain
 	Enabled bool `json:"enabled"`
 
-	// LastAnchoredBlock is the end block of the last successfully anchored batch
+	// LastAnchoredBlock is the end block of the last anchored batch. All
+	// blocks up to it are anchored, as batches leave the queue in order.
 	LastAnchoredBlock uint64 `json:"lastAnchoredBlock"`
 
-	// CurrentBatch is the batch currently being processed (nil if none)
-	CurrentBatch *BatchState `json:"currentBatch,omitempty"`
+	// Batches are the in-flight batches in block order. Each batch starts
+	// after the end of the previous one, except where a failed batch left a
+	// gap that the next trigger covers again. An anchored batch stays queued
+	// until every block before it is anchored.
+	Batches []*BatchState `json:"batches,omitempty"`
+
+	// Expired lists the batches that failed in this block. It is reset for
+	// every block, so the snapshot of a block records its expiries.
+	Expired []*BatchState `json:"expired,omitempty"`
+
+	// PendingClaims counts claims since the last trigger, tracked only while
+	// the trigger policy has a claim threshold
+	PendingClaims uint64 `json:"pendingClaims,omitempty"`
 }
 
 // BatchState represents the state of a single OTS batch
@@ -97,6 +120,10 @@ type BatchState struct {
 	EndBlock   uint64      `json:"endBlock"`
 	RootHash   common.Hash `json:"rootHash"`
 
+	// MerkleScheme is the scheme RootHash was built with. Batches triggered
+	// before schemes were recorded leave it unset, which is SchemeLegacy.
+	MerkleScheme merkle.Scheme `json:"merkleScheme,omitempty"`
+
 	// Current status
 	Status BatchStatus `json:"status"`
 
@@ -113,12 +140,16 @@ type BatchState struct {
 	BTCBlockHeight uint64 `json:"btcBlockHeight,omitempty"`
 	BTCTxID        string `json:"btcTxId,omitempty"`
 	BTCTimestamp   uint64 `json:"btcTimestamp,omitempty"`
+	ProofHash      common.Hash `json:"proofHash,omitempty"`
 	ConfirmedAt    uint64 `json:"confirmedAt,omitempty"`
 	ConfirmedBy    common.Address `json:"confirmedBy,omitempty"`
 
 	// Anchor information (set when anchored)
 	AnchoredAt uint64 `json:"anchoredAt,omitempty"`
 	AnchoredBy common.Address `json:"anchoredBy,omitempty"`
+
+	// Failure information (set when failed)
+	FailedAt uint64 `json:"failedAt,omitempty"`
 }
 
 // NewOTSState creates a new OTS state with default values
@@ -126,7 +157,6 @@ func NewOTSState(enabled bool) *OTSState {
 	return &OTSState{
 		Enabled:           enabled,
 		LastAnchoredBlock: 0,
-		CurrentBatch:      nil,
 	}
 }
 
@@ -135,9 +165,21 @@ func (s *OTSState) Copy() *OTSState {
 	cpy := &OTSState{
 		Enabled:           s.Enabled,
 		LastAnchoredBlock: s.LastAnchoredBlock,
+		PendingClaims:     s.PendingClaims,
+	}
+	cpy.Batches = copyBatches(s.Batches)
+	cpy.Expired = copyBatches(s.Expired)
+	return cpy
+}
+
+// copyBatches deep copies a batch list, keeping nil for an empty list
+func copyBatches(batches []*BatchState) []*BatchState {
+	if len(batches) == 0 {
+		return nil
 	}
-	if s.CurrentBatch != nil {
-		cpy.CurrentBatch = s.CurrentBatch.Copy()
+	cpy := make([]*BatchState, len(batches))
+	for i, batch := range batches {
+		cpy[i] = batch.Copy()
 	}
 	return cpy
 }
@@ -150,24 +192,121 @@ func (b *BatchState) Copy() *BatchState {
 
 // HasActiveBatch returns true if there's an active batch being processed
 func (s *OTSState) HasActiveBatch() bool {
-	return s.CurrentBatch != nil && s.CurrentBatch.Status != BatchStatusNone
+	return len(s.Batches) > 0
 }
 
 // CanTrigger returns true if a new batch can be triggered
 func (s *OTSState) CanTrigger() bool {
-	return s.Enabled && !s.HasActiveBatch()
+	return s.Enabled && len(s.Batches) < MaxInFlightBatches
+}
+
+// NextStartBlock returns the first block not covered by any batch
+func (s *OTSState) NextStartBlock() uint64 {
+	start, _, _ := s.nextGap()
+	return start
+}
+
+// HasGap reports whether a failed batch left blocks uncovered before a
+// queued batch
+func (s *OTSState) HasGap() bool {
+	_, _, ok := s.nextGap()
+	return ok
+}
+
+// NextRange returns the range the next trigger at blockNumber covers: the
+// first gap left by a failed batch, or else the blocks after the last batch
+func (s *OTSState) NextRange(blockNumber uint64) (uint64, uint64) {
+	start, end, ok := s.nextGap()
+	if !ok {
+		end = blockNumber - 1
+	}
+	return start, end
+}
+
+// nextGap returns the first block not covered by any batch and, if it lies
+// before a queued batch, the last block of that gap
+func (s *OTSState) nextGap() (uint64, uint64, bool) {
+	next := s.LastAnchoredBlock + 1
+	for _, batch := range s.Batches {
+		if batch.StartBlock > next {
+			return next, batch.StartBlock - 1, true
+		}
+		next = batch.EndBlock + 1
+	}
+	return next, 0, false
+}
+
+// OldestBatch returns the oldest in-flight batch, or nil if there is none
+func (s *OTSState) OldestBatch() *BatchState {
+	if len(s.Batches) == 0 {
+		return nil
+	}
+	return s.Batches[0]
+}
+
+// Batch returns the oldest in-flight batch with the given root hash, or nil
+func (s *OTSState) Batch(rootHash common.Hash) *BatchState {
+	return s.findBatch(rootHash, nil)
+}
+
+// findBatch returns the oldest batch with the root hash and, if status is
+// set, in that status. Empty batches share a root hash, so the oldest match
+// is taken to keep transitions deterministic.
+func (s *OTSState) findBatch(rootHash common.Hash, status *BatchStatus) *BatchState {
+	for _, batch := range s.Batches {
+		if batch.RootHash == rootHash && (status == nil || batch.Status == *status) {
+			return batch
+		}
+	}
+	return nil
+}
+
+// batchInStatus returns the batch a transition from status applies to, or
+// errStatus if the root hash is known but not in that status
+func (s *OTSState) batchInStatus(rootHash common.Hash, status BatchStatus, errStatus error) (*BatchState, error) {
+	if batch := s.findBatch(rootHash, &status); batch != nil {
+		return batch, nil
+	}
+	if s.Batch(rootHash) == nil {
+		return nil, ErrBatchNotFound
+	}
+	return nil, errStatus
 }
 
-// Trigger starts a new batch
+// Trigger starts a new batch covering [startBlock, endBlock], which must
+// start at NextStartBlock and, when covering a gap, end within it. The root
+// is recorded as built with merkle.SchemeLegacy.
 func (s *OTSState) Trigger(startBlock, endBlock, triggerBlock uint64, triggerNode common.Address, rootHash common.Hash) error {
-	if !s.CanTrigger() {
+	return s.TriggerWithScheme(merkle.SchemeLegacy, startBlock, endBlock, triggerBlock, triggerNode, rootHash)
+}
+
+// TriggerWithScheme is Trigger for a root built with the given scheme
+func (s *OTSState) TriggerWithScheme(scheme merkle.Scheme, startBlock, endBlock, triggerBlock uint64, triggerNode common.Address, rootHash common.Hash) error {
+	if !s.Enabled {
+		return ErrInvalidState
+	}
+	if len(s.Batches) >= MaxInFlightBatches {
+		return ErrTooManyBatches
+	}
+	next, gapEnd, gap := s.nextGap()
+	if startBlock < next {
 		return ErrAlreadyTriggered
+	} else if startBlock > next || endBlock < startBlock || (gap && endBlock > gapEnd) {
+		return ErrInvalidTransition
 	}
 
-	s.CurrentBatch = &BatchState{
+	// Keep the queue in block order
+	i := len(s.Batches)
+	for i > 0 && s.Batches[i-1].StartBlock > startBlock {
+		i--
+	}
+	s.Batches = append(s.Batches, nil)
+	copy(s.Batches[i+1:], s.Batches[i:])
+	s.Batches[i] = &BatchState{
 		StartBlock:   startBlock,
 		EndBlock:     endBlock,
 		RootHash:     rootHash,
+		MerkleScheme: scheme,
 		Status:       BatchStatusTriggered,
 		TriggerBlock: triggerBlock,
 		TriggerNode:  triggerNode,
@@ -175,67 +314,148 @@ func (s *OTSState) Trigger(startBlock, endBlock, triggerBlock uint64, triggerNod
 	return nil
 }
 
-// MarkSubmitted marks the current batch as submitted to OTS calendar
-func (s *OTSState) MarkSubmitted(digest [32]byte, blockNumber uint64, submitter common.Address) error {
-	if s.CurrentBatch == nil || s.CurrentBatch.Status != BatchStatusTriggered {
-		return ErrNotTriggered
+// MarkSubmitted marks the batch with the root hash as submitted to OTS calendar
+func (s *OTSState) MarkSubmitted(rootHash common.Hash, digest [32]byte, blockNumber uint64, submitter common.Address) error {
+	batch, err := s.batchInStatus(rootHash, BatchStatusTriggered, ErrNotTriggered)
+	if err != nil {
+		return err
 	}
-	if !s.CurrentBatch.Status.CanTransitionTo(BatchStatusSubmitted) {
+	if !batch.Status.CanTransitionTo(BatchStatusSubmitted) {
 		return ErrInvalidTransition
 	}
 
-	s.CurrentBatch.OTSDigest = digest
-	s.CurrentBatch.SubmittedAt = blockNumber
-	s.CurrentBatch.SubmittedBy = submitter
-	s.CurrentBatch.Status = BatchStatusSubmitted
+	batch.OTSDigest = digest
+	batch.SubmittedAt = blockNumber
+	batch.SubmittedBy = submitter
+	batch.Status = BatchStatusSubmitted
 	return nil
 }
 
-// MarkConfirmed marks the current batch as confirmed on Bitcoin
-func (s *OTSState) MarkConfirmed(btcBlockHeight uint64, btcTxID string, btcTimestamp uint64, blockNumber uint64, confirmer common.Address) error {
-	if s.CurrentBatch == nil || s.CurrentBatch.Status != BatchStatusSubmitted {
-		return ErrNotSubmitted
+// MarkConfirmed marks the batch with the root hash as confirmed on Bitcoin.
+// proofHash commits to the upgraded OTS proof and is zero if none was published.
+func (s *OTSState) MarkConfirmed(rootHash common.Hash, btcBlockHeight uint64, btcTxID string, btcTimestamp uint64, proofHash common.Hash, blockNumber uint64, confirmer common.Address) error {
+	batch, err := s.batchInStatus(rootHash, BatchStatusSubmitted, ErrNotSubmitted)
+	if err != nil {
+		return err
 	}
-	if !s.CurrentBatch.Status.CanTransitionTo(BatchStatusConfirmed) {
+	if !batch.Status.CanTransitionTo(BatchStatusConfirmed) {
 		return ErrInvalidTransition
 	}
 
-	s.CurrentBatch.BTCBlockHeight = btcBlockHeight
-	s.CurrentBatch.BTCTxID = btcTxID
-	s.CurrentBatch.BTCTimestamp = btcTimestamp
-	s.CurrentBatch.ConfirmedAt = blockNumber
-	s.CurrentBatch.ConfirmedBy = confirmer
-	s.CurrentBatch.Status = BatchStatusConfirmed
+	batch.BTCBlockHeight = btcBlockHeight
+	batch.BTCTxID = btcTxID
+	batch.BTCTimestamp = btcTimestamp
+	batch.ProofHash = proofHash
+	batch.ConfirmedAt = blockNumber
+	batch.ConfirmedBy = confirmer
+	batch.Status = BatchStatusConfirmed
 	return nil
 }
 
-// MarkAnchored marks the current batch as anchored on-chain and clears it
-func (s *OTSState) MarkAnchored(blockNumber uint64, anchorer common.Address) error {
-	if s.CurrentBatch == nil || s.CurrentBatch.Status != BatchStatusConfirmed {
+// MarkAnchored marks the batch with the root hash and block range as anchored
+// on-chain. Empty batches share a root hash, so the range the Anchored event
+// names must match as well. Batches at the head of the queue that are
+// anchored are removed and advance LastAnchoredBlock.
+func (s *OTSState) MarkAnchored(rootHash common.Hash, startBlock, endBlock, blockNumber uint64, anchorer common.Address) error {
+	var batch *BatchState
+	for _, candidate := range s.Batches {
+		if candidate.RootHash == rootHash && candidate.StartBlock == startBlock && candidate.EndBlock == endBlock {
+			batch = candidate
+			break
+		}
+	}
+	if batch == nil {
+		return ErrBatchNotFound
+	}
+	if batch.Status != BatchStatusConfirmed {
 		return ErrNotConfirmed
 	}
-	if !s.CurrentBatch.Status.CanTransitionTo(BatchStatusAnchored) {
+	if !batch.Status.CanTransitionTo(BatchStatusAnchored) {
 		return ErrInvalidTransition
 	}
 
-	// Update last anchored block
-	s.LastAnchoredBlock = s.CurrentBatch.EndBlock
+	batch.AnchoredAt = blockNumber
+	batch.AnchoredBy = anchorer
+	batch.Status = BatchStatusAnchored
 
-	// Mark as anchored (for historical reference)
-	s.CurrentBatch.AnchoredAt = blockNumber
-	s.CurrentBatch.AnchoredBy = anchorer
-	s.CurrentBatch.Status = BatchStatusAnchored
-
-	// Clear current batch (ready for next trigger)
-	s.CurrentBatch = nil
+	// Release anchored batches in order (ready for further triggers). A batch
+	// after a gap waits until the gap is anchored too.
+	for len(s.Batches) > 0 && s.Batches[0].Status == BatchStatusAnchored && s.Batches[0].StartBlock == s.LastAnchoredBlock+1 {
+		s.LastAnchoredBlock = s.Batches[0].EndBlock
+		s.Batches = s.Batches[1:]
+	}
+	if len(s.Batches) == 0 {
+		s.Batches = nil
+	}
 	return nil
 }
 
+// ExpireBatches fails batches that got no otsSubmitted transaction within
+// submitTimeout blocks of their trigger, or no otsConfirmed transaction within
+// confirmTimeout blocks of their submission. Only those batches fail; the
+// batches queued around them keep their progress, and the gap a failed batch
+// leaves is covered again by the next trigger. Failed batches are removed
+// from the queue, recorded in Expired and returned.
+func (s *OTSState) ExpireBatches(blockNumber, submitTimeout, confirmTimeout uint64) []*BatchState {
+	var (
+		expired []*BatchState
+		kept    []*BatchState
+	)
+	for _, batch := range s.Batches {
+		if !batch.expired(blockNumber, submitTimeout, confirmTimeout) {
+			kept = append(kept, batch)
+			continue
+		}
+		batch.Status = BatchStatusFailed
+		batch.FailedAt = blockNumber
+		expired = append(expired, batch)
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+	s.Expired = append(s.Expired, expired...)
+	s.Batches = kept
+	return expired
+}
+
+// expired reports whether the batch is past its timeout at blockNumber
+func (b *BatchState) expired(blockNumber, submitTimeout, confirmTimeout uint64) bool {
+	switch b.Status {
+	case BatchStatusTriggered:
+		return blockNumber >= b.TriggerBlock+submitTimeout
+	case BatchStatusSubmitted:
+		return blockNumber >= b.SubmittedAt+confirmTimeout
+	default:
+		return false
+	}
+}
+
 // Encode serializes the OTS state to JSON
 func (s *OTSState) Encode() ([]byte, error) {
 	return json.Marshal(s)
 }
 
+// UnmarshalJSON decodes the OTS state, migrating the single-batch format
+// that stored one currentBatch
+func (s *OTSState) UnmarshalJSON(data []byte) error {
+	type otsState OTSState
+	var dec struct {
+		otsState
+		CurrentBatch *BatchState `json:"currentBatch,omitempty"`
+	}
+	if err := json.Unmarshal(data, &dec); err != nil {
+		return err
+	}
+	*s = OTSState(dec.otsState)
+
+	// The old format cleared the batch when anchored
+	if legacy := dec.CurrentBatch; legacy != nil && len(s.Batches) == 0 &&
+		legacy.Status != BatchStatusNone && legacy.Status != BatchStatusAnchored {
+		s.Batches = []*BatchState{legacy}
+	}
+	return nil
+}
+
 // DecodeOTSState deserializes the OTS state from JSON
 func DecodeOTSState(data []byte) (*OTSState, error) {
 	var state OTSState
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ots/merkle"
 )
 
 func TestNewOTSState(t *testing.T) {
@@ -17,8 +18,8 @@ func TestNewOTSState(t *testing.T) {
 	if state.LastAnchoredBlock != 0 {
 		t.Error("Expected LastAnchoredBlock to be 0")
 	}
-	if state.CurrentBatch != nil {
-		t.Error("Expected CurrentBatch to be nil")
+	if state.HasActiveBatch() {
+		t.Error("Expected no batches")
 	}
 }
 
@@ -51,10 +52,18 @@ func TestOTSState_CanTrigger(t *testing.T) {
 		t.Error("Enabled state with no batch should be able to trigger")
 	}
 
-	// State with active batch cannot trigger
-	state.CurrentBatch = &BatchState{Status: BatchStatusTriggered}
+	// State with active batches can trigger until the queue is full
+	for i := uint64(0); i < MaxInFlightBatches; i++ {
+		if !state.CanTrigger() {
+			t.Fatalf("State with %d batches should be able to trigger", i)
+		}
+		_ = state.Trigger(i*10+1, i*10+10, i*10+11, common.Address{}, common.Hash{})
+	}
 	if state.CanTrigger() {
-		t.Error("State with active batch should not be able to trigger")
+		t.Error("State with a full queue should not be able to trigger")
+	}
+	if err := state.Trigger(MaxInFlightBatches*10+1, MaxInFlightBatches*10+10, 0, common.Address{}, common.Hash{}); err != ErrTooManyBatches {
+		t.Errorf("Expected ErrTooManyBatches, got %v", err)
 	}
 }
 
@@ -68,33 +77,52 @@ func TestOTSState_Trigger(t *testing.T) {
 		t.Fatalf("Trigger failed: %v", err)
 	}
 
-	if state.CurrentBatch == nil {
-		t.Fatal("CurrentBatch should not be nil after trigger")
+	if len(state.Batches) != 1 {
+		t.Fatal("Batches should hold the batch after trigger")
 	}
-	if state.CurrentBatch.Status != BatchStatusTriggered {
-		t.Errorf("Expected status Triggered, got %v", state.CurrentBatch.Status)
+	batch := state.Batches[0]
+	if batch.Status != BatchStatusTriggered {
+		t.Errorf("Expected status Triggered, got %v", batch.Status)
 	}
-	if state.CurrentBatch.StartBlock != 1 {
-		t.Errorf("Expected StartBlock 1, got %d", state.CurrentBatch.StartBlock)
+	if batch.StartBlock != 1 {
+		t.Errorf("Expected StartBlock 1, got %d", batch.StartBlock)
 	}
-	if state.CurrentBatch.EndBlock != 100 {
-		t.Errorf("Expected EndBlock 100, got %d", state.CurrentBatch.EndBlock)
+	if batch.EndBlock != 100 {
+		t.Errorf("Expected EndBlock 100, got %d", batch.EndBlock)
 	}
-	if state.CurrentBatch.TriggerBlock != 101 {
-		t.Errorf("Expected TriggerBlock 101, got %d", state.CurrentBatch.TriggerBlock)
+	if batch.TriggerBlock != 101 {
+		t.Errorf("Expected TriggerBlock 101, got %d", batch.TriggerBlock)
 	}
-	if state.CurrentBatch.TriggerNode != triggerNode {
+	if batch.TriggerNode != triggerNode {
 		t.Errorf("TriggerNode mismatch")
 	}
-	if state.CurrentBatch.RootHash != rootHash {
+	if batch.RootHash != rootHash {
 		t.Errorf("RootHash mismatch")
 	}
+	if batch.MerkleScheme != merkle.SchemeLegacy {
+		t.Errorf("Expected MerkleScheme %d, got %d", merkle.SchemeLegacy, batch.MerkleScheme)
+	}
 
-	// Cannot trigger again
-	err = state.Trigger(101, 200, 201, triggerNode, rootHash)
+	// Cannot trigger an overlapping or detached range
+	err = state.Trigger(50, 200, 201, triggerNode, rootHash)
 	if err != ErrAlreadyTriggered {
 		t.Errorf("Expected ErrAlreadyTriggered, got %v", err)
 	}
+	err = state.Trigger(102, 200, 201, triggerNode, rootHash)
+	if err != ErrInvalidTransition {
+		t.Errorf("Expected ErrInvalidTransition, got %v", err)
+	}
+
+	// The next batch can trigger while the first is in flight
+	if err := state.TriggerWithScheme(merkle.SchemeV1, 101, 200, 201, triggerNode, common.HexToHash("0xef01")); err != nil {
+		t.Fatalf("Second trigger failed: %v", err)
+	}
+	if state.NextStartBlock() != 201 {
+		t.Errorf("Expected NextStartBlock 201, got %d", state.NextStartBlock())
+	}
+	if scheme := state.Batches[1].MerkleScheme; scheme != merkle.SchemeV1 {
+		t.Errorf("Expected MerkleScheme %d, got %d", merkle.SchemeV1, scheme)
+	}
 }
 
 func TestOTSState_MarkSubmitted(t *testing.T) {
@@ -105,30 +133,34 @@ func TestOTSState_MarkSubmitted(t *testing.T) {
 	digest := [32]byte{1, 2, 3, 4}
 
 	// Cannot mark submitted without triggering first
-	err := state.MarkSubmitted(digest, 102, submitter)
-	if err != ErrNotTriggered {
-		t.Errorf("Expected ErrNotTriggered, got %v", err)
+	err := state.MarkSubmitted(rootHash, digest, 102, submitter)
+	if err != ErrBatchNotFound {
+		t.Errorf("Expected ErrBatchNotFound, got %v", err)
 	}
 
 	// Trigger first
 	_ = state.Trigger(1, 100, 101, triggerNode, rootHash)
 
 	// Now mark submitted
-	err = state.MarkSubmitted(digest, 102, submitter)
+	err = state.MarkSubmitted(rootHash, digest, 102, submitter)
 	if err != nil {
 		t.Fatalf("MarkSubmitted failed: %v", err)
 	}
+	if err := state.MarkSubmitted(rootHash, digest, 103, submitter); err != ErrNotTriggered {
+		t.Errorf("Expected ErrNotTriggered, got %v", err)
+	}
+	batch := state.Batches[0]
 
-	if state.CurrentBatch.Status != BatchStatusSubmitted {
-		t.Errorf("Expected status Submitted, got %v", state.CurrentBatch.Status)
+	if batch.Status != BatchStatusSubmitted {
+		t.Errorf("Expected status Submitted, got %v", batch.Status)
 	}
-	if state.CurrentBatch.OTSDigest != digest {
+	if batch.OTSDigest != digest {
 		t.Error("OTSDigest mismatch")
 	}
-	if state.CurrentBatch.SubmittedAt != 102 {
-		t.Errorf("Expected SubmittedAt 102, got %d", state.CurrentBatch.SubmittedAt)
+	if batch.SubmittedAt != 102 {
+		t.Errorf("Expected SubmittedAt 102, got %d", batch.SubmittedAt)
 	}
-	if state.CurrentBatch.SubmittedBy != submitter {
+	if batch.SubmittedBy != submitter {
 		t.Error("SubmittedBy mismatch")
 	}
 }
@@ -142,35 +174,40 @@ func TestOTSState_MarkConfirmed(t *testing.T) {
 	digest := [32]byte{1, 2, 3, 4}
 
 	// Cannot confirm without submitting first
-	err := state.MarkConfirmed(800000, "btctx123", 1234567890, 103, confirmer)
+	_ = state.Trigger(1, 100, 101, triggerNode, rootHash)
+	err := state.MarkConfirmed(rootHash, 800000, "btctx123", 1234567890, common.Hash{}, 103, confirmer)
 	if err != ErrNotSubmitted {
 		t.Errorf("Expected ErrNotSubmitted, got %v", err)
 	}
 
-	// Setup: trigger and submit
-	_ = state.Trigger(1, 100, 101, triggerNode, rootHash)
-	_ = state.MarkSubmitted(digest, 102, submitter)
+	// Setup: submit
+	_ = state.MarkSubmitted(rootHash, digest, 102, submitter)
 
 	// Now confirm
-	err = state.MarkConfirmed(800000, "btctx123", 1234567890, 103, confirmer)
+	proofHash := common.HexToHash("0xef01")
+	err = state.MarkConfirmed(rootHash, 800000, "btctx123", 1234567890, proofHash, 103, confirmer)
 	if err != nil {
 		t.Fatalf("MarkConfirmed failed: %v", err)
 	}
+	batch := state.Batches[0]
 
-	if state.CurrentBatch.Status != BatchStatusConfirmed {
-		t.Errorf("Expected status Confirmed, got %v", state.CurrentBatch.Status)
+	if batch.Status != BatchStatusConfirmed {
+		t.Errorf("Expected status Confirmed, got %v", batch.Status)
 	}
-	if state.CurrentBatch.BTCBlockHeight != 800000 {
-		t.Errorf("Expected BTCBlockHeight 800000, got %d", state.CurrentBatch.BTCBlockHeight)
+	if batch.BTCBlockHeight != 800000 {
+		t.Errorf("Expected BTCBlockHeight 800000, got %d", batch.BTCBlockHeight)
 	}
-	if state.CurrentBatch.BTCTxID != "btctx123" {
-		t.Errorf("Expected BTCTxID 'btctx123', got %s", state.CurrentBatch.BTCTxID)
+	if batch.BTCTxID != "btctx123" {
+		t.Errorf("Expected BTCTxID 'btctx123', got %s", batch.BTCTxID)
 	}
-	if state.CurrentBatch.BTCTimestamp != 1234567890 {
-		t.Errorf("Expected BTCTimestamp 1234567890, got %d", state.CurrentBatch.BTCTimestamp)
+	if batch.BTCTimestamp != 1234567890 {
+		t.Errorf("Expected BTCTimestamp 1234567890, got %d", batch.BTCTimestamp)
 	}
-	if state.CurrentBatch.ConfirmedAt != 103 {
-		t.Errorf("Expected ConfirmedAt 103, got %d", state.CurrentBatch.ConfirmedAt)
+	if batch.ProofHash != proofHash {
+		t.Errorf("Expected ProofHash %s, got %s", proofHash.Hex(), batch.ProofHash.Hex())
+	}
+	if batch.ConfirmedAt != 103 {
+		t.Errorf("Expected ConfirmedAt 103, got %d", batch.ConfirmedAt)
 	}
 }
 
@@ -184,25 +221,30 @@ func TestOTSState_MarkAnchored(t *testing.T) {
 	digest := [32]byte{1, 2, 3, 4}
 
 	// Cannot anchor without confirming first
-	err := state.MarkAnchored(104, anchorer)
+	_ = state.Trigger(1, 100, 101, triggerNode, rootHash)
+	err := state.MarkAnchored(rootHash, 1, 100, 104, anchorer)
 	if err != ErrNotConfirmed {
 		t.Errorf("Expected ErrNotConfirmed, got %v", err)
 	}
 
-	// Setup: trigger, submit, confirm
-	_ = state.Trigger(1, 100, 101, triggerNode, rootHash)
-	_ = state.MarkSubmitted(digest, 102, submitter)
-	_ = state.MarkConfirmed(800000, "btctx123", 1234567890, 103, confirmer)
+	// Setup: submit, confirm
+	_ = state.MarkSubmitted(rootHash, digest, 102, submitter)
+	_ = state.MarkConfirmed(rootHash, 800000, "btctx123", 1234567890, common.Hash{}, 103, confirmer)
+
+	// The anchored range must match the batch
+	if err := state.MarkAnchored(rootHash, 1, 99, 104, anchorer); err != ErrBatchNotFound {
+		t.Errorf("Expected ErrBatchNotFound for another range, got %v", err)
+	}
 
 	// Now anchor
-	err = state.MarkAnchored(104, anchorer)
+	err = state.MarkAnchored(rootHash, 1, 100, 104, anchorer)
 	if err != nil {
 		t.Fatalf("MarkAnchored failed: %v", err)
 	}
 
-	// After anchoring, CurrentBatch should be nil
-	if state.CurrentBatch != nil {
-		t.Error("CurrentBatch should be nil after anchoring")
+	// After anchoring, the batch should leave the queue
+	if state.HasActiveBatch() {
+		t.Error("Batches should be empty after anchoring")
 	}
 	// LastAnchoredBlock should be updated
 	if state.LastAnchoredBlock != 100 {
@@ -220,6 +262,7 @@ func TestBatchStatus_String(t *testing.T) {
 		{BatchStatusSubmitted, "submitted"},
 		{BatchStatusConfirmed, "confirmed"},
 		{BatchStatusAnchored, "anchored"},
+		{BatchStatusFailed, "failed"},
 		{BatchStatus(99), "unknown"},
 	}
 
@@ -255,12 +298,168 @@ func TestBatchStatus_CanTransitionTo(t *testing.T) {
 	}
 }
 
+func TestBatchStatus_TransitionTable(t *testing.T) {
+	statuses := []BatchStatus{
+		BatchStatusNone, BatchStatusTriggered, BatchStatusSubmitted,
+		BatchStatusConfirmed, BatchStatusAnchored, BatchStatusFailed,
+	}
+	allowed := map[BatchStatus][]BatchStatus{
+		BatchStatusNone:      {BatchStatusTriggered},
+		BatchStatusTriggered: {BatchStatusSubmitted, BatchStatusFailed},
+		BatchStatusSubmitted: {BatchStatusConfirmed, BatchStatusFailed},
+		BatchStatusConfirmed: {BatchStatusAnchored, BatchStatusNone},
+		BatchStatusAnchored:  {BatchStatusNone},
+		BatchStatusFailed:    {BatchStatusNone},
+	}
+	for _, from := range statuses {
+		for _, to := range statuses {
+			want := false
+			for _, target := range allowed[from] {
+				want = want || target == to
+			}
+			if got := from.CanTransitionTo(to); got != want {
+				t.Errorf("%s.CanTransitionTo(%s) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+	// Unknown statuses never transition
+	for _, to := range statuses {
+		if BatchStatus(99).CanTransitionTo(to) {
+			t.Errorf("unknown.CanTransitionTo(%s) = true", to)
+		}
+	}
+}
+
+func TestOTSState_ExpireBatches(t *testing.T) {
+	node := common.HexToAddress("0x1111")
+	rootA, rootB, rootC := common.HexToHash("0xaa"), common.HexToHash("0xbb"), common.HexToHash("0xcc")
+	const submitTimeout, confirmTimeout = 10, 100
+
+	newState := func() *OTSState {
+		state := NewOTSState(true)
+		state.LastAnchoredBlock = 50
+		_ = state.Trigger(51, 100, 101, node, rootA)
+		_ = state.Trigger(101, 200, 201, node, rootB)
+		_ = state.Trigger(201, 300, 301, node, rootC)
+		return state
+	}
+
+	tests := []struct {
+		name      string
+		setup     func(*OTSState)
+		block     uint64
+		remaining int
+		next      uint64
+		expired   []common.Hash
+	}{
+		{"nothing due", func(*OTSState) {}, 110, 3, 301, nil},
+		{"triggered at deadline", func(*OTSState) {}, 111, 2, 51, []common.Hash{rootA}},
+		{"later batch only", func(s *OTSState) {
+			_ = s.MarkSubmitted(rootA, [32]byte{1}, 102, node)
+			_ = s.MarkConfirmed(rootA, 800000, "txa", 1700000000, common.Hash{}, 110, node)
+			_ = s.MarkSubmitted(rootB, [32]byte{2}, 202, node)
+			_ = s.MarkConfirmed(rootB, 800001, "txb", 1700000600, common.Hash{}, 210, node)
+		}, 311, 2, 201, []common.Hash{rootC}},
+		{"submitted at deadline", func(s *OTSState) {
+			_ = s.MarkSubmitted(rootA, [32]byte{1}, 105, node)
+		}, 205, 2, 51, []common.Hash{rootA}},
+		{"confirmed batch after the expired one", func(s *OTSState) {
+			_ = s.MarkSubmitted(rootA, [32]byte{1}, 102, node)
+			_ = s.MarkSubmitted(rootB, [32]byte{2}, 202, node)
+			_ = s.MarkConfirmed(rootB, 800001, "txb", 1700000600, common.Hash{0x0b}, 210, node)
+		}, 202, 2, 51, []common.Hash{rootA}},
+		{"anchored batch after the expired one", func(s *OTSState) {
+			_ = s.MarkSubmitted(rootA, [32]byte{1}, 102, node)
+			_ = s.MarkSubmitted(rootB, [32]byte{2}, 202, node)
+			_ = s.MarkConfirmed(rootB, 800001, "txb", 1700000600, common.Hash{0x0b}, 210, node)
+			_ = s.MarkAnchored(rootB, 101, 200, 211, node)
+		}, 202, 2, 51, []common.Hash{rootA}},
+		{"confirmed batches never expire", func(s *OTSState) {
+			_ = s.MarkSubmitted(rootA, [32]byte{1}, 105, node)
+			_ = s.MarkConfirmed(rootA, 800000, "tx", 1700000000, common.Hash{}, 106, node)
+			_ = s.MarkSubmitted(rootB, [32]byte{2}, 205, node)
+			_ = s.MarkSubmitted(rootC, [32]byte{3}, 305, node)
+		}, 1000, 1, 101, []common.Hash{rootB, rootC}},
+	}
+	for _, tt := range tests {
+		state := newState()
+		tt.setup(state)
+
+		expired := state.ExpireBatches(tt.block, submitTimeout, confirmTimeout)
+		if len(state.Batches) != tt.remaining || len(expired) != len(tt.expired) {
+			t.Errorf("%s: %d batches remain and %d expired, want %d and %d", tt.name, len(state.Batches), len(expired), tt.remaining, len(tt.expired))
+			continue
+		}
+		for i, batch := range expired {
+			if batch.RootHash != tt.expired[i] || batch.Status != BatchStatusFailed || batch.FailedAt != tt.block {
+				t.Errorf("%s: expired[%d] = %+v", tt.name, i, batch)
+			}
+		}
+		if len(state.Expired) != len(tt.expired) {
+			t.Errorf("%s: state records %d expiries, want %d", tt.name, len(state.Expired), len(tt.expired))
+		}
+
+		// The failed range is rolled into the next trigger
+		if state.NextStartBlock() != tt.next {
+			t.Errorf("%s: NextStartBlock = %d, want %d", tt.name, state.NextStartBlock(), tt.next)
+		}
+		if state.LastAnchoredBlock != 50 {
+			t.Errorf("%s: LastAnchoredBlock = %d, want 50", tt.name, state.LastAnchoredBlock)
+		}
+	}
+
+	// Failed batches accept no further transitions
+	state := newState()
+	_ = state.ExpireBatches(111, submitTimeout, confirmTimeout)
+	if err := state.MarkSubmitted(rootA, [32]byte{1}, 112, node); err != ErrBatchNotFound {
+		t.Errorf("Expected ErrBatchNotFound after expiry, got %v", err)
+	}
+
+	// A batch confirmed after the expired one keeps its confirmation, and the
+	// gap is covered again before anything is released
+	state = newState()
+	_ = state.MarkSubmitted(rootA, [32]byte{1}, 102, node)
+	_ = state.MarkSubmitted(rootB, [32]byte{2}, 202, node)
+	_ = state.MarkConfirmed(rootB, 800001, "txb", 1700000600, common.Hash{0x0b}, 210, node)
+	_ = state.ExpireBatches(202, submitTimeout, confirmTimeout)
+	if b := state.Batch(rootB); b == nil || b.Status != BatchStatusConfirmed || b.BTCTxID != "txb" || b.ProofHash != (common.Hash{0x0b}) {
+		t.Fatalf("Confirmed batch lost its progress: %+v", b)
+	}
+	if !state.HasGap() {
+		t.Error("Expected a gap after the expired batch")
+	}
+	if err := state.MarkAnchored(rootB, 101, 200, 212, node); err != nil {
+		t.Fatalf("MarkAnchored failed: %v", err)
+	}
+	if state.LastAnchoredBlock != 50 {
+		t.Errorf("Anchored batch released past the gap: LastAnchoredBlock = %d", state.LastAnchoredBlock)
+	}
+	if err := state.Trigger(51, 120, 213, node, rootA); err != ErrInvalidTransition {
+		t.Errorf("Expected ErrInvalidTransition for a trigger past the gap, got %v", err)
+	}
+	if start, end := state.NextRange(213); start != 51 || end != 100 {
+		t.Errorf("NextRange = [%d, %d], want [51, 100]", start, end)
+	}
+	if err := state.Trigger(51, 100, 213, node, rootA); err != nil {
+		t.Fatalf("Re-trigger of the failed range failed: %v", err)
+	}
+	if state.Batches[0].RootHash != rootA || state.HasGap() || state.NextStartBlock() != 301 {
+		t.Errorf("Re-triggered batch not queued in block order: %+v", state.Batches[0])
+	}
+	_ = state.MarkSubmitted(rootA, [32]byte{1}, 214, node)
+	_ = state.MarkConfirmed(rootA, 800002, "txa", 1700001200, common.Hash{}, 215, node)
+	_ = state.MarkAnchored(rootA, 51, 100, 216, node)
+	if state.LastAnchoredBlock != 200 || len(state.Batches) != 1 {
+		t.Errorf("Expected blocks up to 200 released, got %d with %d batches", state.LastAnchoredBlock, len(state.Batches))
+	}
+}
+
 func TestOTSState_EncodeDecode(t *testing.T) {
 	state := NewOTSState(true)
 	state.LastAnchoredBlock = 12345
 	triggerNode := common.HexToAddress("0x1234567890123456789012345678901234567890")
 	rootHash := common.HexToHash("0xabcd")
-	_ = state.Trigger(1, 100, 101, triggerNode, rootHash)
+	_ = state.Trigger(12346, 12400, 12401, triggerNode, rootHash)
 
 	// Encode
 	data, err := state.Encode()
@@ -281,13 +480,13 @@ func TestOTSState_EncodeDecode(t *testing.T) {
 	if decoded.LastAnchoredBlock != state.LastAnchoredBlock {
 		t.Error("LastAnchoredBlock mismatch")
 	}
-	if decoded.CurrentBatch == nil {
-		t.Fatal("CurrentBatch should not be nil")
+	if len(decoded.Batches) != 1 {
+		t.Fatal("Batches should hold the triggered batch")
 	}
-	if decoded.CurrentBatch.StartBlock != state.CurrentBatch.StartBlock {
+	if decoded.Batches[0].StartBlock != state.Batches[0].StartBlock {
 		t.Error("StartBlock mismatch")
 	}
-	if decoded.CurrentBatch.RootHash != state.CurrentBatch.RootHash {
+	if decoded.Batches[0].RootHash != state.Batches[0].RootHash {
 		t.Error("RootHash mismatch")
 	}
 }
@@ -335,15 +534,15 @@ func TestFullStateTransitionCycle(t *testing.T) {
 		t.Error("Should have active batch after trigger")
 	}
 
-	if err := state.MarkSubmitted(digest, 1002, submitter); err != nil {
+	if err := state.MarkSubmitted(rootHash, digest, 1002, submitter); err != nil {
 		t.Fatalf("MarkSubmitted failed: %v", err)
 	}
 
-	if err := state.MarkConfirmed(800001, "tx1", 1700000000, 1003, confirmer); err != nil {
+	if err := state.MarkConfirmed(rootHash, 800001, "tx1", 1700000000, common.Hash{}, 1003, confirmer); err != nil {
 		t.Fatalf("MarkConfirmed failed: %v", err)
 	}
 
-	if err := state.MarkAnchored(1004, anchorer); err != nil {
+	if err := state.MarkAnchored(rootHash, 1, 1000, 1004, anchorer); err != nil {
 		t.Fatalf("MarkAnchored failed: %v", err)
 	}
 
@@ -364,7 +563,103 @@ func TestFullStateTransitionCycle(t *testing.T) {
 		t.Fatalf("Second trigger failed: %v", err)
 	}
 
-	if state.CurrentBatch.StartBlock != 1001 {
-		t.Errorf("Second batch StartBlock should be 1001, got %d", state.CurrentBatch.StartBlock)
+	if state.OldestBatch().StartBlock != 1001 {
+		t.Errorf("Second batch StartBlock should be 1001, got %d", state.OldestBatch().StartBlock)
+	}
+}
+
+func TestOTSState_PipelinedBatches(t *testing.T) {
+	state := NewOTSState(true)
+	node := common.HexToAddress("0x1111")
+	rootA, rootB := common.HexToHash("0xaa"), common.HexToHash("0xbb")
+
+	_ = state.Trigger(1, 100, 101, node, rootA)
+	_ = state.Trigger(101, 200, 201, node, rootB)
+
+	// The second batch overtakes the first
+	_ = state.MarkSubmitted(rootB, [32]byte{0x0b}, 202, node)
+	_ = state.MarkSubmitted(rootA, [32]byte{0x0a}, 203, node)
+	_ = state.MarkConfirmed(rootB, 800001, "txb", 1700000000, common.Hash{}, 204, node)
+	if err := state.MarkAnchored(rootB, 101, 200, 205, node); err != nil {
+		t.Fatalf("MarkAnchored failed: %v", err)
+	}
+
+	// Block 101-200 is anchored, but 1-100 is not, so nothing is released
+	if len(state.Batches) != 2 || state.LastAnchoredBlock != 0 {
+		t.Fatalf("Expected 2 queued batches and LastAnchoredBlock 0, got %d and %d", len(state.Batches), state.LastAnchoredBlock)
+	}
+	if state.Batch(rootB).Status != BatchStatusAnchored {
+		t.Errorf("Expected second batch anchored, got %v", state.Batch(rootB).Status)
+	}
+	if err := state.MarkAnchored(rootB, 101, 200, 206, node); err != ErrNotConfirmed {
+		t.Errorf("Expected ErrNotConfirmed for a repeated anchor, got %v", err)
+	}
+
+	_ = state.MarkConfirmed(rootA, 800002, "txa", 1700000600, common.Hash{}, 207, node)
+	_ = state.MarkAnchored(rootA, 1, 100, 208, node)
+	if state.HasActiveBatch() || state.LastAnchoredBlock != 200 {
+		t.Errorf("Expected both batches released up to block 200, got %d batches and %d", len(state.Batches), state.LastAnchoredBlock)
+	}
+	if state.NextStartBlock() != 201 {
+		t.Errorf("Expected NextStartBlock 201, got %d", state.NextStartBlock())
+	}
+}
+
+func TestOTSState_SharedRootHash(t *testing.T) {
+	// Empty ranges share the zero root; transitions apply to the oldest match
+	state := NewOTSState(true)
+	_ = state.Trigger(1, 10, 11, common.Address{}, common.Hash{})
+	_ = state.Trigger(11, 20, 21, common.Address{}, common.Hash{})
+
+	_ = state.MarkSubmitted(common.Hash{}, [32]byte{1}, 22, common.Address{})
+	_ = state.MarkSubmitted(common.Hash{}, [32]byte{2}, 23, common.Address{})
+	if state.Batches[0].OTSDigest != [32]byte{1} || state.Batches[1].OTSDigest != [32]byte{2} {
+		t.Error("Submissions should apply in trigger order")
+	}
+
+	// An anchor applies to the batch with its range, not the oldest match
+	_ = state.MarkConfirmed(common.Hash{}, 800000, "tx", 1700000000, common.Hash{}, 24, common.Address{})
+	_ = state.MarkConfirmed(common.Hash{}, 800000, "tx", 1700000000, common.Hash{}, 25, common.Address{})
+	if err := state.MarkAnchored(common.Hash{}, 11, 20, 26, common.Address{}); err != nil {
+		t.Fatalf("MarkAnchored failed: %v", err)
+	}
+	if state.Batches[0].Status != BatchStatusConfirmed || state.Batches[1].Status != BatchStatusAnchored {
+		t.Errorf("Expected only the second batch anchored, got %v and %v", state.Batches[0].Status, state.Batches[1].Status)
+	}
+}
+
+func TestOTSState_DecodeLegacy(t *testing.T) {
+	legacy := `{"enabled":true,"lastAnchoredBlock":100,"currentBatch":{"startBlock":101,"endBlock":200,` +
+		`"rootHash":"0x00000000000000000000000000000000000000000000000000000000000000ab","status":2,"triggerBlock":201,` +
+		`"triggerNode":"0x0000000000000000000000000000000000001234"}}`
+
+	state, err := DecodeOTSState([]byte(legacy))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if !state.Enabled || state.LastAnchoredBlock != 100 {
+		t.Errorf("Unexpected state: %+v", state)
+	}
+	if len(state.Batches) != 1 || state.Batches[0].Status != BatchStatusSubmitted || state.Batches[0].EndBlock != 200 {
+		t.Fatalf("Legacy batch not migrated: %+v", state.Batches)
+	}
+
+	// Re-encoding uses the queue format only
+	data, err := state.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeOTSState(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Hash() != state.Hash() || len(decoded.Batches) != 1 {
+		t.Error("Migrated state does not round-trip")
+	}
+
+	// A state without a batch decodes to an empty queue
+	state, err = DecodeOTSState([]byte(`{"enabled":true,"lastAnchoredBlock":7}`))
+	if err != nil || state.HasActiveBatch() || state.LastAnchoredBlock != 7 {
+		t.Errorf("Unexpected state %+v, err %v", state, err)
 	}
 }
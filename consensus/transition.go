@@ -7,18 +7,21 @@
 package consensus
 
 import (
-	"bytes"
-	"sort"
-	"time"
+	"errors"
+	"fmt"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/ots/event"
+	"github.com/ethereum/go-ethereum/ots/merkle"
+	"github.com/ethereum/go-ethereum/ots/systx"
 )
 
 const (
-	// TriggerHourUTC is the hour (0-23) at which daily OTS batch is triggered
+	// TriggerHourUTC is the hour (0-23) at which the default daily OTS batch
+	// is triggered, see DefaultTriggerSchedule
 	TriggerHourUTC = 0
 
 	// CopyrightRegistryAddress is the address of the CopyrightRegistry contract
@@ -26,22 +29,27 @@ const (
 )
 
 var (
-	// CopyrightClaimedEventSig is the event signature for CopyrightClaimed
-	// event CopyrightClaimed(bytes32 indexed ruid, bytes32 indexed puid, bytes32 indexed auid, address claimant)
-	CopyrightClaimedEventSig = crypto.Keccak256Hash([]byte("CopyrightClaimed(bytes32,bytes32,bytes32,address)"))
+	// CopyrightClaimedEventSig is the event signature for CopyrightClaimed, as
+	// collected by the event package
+	// event CopyrightClaimed(bytes32 indexed ruid, address indexed claimant, uint64 submitBlock)
+	CopyrightClaimedEventSig = event.CopyrightClaimedEventSig
 
-	// OTS System Transaction selectors
-	// otsSubmitted(bytes32 rootHash, bytes32 otsDigest)
-	OTSSubmittedSelector = crypto.Keccak256([]byte("otsSubmitted(bytes32,bytes32)"))[:4]
-
-	// otsConfirmed(bytes32 rootHash, uint64 btcBlockHeight, bytes32 btcTxID, uint64 btcTimestamp)
-	OTSConfirmedSelector = crypto.Keccak256([]byte("otsConfirmed(bytes32,uint64,bytes32,uint64)"))[:4]
-
-	// anchor(bytes32 rootHash, uint64 startBlock, uint64 endBlock, bytes32[] ruids, uint64 btcBlockHeight, bytes32 btcTxID, uint64 btcTimestamp)
-	AnchorSelector = crypto.Keccak256([]byte("anchor(bytes32,uint64,uint64,bytes32[],uint64,bytes32,uint64)"))[:4]
+	// OTS System Transaction selectors, as encoded by the systx package from
+	// the CopyrightRegistry ABI
+	OTSSubmittedSelector = systx.OTSSubmittedSelector
+	OTSConfirmedSelector = systx.OTSConfirmedSelector
+	AnchorSelector       = systx.AnchorSelector
 
 	// Contract address
 	copyrightRegistryAddr = common.HexToAddress(CopyrightRegistryAddress)
+
+	// errMissingChainData means headers or receipts of the batch range are not
+	// available, so the root cannot be computed deterministically
+	errMissingChainData = errors.New("consensus: missing chain data for batch range")
+
+	// OTSConfirmed event signatures, with and without the proof commitment
+	otsConfirmedSig       = crypto.Keccak256Hash([]byte("OTSConfirmed(bytes32,uint64,bytes32,uint64,bytes32)"))
+	otsConfirmedLegacySig = crypto.Keccak256Hash([]byte("OTSConfirmed(bytes32,uint64,bytes32,uint64)"))
 )
 
 // TransitionEngine processes blocks and updates OTS state
@@ -49,6 +57,15 @@ type TransitionEngine struct {
 	snapshots  *SnapshotManager
 	getReceipts func(hash common.Hash, number uint64) types.Receipts
 	getHeader   func(hash common.Hash, number uint64) *types.Header
+
+	// registry is the CopyrightRegistry contract whose events are batched
+	registry common.Address
+
+	// schedule decides which blocks trigger a batch
+	schedule TriggerSchedule
+
+	// fork is the OTS activation fork, nil if OTS applies from genesis
+	fork *ForkActivation
 }
 
 // NewTransitionEngine creates a new transition engine
@@ -57,24 +74,50 @@ func NewTransitionEngine(snapshots *SnapshotManager, getReceipts func(common.Has
 		snapshots:   snapshots,
 		getReceipts: getReceipts,
 		getHeader:   getHeader,
+		registry:    copyrightRegistryAddr,
+		schedule:    DefaultTriggerSchedule,
 	}
 }
 
+// SetRegistryAddress sets the CopyrightRegistry contract address, replacing
+// the default system contract at 0x9000
+func (te *TransitionEngine) SetRegistryAddress(addr common.Address) {
+	te.registry = addr
+}
+
+// SetTriggerSchedule sets the batch trigger policies, replacing the default
+// daily trigger. The schedule must have been validated.
+func (te *TransitionEngine) SetTriggerSchedule(schedule TriggerSchedule) {
+	te.schedule = schedule
+}
+
+// SetForkActivation sets the OTS activation fork. Blocks before it leave the
+// state unchanged.
+func (te *TransitionEngine) SetForkActivation(fork *ForkActivation) {
+	te.fork = fork
+}
+
 // ProcessBlock applies a block to the OTS state and returns the new snapshot
 func (te *TransitionEngine) ProcessBlock(header *types.Header, parentSnap *Snapshot) (*Snapshot, error) {
 	// Copy parent state
 	newState := parentSnap.State.Copy()
 
-	// Skip if OTS is not enabled
-	if !newState.Enabled {
+	// Skip if OTS is not enabled or not activated yet
+	if !newState.Enabled || !te.fork.IsActive(header.Number.Uint64(), header.Time) {
 		return NewSnapshot(header.Number.Uint64(), header.Hash(), newState), nil
 	}
 
 	// Get block transactions for system tx detection
 	receipts := te.getReceipts(header.Hash(), header.Number.Uint64())
+	if receipts == nil && header.ReceiptHash != types.EmptyReceiptsHash {
+		return nil, fmt.Errorf("%w: receipts of block %d", errMissingChainData, header.Number.Uint64())
+	}
 
-	// Apply state transitions based on current state and block content
-	te.applyTransitions(newState, header, receipts)
+	// Apply state transitions based on current state and block content. A
+	// node missing chain data must not guess the state other nodes derive.
+	if err := te.applyTransitions(newState, header, receipts); err != nil {
+		return nil, err
+	}
 
 	// Create new snapshot
 	newSnap := NewSnapshot(header.Number.Uint64(), header.Hash(), newState)
@@ -88,117 +131,156 @@ func (te *TransitionEngine) ProcessBlock(header *types.Header, parentSnap *Snaps
 }
 
 // applyTransitions applies all applicable state transitions for a block
-func (te *TransitionEngine) applyTransitions(state *OTSState, header *types.Header, receipts types.Receipts) {
+func (te *TransitionEngine) applyTransitions(state *OTSState, header *types.Header, receipts types.Receipts) error {
 	blockNumber := header.Number.Uint64()
 	coinbase := header.Coinbase
 
-	// Rule 1: Check for trigger condition (no active batch + crossing 00:00 UTC)
-	if state.CanTrigger() && te.isTriggerBlock(header) {
-		te.handleTrigger(state, header)
-	}
-
-	// Rule 2: Check for OTS submission system transaction
-	if state.CurrentBatch != nil && state.CurrentBatch.Status == BatchStatusTriggered {
-		if submission := te.extractOTSSubmission(header, receipts); submission != nil {
-			if err := state.MarkSubmitted(submission.Digest, blockNumber, coinbase); err != nil {
-				log.Debug("OTS: Failed to mark submitted", "err", err)
-			} else {
-				log.Info("OTS: Batch marked as submitted",
-					"block", blockNumber,
-					"digest", common.Bytes2Hex(submission.Digest[:]),
-				)
-			}
+	// Expiries are recorded per block
+	state.Expired = nil
+
+	// Rule 1: Check for trigger condition (queue not full + trigger policy)
+	policy := te.schedule.PolicyAt(blockNumber)
+	if state.CanTrigger() && te.isTriggerBlock(state, policy, header) {
+		if err := te.handleTrigger(state, header); err != nil {
+			return err
 		}
 	}
 
-	// Rule 3: Check for BTC confirmation system transaction
-	if state.CurrentBatch != nil && state.CurrentBatch.Status == BatchStatusSubmitted {
-		if confirmation := te.extractBTCConfirmation(header, receipts); confirmation != nil {
-			if err := state.MarkConfirmed(
-				confirmation.BTCBlockHeight,
-				confirmation.BTCTxID,
-				confirmation.BTCTimestamp,
-				blockNumber,
-				coinbase,
-			); err != nil {
-				log.Debug("OTS: Failed to mark confirmed", "err", err)
-			} else {
-				log.Info("OTS: Batch marked as confirmed",
-					"block", blockNumber,
-					"btcBlock", confirmation.BTCBlockHeight,
-					"btcTxID", confirmation.BTCTxID,
-				)
-			}
+	// Count claims for the claim trigger. Claims of this block belong to the
+	// next batch, so they are added after the trigger check.
+	if policy.Claims > 0 {
+		state.PendingClaims += uint64(len(ruidsFromReceipts(receipts, te.registry)))
+	} else {
+		state.PendingClaims = 0
+	}
+
+	// Rule 2: Check for OTS submission system transactions
+	for _, submission := range te.extractOTSSubmissions(header, receipts) {
+		if err := state.MarkSubmitted(submission.RootHash, submission.Digest, blockNumber, coinbase); err != nil {
+			log.Debug("OTS: Failed to mark submitted", "rootHash", submission.RootHash.Hex(), "err", err)
+		} else {
+			log.Info("OTS: Batch marked as submitted",
+				"block", blockNumber,
+				"rootHash", submission.RootHash.Hex(),
+				"digest", common.Bytes2Hex(submission.Digest[:]),
+			)
 		}
 	}
 
-	// Rule 4: Check for anchor system transaction
-	if state.CurrentBatch != nil && state.CurrentBatch.Status == BatchStatusConfirmed {
-		if te.hasValidAnchorTx(header, receipts, state.CurrentBatch) {
-			if err := state.MarkAnchored(blockNumber, coinbase); err != nil {
-				log.Debug("OTS: Failed to mark anchored", "err", err)
-			} else {
-				log.Info("OTS: Batch anchored",
-					"block", blockNumber,
-					"lastAnchoredBlock", state.LastAnchoredBlock,
-				)
-			}
+	// Rule 3: Check for BTC confirmation system transactions
+	for _, confirmation := range te.extractBTCConfirmations(header, receipts) {
+		if err := state.MarkConfirmed(
+			confirmation.RootHash,
+			confirmation.BTCBlockHeight,
+			confirmation.BTCTxID,
+			confirmation.BTCTimestamp,
+			confirmation.ProofHash,
+			blockNumber,
+			coinbase,
+		); err != nil {
+			log.Debug("OTS: Failed to mark confirmed", "rootHash", confirmation.RootHash.Hex(), "err", err)
+		} else {
+			log.Info("OTS: Batch marked as confirmed",
+				"block", blockNumber,
+				"rootHash", confirmation.RootHash.Hex(),
+				"btcBlock", confirmation.BTCBlockHeight,
+				"btcTxID", confirmation.BTCTxID,
+				"proofHash", confirmation.ProofHash.Hex(),
+			)
 		}
 	}
-}
 
-// isTriggerBlock checks if this block crosses the trigger hour (00:00 UTC)
-func (te *TransitionEngine) isTriggerBlock(header *types.Header) bool {
-	// Get parent header
-	parentHeader := te.getHeader(header.ParentHash, header.Number.Uint64()-1)
-	if parentHeader == nil {
-		return false
+	// Rule 4: Check for anchor system transactions
+	for _, anchored := range systx.AnchoredEvents(blockNumber, receipts, te.registry) {
+		if err := state.MarkAnchored(anchored.RootHash, anchored.StartBlock, anchored.EndBlock, blockNumber, coinbase); err != nil {
+			log.Debug("OTS: Failed to mark anchored", "rootHash", anchored.RootHash.Hex(),
+				"startBlock", anchored.StartBlock, "endBlock", anchored.EndBlock, "err", err)
+		} else {
+			log.Info("OTS: Batch anchored",
+				"block", blockNumber,
+				"rootHash", anchored.RootHash.Hex(),
+				"startBlock", anchored.StartBlock,
+				"endBlock", anchored.EndBlock,
+				"lastAnchoredBlock", state.LastAnchoredBlock,
+			)
+		}
 	}
 
-	// Convert timestamps to UTC time
-	currentTime := time.Unix(int64(header.Time), 0).UTC()
-	parentTime := time.Unix(int64(parentHeader.Time), 0).UTC()
+	// Rule 5: Fail batches past their timeout. Transactions in this block
+	// still count, and the next block triggers the failed range again.
+	submitTimeout, confirmTimeout := policy.timeouts()
+	for _, batch := range state.ExpireBatches(blockNumber, submitTimeout, confirmTimeout) {
+		log.Warn("OTS: Batch expired",
+			"block", blockNumber,
+			"rootHash", batch.RootHash.Hex(),
+			"startBlock", batch.StartBlock,
+			"endBlock", batch.EndBlock,
+			"triggerBlock", batch.TriggerBlock,
+			"submittedAt", batch.SubmittedAt,
+		)
+	}
+	return nil
+}
 
-	// Check if we crossed midnight (00:00 UTC)
-	// This happens when:
-	// 1. Parent was on previous day and current is on new day, OR
-	// 2. Parent hour < TriggerHourUTC and current hour >= TriggerHourUTC
-	currentDay := currentTime.YearDay()
-	parentDay := parentTime.YearDay()
-	currentYear := currentTime.Year()
-	parentYear := parentTime.Year()
+// isTriggerBlock checks if this block meets any condition of the policy
+func (te *TransitionEngine) isTriggerBlock(state *OTSState, policy *TriggerPolicy, header *types.Header) bool {
+	blockNumber := header.Number.Uint64()
 
-	// Year change or day change
-	if currentYear > parentYear || currentDay > parentDay {
-		// We crossed midnight
-		return currentTime.Hour() >= TriggerHourUTC
+	// A range left by a failed batch is covered again right away
+	if state.HasGap() {
+		return true
 	}
-
-	// Same day: check if we crossed the trigger hour
-	return parentTime.Hour() < TriggerHourUTC && currentTime.Hour() >= TriggerHourUTC
+	if policy.Claims > 0 && state.PendingClaims >= policy.Claims {
+		return true
+	}
+	// The untriggered range is [NextStartBlock, blockNumber-1]
+	if policy.Blocks > 0 && blockNumber >= state.NextStartBlock()+policy.Blocks {
+		return true
+	}
+	if policy.Interval > 0 {
+		parentHeader := te.getHeader(header.ParentHash, blockNumber-1)
+		if parentHeader == nil {
+			return false
+		}
+		return policy.crossesInterval(parentHeader.Time, header.Time)
+	}
+	return false
 }
 
-// handleTrigger handles the trigger of a new OTS batch
-func (te *TransitionEngine) handleTrigger(state *OTSState, header *types.Header) {
+// handleTrigger handles the trigger of a new OTS batch. It fails if the root
+// of the batch cannot be computed from chain data.
+func (te *TransitionEngine) handleTrigger(state *OTSState, header *types.Header) error {
 	blockNumber := header.Number.Uint64()
 
-	// Calculate block range: from last anchored + 1 to previous block
-	startBlock := state.LastAnchoredBlock + 1
-	endBlock := blockNumber - 1
+	// Calculate block range: the first gap left by a failed batch, or from
+	// the end of the last batch to the previous block
+	refill := state.HasGap()
+	startBlock, endBlock := state.NextRange(blockNumber)
 
 	// Skip if no blocks to process
 	if endBlock < startBlock {
 		log.Debug("OTS: No blocks to process for trigger", "start", startBlock, "end", endBlock)
-		return
+		return nil
 	}
 
-	// Calculate root hash from events in the block range
-	rootHash := te.calculateRootHash(startBlock, endBlock)
+	// Calculate root hash from events in the block range, with the scheme
+	// active at this block
+	scheme := te.fork.MerkleScheme(blockNumber)
+	rootHash, err := te.calculateRootHash(header, startBlock, endBlock, scheme)
+	if err != nil {
+		log.Error("OTS: Failed to calculate batch root", "startBlock", startBlock, "endBlock", endBlock, "err", err)
+		return fmt.Errorf("batch %d-%d: %w", startBlock, endBlock, err)
+	}
 
 	// Trigger the batch
-	if err := state.Trigger(startBlock, endBlock, blockNumber, header.Coinbase, rootHash); err != nil {
+	if err := state.TriggerWithScheme(scheme, startBlock, endBlock, blockNumber, header.Coinbase, rootHash); err != nil {
 		log.Debug("OTS: Failed to trigger batch", "err", err)
-		return
+		return nil
+	}
+
+	// Claims after the last batch are still pending after a refill
+	if !refill {
+		state.PendingClaims = 0
 	}
 
 	log.Info("OTS: Batch triggered",
@@ -206,42 +288,70 @@ func (te *TransitionEngine) handleTrigger(state *OTSState, header *types.Header)
 		"endBlock", endBlock,
 		"triggerBlock", blockNumber,
 		"rootHash", rootHash.Hex(),
+		"refill", refill,
 	)
+	return nil
 }
 
 // calculateRootHash calculates the Merkle root from CopyrightClaimed events
-func (te *TransitionEngine) calculateRootHash(startBlock, endBlock uint64) common.Hash {
+// in [startBlock, endBlock]. The range is read along the ancestry of header,
+// so every node processing the same block derives the same root regardless of
+// its current canonical chain. The root is built with the given scheme, which
+// the trigger records with the batch so proofs are rebuilt with it.
+func (te *TransitionEngine) calculateRootHash(header *types.Header, startBlock, endBlock uint64, scheme merkle.Scheme) (common.Hash, error) {
 	var ruids []common.Hash
 
-	// Collect all RUIDs from the block range
-	for blockNum := startBlock; blockNum <= endBlock; blockNum++ {
-		// Get block header to get the hash
-		// Note: We need to iterate through headers to get receipts
-		// In a real implementation, this would use the chain's GetBlockByNumber
-		// For now, we'll use a simplified approach
-		ruidsFromBlock := te.getRUIDsFromBlock(blockNum)
-		ruids = append(ruids, ruidsFromBlock...)
-	}
-
-	if len(ruids) == 0 {
-		return common.Hash{}
+	hash, number := header.ParentHash, header.Number.Uint64()-1
+	for number >= startBlock {
+		ancestor := te.getHeader(hash, number)
+		if ancestor == nil {
+			return common.Hash{}, fmt.Errorf("%w: header %d", errMissingChainData, number)
+		}
+		if number <= endBlock {
+			blockRUIDs, err := te.getRUIDsFromBlock(ancestor)
+			if err != nil {
+				return common.Hash{}, err
+			}
+			ruids = append(ruids, blockRUIDs...)
+		}
+		if number == 0 {
+			break
+		}
+		hash, number = ancestor.ParentHash, number-1
 	}
 
-	// Sort RUIDs for deterministic ordering
-	sort.Slice(ruids, func(i, j int) bool {
-		return bytes.Compare(ruids[i][:], ruids[j][:]) < 0
-	})
-
-	// Build Merkle tree
-	return buildMerkleRoot(ruids)
+	// The scheme orders the RUIDs
+	return merkle.ComputeRootWithScheme(scheme, ruids)
 }
 
 // getRUIDsFromBlock extracts RUIDs from CopyrightClaimed events in a block
-func (te *TransitionEngine) getRUIDsFromBlock(blockNum uint64) []common.Hash {
-	// This is a placeholder - in real implementation, we need access to
-	// block hash to get receipts. The actual implementation will be
-	// provided when integrating with the chain.
-	return nil
+func (te *TransitionEngine) getRUIDsFromBlock(header *types.Header) ([]common.Hash, error) {
+	receipts := te.getReceipts(header.Hash(), header.Number.Uint64())
+	if receipts == nil && header.ReceiptHash != types.EmptyReceiptsHash {
+		return nil, fmt.Errorf("%w: receipts of block %d", errMissingChainData, header.Number.Uint64())
+	}
+	return ruidsFromReceipts(receipts, te.registry), nil
+}
+
+// ruidsFromReceipts returns the RUIDs of the CopyrightClaimed events emitted
+// by registry, in receipt and log order. Only consensus fields of the logs
+// are used, so raw receipts without derived fields are sufficient.
+func ruidsFromReceipts(receipts types.Receipts, registry common.Address) []common.Hash {
+	var ruids []common.Hash
+	for _, receipt := range receipts {
+		for _, logEntry := range receipt.Logs {
+			if !event.IsCopyrightClaimedLog(logEntry, registry) {
+				continue
+			}
+			claim, err := event.ParseLog(logEntry)
+			if err != nil {
+				log.Warn("OTS: Failed to parse log", "txHash", logEntry.TxHash.Hex(), "error", err)
+				continue
+			}
+			ruids = append(ruids, claim.RUID)
+		}
+	}
+	return ruids
 }
 
 // OTSSubmission represents a parsed OTS submission
@@ -250,25 +360,26 @@ type OTSSubmission struct {
 	Digest   [32]byte
 }
 
-// extractOTSSubmission extracts OTS submission info from block transactions
-func (te *TransitionEngine) extractOTSSubmission(header *types.Header, receipts types.Receipts) *OTSSubmission {
-	// Look for otsSubmitted system transaction in receipts
+// extractOTSSubmissions extracts OTS submissions from block transactions
+func (te *TransitionEngine) extractOTSSubmissions(header *types.Header, receipts types.Receipts) []*OTSSubmission {
+	var submissions []*OTSSubmission
+	// Look for otsSubmitted system transactions in receipts
 	for _, receipt := range receipts {
 		if receipt.Status != types.ReceiptStatusSuccessful {
 			continue
 		}
 		// Check transaction logs for OTS submission event
 		for _, log := range receipt.Logs {
-			if log.Address == copyrightRegistryAddr {
+			if log.Address == te.registry {
 				// Parse OTSSubmitted event if present
 				submission := te.parseOTSSubmittedLog(log)
 				if submission != nil {
-					return submission
+					submissions = append(submissions, submission)
 				}
 			}
 		}
 	}
-	return nil
+	return submissions
 }
 
 // parseOTSSubmittedLog parses an OTSSubmitted event log
@@ -303,41 +414,51 @@ type BTCConfirmation struct {
 	BTCBlockHeight uint64
 	BTCTxID        string
 	BTCTimestamp   uint64
+	ProofHash      common.Hash
 }
 
-// extractBTCConfirmation extracts BTC confirmation info from block transactions
-func (te *TransitionEngine) extractBTCConfirmation(header *types.Header, receipts types.Receipts) *BTCConfirmation {
-	// Look for otsConfirmed system transaction in receipts
+// extractBTCConfirmations extracts BTC confirmations from block transactions
+func (te *TransitionEngine) extractBTCConfirmations(header *types.Header, receipts types.Receipts) []*BTCConfirmation {
+	var confirmations []*BTCConfirmation
+	// Look for otsConfirmed system transactions in receipts
 	for _, receipt := range receipts {
 		if receipt.Status != types.ReceiptStatusSuccessful {
 			continue
 		}
 		for _, log := range receipt.Logs {
-			if log.Address == copyrightRegistryAddr {
+			if log.Address == te.registry {
 				confirmation := te.parseOTSConfirmedLog(log)
 				if confirmation != nil {
-					return confirmation
+					confirmations = append(confirmations, confirmation)
 				}
 			}
 		}
 	}
-	return nil
+	return confirmations
 }
 
 // parseOTSConfirmedLog parses an OTSConfirmed event log
 func (te *TransitionEngine) parseOTSConfirmedLog(log *types.Log) *BTCConfirmation {
-	// Event: OTSConfirmed(bytes32 indexed rootHash, uint64 btcBlockHeight, bytes32 btcTxID, uint64 btcTimestamp)
+	// Event: OTSConfirmed(bytes32 indexed rootHash, uint64 btcBlockHeight, bytes32 btcTxID, uint64 btcTimestamp, bytes32 proofHash)
 	// Topics[0] = event signature
 	// Topics[1] = rootHash (indexed)
-	// Data = btcBlockHeight (32 bytes) + btcTxID (32 bytes) + btcTimestamp (32 bytes)
+	// Data = btcBlockHeight (32 bytes) + btcTxID (32 bytes) + btcTimestamp (32 bytes) + proofHash (32 bytes)
+	// The legacy event has no proofHash.
 
-	otsConfirmedSig := crypto.Keccak256Hash([]byte("OTSConfirmed(bytes32,uint64,bytes32,uint64)"))
-
-	if len(log.Topics) < 2 || log.Topics[0] != otsConfirmedSig {
+	if len(log.Topics) < 2 {
+		return nil
+	}
+	dataSize := 0
+	switch log.Topics[0] {
+	case otsConfirmedSig:
+		dataSize = 128
+	case otsConfirmedLegacySig:
+		dataSize = 96
+	default:
 		return nil
 	}
 
-	if len(log.Data) < 96 {
+	if len(log.Data) < dataSize {
 		return nil
 	}
 
@@ -346,89 +467,20 @@ func (te *TransitionEngine) parseOTSConfirmedLog(log *types.Log) *BTCConfirmatio
 	btcTxID := common.Bytes2Hex(log.Data[32:64])
 	btcTimestamp := common.BytesToHash(log.Data[64:96]).Big().Uint64()
 
+	var proofHash common.Hash
+	if dataSize == 128 {
+		proofHash = common.BytesToHash(log.Data[96:128])
+	}
+
 	return &BTCConfirmation{
 		RootHash:       log.Topics[1],
 		BTCBlockHeight: btcBlockHeight,
 		BTCTxID:        btcTxID,
 		BTCTimestamp:   btcTimestamp,
+		ProofHash:      proofHash,
 	}
 }
 
-// hasValidAnchorTx checks if the block contains a valid anchor transaction
-func (te *TransitionEngine) hasValidAnchorTx(header *types.Header, receipts types.Receipts, batch *BatchState) bool {
-	// Look for anchor system transaction that matches current batch
-	for _, receipt := range receipts {
-		if receipt.Status != types.ReceiptStatusSuccessful {
-			continue
-		}
-		for _, log := range receipt.Logs {
-			if log.Address == copyrightRegistryAddr {
-				if te.isValidAnchorLog(log, batch) {
-					return true
-				}
-			}
-		}
-	}
-	return false
-}
-
-// isValidAnchorLog checks if a log is a valid Anchored event for the batch
-func (te *TransitionEngine) isValidAnchorLog(log *types.Log, batch *BatchState) bool {
-	// Event: Anchored(bytes32 indexed rootHash, uint64 startBlock, uint64 endBlock, uint64 btcBlockHeight)
-	// Topics[0] = event signature
-	// Topics[1] = rootHash (indexed)
-
-	anchoredSig := crypto.Keccak256Hash([]byte("Anchored(bytes32,uint64,uint64,uint64)"))
-
-	if len(log.Topics) < 2 || log.Topics[0] != anchoredSig {
-		return false
-	}
-
-	// Verify rootHash matches
-	if log.Topics[1] != batch.RootHash {
-		return false
-	}
-
-	return true
-}
-
-// buildMerkleRoot constructs a Merkle root from a list of RUIDs
-// Uses Bitcoin-style duplication for odd number of nodes
-func buildMerkleRoot(ruids []common.Hash) common.Hash {
-	if len(ruids) == 0 {
-		return common.Hash{}
-	}
-
-	// Build leaf hashes: leafHash = keccak256(ruid)
-	leaves := make([]common.Hash, len(ruids))
-	for i, ruid := range ruids {
-		leaves[i] = crypto.Keccak256Hash(ruid[:])
-	}
-
-	// Build tree layers (Bitcoin-style: duplicate last node if odd count)
-	currentLayer := leaves
-	for len(currentLayer) > 1 {
-		// If odd number of nodes, duplicate the last one
-		if len(currentLayer)%2 == 1 {
-			currentLayer = append(currentLayer, currentLayer[len(currentLayer)-1])
-		}
-
-		nextLayer := make([]common.Hash, len(currentLayer)/2)
-		for i := 0; i < len(currentLayer); i += 2 {
-			// Combine two nodes: sort them first for deterministic ordering
-			left, right := currentLayer[i], currentLayer[i+1]
-			if bytes.Compare(left[:], right[:]) > 0 {
-				left, right = right, left
-			}
-			combined := append(left[:], right[:]...)
-			nextLayer[i/2] = crypto.Keccak256Hash(combined)
-		}
-		currentLayer = nextLayer
-	}
-
-	return currentLayer[0]
-}
-
 // RebuildState rebuilds OTS state from chain data starting from a snapshot
 func (te *TransitionEngine) RebuildState(fromSnap *Snapshot, targetNumber uint64, getHeader func(uint64) *types.Header) (*Snapshot, error) {
 	currentSnap := fromSnap.Copy()
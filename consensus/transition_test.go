@@ -0,0 +1,288 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ots/merkle"
+	"github.com/ethereum/go-ethereum/ots/systx"
+)
+
+// testChain is a header chain with receipts, indexed by block hash
+type testChain struct {
+	headers  []*types.Header
+	receipts map[common.Hash]types.Receipts
+}
+
+func (c *testChain) getHeader(hash common.Hash, number uint64) *types.Header {
+	if number < uint64(len(c.headers)) && c.headers[number].Hash() == hash {
+		return c.headers[number]
+	}
+	return nil
+}
+
+func (c *testChain) getReceipts(hash common.Hash, number uint64) types.Receipts {
+	return c.receipts[hash]
+}
+
+func claimLog(registry common.Address, ruid common.Hash) *types.Log {
+	return &types.Log{
+		Address: registry,
+		Topics:  []common.Hash{CopyrightClaimedEventSig, ruid, common.BytesToHash(common.Address{0x01}.Bytes())},
+		Data:    make([]byte, 32),
+	}
+}
+
+// newTestChain builds blocks 0..len(claims) one hour apart, starting on the
+// day before the trigger, followed by a block after midnight UTC. Block i+1
+// carries a receipt with claims[i].
+func newTestChain(registry common.Address, claims [][]*types.Log) *testChain {
+	midnight := time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC)
+	start := midnight.Add(-time.Duration(len(claims)+1) * time.Hour)
+
+	chain := &testChain{receipts: make(map[common.Hash]types.Receipts)}
+	parent := common.Hash{}
+	for i := 0; i <= len(claims)+1; i++ {
+		header := &types.Header{
+			ParentHash:  parent,
+			Number:      big.NewInt(int64(i)),
+			Time:        uint64(start.Add(time.Duration(i) * time.Hour).Unix()),
+			ReceiptHash: types.EmptyReceiptsHash,
+		}
+		if i == len(claims)+1 {
+			header.Time = uint64(midnight.Add(time.Minute).Unix())
+		}
+		var receipts types.Receipts
+		if i > 0 && i <= len(claims) {
+			receipts = types.Receipts{{Status: types.ReceiptStatusSuccessful, Logs: claims[i-1]}}
+			header.ReceiptHash = common.Hash{byte(i)} // any non-empty root
+		}
+		chain.headers = append(chain.headers, header)
+		chain.receipts[header.Hash()] = receipts
+		parent = header.Hash()
+	}
+	return chain
+}
+
+// replay processes the chain on a fresh node and returns the last state
+func replay(t *testing.T, chain *testChain) *OTSState {
+	return replaySchedule(t, chain, DefaultTriggerSchedule)
+}
+
+// replaySchedule is replay with the given trigger schedule
+func replaySchedule(t *testing.T, chain *testChain, schedule TriggerSchedule) *OTSState {
+	return replayFork(t, chain, schedule, nil)
+}
+
+// replayFork is replay with the given trigger schedule and activation fork
+func replayFork(t *testing.T, chain *testChain, schedule TriggerSchedule, fork *ForkActivation) *OTSState {
+	t.Helper()
+	snapshots, err := NewSnapshotManager(rawdb.NewMemoryDatabase(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewTransitionEngine(snapshots, chain.getReceipts, chain.getHeader)
+	engine.SetTriggerSchedule(schedule)
+	engine.SetForkActivation(fork)
+
+	snap := snapshots.GetGenesisSnapshot(chain.headers[0].Hash())
+	for _, header := range chain.headers[1:] {
+		if snap, err = engine.ProcessBlock(header, snap); err != nil {
+			t.Fatalf("ProcessBlock %d failed: %v", header.Number, err)
+		}
+	}
+	return snap.State
+}
+
+func TestTransitionEngine_RootFromReceipts(t *testing.T) {
+	registry := common.HexToAddress(CopyrightRegistryAddress)
+	a, b, c := common.Hash{0xaa}, common.Hash{0x0b}, common.Hash{0xcc}
+
+	other := claimLog(common.HexToAddress("0x1234"), common.Hash{0xee})
+	wrongEvent := claimLog(registry, common.Hash{0xef})
+	wrongEvent.Topics[0] = common.Hash{0x01}
+	short := claimLog(registry, common.Hash{0xfe})
+	short.Topics = short.Topics[:2]
+
+	chain := newTestChain(registry, [][]*types.Log{
+		{claimLog(registry, a), other},
+		{wrongEvent, claimLog(registry, b), short},
+		{claimLog(registry, c), claimLog(registry, a)}, // a is claimed twice
+	})
+
+	state := replay(t, chain)
+	if state.OldestBatch() == nil || state.OldestBatch().Status != BatchStatusTriggered {
+		t.Fatalf("expected a triggered batch, got %+v", state.OldestBatch())
+	}
+	if state.OldestBatch().StartBlock != 1 || state.OldestBatch().EndBlock != 3 {
+		t.Errorf("range = [%d, %d], want [1, 3]", state.OldestBatch().StartBlock, state.OldestBatch().EndBlock)
+	}
+
+	// Only registry claims count. Without the Merkle fork the root is the
+	// legacy one, over every claim.
+	want, _ := merkle.ComputeRootWithScheme(merkle.SchemeLegacy, []common.Hash{a, b, c, a})
+	if state.OldestBatch().RootHash != want || state.OldestBatch().MerkleScheme != merkle.SchemeLegacy {
+		t.Errorf("RootHash = %x scheme %d, want %x", state.OldestBatch().RootHash, state.OldestBatch().MerkleScheme, want)
+	}
+
+	// From the fork on, each RUID counts once
+	v1Block := uint64(4)
+	state = replayFork(t, chain, DefaultTriggerSchedule, &ForkActivation{MerkleV1Block: &v1Block})
+	if root := merkle.ComputeRoot([]common.Hash{a, b, c}); state.OldestBatch().RootHash != root || state.OldestBatch().MerkleScheme != merkle.SchemeV1 {
+		t.Errorf("RootHash = %x scheme %d, want %x", state.OldestBatch().RootHash, state.OldestBatch().MerkleScheme, root)
+	}
+
+	// A trigger before the fork keeps the legacy root
+	v1Block = 5
+	state = replayFork(t, chain, DefaultTriggerSchedule, &ForkActivation{MerkleV1Block: &v1Block})
+	if state.OldestBatch().RootHash != want {
+		t.Errorf("RootHash = %x before the fork, want %x", state.OldestBatch().RootHash, want)
+	}
+}
+
+func TestTransitionEngine_RootIsDeterministic(t *testing.T) {
+	registry := common.HexToAddress(CopyrightRegistryAddress)
+	var claims [][]*types.Log
+	for i := 0; i < 5; i++ {
+		claims = append(claims, []*types.Log{
+			claimLog(registry, common.BigToHash(big.NewInt(int64(1000-i)))),
+			claimLog(registry, common.BigToHash(big.NewInt(int64(i)))),
+		})
+	}
+	first := newTestChain(registry, claims)
+
+	// The second node has raw receipts: no derived log fields
+	second := &testChain{headers: first.headers, receipts: make(map[common.Hash]types.Receipts)}
+	for hash, receipts := range first.receipts {
+		var raw types.Receipts
+		for _, r := range receipts {
+			var logs []*types.Log
+			for _, l := range r.Logs {
+				logs = append(logs, &types.Log{Address: l.Address, Topics: l.Topics, Data: l.Data})
+			}
+			raw = append(raw, &types.Receipt{Status: r.Status, Logs: logs})
+		}
+		second.receipts[hash] = raw
+	}
+	for i, r := range first.receipts[first.headers[2].Hash()][0].Logs {
+		r.BlockNumber, r.TxIndex, r.Index = 2, 0, uint(i)
+	}
+
+	a, b := replay(t, first), replay(t, second)
+	if a.OldestBatch() == nil || b.OldestBatch() == nil {
+		t.Fatal("both nodes should trigger a batch")
+	}
+	if a.OldestBatch().RootHash == (common.Hash{}) {
+		t.Fatal("root should commit to the claims")
+	}
+	if a.OldestBatch().RootHash != b.OldestBatch().RootHash {
+		t.Errorf("nodes disagree on root: %x != %x", a.OldestBatch().RootHash, b.OldestBatch().RootHash)
+	}
+	if a.Hash() != b.Hash() {
+		t.Error("nodes disagree on state hash")
+	}
+}
+
+func TestTransitionEngine_MissingReceiptsFailBlock(t *testing.T) {
+	registry := common.HexToAddress(CopyrightRegistryAddress)
+	chain := newTestChain(registry, [][]*types.Log{{claimLog(registry, common.Hash{0x01})}})
+	snapshots, err := NewSnapshotManager(rawdb.NewMemoryDatabase(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewTransitionEngine(snapshots, chain.getReceipts, chain.getHeader)
+	genesis := snapshots.GetGenesisSnapshot(chain.headers[0].Hash())
+	snap, err := engine.ProcessBlock(chain.headers[1], genesis)
+	if err != nil {
+		t.Fatalf("ProcessBlock 1 failed: %v", err)
+	}
+
+	// Triggering with a guessed root would diverge from nodes with receipts
+	receipts := chain.receipts[chain.headers[1].Hash()]
+	delete(chain.receipts, chain.headers[1].Hash())
+	trigger := chain.headers[2]
+	if _, err := engine.ProcessBlock(trigger, snap); !errors.Is(err, errMissingChainData) {
+		t.Fatalf("expected errMissingChainData at the trigger, got %v", err)
+	}
+	if snapshots.HasSnapshot(trigger.Hash()) {
+		t.Error("snapshot stored for a block processed without chain data")
+	}
+
+	// A block missing its own receipts is not applied either
+	if _, err := engine.ProcessBlock(chain.headers[1], genesis); !errors.Is(err, errMissingChainData) {
+		t.Errorf("expected errMissingChainData for the block, got %v", err)
+	}
+
+	chain.receipts[chain.headers[1].Hash()] = receipts
+	next, err := engine.ProcessBlock(trigger, snap)
+	if err != nil || next.State.OldestBatch() == nil {
+		t.Errorf("batch not triggered once receipts are available: %v", err)
+	}
+}
+
+func TestParseOTSConfirmedLog(t *testing.T) {
+	te := &TransitionEngine{}
+	rootHash, proofHash := common.HexToHash("0xaa"), common.HexToHash("0xcc")
+	data := append(common.BigToHash(big.NewInt(800000)).Bytes(), make([]byte, 32)...)
+	data = append(data, common.BigToHash(big.NewInt(1700000000)).Bytes()...)
+
+	legacy := te.parseOTSConfirmedLog(&types.Log{Topics: []common.Hash{otsConfirmedLegacySig, rootHash}, Data: data})
+	if legacy == nil || legacy.RootHash != rootHash || legacy.BTCBlockHeight != 800000 || legacy.ProofHash != (common.Hash{}) {
+		t.Errorf("unexpected legacy confirmation %+v", legacy)
+	}
+
+	committed := te.parseOTSConfirmedLog(&types.Log{Topics: []common.Hash{otsConfirmedSig, rootHash}, Data: append(data, proofHash.Bytes()...)})
+	if committed == nil || committed.BTCTimestamp != 1700000000 || committed.ProofHash != proofHash {
+		t.Errorf("unexpected confirmation %+v", committed)
+	}
+
+	// The proof hash is required by the new event
+	if c := te.parseOTSConfirmedLog(&types.Log{Topics: []common.Hash{otsConfirmedSig, rootHash}, Data: data}); c != nil {
+		t.Errorf("short event parsed: %+v", c)
+	}
+}
+
+// anchoredLog returns an Anchored event log of the registry
+func anchoredLog(registry common.Address, rootHash common.Hash, start, end uint64) *types.Log {
+	var data []byte
+	for _, n := range []uint64{start, end, 800000} {
+		data = append(data, common.BigToHash(new(big.Int).SetUint64(n)).Bytes()...)
+	}
+	return &types.Log{Address: registry, Topics: []common.Hash{systx.AnchoredEventTopic, rootHash}, Data: data}
+}
+
+func TestTransitionEngine_AnchoredRange(t *testing.T) {
+	registry := common.HexToAddress(CopyrightRegistryAddress)
+	te := NewTransitionEngine(nil, nil, func(common.Hash, uint64) *types.Header { return nil })
+
+	// Two confirmed empty batches share the zero root
+	state := NewOTSState(true)
+	for _, r := range [][2]uint64{{1, 10}, {11, 20}} {
+		_ = state.Trigger(r[0], r[1], r[1]+1, common.Address{}, common.Hash{})
+		_ = state.MarkSubmitted(common.Hash{}, [32]byte{byte(r[0])}, 30, common.Address{})
+		_ = state.MarkConfirmed(common.Hash{}, 800000, "tx", 1700000000, common.Hash{}, 31, common.Address{})
+	}
+
+	header := &types.Header{Number: big.NewInt(40), ReceiptHash: common.Hash{0x01}}
+	receipts := types.Receipts{{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{
+		anchoredLog(registry, common.Hash{}, 5, 20), // no such batch
+		anchoredLog(registry, common.Hash{}, 11, 20),
+	}}}
+	if err := te.applyTransitions(state, header, receipts); err != nil {
+		t.Fatal(err)
+	}
+	if state.Batches[0].Status != BatchStatusConfirmed || state.Batches[1].Status != BatchStatusAnchored {
+		t.Errorf("expected only blocks 11-20 anchored, got %v and %v", state.Batches[0].Status, state.Batches[1].Status)
+	}
+	if state.LastAnchoredBlock != 0 {
+		t.Errorf("LastAnchoredBlock = %d, want 0", state.LastAnchoredBlock)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+//
+// This file implements the batch trigger policy. Policies are part of the
+// chain configuration and are evaluated from block data only, so every node
+// triggers the same batches.
+
+package consensus
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// secondsPerDay is the interval of the default daily trigger
+	secondsPerDay = 24 * 60 * 60
+
+	// DefaultSubmitTimeout is the number of blocks a batch may wait for its
+	// otsSubmitted transaction, about an hour at 3s blocks
+	DefaultSubmitTimeout = 1200
+
+	// DefaultConfirmTimeout is the number of blocks a submitted batch may wait
+	// for its otsConfirmed transaction, about two days at 3s blocks
+	DefaultConfirmTimeout = 57600
+)
+
+var (
+	ErrEmptyTriggerSchedule   = errors.New("trigger schedule is empty")
+	ErrTriggerScheduleGenesis = errors.New("trigger schedule must start at block 0")
+	ErrTriggerScheduleOrder   = errors.New("trigger schedule blocks must be strictly increasing")
+	ErrInvalidTriggerPolicy   = errors.New("invalid trigger policy")
+)
+
+// DefaultTriggerSchedule triggers once a day when block time crosses
+// TriggerHourUTC
+var DefaultTriggerSchedule = TriggerSchedule{{
+	Interval: secondsPerDay,
+	Offset:   TriggerHourUTC * 60 * 60,
+}}
+
+// TriggerPolicy decides which blocks trigger a new batch. A block triggers
+// when any of the configured conditions holds; zero values are disabled.
+type TriggerPolicy struct {
+	// Block is the block number from which the policy applies
+	Block uint64 `json:"block"`
+
+	// Interval triggers on the first block whose timestamp crosses a multiple
+	// of Interval seconds since the Unix epoch, shifted by Offset seconds.
+	// Interval 86400 with Offset 3600 triggers daily at 01:00 UTC.
+	Interval uint64 `json:"interval,omitempty"`
+	Offset   uint64 `json:"offset,omitempty"`
+
+	// Blocks triggers once the blocks not yet batched span at least this many
+	// blocks
+	Blocks uint64 `json:"blocks,omitempty"`
+
+	// Claims triggers once this many CopyrightClaimed events are pending.
+	// Claims are counted from the block the policy applies.
+	Claims uint64 `json:"claims,omitempty"`
+
+	// SubmitTimeout fails a batch without otsSubmitted transaction this many
+	// blocks after its trigger, and ConfirmTimeout one without otsConfirmed
+	// transaction this many blocks after its submission. Zero selects
+	// DefaultSubmitTimeout and DefaultConfirmTimeout.
+	SubmitTimeout  uint64 `json:"submitTimeout,omitempty"`
+	ConfirmTimeout uint64 `json:"confirmTimeout,omitempty"`
+}
+
+// timeouts returns the submit and confirm timeouts of the policy
+func (p *TriggerPolicy) timeouts() (submit, confirm uint64) {
+	submit, confirm = p.SubmitTimeout, p.ConfirmTimeout
+	if submit == 0 {
+		submit = DefaultSubmitTimeout
+	}
+	if confirm == 0 {
+		confirm = DefaultConfirmTimeout
+	}
+	return submit, confirm
+}
+
+// validate checks that the policy has a usable condition
+func (p *TriggerPolicy) validate() error {
+	if p.Interval == 0 && p.Blocks == 0 && p.Claims == 0 {
+		return fmt.Errorf("%w: no trigger condition at block %d", ErrInvalidTriggerPolicy, p.Block)
+	}
+	if p.Offset != 0 && p.Offset >= p.Interval {
+		return fmt.Errorf("%w: offset %d not below interval %d at block %d", ErrInvalidTriggerPolicy, p.Offset, p.Interval, p.Block)
+	}
+	return nil
+}
+
+// period returns the index of the interval containing the given time
+func (p *TriggerPolicy) period(time uint64) uint64 {
+	return (time + p.Interval - p.Offset) / p.Interval
+}
+
+// crossesInterval reports whether an interval boundary lies in
+// (parentTime, time]
+func (p *TriggerPolicy) crossesInterval(parentTime, time uint64) bool {
+	return p.Interval > 0 && p.period(time) > p.period(parentTime)
+}
+
+// TriggerSchedule is the list of trigger policies by activation block. The
+// first policy applies from genesis and later ones replace it at fork blocks.
+type TriggerSchedule []TriggerPolicy
+
+// Validate checks that the schedule is ordered and every policy is usable
+func (s TriggerSchedule) Validate() error {
+	if len(s) == 0 {
+		return ErrEmptyTriggerSchedule
+	}
+	if s[0].Block != 0 {
+		return ErrTriggerScheduleGenesis
+	}
+	for i := range s {
+		if i > 0 && s[i].Block <= s[i-1].Block {
+			return ErrTriggerScheduleOrder
+		}
+		if err := s[i].validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PolicyAt returns the policy that applies to the given block
+func (s TriggerSchedule) PolicyAt(number uint64) *TriggerPolicy {
+	for i := len(s) - 1; i > 0; i-- {
+		if number >= s[i].Block {
+			return &s[i]
+		}
+	}
+	return &s[0]
+}
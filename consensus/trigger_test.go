@@ -0,0 +1,230 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// newTimedChain builds blocks 0..len(times)-1 with the given timestamps.
+// Block i carries one claim per RUID in claims[i].
+func newTimedChain(times []time.Time, claims map[int][]common.Hash) *testChain {
+	registry := common.HexToAddress(CopyrightRegistryAddress)
+	chain := &testChain{receipts: make(map[common.Hash]types.Receipts)}
+	parent := common.Hash{}
+	for i, blockTime := range times {
+		header := &types.Header{
+			ParentHash:  parent,
+			Number:      big.NewInt(int64(i)),
+			Time:        uint64(blockTime.Unix()),
+			ReceiptHash: types.EmptyReceiptsHash,
+		}
+		var receipts types.Receipts
+		if ruids := claims[i]; len(ruids) > 0 {
+			var logs []*types.Log
+			for _, ruid := range ruids {
+				logs = append(logs, claimLog(registry, ruid))
+			}
+			receipts = types.Receipts{{Status: types.ReceiptStatusSuccessful, Logs: logs}}
+			header.ReceiptHash = common.Hash{byte(i + 1)}
+		}
+		chain.headers = append(chain.headers, header)
+		chain.receipts[header.Hash()] = receipts
+		parent = header.Hash()
+	}
+	return chain
+}
+
+// sameHour returns n block times one second apart
+func sameHour(n int) []time.Time {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	times := make([]time.Time, n)
+	for i := range times {
+		times[i] = start.Add(time.Duration(i) * time.Second)
+	}
+	return times
+}
+
+func TestTriggerPolicy_CrossesInterval(t *testing.T) {
+	at := func(year int, month time.Month, day, hour, min, sec int) uint64 {
+		return uint64(time.Date(year, month, day, hour, min, sec, 0, time.UTC).Unix())
+	}
+	daily := &DefaultTriggerSchedule[0]
+	sixHours := &TriggerPolicy{Interval: 6 * 60 * 60}
+	oneAM := &TriggerPolicy{Interval: secondsPerDay, Offset: 60 * 60}
+
+	tests := []struct {
+		name    string
+		policy  *TriggerPolicy
+		parent  uint64
+		current uint64
+		want    bool
+	}{
+		{"day rollover", daily, at(2024, 6, 1, 23, 59, 57), at(2024, 6, 2, 0, 0, 0), true},
+		{"year rollover", daily, at(2024, 12, 31, 23, 59, 59), at(2025, 1, 1, 0, 0, 2), true},
+		{"leap day", daily, at(2024, 2, 28, 23, 59, 59), at(2024, 2, 29, 0, 0, 1), true},
+		{"same day", daily, at(2024, 6, 1, 0, 0, 0), at(2024, 6, 1, 23, 59, 59), false},
+		{"boundary in parent", daily, at(2024, 6, 2, 0, 0, 0), at(2024, 6, 2, 0, 0, 3), false},
+		{"skipped days", daily, at(2024, 6, 1, 12, 0, 0), at(2024, 6, 4, 12, 0, 0), true},
+		{"six hours", sixHours, at(2024, 6, 1, 5, 59, 59), at(2024, 6, 1, 6, 0, 2), true},
+		{"within six hours", sixHours, at(2024, 6, 1, 6, 0, 2), at(2024, 6, 1, 11, 59, 59), false},
+		{"offset hour", oneAM, at(2024, 6, 1, 0, 59, 59), at(2024, 6, 1, 1, 0, 0), true},
+		{"offset midnight", oneAM, at(2024, 6, 1, 23, 59, 59), at(2024, 6, 2, 0, 0, 2), false},
+		{"offset year rollover", oneAM, at(2024, 12, 31, 23, 0, 0), at(2025, 1, 1, 1, 0, 0), true},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.crossesInterval(tt.parent, tt.current); got != tt.want {
+			t.Errorf("%s: crossesInterval = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTriggerSchedule_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule TriggerSchedule
+		want     error
+	}{
+		{"default", DefaultTriggerSchedule, nil},
+		{"combined", TriggerSchedule{{Interval: 3600, Blocks: 100, Claims: 50}}, nil},
+		{"fork", TriggerSchedule{{Interval: secondsPerDay}, {Block: 100, Claims: 10}}, nil},
+		{"empty", TriggerSchedule{}, ErrEmptyTriggerSchedule},
+		{"late start", TriggerSchedule{{Block: 1, Blocks: 10}}, ErrTriggerScheduleGenesis},
+		{"unordered", TriggerSchedule{{Blocks: 10}, {Block: 5, Blocks: 2}, {Block: 5, Blocks: 3}}, ErrTriggerScheduleOrder},
+		{"no condition", TriggerSchedule{{Blocks: 10}, {Block: 5}}, ErrInvalidTriggerPolicy},
+		{"offset without interval", TriggerSchedule{{Blocks: 10, Offset: 60}}, ErrInvalidTriggerPolicy},
+		{"offset too large", TriggerSchedule{{Interval: 3600, Offset: 3600}}, ErrInvalidTriggerPolicy},
+	}
+	for _, tt := range tests {
+		if err := tt.schedule.Validate(); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Validate = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestTriggerSchedule_PolicyAt(t *testing.T) {
+	schedule := TriggerSchedule{{Blocks: 1}, {Block: 10, Blocks: 2}, {Block: 20, Blocks: 3}}
+	for number, want := range map[uint64]uint64{0: 1, 9: 1, 10: 2, 19: 2, 20: 3, 1000: 3} {
+		if got := schedule.PolicyAt(number).Blocks; got != want {
+			t.Errorf("PolicyAt(%d) = policy %d, want %d", number, got, want)
+		}
+	}
+}
+
+func TestTransitionEngine_BlockTrigger(t *testing.T) {
+	chain := newTimedChain(sameHour(6), nil)
+
+	state := replaySchedule(t, chain, TriggerSchedule{{Blocks: 3}})
+	if state.OldestBatch() == nil {
+		t.Fatal("expected a triggered batch")
+	}
+	if b := state.OldestBatch(); b.TriggerBlock != 4 || b.StartBlock != 1 || b.EndBlock != 3 {
+		t.Errorf("batch = trigger %d range [%d, %d], want trigger 4 range [1, 3]", b.TriggerBlock, b.StartBlock, b.EndBlock)
+	}
+
+	// No time boundary is crossed, so the daily policy does not trigger
+	if state := replay(t, chain); state.OldestBatch() != nil {
+		t.Errorf("daily policy triggered within the hour: %+v", state.OldestBatch())
+	}
+}
+
+func TestTransitionEngine_ClaimTrigger(t *testing.T) {
+	chain := newTimedChain(sameHour(7), map[int][]common.Hash{
+		1: {{0x01}},
+		3: {{0x02}, {0x03}},
+		4: {{0x04}}, // claimed in the trigger block, belongs to the next batch
+		5: {{0x05}},
+	})
+
+	state := replaySchedule(t, chain, TriggerSchedule{{Claims: 3}})
+	if state.OldestBatch() == nil {
+		t.Fatal("expected a triggered batch")
+	}
+	if b := state.OldestBatch(); b.TriggerBlock != 4 || b.EndBlock != 3 {
+		t.Errorf("batch = trigger %d end %d, want trigger 4 end 3", b.TriggerBlock, b.EndBlock)
+	}
+	if state.PendingClaims != 2 {
+		t.Errorf("PendingClaims = %d, want 2", state.PendingClaims)
+	}
+
+	// Without a claim threshold nothing is counted, keeping the state hash
+	// unchanged for chains that do not use it
+	if state := replaySchedule(t, chain, TriggerSchedule{{Blocks: 100}}); state.PendingClaims != 0 {
+		t.Errorf("PendingClaims = %d without claim policy", state.PendingClaims)
+	}
+}
+
+func TestTransitionEngine_PolicyChangeAtFork(t *testing.T) {
+	// Blocks 1-3 before midnight, blocks 4-7 after
+	midnight := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var times []time.Time
+	for i := -4; i < 4; i++ {
+		times = append(times, midnight.Add(time.Duration(i)*time.Minute+time.Second))
+	}
+	chain := newTimedChain(times, map[int][]common.Hash{2: {{0x01}}, 5: {{0x02}}})
+
+	// The daily policy would trigger at block 4; the fork replaces it first
+	schedule := TriggerSchedule{
+		{Interval: secondsPerDay},
+		{Block: 3, Claims: 1},
+	}
+	state := replaySchedule(t, chain, schedule)
+	if state.OldestBatch() == nil {
+		t.Fatal("expected a triggered batch")
+	}
+	// The claim in block 2 predates the fork and is not counted, so the
+	// batch triggers after the claim in block 5
+	if b := state.OldestBatch(); b.TriggerBlock != 6 || b.StartBlock != 1 || b.EndBlock != 5 {
+		t.Errorf("batch = trigger %d range [%d, %d], want trigger 6 range [1, 5]", b.TriggerBlock, b.StartBlock, b.EndBlock)
+	}
+
+	// Before the fork the daily policy still applies
+	schedule[1].Block = 5
+	if state := replaySchedule(t, chain, schedule); state.OldestBatch() == nil || state.OldestBatch().TriggerBlock != 4 {
+		t.Errorf("expected the daily trigger at block 4, got %+v", state.OldestBatch())
+	}
+}
+
+func TestTransitionEngine_ExpiredRangeRollsOver(t *testing.T) {
+	chain := newTimedChain(sameHour(9), nil)
+	schedule := TriggerSchedule{{Blocks: 2, SubmitTimeout: 3}}
+	upTo := func(number int) *testChain {
+		return &testChain{headers: chain.headers[:number+1], receipts: chain.receipts}
+	}
+
+	// Batches [1, 2] and [3, 4] trigger at blocks 3 and 5 and get no
+	// otsSubmitted transaction
+	state := replaySchedule(t, upTo(5), schedule)
+	if len(state.Batches) != 2 {
+		t.Fatalf("expected 2 batches, got %d", len(state.Batches))
+	}
+
+	// The first times out at block 6, the second stays queued
+	state = replaySchedule(t, upTo(6), schedule)
+	if len(state.Batches) != 1 || state.Batches[0].StartBlock != 3 || len(state.Expired) != 1 {
+		t.Fatalf("expected only the first batch expired, got %d queued and %d expired", len(state.Batches), len(state.Expired))
+	}
+	if b := state.Expired[0]; b.Status != BatchStatusFailed || b.FailedAt != 6 || b.StartBlock != 1 {
+		t.Errorf("unexpected expiry record %+v", b)
+	}
+
+	// The next block covers the failed range again, and the expiry is only
+	// recorded in the block where it happened
+	state = replaySchedule(t, upTo(7), schedule)
+	if len(state.Expired) != 0 {
+		t.Errorf("expiries carried over: %+v", state.Expired)
+	}
+	if b := state.OldestBatch(); b == nil || b.TriggerBlock != 7 || b.StartBlock != 1 || b.EndBlock != 2 {
+		t.Errorf("expected re-trigger of [1, 2] at block 7, got %+v", b)
+	}
+	if len(state.Batches) != 2 || state.NextStartBlock() != 5 {
+		t.Errorf("expected batches [1, 2] and [3, 4], got %d batches up to %d", len(state.Batches), state.NextStartBlock()-1)
+	}
+}
@@ -7,10 +7,19 @@ import "errors"
 
 // Configuration errors
 var (
-	ErrInvalidMode            = errors.New("ots: invalid mode, must be producer/watcher/full")
-	ErrInvalidTriggerHour     = errors.New("ots: invalid trigger hour, must be 0-23")
-	ErrInvalidConfirmations   = errors.New("ots: confirmations must be at least 1")
-	ErrInvalidContractAddress = errors.New("ots: contract address cannot be zero")
+	ErrInvalidMode             = errors.New("ots: invalid mode, must be producer/watcher/full")
+	ErrInvalidTriggerHour      = errors.New("ots: invalid trigger hour, must be 0-23")
+	ErrInvalidConfirmations    = errors.New("ots: confirmations must be at least 1")
+	ErrInvalidContractAddress  = errors.New("ots: contract address cannot be zero")
+	ErrInvalidExplorer         = errors.New("ots: invalid bitcoin explorer, must be blockstream/mempool/esplora/bitcoind/local/quorum")
+	ErrInvalidBitcoindConfig   = errors.New("ots: bitcoind explorer requires a URL and cookie file or user")
+	ErrInvalidHeaderFile       = errors.New("ots: local explorer requires a header file")
+	ErrInvalidHeaderCheckpoint = errors.New("ots: header checkpoint must be a block hash at a multiple of 2016")
+	ErrInvalidEsploraURL       = errors.New("ots: esplora explorer requires a URL")
+	ErrInvalidExplorerQuorum   = errors.New("ots: explorer quorum must be between 1 and the number of explorer backends")
+	ErrInvalidCalendarQuorum   = errors.New("ots: minimum calendar responses exceeds the number of calendar servers")
+	ErrInvalidOTSClient        = errors.New("ots: invalid OTS client, must be native/cli")
+	ErrInvalidOTSBinary        = errors.New("ots: cli client requires a binary path")
 )
 
 // Module lifecycle errors
@@ -18,6 +27,7 @@ var (
 	ErrModuleNotStarted = errors.New("ots: module not started")
 	ErrModuleAlreadyStarted = errors.New("ots: module already started")
 	ErrModuleStopping   = errors.New("ots: module is stopping")
+	ErrConsensusNotReady = errors.New("ots: consensus manager not set")
 )
 
 // Processing errors
@@ -27,6 +37,7 @@ var (
 	ErrRootMismatch       = errors.New("ots: root hash mismatch")
 	ErrReorgDetected      = errors.New("ots: reorg detected")
 	ErrEmptyBatch         = errors.New("ots: empty batch, no events to process")
+	ErrProofNotCommitted  = errors.New("ots: no proof committed for root hash")
 )
 
 // Calendar errors
@@ -35,6 +46,7 @@ var (
 	ErrCalendarUnavailable = errors.New("ots: all calendar servers unavailable")
 	ErrCalendarSubmitFailed = errors.New("ots: calendar submit failed")
 	ErrBTCNotConfirmed     = errors.New("ots: BTC transaction not yet confirmed")
+	ErrOTSClientNotReady   = errors.New("ots: OTS client not initialized")
 )
 
 // Storage errors
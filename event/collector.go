@@ -198,8 +198,23 @@ func (c *Collector) queryLogs(ctx context.Context, startBlock, endBlock uint64)
 	return c.filterer.FilterLogs(ctx, query)
 }
 
+// IsCopyrightClaimedLog reports whether a log is a CopyrightClaimed event
+// emitted by contract, the selection the collector's log filter applies
+func IsCopyrightClaimedLog(logEntry *types.Log, contract common.Address) bool {
+	return !logEntry.Removed &&
+		logEntry.Address == contract &&
+		len(logEntry.Topics) > 0 &&
+		logEntry.Topics[0] == CopyrightClaimedEventSig
+}
+
 // parseLog parses a log entry into an EventForMerkle
 func (c *Collector) parseLog(logEntry *types.Log) (*otstypes.EventForMerkle, error) {
+	return ParseLog(logEntry)
+}
+
+// ParseLog parses a CopyrightClaimed log entry into an EventForMerkle. It is
+// shared with consensus, which reads the same events from block receipts.
+func ParseLog(logEntry *types.Log) (*otstypes.EventForMerkle, error) {
 	// CopyrightClaimed(bytes32 indexed ruid, address indexed claimant, uint64 submitBlock)
 	// Topics[0] = event signature
 	// Topics[1] = ruid (indexed)
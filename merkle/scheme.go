@@ -0,0 +1,100 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package merkle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Scheme identifies a Merkle construction. Batch roots committed in consensus
+// state, stored batch metadata and RPC proofs must all use the same scheme, so
+// any change to the construction requires a new version.
+type Scheme uint8
+
+const (
+	// SchemeLegacy is the construction consensus used before schemes were
+	// versioned, and the scheme of every batch recorded without one. It
+	// builds the tree over all RUIDs, duplicates included, in ascending byte
+	// order. Leaves are keccak256(ruid), a parent is keccak256(min || max) of
+	// its two children, and an odd node is paired with itself.
+	SchemeLegacy Scheme = 0
+
+	// SchemeV1 builds the tree over the distinct RUIDs in ascending byte
+	// order. Leaves and parents are hashed as in SchemeLegacy, but an odd
+	// node is promoted to the next level.
+	SchemeV1 Scheme = 1
+
+	// CurrentScheme is the newest scheme. Consensus only builds batch roots
+	// with it from its activation block on.
+	CurrentScheme = SchemeV1
+)
+
+var ErrUnknownScheme = errors.New("merkle: unknown scheme")
+
+// check returns ErrUnknownScheme if the scheme is not implemented
+func (s Scheme) check() error {
+	if s != SchemeLegacy && s != SchemeV1 {
+		return fmt.Errorf("%w: %d", ErrUnknownScheme, s)
+	}
+	return nil
+}
+
+// LeafRUIDs returns the RUIDs in the leaf order of the scheme: sorted
+// ascending, and without duplicates from SchemeV1 on. The input is not
+// modified.
+func (s Scheme) LeafRUIDs(ruids []common.Hash) []common.Hash {
+	if s == SchemeLegacy {
+		return sortedRUIDs(ruids)
+	}
+	return CanonicalRUIDs(ruids)
+}
+
+// sortedRUIDs returns a copy of the RUIDs sorted ascending
+func sortedRUIDs(ruids []common.Hash) []common.Hash {
+	sorted := make([]common.Hash, len(ruids))
+	copy(sorted, ruids)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i][:], sorted[j][:]) < 0
+	})
+	return sorted
+}
+
+// CanonicalRUIDs returns the leaf order of SchemeV1: the distinct RUIDs
+// sorted ascending. The input is not modified.
+func CanonicalRUIDs(ruids []common.Hash) []common.Hash {
+	sorted := sortedRUIDs(ruids)
+
+	unique := sorted[:0]
+	for i, ruid := range sorted {
+		if i == 0 || ruid != sorted[i-1] {
+			unique = append(unique, ruid)
+		}
+	}
+	return unique
+}
+
+// ComputeRoot returns the root of the RUIDs under the current scheme, or the
+// zero hash if there are none. The result does not depend on input order.
+func ComputeRoot(ruids []common.Hash) common.Hash {
+	root, _ := ComputeRootWithScheme(CurrentScheme, ruids)
+	return root
+}
+
+// ComputeRootWithScheme returns the root of the RUIDs under the given scheme,
+// or the zero hash if there are none
+func ComputeRootWithScheme(scheme Scheme, ruids []common.Hash) (common.Hash, error) {
+	tree, err := BuildWithScheme(scheme, ruids)
+	if errors.Is(err, ErrEmptyLeaves) {
+		return common.Hash{}, nil
+	}
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return tree.Root(), nil
+}
@@ -0,0 +1,177 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package merkle
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	otstypes "github.com/ethereum/go-ethereum/ots/types"
+)
+
+// ruidRange returns the RUIDs 1..n as big-endian hashes
+func ruidRange(n int) []common.Hash {
+	ruids := make([]common.Hash, n)
+	for i := range ruids {
+		ruids[i] = common.BigToHash(big.NewInt(int64(i + 1)))
+	}
+	return ruids
+}
+
+// TestSchemeV1Vectors pins the roots of the current scheme. Consensus state
+// commits to these roots, so a failure here is a consensus change.
+func TestSchemeV1Vectors(t *testing.T) {
+	vectors := []struct {
+		n    int
+		root string
+	}{
+		{1, "0xb10e2d527612073b26eecdfd717e6a320cf44b4afac2b0732d9fcbe2b7fa0cf6"},
+		{2, "0x2a171b5bcd1449348c3e09a5424946b5e6d6f5471221941d585131d673952ee4"},
+		{3, "0x4cdbcd942bd29b80bbd5eb9929ec8d0ea9c97d2690f9d2f8318390505ec1a769"},
+		{4, "0x9cb86f87624f55e4956a62a87acdd72769cdb21f746c27d345ef90343a9b2316"},
+		{5, "0x9be4d908ee1467e12177bdda3d2712a12e7a2445350dccd4be9c218066530b19"},
+		{6, "0x80b5135754798ea8ab086039183fa5c9e6e18b0bb5db3540747f611db69ae456"},
+		{7, "0x550fd329499a802d5d4f3d4622dc6c1da8efc442c0f3b4542d5872ecd3818bb9"},
+	}
+	for _, v := range vectors {
+		if root := ComputeRoot(ruidRange(v.n)); root != common.HexToHash(v.root) {
+			t.Errorf("%d leaves: root = %s, want %s", v.n, root.Hex(), v.root)
+		}
+	}
+	if root := ComputeRoot(nil); root != (common.Hash{}) {
+		t.Errorf("empty root = %s, want zero", root.Hex())
+	}
+}
+
+// TestSchemeLegacyVectors pins the roots consensus built before schemes were
+// versioned. Batches recorded without a scheme are rebuilt with it, so a
+// failure here breaks their proofs and the replay of pre-fork history.
+func TestSchemeLegacyVectors(t *testing.T) {
+	vectors := []struct {
+		n    int
+		root string
+	}{
+		{1, "0xb10e2d527612073b26eecdfd717e6a320cf44b4afac2b0732d9fcbe2b7fa0cf6"},
+		{2, "0x2a171b5bcd1449348c3e09a5424946b5e6d6f5471221941d585131d673952ee4"},
+		{3, "0x28ba380b3c6003d6d833999c98d92f7976556bd64d4101054d164a1e8deefe92"},
+		{4, "0x9cb86f87624f55e4956a62a87acdd72769cdb21f746c27d345ef90343a9b2316"},
+		{5, "0x75493d043d3e98ae09bb99c541305561e49e95f2ea36660301249611a10efc0c"},
+		{6, "0x51a5a614acd8b6fe85fe52352bf5f26b4d62f54f358ce25a5fb7299cb044143a"},
+		{7, "0xc84f3841eb1c1d9a0a9a4f3b7e876d3c901a6f2837b8c4cefe923f703ba6fef2"},
+	}
+	for _, v := range vectors {
+		root, err := ComputeRootWithScheme(SchemeLegacy, ruidRange(v.n))
+		if err != nil || root != common.HexToHash(v.root) {
+			t.Errorf("%d leaves: root = %s, %v, want %s", v.n, root.Hex(), err, v.root)
+		}
+	}
+
+	// Duplicates are leaves of their own, and every leaf still proves
+	r := ruidRange(3)
+	ruids := []common.Hash{r[2], r[0], r[1], r[0]}
+	tree, err := BuildWithScheme(SchemeLegacy, ruids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := common.HexToHash("0x59d1dae90d34f73fca20dd72591b74e1a8f3c1585cf71b7aebb4c71c22eb74a6"); tree.Root() != want {
+		t.Errorf("root with duplicates = %s, want %s", tree.Root().Hex(), want.Hex())
+	}
+	if tree.LeafCount() != len(ruids) {
+		t.Errorf("LeafCount = %d, want %d", tree.LeafCount(), len(ruids))
+	}
+	odd, err := BuildWithScheme(SchemeLegacy, ruidRange(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ruid := range ruidRange(5) {
+		proof, err := odd.GetProof(ruid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !proof.VerifyRUID(ruid) {
+			t.Errorf("legacy proof for %s does not verify", ruid.Hex())
+		}
+	}
+}
+
+func TestComputeRoot_OrderAndDuplicates(t *testing.T) {
+	ruids := ruidRange(5)
+	want := ComputeRoot(ruids)
+
+	shuffled := []common.Hash{ruids[3], ruids[0], ruids[4], ruids[2], ruids[1], ruids[3], ruids[0]}
+	if root := ComputeRoot(shuffled); root != want {
+		t.Errorf("root depends on order or duplicates: %s != %s", root.Hex(), want.Hex())
+	}
+	if shuffled[0] != ruids[3] {
+		t.Error("input was modified")
+	}
+}
+
+func TestBuild_ProofsAndSources(t *testing.T) {
+	ruids := ruidRange(5)
+
+	// Events in collector order must give the same tree as the RUIDs
+	events := make([]otstypes.EventForMerkle, len(ruids))
+	for i := range ruids {
+		events[i] = otstypes.EventForMerkle{
+			RUID:    ruids[len(ruids)-1-i],
+			SortKey: otstypes.SortKey{BlockNumber: uint64(i)},
+		}
+	}
+	fromEvents, err := BuildFromEvents(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromRUIDs, err := BuildFromRUIDs(ruids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromEvents.Root() != fromRUIDs.Root() || fromRUIDs.Root() != ComputeRoot(ruids) {
+		t.Fatal("builders disagree on root")
+	}
+	if fromRUIDs.Scheme() != CurrentScheme {
+		t.Errorf("Scheme = %d, want %d", fromRUIDs.Scheme(), CurrentScheme)
+	}
+
+	// Every leaf proves, including the promoted odd one
+	for _, ruid := range ruids {
+		proof, err := fromEvents.GetProof(ruid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !proof.VerifyRUID(ruid) {
+			t.Errorf("proof for %s does not verify", ruid.Hex())
+		}
+	}
+}
+
+func TestBuildWithScheme(t *testing.T) {
+	ruids := ruidRange(3)
+
+	tree, err := BuildWithScheme(SchemeV1, ruids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Scheme() != SchemeV1 || tree.Root() != ComputeRoot(ruids) {
+		t.Errorf("scheme %d tree does not match the current scheme", tree.Scheme())
+	}
+
+	// The schemes differ on an odd layer
+	legacy, err := BuildWithScheme(SchemeLegacy, ruids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if legacy.Scheme() != SchemeLegacy || legacy.Root() == tree.Root() {
+		t.Errorf("legacy tree has scheme %d and root %s", legacy.Scheme(), legacy.Root().Hex())
+	}
+
+	if _, err := BuildWithScheme(SchemeV1+1, ruids); !errors.Is(err, ErrUnknownScheme) {
+		t.Errorf("expected ErrUnknownScheme, got %v", err)
+	}
+	if _, err := ComputeRootWithScheme(SchemeV1+1, nil); !errors.Is(err, ErrUnknownScheme) {
+		t.Errorf("expected ErrUnknownScheme for no RUIDs, got %v", err)
+	}
+}
@@ -9,11 +9,10 @@ package merkle
 import (
 	"crypto/sha256"
 	"errors"
-	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ots"
+	otstypes "github.com/ethereum/go-ethereum/ots/types"
 )
 
 var (
@@ -35,52 +34,41 @@ type Tree struct {
 
 	// leafIndex maps RUID to its index in the leaves array
 	leafIndex map[common.Hash]int
-}
 
-// BuildFromEvents constructs a MerkleTree from sorted events.
-// Events MUST be pre-sorted by SortKey: (BlockNumber, TxIndex, LogIndex, RUID)
-//
-// Leaf hash calculation: leafHash = keccak256(ruid)
-// Internal node: hash = keccak256(sort(left, right))
-//
-// Returns the tree and the root hash.
-func BuildFromEvents(events []ots.EventForMerkle) (*Tree, error) {
-	if len(events) == 0 {
-		return nil, ErrEmptyLeaves
-	}
-
-	// Verify events are sorted
-	if !isSorted(events) {
-		// Sort them if not already sorted
-		sortEvents(events)
-	}
-
-	// Build leaf hashes: leafHash = keccak256(ruid)
-	leaves := make([]common.Hash, len(events))
-	leafIndex := make(map[common.Hash]int, len(events))
+	// scheme is the construction the tree was built with
+	scheme Scheme
+}
 
+// BuildFromEvents constructs a MerkleTree from copyright events under the
+// current scheme. Only the event RUIDs are committed, so the result is the
+// same as BuildFromRUIDs over those RUIDs regardless of event order.
+func BuildFromEvents(events []otstypes.EventForMerkle) (*Tree, error) {
+	ruids := make([]common.Hash, len(events))
 	for i, event := range events {
-		leaves[i] = crypto.Keccak256Hash(event.RUID[:])
-		leafIndex[event.RUID] = i
-	}
-
-	// Build the tree
-	tree := &Tree{
-		leaves:    leaves,
-		leafIndex: leafIndex,
+		ruids[i] = event.RUID
 	}
-
-	tree.buildLayers()
-
-	return tree, nil
+	return BuildFromRUIDs(ruids)
 }
 
-// BuildFromRUIDs constructs a MerkleTree from a sorted list of RUIDs.
-// RUIDs MUST be pre-sorted.
+// BuildFromRUIDs constructs a MerkleTree from RUIDs under the current scheme.
+// The input may be in any order and contain duplicates; see Scheme.LeafRUIDs.
 func BuildFromRUIDs(ruids []common.Hash) (*Tree, error) {
+	return BuildWithScheme(CurrentScheme, ruids)
+}
+
+// BuildWithScheme constructs a MerkleTree from RUIDs under the given scheme,
+// e.g. the scheme recorded with a stored batch.
+//
+// Leaf hash calculation: leafHash = keccak256(ruid)
+// Internal node: hash = keccak256(sort(left, right))
+func BuildWithScheme(scheme Scheme, ruids []common.Hash) (*Tree, error) {
+	if err := scheme.check(); err != nil {
+		return nil, err
+	}
 	if len(ruids) == 0 {
 		return nil, ErrEmptyLeaves
 	}
+	ruids = scheme.LeafRUIDs(ruids)
 
 	// Build leaf hashes: leafHash = keccak256(ruid)
 	leaves := make([]common.Hash, len(ruids))
@@ -95,6 +83,7 @@ func BuildFromRUIDs(ruids []common.Hash) (*Tree, error) {
 	tree := &Tree{
 		leaves:    leaves,
 		leafIndex: leafIndex,
+		scheme:    scheme,
 	}
 
 	tree.buildLayers()
@@ -119,6 +108,9 @@ func (t *Tree) buildLayers() {
 			if i+1 < len(currentLayer) {
 				// Two children: hash them together (sorted)
 				nextLayer[i/2] = hashPair(currentLayer[i], currentLayer[i+1])
+			} else if t.scheme == SchemeLegacy {
+				// Odd node: pair it with itself
+				nextLayer[i/2] = hashPair(currentLayer[i], currentLayer[i])
 			} else {
 				// Odd node: promote it
 				nextLayer[i/2] = currentLayer[i]
@@ -152,6 +144,11 @@ func hashPair(a, b common.Hash) common.Hash {
 	return crypto.Keccak256Hash(data)
 }
 
+// Scheme returns the Merkle scheme the tree was built with
+func (t *Tree) Scheme() Scheme {
+	return t.scheme
+}
+
 // Root returns the MerkleTree root hash
 func (t *Tree) Root() common.Hash {
 	return t.root
@@ -212,10 +209,14 @@ func (t *Tree) getProofByIndex(index int) *Proof {
 			isRight = false
 		}
 
-		// Add sibling to proof if it exists
+		// Add sibling to proof if it exists. An odd node is its own
+		// sibling under SchemeLegacy.
 		if siblingIndex < layerLen {
 			proof.Path = append(proof.Path, t.layers[layer][siblingIndex])
 			proof.Position = append(proof.Position, isRight)
+		} else if t.scheme == SchemeLegacy {
+			proof.Path = append(proof.Path, t.layers[layer][currentIndex])
+			proof.Position = append(proof.Position, isRight)
 		}
 
 		// Move to parent index
@@ -224,34 +225,3 @@ func (t *Tree) getProofByIndex(index int) *Proof {
 
 	return proof
 }
-
-// isSorted checks if events are sorted by SortKey
-func isSorted(events []ots.EventForMerkle) bool {
-	for i := 1; i < len(events); i++ {
-		if !events[i-1].SortKey.Less(events[i].SortKey) {
-			// If equal SortKey, compare by RUID
-			if events[i-1].SortKey == events[i].SortKey {
-				if events[i-1].RUID.Big().Cmp(events[i].RUID.Big()) > 0 {
-					return false
-				}
-			} else {
-				return false
-			}
-		}
-	}
-	return true
-}
-
-// sortEvents sorts events by SortKey, then by RUID for tie-breaking
-func sortEvents(events []ots.EventForMerkle) {
-	sort.Slice(events, func(i, j int) bool {
-		if events[i].SortKey.Less(events[j].SortKey) {
-			return true
-		}
-		if events[j].SortKey.Less(events[i].SortKey) {
-			return false
-		}
-		// Tie-break by RUID
-		return events[i].RUID.Big().Cmp(events[j].RUID.Big()) < 0
-	})
-}
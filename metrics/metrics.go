@@ -109,6 +109,30 @@ var (
 
 	// CalendarServerHealthGauge shows calendar server health (0=down, 1=up)
 	CalendarServerHealthGauge = metrics.NewRegisteredGauge(namespace+"calendar/health", nil)
+
+	// ExplorerDisagreementsCounter counts Bitcoin explorer answers that contradict the quorum
+	ExplorerDisagreementsCounter = metrics.NewRegisteredCounter(namespace+"btc/explorer/disagreements", nil)
+
+	// ExplorerQuorumFailuresCounter counts lookups where the explorers reached no quorum
+	ExplorerQuorumFailuresCounter = metrics.NewRegisteredCounter(namespace+"btc/explorer/quorumfailures", nil)
+
+	// ConfirmationsUnverifiedCounter counts otsConfirmed claims accepted without a Bitcoin view to check them
+	ConfirmationsUnverifiedCounter = metrics.NewRegisteredCounter(namespace+"btc/confirmations/unverified", nil)
+
+	// ConfirmationsRejectedCounter counts otsConfirmed claims rejected as contradicting the Bitcoin view
+	ConfirmationsRejectedCounter = metrics.NewRegisteredCounter(namespace+"btc/confirmations/rejected", nil)
+)
+
+// Snapshot rebuild metrics
+var (
+	// RebuildBlocksMeter measures blocks replayed per second by the snapshot rebuild
+	RebuildBlocksMeter = metrics.NewRegisteredMeter(namespace+"rebuild/blocks", nil)
+
+	// RebuildProgressGauge shows the last block whose snapshot was rebuilt
+	RebuildProgressGauge = metrics.NewRegisteredGauge(namespace+"rebuild/progress", nil)
+
+	// RebuildTargetGauge shows the last block the snapshot rebuild replays
+	RebuildTargetGauge = metrics.NewRegisteredGauge(namespace+"rebuild/target", nil)
 )
 
 // Error metrics
@@ -116,9 +140,13 @@ var (
 	// CollectorErrorsCounter counts event collection errors
 	CollectorErrorsCounter = metrics.NewRegisteredCounter(namespace+"errors/collector", nil)
 
-	// CalendarErrorsCounter counts calendar submission errors
+	// CalendarErrorsCounter counts calendar submission errors, one per failed
+	// calendar when the client reports them
 	CalendarErrorsCounter = metrics.NewRegisteredCounter(namespace+"errors/calendar", nil)
 
+	// CalendarQuorumFailuresCounter counts submissions that missed the calendar quorum
+	CalendarQuorumFailuresCounter = metrics.NewRegisteredCounter(namespace+"errors/calendarquorum", nil)
+
 	// StorageErrorsCounter counts storage operation errors
 	StorageErrorsCounter = metrics.NewRegisteredCounter(namespace+"errors/storage", nil)
 
@@ -184,6 +212,37 @@ func UpdateBTCBlockHeight(height uint64) {
 	BTCBlockHeightGauge.Update(int64(height))
 }
 
+// IncExplorerDisagreement records a Bitcoin explorer answer contradicting the quorum
+func IncExplorerDisagreement() {
+	ExplorerDisagreementsCounter.Inc(1)
+}
+
+// IncExplorerQuorumFailure records a Bitcoin explorer lookup without quorum
+func IncExplorerQuorumFailure() {
+	ExplorerQuorumFailuresCounter.Inc(1)
+}
+
+// IncConfirmationUnverified records an otsConfirmed claim accepted unverified
+func IncConfirmationUnverified() {
+	ConfirmationsUnverifiedCounter.Inc(1)
+}
+
+// IncConfirmationRejected records an otsConfirmed claim rejected as false
+func IncConfirmationRejected() {
+	ConfirmationsRejectedCounter.Inc(1)
+}
+
+// MarkBlockRebuilt records a block replayed by the snapshot rebuild
+func MarkBlockRebuilt(number uint64) {
+	RebuildBlocksMeter.Mark(1)
+	RebuildProgressGauge.Update(int64(number))
+}
+
+// UpdateRebuildTarget updates the snapshot rebuild target gauge
+func UpdateRebuildTarget(number uint64) {
+	RebuildTargetGauge.Update(int64(number))
+}
+
 // MarkEventsCollected records events collected
 func MarkEventsCollected(count int) {
 	EventsCollectedCounter.Inc(int64(count))
@@ -208,6 +267,11 @@ func IncCalendarError() {
 	CalendarErrorsCounter.Inc(1)
 }
 
+// IncCalendarQuorumFailure records a submission that missed the calendar quorum
+func IncCalendarQuorumFailure() {
+	CalendarQuorumFailuresCounter.Inc(1)
+}
+
 // IncStorageError records a storage error
 func IncStorageError() {
 	StorageErrorsCounter.Inc(1)
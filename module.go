@@ -9,8 +9,10 @@ package ots
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -21,12 +23,12 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/ots/consensus"
 	"github.com/ethereum/go-ethereum/ots/event"
 	"github.com/ethereum/go-ethereum/ots/hook"
+	"github.com/ethereum/go-ethereum/ots/merkle"
 	otsmetrics "github.com/ethereum/go-ethereum/ots/metrics"
 	"github.com/ethereum/go-ethereum/ots/opentimestamps"
 	"github.com/ethereum/go-ethereum/ots/storage"
@@ -138,6 +140,89 @@ func (a *logFiltererAdapter) matchLog(logEntry *types.Log, query ethereum.Filter
 	return true
 }
 
+// btcOracleTimeout bounds explorer lookups made while validating blocks
+const btcOracleTimeout = 5 * time.Second
+
+// btcHeaderOracle wraps a BitcoinExplorer to implement consensus.BTCHeaderOracle
+type btcHeaderOracle struct {
+	explorer opentimestamps.BitcoinExplorer
+}
+
+func (o *btcHeaderOracle) BTCTipHeight() (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), btcOracleTimeout)
+	defer cancel()
+	return o.explorer.GetTipHeight(ctx)
+}
+
+func (o *btcHeaderOracle) BTCBlockTime(height uint64) (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), btcOracleTimeout)
+	defer cancel()
+	header, err := o.explorer.GetBlockHeader(ctx, height)
+	if err != nil {
+		return 0, err
+	}
+	return header.Timestamp, nil
+}
+
+// otsClientAdapter implements consensus.OTSClientInterface over the batches
+// the module stamped and confirmed. The consensus manager builds otsSubmitted
+// and otsConfirmed transactions from them, committing to the upgraded proof,
+// without calendar or explorer lookups of its own.
+type otsClientAdapter struct {
+	store *storage.Store
+}
+
+// Stamp returns the stored proof and digest of the unanchored batch with the
+// root hash. The batch processor submits batches to the calendars.
+func (a *otsClientAdapter) Stamp(rootHash common.Hash) ([]byte, [32]byte, error) {
+	indexMgr := storage.NewIndexManager(a.store)
+	pending, _, err := indexMgr.GetPendingBatches()
+	if err != nil {
+		return nil, [32]byte{}, err
+	}
+	confirmed, _, err := indexMgr.GetConfirmedBatches()
+	if err != nil {
+		return nil, [32]byte{}, err
+	}
+	for _, meta := range append(pending, confirmed...) {
+		if meta.RootHash == rootHash {
+			proof, err := a.store.GetOTSProof(meta.OTSDigest)
+			if err != nil {
+				return nil, [32]byte{}, err
+			}
+			return proof, meta.OTSDigest, nil
+		}
+	}
+	return nil, [32]byte{}, fmt.Errorf("%w: root %s not stamped", ErrBatchNotFound, rootHash.Hex())
+}
+
+// CheckConfirmation reports the Bitcoin attestation of the batch with the
+// digest and its upgraded proof once the calendar scanner confirmed it
+func (a *otsClientAdapter) CheckConfirmation(digest [32]byte) (*consensus.BTCConfirmationResult, error) {
+	meta, err := a.store.GetBatchByDigest(digest)
+	if err != nil {
+		return nil, err
+	}
+	attempt, err := a.store.GetAttempt(meta.BatchID)
+	if err != nil {
+		return nil, err
+	}
+	if attempt.Status != AttemptStatusConfirmed && attempt.Status != AttemptStatusAnchored {
+		return &consensus.BTCConfirmationResult{}, nil
+	}
+	proof, err := a.store.GetOTSProof(meta.OTSDigest)
+	if err != nil {
+		return nil, err
+	}
+	return &consensus.BTCConfirmationResult{
+		Confirmed:      true,
+		BTCBlockHeight: attempt.BTCBlockHeight,
+		BTCTxID:        attempt.BTCTxID,
+		BTCTimestamp:   attempt.BTCTimestamp,
+		Proof:          proof,
+	}, nil
+}
+
 // ModuleState represents the current state of the OTS module
 type ModuleState uint32
 
@@ -193,6 +278,7 @@ type Module struct {
 	store            *storage.Store
 	collector        *event.Collector
 	otsClient        opentimestamps.ClientInterface
+	btcExplorer      opentimestamps.BitcoinExplorer
 	txBuilder        *systx.Builder
 	consensusManager *consensus.OTSConsensusManager
 
@@ -201,6 +287,12 @@ type Module struct {
 	lastProcessedBlock     uint64      // End block of last processed batch (for metrics/status)
 	pendingBatches         []string    // batch IDs waiting for confirmation
 
+	// Anchor tracking - owned by the anchor listener except for injected
+	anchorMu       sync.Mutex
+	injected       map[string]uint64 // batch ID -> block its anchor was injected in
+	lastAnchorScan uint64            // last block scanned for anchors
+	lastAnchorHash common.Hash       // hash of lastAnchorScan, to detect reorgs
+
 	// Metrics
 	lastAnchorTime     time.Time
 	pendingBatchCount  int
@@ -258,6 +350,15 @@ func (m *Module) Start() error {
 		m.startBackgroundJobs()
 	}
 
+	// Follow the chain for included anchors in every mode that keeps batches
+	if m.blockchain != nil {
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			m.runAnchorListener()
+		}()
+	}
+
 	m.state.Store(uint32(StateRunning))
 	otsmetrics.UpdateModuleState(int(StateRunning))
 	log.Info("OTS: Module started successfully")
@@ -333,9 +434,27 @@ func (m *Module) SetConsensusManager(cm *consensus.OTSConsensusManager) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.consensusManager = cm
+	m.connectConsensusManager()
 	log.Info("OTS: Consensus manager set")
 }
 
+// connectConsensusManager hands the sub-modules the consensus manager uses to
+// the consensus manager: the Bitcoin explorer to check otsConfirmed claims,
+// the store to publish proof sidecars and the stored batches to build OTS
+// system transactions from. The caller must hold m.mu.
+func (m *Module) connectConsensusManager() {
+	if m.consensusManager == nil {
+		return
+	}
+	if m.btcExplorer != nil {
+		m.consensusManager.SetBTCHeaderOracle(&btcHeaderOracle{explorer: m.btcExplorer})
+	}
+	if m.store != nil {
+		m.consensusManager.SetProofSidecar(m.store)
+		m.consensusManager.SetOTSClient(&otsClientAdapter{store: m.store})
+	}
+}
+
 // OnFinalize implements the FinalizeHook interface.
 // This is called during block finalization to inject OTS system transactions.
 //
@@ -388,10 +507,14 @@ func (m *Module) tryInjectSystemTx(header *types.Header, stateDB *state.StateDB)
 		return nil
 	}
 
-	var txs []*types.Transaction
-	coinbase := header.Coinbase
-
+	// Collect the confirmed batches, oldest first
+	var batches []*CandidateBatch
 	for _, batchID := range candidates {
+		// Skip batches whose anchor may still be included
+		if m.recentlyInjected(batchID, header.Number.Uint64()) {
+			continue
+		}
+
 		// Get batch metadata
 		meta, err := m.store.GetBatchMeta(batchID)
 		if err != nil {
@@ -405,41 +528,175 @@ func (m *Module) tryInjectSystemTx(header *types.Header, stateDB *state.StateDB)
 			continue
 		}
 
-		// Build candidate batch
-		candidate := &CandidateBatch{
+		batches = append(batches, &CandidateBatch{
 			BatchMeta:      meta,
 			EventRUIDs:     meta.EventRUIDs,
 			BTCBlockHeight: attempt.BTCBlockHeight,
 			BTCTxID:        attempt.BTCTxID,
 			BTCTimestamp:   attempt.BTCTimestamp,
-		}
+		})
+	}
+	if len(batches) == 0 {
+		return nil
+	}
+	sort.Slice(batches, func(i, j int) bool {
+		return batches[i].StartBlock < batches[j].StartBlock
+	})
+
+	// Anchor as many batches as fit in the gas limit in one transaction. A
+	// single batch is anchored with the plain anchor call.
+	gasLimit := m.config.SystemTxGasLimit
+	confirmed := len(batches)
+	limit := m.txBuilder.MaxAnchorBatch(gasLimit)
+	if limit == 0 {
+		limit = 1 // Below the estimate a single anchor still gets the configured limit
+	}
+	if confirmed > limit {
+		batches = batches[:limit]
+	}
 
-		// Get nonce for coinbase
-		nonce := stateDB.GetNonce(coinbase)
+	coinbase := header.Coinbase
+	nonce := stateDB.GetNonce(coinbase)
 
-		// Build system transaction
-		sysTxStart := time.Now()
-		tx, err := m.txBuilder.BuildSystemTx(candidate, coinbase, nonce, m.config.SystemTxGasLimit)
-		otsmetrics.SystemTxBuildTimer.UpdateSince(sysTxStart)
-		if err != nil {
-			log.Warn("OTS: Failed to build system tx", "batchID", batchID, "err", err)
-			otsmetrics.IncSystemTxError()
-			continue
-		}
+	// Build system transaction
+	var (
+		tx         *types.Transaction
+		sysTxStart = time.Now()
+	)
+	if len(batches) == 1 {
+		tx, err = m.txBuilder.BuildSystemTx(batches[0], coinbase, nonce, gasLimit)
+	} else {
+		tx, err = m.txBuilder.BuildAnchorBatchTx(batches, coinbase, nonce, gasLimit)
+	}
+	otsmetrics.SystemTxBuildTimer.UpdateSince(sysTxStart)
+	if err != nil {
+		log.Warn("OTS: Failed to build system tx", "batchID", batches[0].BatchID, "batches", len(batches), "err", err)
+		otsmetrics.IncSystemTxError()
+		return nil
+	}
 
-		txs = append(txs, tx)
-		otsmetrics.IncBatchAnchored()
+	// Batches are counted as anchored once the anchor listener sees the
+	// transaction included; until then they are not injected again
+	m.markInjected(batches, header.Number.Uint64())
+	for _, batch := range batches {
 		log.Info("OTS: Injecting system transaction",
-			"batchID", batchID,
-			"btcBlock", attempt.BTCBlockHeight,
+			"batchID", batch.BatchID,
+			"btcBlock", batch.BTCBlockHeight,
 			"hash", tx.Hash().Hex(),
 		)
+	}
+	if len(batches) < confirmed {
+		log.Debug("OTS: Deferring confirmed batches to later blocks", "anchored", len(batches), "confirmed", confirmed)
+	}
 
-		// Only inject one transaction per block to avoid issues
-		break
+	return []*types.Transaction{tx}
+}
+
+// initOTSClient creates the OpenTimestamps client selected in the config.
+// The CLI client verifies against the Bitcoin node configured for the ots
+// binary, which does not check confirmation depth, so the explorer is only
+// used to read the Bitcoin tip for it.
+func (m *Module) initOTSClient() error {
+	if m.config.OTS.Client == OTSClientCLI {
+		client, err := opentimestamps.NewClient(m.config.OTS.BinaryPath, m.config.OTS.CalendarServers, m.config.OTS.Timeout, m.config.DataDir)
+		if err != nil {
+			return err
+		}
+		if m.config.OTS.BTCConfirmations > 0 {
+			explorer, err := newBitcoinExplorer(&m.config.OTS)
+			if err != nil {
+				return err
+			}
+			if explorer == nil {
+				explorer = opentimestamps.NewBlockstreamExplorer(m.config.OTS.Timeout)
+			}
+			m.btcExplorer = explorer
+		}
+		m.otsClient = client
+		return nil
 	}
 
-	return txs
+	explorer, err := newBitcoinExplorer(&m.config.OTS)
+	if err != nil {
+		return err
+	}
+	client, err := opentimestamps.NewNativeClientWithConfig(opentimestamps.ServiceConfig{
+		CalendarServers:      m.config.OTS.CalendarServers,
+		Timeout:              m.config.OTS.Timeout,
+		BTCConfirmations:     uint64(m.config.OTS.BTCConfirmations),
+		Explorer:             explorer,
+		MinCalendarResponses: m.config.OTS.MinCalendarResponses,
+		PendingStore:         m.store,
+	}, m.config.DataDir)
+	if err != nil {
+		return err
+	}
+	m.btcExplorer = explorer
+	m.otsClient = client
+	return nil
+}
+
+// newBitcoinExplorer creates the Bitcoin explorer selected in the config.
+// A nil explorer selects the service default (Blockstream).
+func newBitcoinExplorer(cfg *OTSConfig) (opentimestamps.BitcoinExplorer, error) {
+	if cfg.BitcoinExplorer != ExplorerQuorum {
+		single := cfg.singleExplorer()
+		if single.Type == "" || single.Type == ExplorerBlockstream {
+			return nil, nil
+		}
+		return newExplorerBackend(&single, cfg.Timeout)
+	}
+
+	members := make([]opentimestamps.NamedExplorer, 0, len(cfg.ExplorerBackends))
+	for i := range cfg.ExplorerBackends {
+		backend := &cfg.ExplorerBackends[i]
+		name := backend.Name
+		if name == "" {
+			name = fmt.Sprintf("%s-%d", backend.Type, i)
+		}
+		explorer, err := newExplorerBackend(backend, cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("explorer %s: %w", name, err)
+		}
+		members = append(members, opentimestamps.NamedExplorer{Name: name, Explorer: explorer})
+	}
+	return opentimestamps.NewQuorumExplorer(cfg.QuorumThreshold, members...)
+}
+
+// newExplorerBackend creates a single Bitcoin explorer
+func newExplorerBackend(backend *ExplorerBackend, timeout time.Duration) (opentimestamps.BitcoinExplorer, error) {
+	switch backend.Type {
+	case "", ExplorerBlockstream:
+		return opentimestamps.NewBlockstreamExplorer(timeout), nil
+	case ExplorerMempool:
+		return opentimestamps.NewEsploraExplorer(opentimestamps.MempoolSpaceAPI, timeout), nil
+	case ExplorerEsplora:
+		return opentimestamps.NewEsploraExplorer(backend.URL, timeout), nil
+	case ExplorerBitcoind:
+		explorer, err := opentimestamps.NewBitcoindExplorer(opentimestamps.BitcoindConfig{
+			URL:        backend.Bitcoind.URL,
+			User:       backend.Bitcoind.User,
+			Password:   backend.Bitcoind.Password,
+			CookieFile: backend.Bitcoind.CookieFile,
+			Timeout:    timeout,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return explorer, nil
+	case ExplorerLocal:
+		checkpoint, err := backend.headerCheckpoint()
+		if err != nil {
+			return nil, err
+		}
+		explorer, err := opentimestamps.NewLocalExplorerFromFile(backend.HeaderFile, checkpoint)
+		if err != nil {
+			return nil, err
+		}
+		return explorer, nil
+	default:
+		return nil, ErrInvalidExplorer
+	}
 }
 
 // initSubModules initializes sub-modules based on the running mode
@@ -464,17 +721,11 @@ func (m *Module) initSubModules() error {
 
 	// 3. Initialize OTS client (watcher/full modes)
 	if m.config.Mode == ModeWatcher || m.config.Mode == ModeFull {
-		// Create OTS client (native implementation)
-		var otsErr error
-		m.otsClient, otsErr = opentimestamps.NewNativeClient(
-			m.config.OTS.CalendarServers,
-			m.config.OTS.Timeout,
-			m.config.DataDir,
-		)
-		if otsErr != nil {
+		// Create OTS client (native implementation unless the CLI is selected)
+		if otsErr := m.initOTSClient(); otsErr != nil {
 			log.Warn("OTS: Failed to create OTS client, will retry", "err", otsErr)
 		} else {
-			log.Debug("OTS: OTS client initialized", "calendars", m.config.OTS.CalendarServers)
+			log.Debug("OTS: OTS client initialized", "client", m.config.OTS.Client, "calendars", m.config.OTS.CalendarServers)
 		}
 
 		// Load last processed block from storage
@@ -501,6 +752,10 @@ func (m *Module) initSubModules() error {
 		log.Debug("OTS: Event collector initialized")
 	}
 
+	m.mu.Lock()
+	m.connectConsensusManager()
+	m.mu.Unlock()
+
 	return nil
 }
 
@@ -537,6 +792,13 @@ func (m *Module) loadPendingBatches() {
 func (m *Module) cleanupSubModules() {
 	log.Debug("OTS: Cleaning up sub-modules")
 
+	// Stop the native OTS service before the store it persists to
+	if closer, ok := m.otsClient.(interface{ Close() error }); ok {
+		if err := closer.Close(); err != nil {
+			log.Error("OTS: Failed to close OTS client", "err", err)
+		}
+	}
+
 	// Close storage
 	if m.store != nil {
 		if err := m.store.Close(); err != nil {
@@ -545,9 +807,17 @@ func (m *Module) cleanupSubModules() {
 		m.store = nil
 	}
 
+	// Detach the sub-modules we are about to drop from consensus
+	if m.consensusManager != nil {
+		m.consensusManager.SetBTCHeaderOracle(nil)
+		m.consensusManager.SetProofSidecar(nil)
+		m.consensusManager.SetOTSClient(nil)
+	}
+
 	// Clear other references
 	m.collector = nil
 	m.otsClient = nil
+	m.btcExplorer = nil
 	m.txBuilder = nil
 }
 
@@ -621,19 +891,29 @@ func (m *Module) processOneTick() {
 		return
 	}
 
-	if otsState == nil || otsState.CurrentBatch == nil {
+	if otsState == nil {
 		return
 	}
 
-	batch := otsState.CurrentBatch
-
-	// Only process if batch is in Triggered state and we haven't processed it yet
-	if batch.Status != consensus.BatchStatusTriggered {
-		return
+	// Process the oldest Triggered batch we haven't processed yet. Batches
+	// are in block order; one covering the range of a failed batch again may
+	// end before batches already processed, and is processed unless it is
+	// stored already.
+	var batch *consensus.BatchState
+	for _, candidate := range otsState.Batches {
+		if candidate.Status != consensus.BatchStatusTriggered {
+			continue
+		}
+		if candidate.EndBlock > m.lastProcessedBlock {
+			batch = candidate
+			break
+		}
+		if _, err := m.store.GetBatchMeta(fmt.Sprintf("batch-%d-%d", candidate.StartBlock, candidate.EndBlock)); errors.Is(err, storage.ErrNotFound) {
+			batch = candidate
+			break
+		}
 	}
-
-	// Check if we've already processed this batch (by comparing RootHash)
-	if batch.RootHash == m.lastProcessedBatchHash {
+	if batch == nil {
 		return
 	}
 
@@ -659,20 +939,23 @@ func (m *Module) processOneTick() {
 	calendarStart := time.Now()
 	proof, err := m.otsClient.Stamp(m.ctx, rootHash)
 	otsmetrics.CalendarSubmitTimer.UpdateSince(calendarStart)
+	reported := m.logCalendarResults(rootHash)
 	if err != nil {
 		log.Error("OTS: Failed to submit to calendar", "err", err, "rootHash", rootHash.Hex())
-		otsmetrics.IncCalendarError()
+		// Failed calendars are already counted one by one when reported
+		if reported {
+			otsmetrics.IncCalendarQuorumFailure()
+		} else {
+			otsmetrics.IncCalendarError()
+		}
 		return
 	}
 
 	// Generate batch ID based on consensus block range
 	batchID := fmt.Sprintf("batch-%d-%d", startBlock, endBlock)
 
-	// Compute OTS digest from proof
-	var otsDigest [32]byte
-	if len(proof) >= 32 {
-		copy(otsDigest[:], proof[:32])
-	}
+	// Index the batch by the digest the proof commits to
+	otsDigest := stampedDigest(proof, rootHash)
 
 	// Collect RUIDs for metadata (optional, for local tracking)
 	var ruids []common.Hash
@@ -686,6 +969,15 @@ func (m *Module) processOneTick() {
 				ruids[i] = evt.RUID
 			}
 			otsmetrics.MarkEventsCollected(len(events))
+
+			// Store RUIDs in leaf order so proofs can be rebuilt from metadata
+			ruids = batch.MerkleScheme.LeafRUIDs(ruids)
+			if local, err := merkle.ComputeRootWithScheme(batch.MerkleScheme, ruids); err != nil {
+				log.Warn("OTS: Failed to compute local root", "scheme", batch.MerkleScheme, "err", err)
+			} else if local != rootHash {
+				log.Warn("OTS: Collected events do not match consensus root",
+					"rootHash", rootHash.Hex(), "local", local.Hex(), "ruids", len(ruids))
+			}
 		}
 	}
 
@@ -700,6 +992,8 @@ func (m *Module) processOneTick() {
 		CreatedAt:   time.Now(),
 		TriggerType: TriggerTypeDaily, // All triggers now come from consensus (BreatheBlock)
 		EventRUIDs:  ruids,
+
+		MerkleScheme: uint8(batch.MerkleScheme),
 	}
 
 	if err := m.store.SaveBatchMeta(batchMeta); err != nil {
@@ -727,7 +1021,9 @@ func (m *Module) processOneTick() {
 
 	// Update state - mark this batch as processed
 	m.lastProcessedBatchHash = rootHash
-	m.lastProcessedBlock = endBlock
+	if endBlock > m.lastProcessedBlock {
+		m.lastProcessedBlock = endBlock
+	}
 	m.pendingBatches = append(m.pendingBatches, batchID)
 	m.pendingBatchCount = len(m.pendingBatches)
 	m.totalBatchesCreated++
@@ -747,6 +1043,213 @@ func (m *Module) processOneTick() {
 	)
 }
 
+// logCalendarResults logs the per-calendar outcome of the last submission
+// and counts the failed calendars. It reports whether the client gave
+// per-calendar results. The caller must hold m.mu.
+func (m *Module) logCalendarResults(rootHash common.Hash) bool {
+	reporter, ok := m.otsClient.(opentimestamps.CalendarReporter)
+	if !ok {
+		return false
+	}
+	for _, r := range reporter.CalendarResults() {
+		if r.OK() {
+			log.Debug("OTS: Calendar accepted digest", "calendar", r.URL, "latency", r.Latency, "rootHash", rootHash.Hex())
+		} else {
+			log.Warn("OTS: Calendar rejected digest", "calendar", r.URL, "status", r.StatusCode,
+				"latency", r.Latency, "err", r.Err, "rootHash", rootHash.Hex())
+			otsmetrics.IncCalendarError()
+		}
+	}
+	return true
+}
+
+// stampedDigest returns the digest the OTS proof commits to. Proofs
+// that do not parse fall back to the root hash, which the native client stamps.
+func stampedDigest(proof []byte, rootHash common.Hash) [32]byte {
+	var digest [32]byte
+	if ts, err := opentimestamps.Parse(proof); err == nil && len(ts.Digest) == len(digest) {
+		copy(digest[:], ts.Digest)
+		return digest
+	}
+	return rootHash
+}
+
+// CalendarResults returns the per-calendar results of the last submission,
+// or nil if the OTS client does not report them
+func (m *Module) CalendarResults() []opentimestamps.CalendarResult {
+	m.mu.RLock()
+	client := m.otsClient
+	m.mu.RUnlock()
+
+	if reporter, ok := client.(opentimestamps.CalendarReporter); ok {
+		return reporter.CalendarResults()
+	}
+	return nil
+}
+
+// VerifyOTSProof verifies an OTS proof for digest against Bitcoin with the
+// configured OTS client
+func (m *Module) VerifyOTSProof(ctx context.Context, digest [32]byte, proof []byte) (*opentimestamps.AttestationInfo, error) {
+	m.mu.RLock()
+	client := m.otsClient
+	m.mu.RUnlock()
+
+	if client == nil {
+		return nil, ErrOTSClientNotReady
+	}
+	return client.Verify(ctx, digest, proof)
+}
+
+// CommittedProofHash returns the proof hash committed on-chain for the batch
+// with the root hash: from the consensus state at the chain head while the
+// batch is in flight, or from the local record once it is anchored
+func (m *Module) CommittedProofHash(rootHash common.Hash) (common.Hash, error) {
+	m.mu.RLock()
+	cm := m.consensusManager
+	m.mu.RUnlock()
+
+	if cm != nil && m.blockchain != nil {
+		if head := m.blockchain.CurrentHeader(); head != nil {
+			for _, batch := range cm.GetBatches(head.Hash()) {
+				if batch.RootHash == rootHash && batch.ProofHash != (common.Hash{}) {
+					return batch.ProofHash, nil
+				}
+			}
+		}
+	}
+	return committedProofHash(m.store, rootHash)
+}
+
+// committedProofHash returns the proof hash recorded with the confirmed or
+// anchored local batch with the root hash
+func committedProofHash(store *storage.Store, rootHash common.Hash) (common.Hash, error) {
+	if store == nil {
+		return common.Hash{}, ErrProofNotCommitted
+	}
+	var (
+		proofHash common.Hash
+		lookupErr error
+	)
+	indexMgr := storage.NewIndexManager(store)
+	err := indexMgr.ListBatches([]BatchStatus{BatchStatusConfirmed, BatchStatusAnchored}, 0, 0, nil, func(meta *BatchMeta) bool {
+		if meta.RootHash != rootHash {
+			return true
+		}
+		attempt, err := store.GetAttempt(meta.BatchID)
+		if err != nil {
+			lookupErr = err
+			return false
+		}
+		proofHash = attempt.ProofHash
+		return proofHash == (common.Hash{})
+	})
+	if err == nil {
+		err = lookupErr
+	}
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if proofHash == (common.Hash{}) {
+		return common.Hash{}, fmt.Errorf("%w: %s", ErrProofNotCommitted, rootHash.Hex())
+	}
+	return proofHash, nil
+}
+
+// StartRebuild starts regenerating the OTS consensus snapshots of the
+// canonical blocks fromBlock..toBlock in the background
+func (m *Module) StartRebuild(fromBlock, toBlock uint64) error {
+	cm, err := m.rebuildManager()
+	if err != nil {
+		return err
+	}
+	return cm.StartRebuild(fromBlock, toBlock)
+}
+
+// StopRebuild stops the running snapshot rebuild, keeping its progress
+func (m *Module) StopRebuild() error {
+	cm, err := m.rebuildManager()
+	if err != nil {
+		return err
+	}
+	return cm.StopRebuild()
+}
+
+// RebuildStatus returns the progress of the running or unfinished snapshot
+// rebuild, or nil if there is none
+func (m *Module) RebuildStatus() (*consensus.RebuildProgress, error) {
+	cm, err := m.rebuildManager()
+	if err != nil {
+		return nil, err
+	}
+	return cm.RebuildStatus(), nil
+}
+
+// rebuildManager returns the consensus manager whose snapshots are rebuilt
+func (m *Module) rebuildManager() (*consensus.OTSConsensusManager, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.consensusManager == nil {
+		return nil, ErrConsensusNotReady
+	}
+	return m.consensusManager, nil
+}
+
+// checkBTCConfirmation upgrades a batch proof and verifies its Bitcoin
+// attestation. It returns ErrBTCNotConfirmed while the proof has no Bitcoin
+// attestation yet or the attested block is not buried under the configured
+// number of confirmations.
+func (m *Module) checkBTCConfirmation(rootHash [32]byte, proof []byte) ([]byte, *opentimestamps.AttestationInfo, error) {
+	upgradedProof, err := m.otsClient.Upgrade(m.ctx, proof)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrBTCNotConfirmed, err)
+	}
+
+	attestation, err := m.otsClient.Verify(m.ctx, rootHash, upgradedProof)
+	if errors.Is(err, opentimestamps.ErrInsufficientDepth) {
+		return nil, nil, fmt.Errorf("%w: %v", ErrBTCNotConfirmed, err)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if attestation == nil || attestation.BTCBlockHeight == 0 {
+		return nil, nil, ErrBTCNotConfirmed
+	}
+
+	// The native client checks the depth while verifying, the ots CLI not
+	if m.config.OTS.Client == OTSClientCLI {
+		if err := m.checkBTCDepth(attestation.BTCBlockHeight); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return upgradedProof, attestation, nil
+}
+
+// checkBTCDepth checks that the Bitcoin block at height is buried under the
+// configured number of confirmations
+func (m *Module) checkBTCDepth(height uint64) error {
+	required := uint64(m.config.OTS.BTCConfirmations)
+	if required == 0 {
+		return nil
+	}
+	if m.btcExplorer == nil {
+		return fmt.Errorf("%w: no Bitcoin explorer to count confirmations", ErrBTCNotConfirmed)
+	}
+	tip, err := m.btcExplorer.GetTipHeight(m.ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBTCNotConfirmed, err)
+	}
+	var confirmations uint64
+	if tip >= height {
+		confirmations = tip - height + 1
+	}
+	if confirmations < required {
+		return fmt.Errorf("%w: Bitcoin block %d has %d of %d required confirmations", ErrBTCNotConfirmed, height, confirmations, required)
+	}
+	return nil
+}
+
 // runCalendarScanner scans for confirmed OTS proofs
 func (m *Module) runCalendarScanner() {
 	log.Info("OTS: Calendar scanner started")
@@ -795,31 +1298,29 @@ func (m *Module) scanCalendars() {
 			continue
 		}
 
-		// Try to upgrade the proof (check for BTC confirmation)
-		upgradedProof, err := m.otsClient.Upgrade(m.ctx, proof)
-		if err != nil {
-			log.Debug("OTS: Proof not yet upgradeable", "batchID", batchID)
+		// Upgrade and verify the proof (check for BTC confirmation)
+		upgradedProof, attestation, err := m.checkBTCConfirmation(meta.RootHash, proof)
+		if errors.Is(err, ErrBTCNotConfirmed) {
+			log.Debug("OTS: Proof not yet confirmed", "batchID", batchID, "reason", err)
 			continue
 		}
-
-		// Verify the upgraded proof
-		attestation, err := m.otsClient.Verify(m.ctx, meta.RootHash, upgradedProof)
 		if err != nil {
 			log.Warn("OTS: Failed to verify upgraded proof", "batchID", batchID, "err", err)
 			continue
 		}
 
-		if attestation == nil || attestation.BTCBlockHeight == 0 {
-			log.Debug("OTS: Proof not yet confirmed", "batchID", batchID)
-			continue
-		}
-
 		log.Info("OTS: Batch confirmed on Bitcoin",
 			"batchID", batchID,
 			"btcBlock", attestation.BTCBlockHeight,
 			"btcTxID", attestation.BTCTxID,
 		)
 
+		// Publish the upgraded proof for peers that lose their own copy
+		proofHash, err := m.store.SaveProofSidecar(upgradedProof)
+		if err != nil {
+			log.Error("OTS: Failed to save proof sidecar", "batchID", batchID, "err", err)
+		}
+
 		// Update attempt status
 		attempt, _ := m.store.GetAttempt(batchID)
 		if attempt != nil {
@@ -827,15 +1328,19 @@ func (m *Module) scanCalendars() {
 			attempt.BTCBlockHeight = attestation.BTCBlockHeight
 			attempt.BTCTxID = attestation.BTCTxID
 			attempt.BTCTimestamp = attestation.BTCTimestamp
+			attempt.ProofHash = proofHash
 			attempt.ConfirmedAt = time.Now()
 			if err := m.store.SaveAttempt(attempt); err != nil {
 				log.Error("OTS: Failed to update attempt", "batchID", batchID, "err", err)
 			}
 		}
 
-		// Save upgraded proof
+		// Save upgraded proof. Once it is stored with the batch the client
+		// no longer needs to track the submitted digest.
 		if err := m.store.SaveOTSProof(meta.OTSDigest, upgradedProof); err != nil {
 			log.Error("OTS: Failed to save upgraded proof", "batchID", batchID, "err", err)
+		} else if tracker, ok := m.otsClient.(opentimestamps.PendingTracker); ok {
+			tracker.RemovePending(meta.RootHash)
 		}
 
 		confirmedBatches = append(confirmedBatches, batchID)
@@ -917,6 +1422,22 @@ func (m *Module) Health() HealthStatus {
 		status.Components["otsClient"] = ComponentStatus{Healthy: false, Message: "not initialized"}
 	}
 
+	// Check Bitcoin explorer quorum health
+	if quorum, ok := m.btcExplorer.(*opentimestamps.QuorumExplorer); ok {
+		qs := quorum.Status()
+		healthy := 0
+		for _, b := range qs.Backends {
+			status.Components["explorer/"+b.Name] = ComponentStatus{Healthy: b.Healthy, Message: b.LastError}
+			if b.Healthy {
+				healthy++
+			}
+		}
+		status.Components["btcExplorer"] = ComponentStatus{
+			Healthy: healthy >= qs.Threshold,
+			Message: fmt.Sprintf("%d of %d explorers healthy, quorum %d", healthy, len(qs.Backends), qs.Threshold),
+		}
+	}
+
 	// Set last anchor time
 	if !m.lastAnchorTime.IsZero() {
 		status.LastAnchor = &m.lastAnchorTime
@@ -956,40 +1477,3 @@ type ComponentStatus struct {
 	Healthy bool   `json:"healthy"`
 	Message string `json:"message,omitempty"`
 }
-
-// buildMerkleRoot constructs a Merkle root from a list of RUIDs.
-// This is a simplified inline version to avoid circular imports with the merkle package.
-func buildMerkleRoot(ruids []common.Hash) common.Hash {
-	if len(ruids) == 0 {
-		return common.Hash{}
-	}
-
-	// Build leaf hashes: leafHash = keccak256(ruid)
-	leaves := make([]common.Hash, len(ruids))
-	for i, ruid := range ruids {
-		leaves[i] = crypto.Keccak256Hash(ruid[:])
-	}
-
-	// Build tree layers (Bitcoin-style: duplicate last node if odd count)
-	currentLayer := leaves
-	for len(currentLayer) > 1 {
-		// If odd number of nodes, duplicate the last one
-		if len(currentLayer)%2 == 1 {
-			currentLayer = append(currentLayer, currentLayer[len(currentLayer)-1])
-		}
-
-		nextLayer := make([]common.Hash, len(currentLayer)/2)
-		for i := 0; i < len(currentLayer); i += 2 {
-			// Combine two nodes: sort them first for deterministic ordering
-			left, right := currentLayer[i], currentLayer[i+1]
-			if left.Big().Cmp(right.Big()) > 0 {
-				left, right = right, left
-			}
-			combined := append(left[:], right[:]...)
-			nextLayer[i/2] = crypto.Keccak256Hash(combined)
-		}
-		currentLayer = nextLayer
-	}
-
-	return currentLayer[0]
-}
@@ -0,0 +1,488 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package ots
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ots/consensus"
+	"github.com/ethereum/go-ethereum/ots/opentimestamps"
+	"github.com/ethereum/go-ethereum/ots/storage"
+	"github.com/ethereum/go-ethereum/ots/systx"
+)
+
+// newTestCalendar serves pending timestamps for every submitted digest
+func newTestCalendar(t *testing.T) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		node := &opentimestamps.Node{}
+		node.Add(opentimestamps.Operation{Tag: opentimestamps.OpAppend, Argument: []byte{0x01}}).
+			Add(opentimestamps.Operation{Tag: opentimestamps.OpSHA256}).
+			AddAttestation(opentimestamps.Attestation{Type: opentimestamps.AttestationPending, CalendarURL: server.URL})
+		data, err := node.Serialize()
+		if err != nil {
+			t.Errorf("serialize: %v", err)
+		}
+		w.Write(data)
+	}))
+	return server
+}
+
+// confirmingClient is a native client whose proofs are always confirmed
+type confirmingClient struct {
+	*opentimestamps.NativeClient
+	attestation *opentimestamps.AttestationInfo
+}
+
+func (c *confirmingClient) Upgrade(ctx context.Context, proof []byte) ([]byte, error) {
+	return proof, nil
+}
+
+func (c *confirmingClient) Verify(ctx context.Context, digest [32]byte, proof []byte) (*opentimestamps.AttestationInfo, error) {
+	return c.attestation, nil
+}
+
+func countPendingTimestamps(t *testing.T, store *storage.Store) int {
+	t.Helper()
+	n := 0
+	if err := store.IteratePendingTimestamps(func([32]byte, []byte) error {
+		n++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestScanCalendars_RemovesConfirmedPending(t *testing.T) {
+	calendar := newTestCalendar(t)
+	defer calendar.Close()
+
+	store := storage.NewStoreWithDB(rawdb.NewMemoryDatabase())
+	native, err := opentimestamps.NewNativeClientWithConfig(opentimestamps.ServiceConfig{
+		CalendarServers: []string{calendar.URL},
+		Timeout:         5 * time.Second,
+		PendingStore:    store,
+	}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer native.Close()
+
+	rootHash := common.HexToHash("0x1234")
+	proof, err := native.Stamp(context.Background(), rootHash)
+	if err != nil {
+		t.Fatalf("Stamp failed: %v", err)
+	}
+	if n := countPendingTimestamps(t, store); n != 1 {
+		t.Fatalf("%d pending timestamps stored after submission, want 1", n)
+	}
+
+	batchID := "batch-1-100"
+	meta := &BatchMeta{
+		BatchID:    batchID,
+		StartBlock: 1,
+		EndBlock:   100,
+		RootHash:   rootHash,
+		OTSDigest:  [32]byte{0x01},
+		CreatedAt:  time.Now(),
+	}
+	if err := store.SaveBatchMeta(meta); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveOTSProof(meta.OTSDigest, proof); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveAttempt(&Attempt{BatchID: batchID, Status: AttemptStatusSubmitted}); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Module{
+		config: DefaultConfig(),
+		ctx:    context.Background(),
+		store:  store,
+		otsClient: &confirmingClient{
+			NativeClient: native,
+			attestation:  &opentimestamps.AttestationInfo{BTCTxID: "beef", BTCBlockHeight: 800000, BTCTimestamp: 1700000000},
+		},
+		pendingBatches: []string{batchID},
+	}
+	m.scanCalendars()
+
+	attempt, err := store.GetAttempt(batchID)
+	if err != nil || attempt.Status != AttemptStatusConfirmed {
+		t.Fatalf("batch not confirmed: %+v, %v", attempt, err)
+	}
+	if n := countPendingTimestamps(t, store); n != 0 {
+		t.Errorf("%d pending timestamps left after confirmation, want 0", n)
+	}
+	if n := native.GetService().GetPendingCount(); n != 0 {
+		t.Errorf("%d pending timestamps tracked after confirmation, want 0", n)
+	}
+}
+
+func TestOTSClientAdapter_BatchesInFlight(t *testing.T) {
+	calendar := newTestCalendar(t)
+	defer calendar.Close()
+
+	store := storage.NewStoreWithDB(rawdb.NewMemoryDatabase())
+	native, err := opentimestamps.NewNativeClientWithConfig(opentimestamps.ServiceConfig{
+		CalendarServers: []string{calendar.URL},
+		Timeout:         5 * time.Second,
+	}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer native.Close()
+
+	// Stamp and index two batches the way the batch processor does
+	roots := []common.Hash{common.HexToHash("0x1111"), common.HexToHash("0x2222")}
+	proofs := make([][]byte, len(roots))
+	for i, rootHash := range roots {
+		proof, err := native.Stamp(context.Background(), rootHash)
+		if err != nil {
+			t.Fatalf("Stamp failed: %v", err)
+		}
+		proofs[i] = proof
+		digest := stampedDigest(proof, rootHash)
+		if digest != rootHash {
+			t.Fatalf("batch %d indexed by %x, want stamped root %s", i, digest, rootHash.Hex())
+		}
+		meta := &BatchMeta{
+			BatchID:    fmt.Sprintf("batch-%d-%d", i+1, i+1),
+			StartBlock: uint64(i + 1),
+			EndBlock:   uint64(i + 1),
+			RootHash:   rootHash,
+			OTSDigest:  digest,
+			CreatedAt:  time.Now(),
+		}
+		if err := store.SaveBatchMeta(meta); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SaveOTSProof(digest, proof); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SaveAttempt(&Attempt{BatchID: meta.BatchID, Status: AttemptStatusPending}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Only the second batch is confirmed
+	if err := store.SaveAttempt(&Attempt{BatchID: "batch-2-2", Status: AttemptStatusConfirmed, BTCBlockHeight: 800000}); err != nil {
+		t.Fatal(err)
+	}
+
+	adapter := &otsClientAdapter{store: store}
+	for i, rootHash := range roots {
+		proof, digest, err := adapter.Stamp(rootHash)
+		if err != nil {
+			t.Fatalf("batch %d: Stamp failed: %v", i, err)
+		}
+		if digest != rootHash || string(proof) != string(proofs[i]) {
+			t.Errorf("batch %d: adapter returned digest %x and another batch's proof", i, digest)
+		}
+	}
+	if result, err := adapter.CheckConfirmation(roots[0]); err != nil || result.Confirmed {
+		t.Errorf("pending batch reported as %+v, %v", result, err)
+	}
+	result, err := adapter.CheckConfirmation(roots[1])
+	if err != nil || !result.Confirmed || string(result.Proof) != string(proofs[1]) {
+		t.Errorf("confirmed batch reported as %+v, %v", result, err)
+	}
+}
+
+func TestStampedDigest(t *testing.T) {
+	rootHash := common.HexToHash("0x1234")
+	fileDigest := crypto.Keccak256Hash([]byte("file"))
+	ts := opentimestamps.NewTimestamp(fileDigest)
+	ts.Root.AddAttestation(opentimestamps.Attestation{Type: opentimestamps.AttestationPending, CalendarURL: "https://calendar.example"})
+	proof, err := ts.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stampedDigest(proof, rootHash); got != fileDigest {
+		t.Errorf("digest of parsed proof = %x, want %x", got, fileDigest)
+	}
+	if got := stampedDigest([]byte("not a proof"), rootHash); got != rootHash {
+		t.Errorf("digest of unparsable proof = %x, want root %x", got, rootHash)
+	}
+}
+
+func TestCommittedProofHash_LocalRecord(t *testing.T) {
+	store := storage.NewStoreWithDB(rawdb.NewMemoryDatabase())
+	proofHash := common.HexToHash("0xf00d")
+	batches := []struct {
+		rootHash common.Hash
+		attempt  *Attempt
+	}{
+		{common.HexToHash("0x01"), &Attempt{Status: AttemptStatusSubmitted}},
+		{common.HexToHash("0x02"), &Attempt{Status: AttemptStatusAnchored, ProofHash: proofHash}},
+	}
+	for i, b := range batches {
+		meta := &BatchMeta{
+			BatchID:    fmt.Sprintf("batch-%d-%d", i+1, i+1),
+			StartBlock: uint64(i + 1),
+			EndBlock:   uint64(i + 1),
+			RootHash:   b.rootHash,
+			OTSDigest:  b.rootHash,
+			CreatedAt:  time.Now(),
+		}
+		if err := store.SaveBatchMeta(meta); err != nil {
+			t.Fatal(err)
+		}
+		b.attempt.BatchID = meta.BatchID
+		if err := store.SaveAttempt(b.attempt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := &Module{store: store}
+	if got, err := m.CommittedProofHash(batches[1].rootHash); err != nil || got != proofHash {
+		t.Errorf("anchored batch commits to %s, %v, want %s", got.Hex(), err, proofHash.Hex())
+	}
+	for _, rootHash := range []common.Hash{batches[0].rootHash, common.HexToHash("0x03")} {
+		if _, err := m.CommittedProofHash(rootHash); !errors.Is(err, ErrProofNotCommitted) {
+			t.Errorf("root %s: expected ErrProofNotCommitted, got %v", rootHash.Hex(), err)
+		}
+	}
+}
+
+// tipExplorer is a Bitcoin explorer that only knows the chain tip
+type tipExplorer struct {
+	opentimestamps.BitcoinExplorer
+	tip uint64
+}
+
+func (e *tipExplorer) GetTipHeight(ctx context.Context) (uint64, error) {
+	return e.tip, nil
+}
+
+func TestCheckBTCConfirmation_CLIDepth(t *testing.T) {
+	config := DefaultConfig()
+	config.OTS.Client = OTSClientCLI
+	config.OTS.BTCConfirmations = 6
+
+	explorer := &tipExplorer{}
+	m := &Module{
+		config: config,
+		ctx:    context.Background(),
+		otsClient: &confirmingClient{
+			attestation: &opentimestamps.AttestationInfo{BTCTxID: "beef", BTCBlockHeight: 800000},
+		},
+		btcExplorer: explorer,
+	}
+
+	tests := []struct {
+		tip       uint64
+		confirmed bool
+	}{
+		{799999, false},
+		{800000, false},
+		{800004, false},
+		{800005, true},
+	}
+	for _, tt := range tests {
+		explorer.tip = tt.tip
+		_, _, err := m.checkBTCConfirmation(common.Hash{}, nil)
+		if tt.confirmed && err != nil {
+			t.Errorf("tip %d: unexpected error %v", tt.tip, err)
+		}
+		if !tt.confirmed && !errors.Is(err, ErrBTCNotConfirmed) {
+			t.Errorf("tip %d: expected ErrBTCNotConfirmed, got %v", tt.tip, err)
+		}
+	}
+
+	// Without an explorer the depth cannot be checked
+	m.btcExplorer = nil
+	if _, _, err := m.checkBTCConfirmation(common.Hash{}, nil); !errors.Is(err, ErrBTCNotConfirmed) {
+		t.Errorf("expected ErrBTCNotConfirmed without an explorer, got %v", err)
+	}
+}
+
+// anchorChain is a header chain with receipts for the consensus manager
+type anchorChain struct {
+	headers  []*types.Header
+	receipts map[common.Hash]types.Receipts
+}
+
+func newAnchorChain() *anchorChain {
+	genesis := &types.Header{Number: big.NewInt(0), ReceiptHash: types.EmptyReceiptsHash}
+	return &anchorChain{headers: []*types.Header{genesis}, receipts: make(map[common.Hash]types.Receipts)}
+}
+
+// next returns the header of the next block with the logs of the registry
+func (c *anchorChain) next(logs ...*types.Log) *types.Header {
+	parent := c.headers[len(c.headers)-1]
+	header := &types.Header{
+		ParentHash:  parent.Hash(),
+		Number:      new(big.Int).Add(parent.Number, big.NewInt(1)),
+		Time:        parent.Time + 3,
+		ReceiptHash: types.EmptyReceiptsHash,
+	}
+	var receipts types.Receipts
+	if len(logs) > 0 {
+		receipts = types.Receipts{{Status: types.ReceiptStatusSuccessful, Logs: logs}}
+		header.ReceiptHash = common.Hash{byte(len(c.headers))}
+	}
+	c.headers = append(c.headers, header)
+	c.receipts[header.Hash()] = receipts
+	return header
+}
+
+func (c *anchorChain) getReceipts(hash common.Hash, number uint64) types.Receipts {
+	return c.receipts[hash]
+}
+
+func (c *anchorChain) getHeader(hash common.Hash, number uint64) *types.Header {
+	if number < uint64(len(c.headers)) && c.headers[number].Hash() == hash {
+		return c.headers[number]
+	}
+	return nil
+}
+
+func (c *anchorChain) getHeaderByNumber(number uint64) *types.Header {
+	if number < uint64(len(c.headers)) {
+		return c.headers[number]
+	}
+	return nil
+}
+
+func TestConsensusCommitsConfirmedProofHash(t *testing.T) {
+	registry := common.HexToAddress(consensus.CopyrightRegistryAddress)
+	chain := newAnchorChain()
+	cm, err := consensus.NewOTSConsensusManager(rawdb.NewMemoryDatabase(), &consensus.OTSManagerConfig{
+		Enabled:         true,
+		TriggerSchedule: consensus.TriggerSchedule{{Blocks: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm.SetChainAccessors(chain.getReceipts, chain.getHeader, chain.getHeaderByNumber)
+	process := func(header *types.Header) *consensus.BatchState {
+		t.Helper()
+		snap, err := cm.ProcessBlock(header, header.ParentHash)
+		if err != nil {
+			t.Fatalf("ProcessBlock %d failed: %v", header.Number, err)
+		}
+		return snap.State.OldestBatch()
+	}
+	systemTx := func(header *types.Header, is func(*types.Transaction) bool) *types.Transaction {
+		t.Helper()
+		txs, err := cm.GetSystemTransactions(header, header.ParentHash, common.Address{}, func(common.Address) uint64 { return 0 })
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tx := range txs {
+			if is(tx) {
+				return tx
+			}
+		}
+		t.Fatalf("block %d: system transaction not built", header.Number)
+		return nil
+	}
+
+	// Block 2 triggers blocks [1, 1]
+	ruid := common.HexToHash("0x01")
+	process(chain.next(&types.Log{
+		Address: registry,
+		Topics:  []common.Hash{consensus.CopyrightClaimedEventSig, ruid, common.BytesToHash(common.Address{0x01}.Bytes())},
+		Data:    make([]byte, 32),
+	}))
+	batch := process(chain.next())
+	if batch == nil || batch.Status != consensus.BatchStatusTriggered {
+		t.Fatalf("expected a triggered batch, got %+v", batch)
+	}
+
+	// The batch processor stamped the batch
+	store := storage.NewStoreWithDB(rawdb.NewMemoryDatabase())
+	meta := &BatchMeta{
+		BatchID:    "batch-1-1",
+		StartBlock: 1,
+		EndBlock:   1,
+		RootHash:   batch.RootHash,
+		OTSDigest:  [32]byte{0xd1},
+		CreatedAt:  time.Now(),
+		EventRUIDs: []common.Hash{ruid},
+	}
+	if err := store.SaveBatchMeta(meta); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveOTSProof(meta.OTSDigest, []byte("pending proof")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveAttempt(&Attempt{BatchID: meta.BatchID, Status: AttemptStatusPending}); err != nil {
+		t.Fatal(err)
+	}
+	m := &Module{store: store, consensusManager: cm}
+	m.connectConsensusManager()
+
+	// The otsSubmitted transaction carries the stored digest
+	header := chain.next()
+	submitted, err := systx.DecodeOTSSubmittedTx(systemTx(header, systx.IsOTSSubmittedTx))
+	if err != nil || submitted.OTSDigest != meta.OTSDigest {
+		t.Fatalf("unexpected otsSubmitted %+v, %v", submitted, err)
+	}
+	chain.headers = chain.headers[:len(chain.headers)-1]
+	process(chain.next(&types.Log{
+		Address: registry,
+		Topics:  []common.Hash{crypto.Keccak256Hash([]byte("OTSSubmitted(bytes32,bytes32)")), batch.RootHash},
+		Data:    submitted.OTSDigest[:],
+	}))
+
+	// The calendar scanner confirmed the batch and stored the upgraded proof
+	upgraded := []byte("upgraded proof")
+	if err := store.SaveOTSProof(meta.OTSDigest, upgraded); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveAttempt(&Attempt{
+		BatchID:        meta.BatchID,
+		Status:         AttemptStatusConfirmed,
+		BTCBlockHeight: 800000,
+		BTCTxID:        "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b",
+		BTCTimestamp:   1700000000,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The otsConfirmed transaction commits to the published upgraded proof
+	header = chain.next()
+	confirmed, err := systx.DecodeOTSConfirmedTx(systemTx(header, systx.IsOTSConfirmedTx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	proofHash := storage.ProofHash(upgraded)
+	if confirmed.ProofHash != proofHash {
+		t.Fatalf("otsConfirmed commits to %s, want %s", confirmed.ProofHash.Hex(), proofHash.Hex())
+	}
+	if proof, err := store.GetProofSidecar(proofHash); err != nil || string(proof) != string(upgraded) {
+		t.Errorf("upgraded proof not published: %q, %v", proof, err)
+	}
+
+	// The registry emits OTSConfirmed, and the proof hash lands in state
+	var data []byte
+	data = append(data, common.BigToHash(new(big.Int).SetUint64(confirmed.BTCBlockHeight)).Bytes()...)
+	data = append(data, confirmed.BTCTxID[:]...)
+	data = append(data, common.BigToHash(new(big.Int).SetUint64(confirmed.BTCTimestamp)).Bytes()...)
+	data = append(data, confirmed.ProofHash.Bytes()...)
+	chain.headers = chain.headers[:len(chain.headers)-1]
+	batch = process(chain.next(&types.Log{
+		Address: registry,
+		Topics:  []common.Hash{crypto.Keccak256Hash([]byte("OTSConfirmed(bytes32,uint64,bytes32,uint64,bytes32)")), meta.RootHash},
+		Data:    data,
+	}))
+	if batch.Status != consensus.BatchStatusConfirmed || batch.ProofHash != proofHash {
+		t.Errorf("batch = %v with proof hash %s, want confirmed with %s", batch.Status, batch.ProofHash.Hex(), proofHash.Hex())
+	}
+}
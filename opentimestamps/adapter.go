@@ -7,7 +7,9 @@
 package opentimestamps
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"time"
 )
 
@@ -20,14 +22,18 @@ type NativeClient struct {
 
 // NewNativeClient creates a new native OTS client
 func NewNativeClient(calendarServers []string, timeout time.Duration, dataDir string) (*NativeClient, error) {
-	config := ServiceConfig{
+	return NewNativeClientWithConfig(ServiceConfig{
 		CalendarServers:  calendarServers,
 		Timeout:          timeout,
 		BTCConfirmations: 6,
 		UseTestnet:       false,
-	}
+	}, dataDir)
+}
 
-	if len(calendarServers) == 0 {
+// NewNativeClientWithConfig creates a new native OTS client from a full
+// service configuration, e.g. to select a Bitcoin explorer
+func NewNativeClientWithConfig(config ServiceConfig, dataDir string) (*NativeClient, error) {
+	if len(config.CalendarServers) == 0 {
 		config.CalendarServers = DefaultCalendarServers
 	}
 
@@ -38,7 +44,7 @@ func NewNativeClient(calendarServers []string, timeout time.Duration, dataDir st
 
 	return &NativeClient{
 		service: service,
-		timeout: timeout,
+		timeout: config.Timeout,
 		dataDir: dataDir,
 	}, nil
 }
@@ -68,23 +74,35 @@ func (c *NativeClient) Upgrade(ctx context.Context, proof []byte) ([]byte, error
 	return upgradedTs.Serialize()
 }
 
-// Verify verifies an OTS proof against the Bitcoin blockchain
+// Verify verifies an OTS proof for digest against the Bitcoin blockchain.
+// Like `ots verify`, it fails with ErrNotConfirmed while the proof is pending
+// and with ErrVerifyFailed if the proof is for another digest or invalid.
 func (c *NativeClient) Verify(ctx context.Context, digest [32]byte, proof []byte) (*AttestationInfo, error) {
 	ts, err := Parse(proof)
 	if err != nil {
 		return nil, err
 	}
+	if !bytes.Equal(ts.Digest, digest[:]) {
+		return nil, fmt.Errorf("%w: proof is for digest %x", ErrVerifyFailed, ts.Digest)
+	}
+	if !ts.IsComplete() {
+		return nil, ErrNotConfirmed
+	}
 
 	result, err := c.service.Verify(ctx, ts)
 	if err != nil {
 		return nil, err
 	}
+	if result.InsufficientDepth {
+		return nil, fmt.Errorf("%w: %s", ErrInsufficientDepth, result.Message)
+	}
+	if !result.Valid {
+		return nil, fmt.Errorf("%w: %s", ErrVerifyFailed, result.Message)
+	}
 
-	return &AttestationInfo{
-		BTCBlockHeight: result.BTCBlockHeight,
-		BTCTimestamp:   result.BTCTimestamp,
-		IsComplete:     result.Complete,
-	}, nil
+	info := ts.Info()
+	info.BTCTimestamp = result.BTCTimestamp
+	return info, nil
 }
 
 // Info extracts attestation info from a proof without full verification
@@ -93,16 +111,7 @@ func (c *NativeClient) Info(ctx context.Context, proof []byte) (*AttestationInfo
 	if err != nil {
 		return nil, err
 	}
-
-	info := &AttestationInfo{
-		IsComplete: ts.IsComplete(),
-	}
-
-	if btcAtt := ts.GetBitcoinAttestation(); btcAtt != nil {
-		info.BTCBlockHeight = btcAtt.BTCBlockHeight
-	}
-
-	return info, nil
+	return ts.Info(), nil
 }
 
 // Close stops the native service
@@ -110,6 +119,17 @@ func (c *NativeClient) Close() error {
 	return c.service.Stop()
 }
 
+// CalendarResults returns the per-calendar results of the last submission
+func (c *NativeClient) CalendarResults() []CalendarResult {
+	return c.service.CalendarResults()
+}
+
+// RemovePending forgets a submitted digest once its confirmed proof is
+// stored elsewhere
+func (c *NativeClient) RemovePending(digest [32]byte) {
+	c.service.RemovePending(digest)
+}
+
 // GetService returns the underlying service for advanced usage
 func (c *NativeClient) GetService() *Service {
 	return c.service
@@ -148,6 +168,20 @@ type ClientInterface interface {
 	Info(ctx context.Context, proof []byte) (*AttestationInfo, error)
 }
 
+// CalendarReporter is implemented by clients that report per-calendar
+// submission results
+type CalendarReporter interface {
+	CalendarResults() []CalendarResult
+}
+
+// PendingTracker is implemented by clients that keep submitted digests until
+// they are told the digest is confirmed
+type PendingTracker interface {
+	RemovePending(digest [32]byte)
+}
+
 // Ensure both clients implement the interface
 var _ ClientInterface = (*Client)(nil)
 var _ ClientInterface = (*NativeClient)(nil)
+var _ PendingTracker = (*NativeClient)(nil)
+var _ CalendarReporter = (*NativeClient)(nil)
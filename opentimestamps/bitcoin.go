@@ -9,12 +9,15 @@ import (
 	"bytes"
 	"context"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/log"
@@ -24,6 +27,7 @@ var (
 	ErrBlockNotFound      = errors.New("ots: bitcoin block not found")
 	ErrInvalidAttestation = errors.New("ots: invalid bitcoin attestation")
 	ErrExplorerError      = errors.New("ots: bitcoin explorer error")
+	ErrInsufficientDepth  = errors.New("ots: bitcoin attestation lacks required confirmations")
 )
 
 // BitcoinExplorer interface for different explorer APIs
@@ -36,6 +40,9 @@ type BitcoinExplorer interface {
 
 	// VerifyMerkleRoot checks if a commitment is in a block's merkle tree
 	VerifyMerkleRoot(ctx context.Context, height uint64, commitment []byte) (bool, error)
+
+	// GetTipHeight returns the height of the best chain tip
+	GetTipHeight(ctx context.Context) (uint64, error)
 }
 
 // BlockHeader represents a Bitcoin block header
@@ -76,6 +83,20 @@ func NewBlockstreamTestnetExplorer(timeout time.Duration) *BlockstreamExplorer {
 	}
 }
 
+// MempoolSpaceAPI is the base URL of the mempool.space Esplora-compatible API
+const MempoolSpaceAPI = "https://mempool.space/api"
+
+// NewEsploraExplorer creates an explorer for any Esplora-compatible API, such
+// as mempool.space or a self-hosted electrs instance
+func NewEsploraExplorer(baseURL string, timeout time.Duration) *BlockstreamExplorer {
+	return &BlockstreamExplorer{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
 // blockstreamBlockResponse is the API response for block info
 type blockstreamBlockResponse struct {
 	ID         string `json:"id"`
@@ -142,6 +163,20 @@ func (e *BlockstreamExplorer) GetBlockHash(ctx context.Context, height uint64) (
 	return reverseBytes(hash), nil
 }
 
+// GetTipHeight returns the height of the best chain tip
+func (e *BlockstreamExplorer) GetTipHeight(ctx context.Context) (uint64, error) {
+	resp, err := e.doRequest(ctx, "/blocks/tip/height")
+	if err != nil {
+		return 0, err
+	}
+
+	height, err := strconv.ParseUint(strings.TrimSpace(string(resp)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: bad tip height %q", ErrExplorerError, string(resp))
+	}
+	return height, nil
+}
+
 // VerifyMerkleRoot checks if a commitment might be in a block
 // Note: Full verification would require the coinbase transaction
 func (e *BlockstreamExplorer) VerifyMerkleRoot(ctx context.Context, height uint64, commitment []byte) (bool, error) {
@@ -256,14 +291,33 @@ func (v *BitcoinVerifier) VerifyAttestation(ctx context.Context, ts *Timestamp)
 		return nil, err
 	}
 
-	return &VerificationResult{
+	result := &VerificationResult{
 		Valid:          true,
 		Complete:       true,
 		BTCBlockHeight: btcAtt.BTCBlockHeight,
 		BTCBlockHash:   hex.EncodeToString(header.Hash),
 		BTCTimestamp:   header.Timestamp,
 		Message:        fmt.Sprintf("Verified at Bitcoin block %d", btcAtt.BTCBlockHeight),
-	}, nil
+	}
+
+	// The attested block must be buried deep enough to survive a reorg
+	if v.confirmations > 0 {
+		tip, err := v.explorer.GetTipHeight(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if tip >= btcAtt.BTCBlockHeight {
+			result.Confirmations = tip - btcAtt.BTCBlockHeight + 1
+		}
+		if result.Confirmations < v.confirmations {
+			result.Valid = false
+			result.InsufficientDepth = true
+			result.Message = fmt.Sprintf("Bitcoin block %d has %d of %d required confirmations",
+				btcAtt.BTCBlockHeight, result.Confirmations, v.confirmations)
+		}
+	}
+
+	return result, nil
 }
 
 // VerificationResult holds the result of a timestamp verification
@@ -283,6 +337,13 @@ type VerificationResult struct {
 	// BTCTimestamp is the Bitcoin block timestamp (if complete)
 	BTCTimestamp uint64
 
+	// Confirmations is the depth of the attested block below the chain tip
+	Confirmations uint64
+
+	// InsufficientDepth indicates the commitment is in the block but the
+	// block does not have the required confirmations yet
+	InsufficientDepth bool
+
 	// Message is a human-readable message
 	Message string
 }
@@ -347,3 +408,22 @@ func computeBlockHash(h *BlockHeader) []byte {
 	serialized := serializeBlockHeader(h)
 	return doubleSHA256(serialized)
 }
+
+// parseBlockHeader parses a serialized 80-byte block header
+func parseBlockHeader(raw []byte, height uint64) (*BlockHeader, error) {
+	if len(raw) != blockHeaderSize {
+		return nil, fmt.Errorf("%w: header is %d bytes", ErrInvalidHeader, len(raw))
+	}
+
+	h := &BlockHeader{
+		Height:     height,
+		Version:    binary.LittleEndian.Uint32(raw[0:4]),
+		PrevHash:   append([]byte{}, raw[4:36]...),
+		MerkleRoot: append([]byte{}, raw[36:68]...),
+		Timestamp:  uint64(binary.LittleEndian.Uint32(raw[68:72])),
+		Bits:       binary.LittleEndian.Uint32(raw[72:76]),
+		Nonce:      binary.LittleEndian.Uint32(raw[76:80]),
+	}
+	h.Hash = computeBlockHash(h)
+	return h, nil
+}
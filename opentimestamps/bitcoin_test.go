@@ -0,0 +1,155 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package opentimestamps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// attestedTimestamp returns a proof committing to the merkle root of the
+// given block, attested at that height
+func attestedTimestamp(t *testing.T, explorer BitcoinExplorer, height uint64) *Timestamp {
+	t.Helper()
+	header, err := explorer.GetBlockHeader(context.Background(), height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var root [32]byte
+	copy(root[:], header.MerkleRoot)
+	ts := NewTimestamp(root)
+	ts.Root.AddAttestation(Attestation{Type: AttestationBitcoin, BTCBlockHeight: height})
+	return ts
+}
+
+func TestBitcoinVerifier_Confirmations(t *testing.T) {
+	explorer, err := NewLocalExplorerFromFile(filepath.Join("testdata", "headers.bin"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The tip is block 2, so block h has 3-h confirmations
+	tests := []struct {
+		height        uint64
+		required      uint64
+		valid         bool
+		confirmations uint64
+	}{
+		{height: 2, required: 0, valid: true, confirmations: 0},
+		{height: 2, required: 1, valid: true, confirmations: 1},
+		{height: 2, required: 2, valid: false, confirmations: 1},
+		{height: 0, required: 3, valid: true, confirmations: 3},
+		{height: 0, required: 6, valid: false, confirmations: 3},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("height%d/required%d", tt.height, tt.required), func(t *testing.T) {
+			verifier := NewBitcoinVerifier(explorer, tt.required)
+			result, err := verifier.VerifyAttestation(context.Background(), attestedTimestamp(t, explorer, tt.height))
+			if err != nil {
+				t.Fatalf("VerifyAttestation failed: %v", err)
+			}
+			if result.Valid != tt.valid {
+				t.Errorf("Valid = %v, want %v (%s)", result.Valid, tt.valid, result.Message)
+			}
+			if result.InsufficientDepth == tt.valid {
+				t.Errorf("InsufficientDepth = %v, want %v", result.InsufficientDepth, !tt.valid)
+			}
+			if result.Confirmations != tt.confirmations {
+				t.Errorf("Confirmations = %d, want %d", result.Confirmations, tt.confirmations)
+			}
+			if result.BTCBlockHeight != tt.height {
+				t.Errorf("BTCBlockHeight = %d, want %d", result.BTCBlockHeight, tt.height)
+			}
+		})
+	}
+}
+
+func TestBitcoinVerifier_WrongCommitmentIsNotDepthFailure(t *testing.T) {
+	explorer, err := NewLocalExplorerFromFile(filepath.Join("testdata", "headers.bin"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := NewTimestamp([32]byte{0x01})
+	ts.Root.AddAttestation(Attestation{Type: AttestationBitcoin, BTCBlockHeight: 2})
+	result, err := NewBitcoinVerifier(explorer, 6).VerifyAttestation(context.Background(), ts)
+	if err != nil {
+		t.Fatalf("VerifyAttestation failed: %v", err)
+	}
+	if result.Valid || result.InsufficientDepth {
+		t.Errorf("Valid = %v, InsufficientDepth = %v; want both false", result.Valid, result.InsufficientDepth)
+	}
+}
+
+func TestBitcoinVerifier_BitcoindTip(t *testing.T) {
+	server := httptest.NewServer(newFakeBitcoind(t, "u", "p"))
+	defer server.Close()
+
+	explorer, err := NewBitcoindExplorer(BitcoindConfig{URL: server.URL, User: "u", Password: "p", Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tip, err := explorer.GetTipHeight(context.Background())
+	if err != nil || tip != 2 {
+		t.Fatalf("GetTipHeight = %d, %v; want 2", tip, err)
+	}
+
+	result, err := NewBitcoinVerifier(explorer, 2).VerifyAttestation(context.Background(), attestedTimestamp(t, explorer, 1))
+	if err != nil {
+		t.Fatalf("VerifyAttestation failed: %v", err)
+	}
+	if !result.Valid || result.Confirmations != 2 {
+		t.Errorf("Valid = %v, Confirmations = %d; want true, 2", result.Valid, result.Confirmations)
+	}
+}
+
+func TestBlockstreamExplorer_GetTipHeight(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/blocks/tip/height" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "850000")
+	}))
+	defer server.Close()
+
+	explorer := NewBlockstreamExplorer(5 * time.Second)
+	explorer.baseURL = server.URL
+
+	tip, err := explorer.GetTipHeight(context.Background())
+	if err != nil {
+		t.Fatalf("GetTipHeight failed: %v", err)
+	}
+	if tip != 850000 {
+		t.Errorf("GetTipHeight = %d, want 850000", tip)
+	}
+}
+
+func TestNativeClient_VerifyInsufficientDepth(t *testing.T) {
+	explorer, err := NewLocalExplorerFromFile(filepath.Join("testdata", "headers.bin"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewNativeClientWithConfig(ServiceConfig{Explorer: explorer, BTCConfirmations: 6}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := attestedTimestamp(t, explorer, 2)
+	proof, err := ts.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var digest [32]byte
+	copy(digest[:], ts.Digest)
+	if _, err := client.Verify(context.Background(), digest, proof); !errors.Is(err, ErrInsufficientDepth) {
+		t.Errorf("expected ErrInsufficientDepth, got %v", err)
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+//
+// Bitcoin Core JSON-RPC explorer for OpenTimestamps verification.
+
+package opentimestamps
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Bitcoin Core RPC error codes mapped to ErrBlockNotFound
+const (
+	bitcoindErrInvalidAddressOrKey = -5 // unknown block hash
+	bitcoindErrInvalidParameter    = -8 // block height out of range
+)
+
+// BitcoindConfig holds the connection settings of a Bitcoin Core node
+type BitcoindConfig struct {
+	// URL is the JSON-RPC endpoint, e.g. http://127.0.0.1:8332
+	URL string
+
+	// User and Password are the rpcuser/rpcpassword credentials
+	User     string
+	Password string
+
+	// CookieFile is the path to bitcoind's .cookie file. When set it takes
+	// precedence over User/Password and is re-read on every request, so
+	// node restarts are picked up.
+	CookieFile string
+
+	// Timeout for RPC requests
+	Timeout time.Duration
+}
+
+// BitcoindExplorer queries a Bitcoin Core node over JSON-RPC
+type BitcoindExplorer struct {
+	config     BitcoindConfig
+	httpClient *http.Client
+	nextID     atomic.Uint64
+}
+
+// NewBitcoindExplorer creates a new Bitcoin Core explorer
+func NewBitcoindExplorer(config BitcoindConfig) (*BitcoindExplorer, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("%w: bitcoind URL not configured", ErrExplorerError)
+	}
+	if config.CookieFile == "" && config.User == "" {
+		return nil, fmt.Errorf("%w: bitcoind credentials not configured", ErrExplorerError)
+	}
+
+	return &BitcoindExplorer{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: config.Timeout,
+		},
+	}, nil
+}
+
+// bitcoindRequest is a JSON-RPC 1.0 request as accepted by bitcoind
+type bitcoindRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+// bitcoindResponse is a JSON-RPC response from bitcoind
+type bitcoindResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	ID uint64 `json:"id"`
+}
+
+// GetBlockHeader returns the block header for a given height
+func (e *BitcoindExplorer) GetBlockHeader(ctx context.Context, height uint64) (*BlockHeader, error) {
+	hash, err := e.GetBlockHash(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry headerDumpEntry
+	if err := e.call(ctx, "getblockheader", []interface{}{displayHash(hash), true}, &entry); err != nil {
+		return nil, err
+	}
+
+	header, err := entry.toHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	// Do not take the node's word for the fields: they must hash to the
+	// block hash we asked for
+	if !bytes.Equal(header.Hash, hash) || !bytes.Equal(computeBlockHash(header), hash) {
+		return nil, fmt.Errorf("%w: header at height %d does not match its hash", ErrInvalidHeader, height)
+	}
+	if header.Height != height {
+		return nil, fmt.Errorf("%w: requested height %d, got %d", ErrInvalidHeader, height, header.Height)
+	}
+
+	return header, nil
+}
+
+// GetBlockHash returns the block hash for a given height
+func (e *BitcoindExplorer) GetBlockHash(ctx context.Context, height uint64) ([]byte, error) {
+	var hashHex string
+	if err := e.call(ctx, "getblockhash", []interface{}{height}, &hashHex); err != nil {
+		return nil, err
+	}
+
+	hash, err := hex.DecodeString(hashHex)
+	if err != nil || len(hash) != 32 {
+		return nil, fmt.Errorf("%w: bad block hash %q", ErrExplorerError, hashHex)
+	}
+
+	return reverseBytes(hash), nil
+}
+
+// GetBlockCount returns the height of the node's best chain
+func (e *BitcoindExplorer) GetBlockCount(ctx context.Context) (uint64, error) {
+	var count uint64
+	if err := e.call(ctx, "getblockcount", nil, &count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetTipHeight returns the height of the best chain tip
+func (e *BitcoindExplorer) GetTipHeight(ctx context.Context) (uint64, error) {
+	return e.GetBlockCount(ctx)
+}
+
+// VerifyMerkleRoot checks that the commitment equals the block's merkle root,
+// which is what an OpenTimestamps Bitcoin attestation commits to
+func (e *BitcoindExplorer) VerifyMerkleRoot(ctx context.Context, height uint64, commitment []byte) (bool, error) {
+	header, err := e.GetBlockHeader(ctx, height)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(commitment, header.MerkleRoot), nil
+}
+
+// call performs a JSON-RPC call and decodes its result into out
+func (e *BitcoindExplorer) call(ctx context.Context, method string, params []interface{}, out interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	body, err := json.Marshal(&bitcoindRequest{
+		JSONRPC: "1.0",
+		ID:      e.nextID.Add(1),
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	user, password, err := e.credentials()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", e.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(user, password)
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrExplorerError, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("%w: bitcoind rejected credentials (%s)", ErrExplorerError, resp.Status)
+	}
+
+	// bitcoind reports RPC errors with a 404/500 status and a JSON body
+	var rpcResp bitcoindResponse
+	if err := json.Unmarshal(data, &rpcResp); err != nil {
+		return fmt.Errorf("%w: %s - %s", ErrExplorerError, resp.Status, strings.TrimSpace(string(data)))
+	}
+	if rpcResp.Error != nil {
+		switch rpcResp.Error.Code {
+		case bitcoindErrInvalidAddressOrKey, bitcoindErrInvalidParameter:
+			return fmt.Errorf("%w: %s", ErrBlockNotFound, rpcResp.Error.Message)
+		}
+		return fmt.Errorf("%w: %s: rpc error %d: %s", ErrExplorerError, method, rpcResp.Error.Code, rpcResp.Error.Message)
+	}
+
+	if err := json.Unmarshal(rpcResp.Result, out); err != nil {
+		return fmt.Errorf("%w: bad %s result: %v", ErrExplorerError, method, err)
+	}
+	return nil
+}
+
+// credentials returns the RPC credentials, reading the cookie file if configured
+func (e *BitcoindExplorer) credentials() (string, string, error) {
+	if e.config.CookieFile == "" {
+		return e.config.User, e.config.Password, nil
+	}
+
+	cookie, err := os.ReadFile(e.config.CookieFile)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: failed to read cookie: %v", ErrExplorerError, err)
+	}
+	user, password, ok := strings.Cut(strings.TrimSpace(string(cookie)), ":")
+	if !ok {
+		return "", "", fmt.Errorf("%w: malformed cookie file %s", ErrExplorerError, e.config.CookieFile)
+	}
+	return user, password, nil
+}
+
+// displayHash converts an internal byte order hash to bitcoind's hex form
+func displayHash(hash []byte) string {
+	return hex.EncodeToString(reverseBytes(hash))
+}
@@ -0,0 +1,199 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package opentimestamps
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeBitcoind mimics the subset of bitcoind's JSON-RPC used by the explorer,
+// serving mainnet blocks 0-2 from testdata/headers.json
+type fakeBitcoind struct {
+	t        *testing.T
+	user     string
+	password string
+	headers  []map[string]interface{}
+	tamper   bool
+}
+
+func newFakeBitcoind(t *testing.T, user, password string) *fakeBitcoind {
+	data, err := os.ReadFile(filepath.Join("testdata", "headers.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeBitcoind{t: t, user: user, password: password}
+	if err := json.Unmarshal(data, &f.headers); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func (f *fakeBitcoind) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, password, ok := r.BasicAuth()
+	if !ok || user != f.user || password != f.password {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req bitcoindRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		f.t.Errorf("bad request: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	reply := func(status int, result interface{}, code int, message string) {
+		resp := map[string]interface{}{"id": req.ID, "result": result, "error": nil}
+		if code != 0 {
+			resp["error"] = map[string]interface{}{"code": code, "message": message}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(resp)
+	}
+
+	switch req.Method {
+	case "getblockcount":
+		reply(http.StatusOK, len(f.headers)-1, 0, "")
+
+	case "getblockhash":
+		height := int(req.Params[0].(float64))
+		if height < 0 || height >= len(f.headers) {
+			reply(http.StatusInternalServerError, nil, -8, "Block height out of range")
+			return
+		}
+		reply(http.StatusOK, f.headers[height]["hash"], 0, "")
+
+	case "getblockheader":
+		for _, h := range f.headers {
+			if h["hash"] == req.Params[0] {
+				if f.tamper {
+					tampered := make(map[string]interface{})
+					for k, v := range h {
+						tampered[k] = v
+					}
+					tampered["time"] = 1
+					h = tampered
+				}
+				reply(http.StatusOK, h, 0, "")
+				return
+			}
+		}
+		reply(http.StatusInternalServerError, nil, -5, "Block not found")
+
+	default:
+		reply(http.StatusNotFound, nil, -32601, "Method not found")
+	}
+}
+
+func TestBitcoindExplorer_UserPassword(t *testing.T) {
+	fake := newFakeBitcoind(t, "rpcuser", "rpcpass")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	e, err := NewBitcoindExplorer(BitcoindConfig{URL: server.URL, User: "rpcuser", Password: "rpcpass", Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("NewBitcoindExplorer failed: %v", err)
+	}
+	ctx := context.Background()
+
+	count, err := e.GetBlockCount(ctx)
+	if err != nil {
+		t.Fatalf("GetBlockCount failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("GetBlockCount = %d, want 2", count)
+	}
+
+	for height, want := range testBlockHashes {
+		hash, err := e.GetBlockHash(ctx, uint64(height))
+		if err != nil {
+			t.Fatalf("GetBlockHash(%d) failed: %v", height, err)
+		}
+		if displayHash(hash) != want {
+			t.Errorf("hash at %d = %s, want %s", height, displayHash(hash), want)
+		}
+	}
+
+	header, err := e.GetBlockHeader(ctx, 2)
+	if err != nil {
+		t.Fatalf("GetBlockHeader failed: %v", err)
+	}
+	if header.Timestamp != 1231469744 {
+		t.Errorf("Timestamp = %d, want 1231469744", header.Timestamp)
+	}
+
+	ok, err := e.VerifyMerkleRoot(ctx, 2, header.MerkleRoot)
+	if err != nil || !ok {
+		t.Errorf("VerifyMerkleRoot = %v, %v; want true", ok, err)
+	}
+	ok, err = e.VerifyMerkleRoot(ctx, 1, header.MerkleRoot)
+	if err != nil || ok {
+		t.Errorf("VerifyMerkleRoot for wrong block = %v, %v; want false", ok, err)
+	}
+
+	if _, err := e.GetBlockHeader(ctx, 3); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("expected ErrBlockNotFound, got %v", err)
+	}
+}
+
+func TestBitcoindExplorer_CookieAuth(t *testing.T) {
+	fake := newFakeBitcoind(t, "__cookie__", "s3cr3t")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	cookie := filepath.Join(t.TempDir(), ".cookie")
+	if err := os.WriteFile(cookie, []byte("__cookie__:s3cr3t\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := NewBitcoindExplorer(BitcoindConfig{URL: server.URL, CookieFile: cookie, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("NewBitcoindExplorer failed: %v", err)
+	}
+	if _, err := e.GetBlockCount(context.Background()); err != nil {
+		t.Fatalf("GetBlockCount with cookie failed: %v", err)
+	}
+
+	// A restarted node writes a new cookie, which is picked up on the next call
+	fake.password = "rotated"
+	if _, err := e.GetBlockCount(context.Background()); !errors.Is(err, ErrExplorerError) {
+		t.Errorf("expected ErrExplorerError with stale cookie, got %v", err)
+	}
+	if err := os.WriteFile(cookie, []byte("__cookie__:rotated"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.GetBlockCount(context.Background()); err != nil {
+		t.Errorf("GetBlockCount after cookie rotation failed: %v", err)
+	}
+}
+
+func TestBitcoindExplorer_RejectsTamperedHeader(t *testing.T) {
+	fake := newFakeBitcoind(t, "u", "p")
+	fake.tamper = true
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	e, _ := NewBitcoindExplorer(BitcoindConfig{URL: server.URL, User: "u", Password: "p", Timeout: 5 * time.Second})
+	if _, err := e.GetBlockHeader(context.Background(), 1); !errors.Is(err, ErrInvalidHeader) {
+		t.Errorf("expected ErrInvalidHeader for tampered header, got %v", err)
+	}
+}
+
+func TestBitcoindExplorer_Config(t *testing.T) {
+	if _, err := NewBitcoindExplorer(BitcoindConfig{User: "u"}); !errors.Is(err, ErrExplorerError) {
+		t.Errorf("expected ErrExplorerError without URL, got %v", err)
+	}
+	if _, err := NewBitcoindExplorer(BitcoindConfig{URL: "http://127.0.0.1:8332"}); !errors.Is(err, ErrExplorerError) {
+		t.Errorf("expected ErrExplorerError without credentials, got %v", err)
+	}
+}
@@ -32,13 +32,38 @@ var (
 	ErrNoCalendarResponse = errors.New("ots: no calendar server responded")
 	ErrCalendarError      = errors.New("ots: calendar server error")
 	ErrDigestNotFound     = errors.New("ots: digest not found on calendar")
+	ErrCalendarQuorum     = errors.New("ots: too few calendar servers accepted the digest")
 )
 
 // CalendarClient communicates with OpenTimestamps calendar servers
 type CalendarClient struct {
-	servers    []string
-	httpClient *http.Client
-	timeout    time.Duration
+	servers      []string
+	httpClient   *http.Client
+	timeout      time.Duration
+	minResponses int
+}
+
+// CalendarResult is the outcome of a submission to a single calendar server
+type CalendarResult struct {
+	// URL is the calendar server URL
+	URL string
+
+	// StatusCode is the HTTP status, or 0 if the server did not answer
+	StatusCode int
+
+	// Latency is the time the request took
+	Latency time.Duration
+
+	// Err is the failure reason, nil on success
+	Err error
+
+	// Time is when the request was made
+	Time time.Time
+}
+
+// OK reports whether the calendar accepted the digest
+func (r *CalendarResult) OK() bool {
+	return r.Err == nil
 }
 
 // NewCalendarClient creates a new calendar client
@@ -52,85 +77,102 @@ func NewCalendarClient(servers []string, timeout time.Duration) *CalendarClient
 		httpClient: &http.Client{
 			Timeout: timeout,
 		},
-		timeout: timeout,
+		timeout:      timeout,
+		minResponses: 1,
 	}
 }
 
-// Submit submits a digest to calendar servers and returns a pending timestamp
-func (c *CalendarClient) Submit(ctx context.Context, digest [32]byte) (*Timestamp, error) {
+// SetMinResponses sets how many calendars must accept a digest for Submit to
+// succeed. It is clamped to the number of servers.
+func (c *CalendarClient) SetMinResponses(n int) {
+	if n < 1 {
+		n = 1
+	}
+	if n > len(c.servers) {
+		n = len(c.servers)
+	}
+	c.minResponses = n
+}
+
+// Submit submits a digest to all calendar servers and returns a pending
+// timestamp holding one branch per accepting calendar, together with the
+// result of every server in configuration order. The results are returned
+// even if fewer than the minimum number of calendars accepted the digest.
+func (c *CalendarClient) Submit(ctx context.Context, digest [32]byte) (*Timestamp, []CalendarResult, error) {
 	// Create timestamp with initial digest
 	ts := NewTimestamp(digest)
 
-	// Try each calendar server
-	var lastErr error
-	var wg sync.WaitGroup
-	responses := make(chan *calendarResponse, len(c.servers))
+	results := make([]CalendarResult, len(c.servers))
+	responses := make([]*calendarResponse, len(c.servers))
 
-	for _, server := range c.servers {
+	var wg sync.WaitGroup
+	for i, server := range c.servers {
 		wg.Add(1)
-		go func(serverURL string) {
+		go func(i int, serverURL string) {
 			defer wg.Done()
-			resp, err := c.submitToServer(ctx, serverURL, digest)
+			start := time.Now()
+			resp, status, err := c.submitToServer(ctx, serverURL, digest)
+			results[i] = CalendarResult{
+				URL:        serverURL,
+				StatusCode: status,
+				Latency:    time.Since(start),
+				Err:        err,
+				Time:       start,
+			}
 			if err != nil {
-				log.Debug("OTS: Calendar submit failed", "server", serverURL, "error", err)
+				log.Debug("OTS: Calendar submit failed", "server", serverURL, "status", status, "error", err)
 				return
 			}
-			responses <- resp
-		}(server)
+			responses[i] = resp
+		}(i, server)
 	}
-
-	// Wait for all responses in background
-	go func() {
-		wg.Wait()
-		close(responses)
-	}()
-
-	// Collect successful responses
-	var pendingAttestations []Attestation
-	for resp := range responses {
-		pendingAttestations = append(pendingAttestations, Attestation{
-			Type:        AttestationPending,
-			CalendarURL: resp.calendarURL,
-		})
-
-		// Add operations from calendar response
-		if len(ts.Operations) == 0 && len(resp.operations) > 0 {
-			ts.Operations = resp.operations
+	wg.Wait()
+
+	// Each calendar contributes its own branch
+	pendingCount := 0
+	var failures []string
+	for i, resp := range responses {
+		if resp == nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", results[i].URL, results[i].Err))
+			continue
 		}
+		ts.Root.Merge(resp.timestamp)
+		pendingCount++
 	}
 
-	if len(pendingAttestations) == 0 {
-		return nil, fmt.Errorf("%w: %v", ErrNoCalendarResponse, lastErr)
+	if pendingCount == 0 {
+		return nil, results, fmt.Errorf("%w: %s", ErrNoCalendarResponse, strings.Join(failures, "; "))
 	}
-
-	// Add pending attestations
-	for _, att := range pendingAttestations {
-		ts.AddAttestation(att)
+	if pendingCount < c.minResponses {
+		return nil, results, fmt.Errorf("%w: %d of %d required (%s)",
+			ErrCalendarQuorum, pendingCount, c.minResponses, strings.Join(failures, "; "))
 	}
 
 	log.Info("OTS: Submitted to calendar servers",
 		"digest", DigestToHex(digest[:])[:16]+"...",
-		"pendingCount", len(pendingAttestations),
+		"pendingCount", pendingCount,
+		"failed", len(failures),
 	)
 
-	return ts, nil
+	return ts, results, nil
 }
 
 // calendarResponse holds a response from a calendar server
 type calendarResponse struct {
 	calendarURL string
-	operations  []Operation
+	timestamp   *Node
 	rawProof    []byte
 }
 
-// submitToServer submits a digest to a single calendar server
-func (c *CalendarClient) submitToServer(ctx context.Context, serverURL string, digest [32]byte) (*calendarResponse, error) {
+// submitToServer submits a digest to a single calendar server. It returns the
+// HTTP status code, or 0 if the server did not answer.
+func (c *CalendarClient) submitToServer(ctx context.Context, serverURL string, digest [32]byte) (*calendarResponse, int, error) {
 	// POST /digest with raw 32-byte digest
 	url := strings.TrimSuffix(serverURL, "/") + "/digest"
 
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(digest[:]))
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
@@ -139,112 +181,53 @@ func (c *CalendarClient) submitToServer(ctx context.Context, serverURL string, d
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("%w: %s - %s", ErrCalendarError, resp.Status, string(body))
+		return nil, resp.StatusCode, fmt.Errorf("%w: %s - %s", ErrCalendarError, resp.Status, string(body))
 	}
 
 	// Read response body (partial timestamp)
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, resp.StatusCode, err
 	}
 
-	// Parse the calendar response to extract operations
-	ops, err := parseCalendarResponse(body)
+	// The response is the commitment tree for the submitted digest
+	node, err := ParseNode(body)
 	if err != nil {
-		log.Debug("OTS: Failed to parse calendar response", "error", err)
-		// Still return with pending attestation even if parse fails
-		ops = nil
+		return nil, resp.StatusCode, fmt.Errorf("invalid calendar response: %w", err)
 	}
 
 	return &calendarResponse{
 		calendarURL: serverURL,
-		operations:  ops,
+		timestamp:   node,
 		rawProof:    body,
-	}, nil
-}
-
-// parseCalendarResponse parses the response from a calendar server
-// The response is a partial OTS proof without the initial digest
-func parseCalendarResponse(data []byte) ([]Operation, error) {
-	var ops []Operation
-	offset := 0
-
-	for offset < len(data) {
-		if offset >= len(data) {
-			break
-		}
-
-		tag := data[offset]
-		offset++
-
-		switch tag {
-		case OpAppend, OpPrepend:
-			// Read argument length
-			argLen, n, err := ReadVarInt(data, offset)
-			if err != nil {
-				return ops, nil
-			}
-			offset += n
-
-			// Read argument
-			if offset+int(argLen) > len(data) {
-				return ops, nil
-			}
-			arg := make([]byte, argLen)
-			copy(arg, data[offset:offset+int(argLen)])
-			offset += int(argLen)
-
-			ops = append(ops, Operation{
-				Tag:      tag,
-				Argument: arg,
-			})
-
-		case OpReverse, OpSHA1, OpSHA256, OpRIPEMD160, OpKECCAK256:
-			ops = append(ops, Operation{
-				Tag: tag,
-			})
-
-		case 0x00:
-			// Fork point - skip
-			continue
-
-		default:
-			// Unknown or attestation - stop parsing
-			return ops, nil
-		}
-	}
-
-	return ops, nil
+	}, resp.StatusCode, nil
 }
 
-// GetTimestamp retrieves a completed timestamp from calendar servers
-func (c *CalendarClient) GetTimestamp(ctx context.Context, commitment []byte) (*Timestamp, error) {
-	// Compute commitment hash
-	commitmentHex := DigestToHex(commitment)
-
+// GetTimestamp retrieves the commitment tree for a commitment from calendar servers
+func (c *CalendarClient) GetTimestamp(ctx context.Context, commitment []byte) (*Node, error) {
 	for _, server := range c.servers {
-		ts, err := c.getFromServer(ctx, server, commitmentHex)
+		node, err := c.getFromServer(ctx, server, commitment)
 		if err != nil {
 			log.Debug("OTS: Get timestamp failed", "server", server, "error", err)
 			continue
 		}
-		if ts != nil {
-			return ts, nil
+		if node != nil {
+			return node, nil
 		}
 	}
 
 	return nil, ErrDigestNotFound
 }
 
-// getFromServer retrieves a timestamp from a single server
-func (c *CalendarClient) getFromServer(ctx context.Context, serverURL, commitmentHex string) (*Timestamp, error) {
-	url := strings.TrimSuffix(serverURL, "/") + "/timestamp/" + commitmentHex
+// getFromServer retrieves the commitment tree for a commitment from a single server
+func (c *CalendarClient) getFromServer(ctx context.Context, serverURL string, commitment []byte) (*Node, error) {
+	url := strings.TrimSuffix(serverURL, "/") + "/timestamp/" + DigestToHex(commitment)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -273,51 +256,72 @@ func (c *CalendarClient) getFromServer(ctx context.Context, serverURL, commitmen
 		return nil, err
 	}
 
-	// Parse as OTS timestamp
-	return Parse(body)
+	// Calendars return the tree without file header
+	return ParseNode(body)
 }
 
-// UpgradeTimestamp attempts to upgrade a pending timestamp to a complete one
+// UpgradeTimestamp attempts to upgrade a pending timestamp to a complete one.
+// Every node carrying a pending attestation is looked up on its calendar, and
+// the returned subtree is merged in place.
 func (c *CalendarClient) UpgradeTimestamp(ctx context.Context, ts *Timestamp) (*Timestamp, error) {
 	if ts.IsComplete() {
 		return ts, nil // Already complete
 	}
 
-	// Get the commitment (final digest after operations)
-	commitment, err := ts.GetFinalDigest()
+	type pendingNode struct {
+		node       *Node
+		commitment []byte
+		calendar   string
+	}
+
+	// Collect pending commitments first, merging mutates the tree
+	var targets []pendingNode
+	err := ts.Root.walk(ts.Digest, func(msg []byte, node *Node) error {
+		for _, att := range node.Attestations {
+			if att.Type == AttestationPending {
+				targets = append(targets, pendingNode{node: node, commitment: msg, calendar: att.CalendarURL})
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Try to get upgraded timestamp from calendar servers
-	pendingAtts := ts.GetPendingAttestations()
-	for _, att := range pendingAtts {
-		upgradedTs, err := c.getFromServer(ctx, att.CalendarURL, DigestToHex(commitment))
+	for _, target := range targets {
+		upgraded, err := c.getFromServer(ctx, target.calendar, target.commitment)
 		if err != nil {
-			log.Debug("OTS: Upgrade check failed", "calendar", att.CalendarURL, "error", err)
+			log.Debug("OTS: Upgrade check failed", "calendar", target.calendar, "error", err)
+			continue
+		}
+		if upgraded == nil {
 			continue
 		}
 
-		if upgradedTs != nil && upgradedTs.IsComplete() {
-			// Merge the upgraded attestation into our timestamp
-			btcAtt := upgradedTs.GetBitcoinAttestation()
-			if btcAtt != nil {
-				ts.Attestations = append(ts.Attestations, *btcAtt)
-
-				log.Info("OTS: Timestamp upgraded",
-					"btcBlock", btcAtt.BTCBlockHeight,
-				)
+		upgradedTs := &Timestamp{Digest: target.commitment, Root: upgraded}
+		if btcAtt := upgradedTs.GetBitcoinAttestation(); btcAtt != nil {
+			// Merge the upgraded branch into our timestamp
+			target.node.Merge(upgraded)
 
-				return ts, nil
-			}
+			log.Info("OTS: Timestamp upgraded",
+				"calendar", target.calendar,
+				"btcBlock", btcAtt.BTCBlockHeight,
+			)
 		}
 	}
 
-	return nil, ErrNotConfirmed
+	if !ts.IsComplete() {
+		return nil, ErrNotConfirmed
+	}
+	return ts, nil
 }
 
 // ComputeMerkleRoot computes a Merkle root for multiple digests
 // Uses Bitcoin-style duplication for odd number of nodes
+//
+// This aggregates digests into one calendar submission, with SHA256 steps
+// that are expressible as timestamp operations. It is not the batch
+// commitment scheme; batch roots are built by the merkle package.
 func ComputeMerkleRoot(digests [][32]byte) [32]byte {
 	if len(digests) == 0 {
 		return [32]byte{}
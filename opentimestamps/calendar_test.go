@@ -0,0 +1,170 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package opentimestamps
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestCalendar serves a pending timestamp committing to digest||nonce
+func newTestCalendar(t *testing.T, nonce []byte) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/digest" {
+			http.NotFound(w, r)
+			return
+		}
+		if _, err := io.ReadAll(r.Body); err != nil {
+			t.Errorf("read body: %v", err)
+		}
+		node := &Node{}
+		node.Add(Operation{Tag: OpAppend, Argument: nonce}).
+			Add(Operation{Tag: OpSHA256}).
+			AddAttestation(Attestation{Type: AttestationPending, CalendarURL: server.URL})
+		data, err := node.Serialize()
+		if err != nil {
+			t.Errorf("serialize: %v", err)
+		}
+		w.Write(data)
+	}))
+	return server
+}
+
+func newFailingCalendar() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+}
+
+func TestCalendarClient_SubmitKeepsEveryCalendarBranch(t *testing.T) {
+	a := newTestCalendar(t, []byte{0xaa})
+	defer a.Close()
+	b := newTestCalendar(t, []byte{0xbb})
+	defer b.Close()
+	down := newFailingCalendar()
+	defer down.Close()
+
+	client := NewCalendarClient([]string{a.URL, down.URL, b.URL}, 5*time.Second)
+	client.SetMinResponses(2)
+
+	digest := sha256.Sum256([]byte("calendar test"))
+	ts, results, err := client.Submit(context.Background(), digest)
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	// Each pending attestation sits on its own calendar's commitment path
+	want := map[string][]byte{
+		a.URL: sha256Sum(append(append([]byte{}, digest[:]...), 0xaa)),
+		b.URL: sha256Sum(append(append([]byte{}, digest[:]...), 0xbb)),
+	}
+	found := 0
+	err = ts.Root.walk(ts.Digest, func(msg []byte, node *Node) error {
+		for _, att := range node.Attestations {
+			if att.Type != AttestationPending {
+				continue
+			}
+			found++
+			if !bytes.Equal(msg, want[att.CalendarURL]) {
+				t.Errorf("pending attestation of %s commits to %x, want %x", att.CalendarURL, msg, want[att.CalendarURL])
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != 2 {
+		t.Errorf("found %d pending attestations, want 2", found)
+	}
+
+	// Results are reported per server, in configuration order
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	for i, tt := range []struct {
+		url    string
+		status int
+		ok     bool
+	}{
+		{a.URL, http.StatusOK, true},
+		{down.URL, http.StatusServiceUnavailable, false},
+		{b.URL, http.StatusOK, true},
+	} {
+		r := results[i]
+		if r.URL != tt.url || r.StatusCode != tt.status || r.OK() != tt.ok {
+			t.Errorf("result %d = {%s %d %v}, want {%s %d %v}", i, r.URL, r.StatusCode, r.Err, tt.url, tt.status, tt.ok)
+		}
+		if r.Latency <= 0 || r.Time.IsZero() {
+			t.Errorf("result %d: missing timing", i)
+		}
+	}
+	if !errors.Is(results[1].Err, ErrCalendarError) {
+		t.Errorf("expected ErrCalendarError for failing calendar, got %v", results[1].Err)
+	}
+}
+
+func TestCalendarClient_SubmitQuorum(t *testing.T) {
+	a := newTestCalendar(t, []byte{0x01})
+	defer a.Close()
+	down := newFailingCalendar()
+	defer down.Close()
+
+	client := NewCalendarClient([]string{a.URL, down.URL}, 5*time.Second)
+	client.SetMinResponses(2)
+
+	ts, results, err := client.Submit(context.Background(), [32]byte{0x01})
+	if !errors.Is(err, ErrCalendarQuorum) {
+		t.Fatalf("expected ErrCalendarQuorum, got %v", err)
+	}
+	if ts != nil {
+		t.Error("no timestamp expected without quorum")
+	}
+	if len(results) != 2 || !results[0].OK() || results[1].OK() {
+		t.Errorf("unexpected results: %+v", results)
+	}
+
+	// No calendar at all is reported as such
+	client = NewCalendarClient([]string{down.URL}, 5*time.Second)
+	if _, _, err := client.Submit(context.Background(), [32]byte{0x01}); !errors.Is(err, ErrNoCalendarResponse) {
+		t.Errorf("expected ErrNoCalendarResponse, got %v", err)
+	}
+}
+
+func TestService_CalendarResults(t *testing.T) {
+	a := newTestCalendar(t, []byte{0x01})
+	defer a.Close()
+	down := newFailingCalendar()
+	defer down.Close()
+
+	client, err := NewNativeClientWithConfig(ServiceConfig{
+		CalendarServers: []string{a.URL, down.URL},
+		Timeout:         5 * time.Second,
+	}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Stamp(context.Background(), [32]byte{0x02}); err != nil {
+		t.Fatalf("Stamp failed: %v", err)
+	}
+
+	results := client.CalendarResults()
+	if len(results) != 2 || !results[0].OK() || results[1].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("unexpected calendar results: %+v", results)
+	}
+}
+
+func sha256Sum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
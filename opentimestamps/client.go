@@ -201,16 +201,31 @@ func (c *Client) Verify(ctx context.Context, digest [32]byte, proof []byte) (*At
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
-		if ctxTimeout.Err() == context.DeadlineExceeded {
-			return nil, ErrTimeout
-		}
+	err := cmd.Run()
+	if ctxTimeout.Err() == context.DeadlineExceeded {
+		return nil, ErrTimeout
+	}
+
+	// The CLI logs its verdict to stderr
+	output := stdout.String() + stderr.String()
+	if strings.Contains(output, "Pending confirmation") {
+		return nil, ErrNotConfirmed
+	}
+	if err != nil {
 		log.Error("OTS: Verify failed", "error", err, "stderr", stderr.String())
 		return nil, ErrVerifyFailed
 	}
 
 	// Parse output to extract attestation info
-	info := parseVerifyOutput(stdout.String())
+	info := parseVerifyOutput(output)
+	if !info.IsComplete {
+		return nil, ErrVerifyFailed
+	}
+
+	// `ots verify` does not print the transaction, `ots info` does
+	if full, err := c.Info(ctx, proof); err == nil && full.BTCBlockHeight == info.BTCBlockHeight {
+		info.BTCTxID = full.BTCTxID
+	}
 
 	log.Info("OTS: Verify successful",
 		"digest", digestHex[:16]+"...",
@@ -259,54 +274,72 @@ type AttestationInfo struct {
 	// BTCBlockHeight is the Bitcoin block height
 	BTCBlockHeight uint64
 
-	// BTCTimestamp is the Bitcoin block timestamp
+	// BTCTimestamp is the Bitcoin block timestamp. Only the native client
+	// reports it, `ots verify` prints the date alone.
 	BTCTimestamp uint64
 
 	// IsComplete indicates if the proof is fully attested
 	IsComplete bool
 }
 
-// parseVerifyOutput parses the output of `ots verify`
+// parseVerifyOutput parses the output of `ots verify`, e.g.
+// "Success! Bitcoin block 358391 attests existence as of 2015-05-28 CEST"
 func parseVerifyOutput(output string) *AttestationInfo {
+	return parseAttestationOutput(output)
+}
+
+// parseInfoOutput parses the output of `ots info`, which lists the commitment
+// tree with "verify BitcoinBlockHeaderAttestation(358391)" attestations and
+// "# Bitcoin transaction id <txid>" comments
+func parseInfoOutput(output string) *AttestationInfo {
+	return parseAttestationOutput(output)
+}
+
+// parseAttestationOutput extracts the lowest Bitcoin attestation and the
+// transaction id listed on its branch, matching Timestamp.Info
+func parseAttestationOutput(output string) *AttestationInfo {
 	info := &AttestationInfo{}
 
-	lines := strings.Split(output, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
+	var txid string
+	for _, line := range strings.Split(output, "\n") {
+		// Branches of a fork are marked with "-> "
+		line = strings.TrimPrefix(strings.TrimSpace(line), "-> ")
 
-		// Look for "Bitcoin block <height>"
-		if strings.Contains(line, "Bitcoin block") {
-			fmt.Sscanf(line, "Bitcoin block %d", &info.BTCBlockHeight)
+		if i := strings.Index(line, "transaction id "); i >= 0 {
+			if fields := strings.Fields(line[i+len("transaction id "):]); len(fields) > 0 {
+				txid = fields[0]
+			}
+			continue
+		}
+
+		height, ok := parseBitcoinHeight(line)
+		if ok && (!info.IsComplete || height < info.BTCBlockHeight) {
 			info.IsComplete = true
+			info.BTCBlockHeight = height
+			info.BTCTxID = txid
 		}
 
-		// Look for timestamp
-		if strings.Contains(line, "attests data existed as of") {
-			// Parse timestamp from various formats
-			// This is simplified - real implementation would be more robust
+		// A transaction id only belongs to the branch it was listed on
+		if strings.HasPrefix(line, "verify ") {
+			txid = ""
 		}
 	}
 
 	return info
 }
 
-// parseInfoOutput parses the output of `ots info`
-func parseInfoOutput(output string) *AttestationInfo {
-	info := &AttestationInfo{}
-
-	lines := strings.Split(output, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		if strings.Contains(line, "Bitcoin block") {
-			fmt.Sscanf(line, "Bitcoin block %d", &info.BTCBlockHeight)
-			info.IsComplete = true
+// parseBitcoinHeight returns the block height of a line reporting a Bitcoin
+// attestation in either `ots info` or `ots verify` form
+func parseBitcoinHeight(line string) (uint64, bool) {
+	for _, prefix := range []string{"BitcoinBlockHeaderAttestation(", "Bitcoin block "} {
+		i := strings.Index(line, prefix)
+		if i < 0 {
+			continue
 		}
-
-		if strings.Contains(line, "pending") {
-			info.IsComplete = false
+		var height uint64
+		if _, err := fmt.Sscanf(line[i+len(prefix):], "%d", &height); err == nil {
+			return height, true
 		}
 	}
-
-	return info
+	return 0, false
 }
@@ -0,0 +1,176 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package opentimestamps
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// conformanceFixtures are the proofs both clients must report identically.
+// The Bitcoin proofs commit to the coinbases of mainnet blocks 1 and 2, so
+// they verify against testdata/headers.bin.
+var conformanceFixtures = []struct {
+	file string
+	want AttestationInfo
+}{
+	{"pending.ots", AttestationInfo{}},
+	{"multi-calendar.ots", AttestationInfo{}},
+	{"bitcoin.ots", AttestationInfo{
+		BTCTxID:        "0e3e2357e806b6cdb1f70b54c3a3a17b6714ee1f0e68bebb44a74b1efd512098",
+		BTCBlockHeight: 1,
+		IsComplete:     true,
+	}},
+	{"bitcoin-tx.ots", AttestationInfo{
+		BTCTxID:        "9b0fc92260312ce44e74ef369f5c66bbb85848f2eddd5a7a1cde251e54ccfdd5",
+		BTCBlockHeight: 2,
+		IsComplete:     true,
+	}},
+}
+
+// cliClient returns a CLI client if the ots binary is installed, selected by
+// OTS_BINARY or found on PATH
+func cliClient(t *testing.T) *Client {
+	t.Helper()
+	binary := os.Getenv("OTS_BINARY")
+	if binary == "" {
+		binary = "ots"
+	}
+	if _, err := exec.LookPath(binary); err != nil {
+		return nil
+	}
+	client, err := NewClient(binary, nil, 30*time.Second, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestConformance_Info(t *testing.T) {
+	native, err := NewNativeClient([]string{"http://127.0.0.1:1"}, time.Second, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer native.Close()
+
+	clients := map[string]ClientInterface{"native": native}
+	if cli := cliClient(t); cli != nil {
+		clients["cli"] = cli
+	} else {
+		t.Log("ots binary not found, checking the native client only")
+	}
+
+	for _, fixture := range conformanceFixtures {
+		proof, err := os.ReadFile(filepath.Join("testdata", fixture.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, client := range clients {
+			info, err := client.Info(context.Background(), proof)
+			if err != nil {
+				t.Errorf("%s/%s: Info failed: %v", name, fixture.file, err)
+				continue
+			}
+			if *info != fixture.want {
+				t.Errorf("%s/%s: Info = %+v, want %+v", name, fixture.file, *info, fixture.want)
+			}
+		}
+	}
+}
+
+func TestConformance_Verify(t *testing.T) {
+	newClient := func(explorer BitcoinExplorer) *NativeClient {
+		client, err := NewNativeClientWithConfig(ServiceConfig{
+			CalendarServers: []string{"http://127.0.0.1:1"},
+			Explorer:        explorer,
+		}, t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { client.Close() })
+		return client
+	}
+	// The fixtures commit to mainnet coinbases, checked against the real headers
+	headers, err := NewLocalExplorerFromFile(filepath.Join("testdata", "headers.bin"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	native := newClient(headers)
+
+	read := func(file string) ([]byte, [32]byte) {
+		proof, err := os.ReadFile(filepath.Join("testdata", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts, err := Parse(proof)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var digest [32]byte
+		copy(digest[:], ts.Digest)
+		return proof, digest
+	}
+
+	// Verification reports the same info as `ots info`, plus the block time
+	proof, digest := read("bitcoin-tx.ots")
+	info, err := native.Verify(context.Background(), digest, proof)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	want := conformanceFixtures[3].want
+	want.BTCTimestamp = 1231469744
+	if *info != want {
+		t.Errorf("Verify = %+v, want %+v", *info, want)
+	}
+
+	// Failures map to the errors the CLI client returns
+	if _, err := native.Verify(context.Background(), [32]byte{0x01}, proof); !errors.Is(err, ErrVerifyFailed) {
+		t.Errorf("wrong digest: expected ErrVerifyFailed, got %v", err)
+	}
+	pending, pendingDigest := read("pending.ots")
+	if _, err := native.Verify(context.Background(), pendingDigest, pending); !errors.Is(err, ErrNotConfirmed) {
+		t.Errorf("pending proof: expected ErrNotConfirmed, got %v", err)
+	}
+	if _, err := newClient(acceptingExplorer{height: 1}).Verify(context.Background(), digest, proof); !errors.Is(err, ErrVerifyFailed) {
+		t.Errorf("unknown block: expected ErrVerifyFailed, got %v", err)
+	}
+}
+
+func TestParseAttestationOutput(t *testing.T) {
+	const info = `File sha256 hash: 7211a824f55b505228e4c3d5194c1fcfaa15a456abdf37f9b9d97a4040afc073
+Timestamp:
+prepend 01000000...4104
+append dee6c890...ac00000000
+# Bitcoin transaction id 9b0fc92260312ce44e74ef369f5c66bbb85848f2eddd5a7a1cde251e54ccfdd5
+sha256
+sha256
+verify BitcoinBlockHeaderAttestation(2)
+# Bitcoin block merkle root 9b0fc92260312ce44e74ef369f5c66bbb85848f2eddd5a7a1cde251e54ccfdd5
+`
+	if got := parseInfoOutput(info); *got != conformanceFixtures[3].want {
+		t.Errorf("parseInfoOutput = %+v, want %+v", *got, conformanceFixtures[3].want)
+	}
+
+	// The lowest attestation wins, with the transaction of its own branch
+	const twoBlocks = ` -> # Bitcoin transaction id aaaa
+    verify BitcoinBlockHeaderAttestation(500)
+ -> verify BitcoinBlockHeaderAttestation(400)
+`
+	if got := parseInfoOutput(twoBlocks); got.BTCBlockHeight != 400 || got.BTCTxID != "" {
+		t.Errorf("parseInfoOutput = %+v, want block 400 without txid", *got)
+	}
+
+	const verify = "Assuming target filename is 'hello.txt'\nSuccess! Bitcoin block 358391 attests existence as of 2015-05-28 CEST\n"
+	if got := parseVerifyOutput(verify); !got.IsComplete || got.BTCBlockHeight != 358391 {
+		t.Errorf("parseVerifyOutput = %+v, want block 358391", *got)
+	}
+	if got := parseInfoOutput("verify PendingAttestation('https://alice.btc.calendar.opentimestamps.org')\n"); got.IsComplete {
+		t.Errorf("pending output parsed as complete: %+v", *got)
+	}
+}
@@ -0,0 +1,465 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+//
+// Local Bitcoin header store for offline verification.
+
+package opentimestamps
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+var (
+	ErrInvalidHeader = errors.New("ots: invalid bitcoin block header")
+	ErrInvalidPoW    = errors.New("ots: bitcoin block header fails proof-of-work")
+	ErrBrokenChain   = errors.New("ots: bitcoin block header does not link to its neighbours")
+	ErrBadDifficulty = errors.New("ots: bitcoin block header has the wrong difficulty")
+
+	ErrInvalidCheckpoint = errors.New("ots: header checkpoint must be a 32-byte hash at a retarget height")
+)
+
+// blockHeaderSize is the size of a serialized Bitcoin block header
+const blockHeaderSize = 80
+
+// MainnetPowLimitBits is the easiest target a Bitcoin mainnet header may carry
+const MainnetPowLimitBits uint32 = 0x1d00ffff
+
+const (
+	// retargetInterval is the number of blocks between difficulty retargets
+	retargetInterval = 2016
+
+	// retargetTimespan is the time a retarget interval is meant to take
+	retargetTimespan = 14 * 24 * 60 * 60
+)
+
+// mainnetGenesisHash is the hash of the mainnet genesis block, in display
+// byte order
+const mainnetGenesisHash = "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+
+// HeaderCheckpoint is a trusted block header a local header chain starts
+// from. Its height must be a retarget height, so that every difficulty
+// change after it can be checked.
+type HeaderCheckpoint struct {
+	Height uint64
+
+	// Hash is the block hash in internal byte order
+	Hash []byte
+}
+
+// MainnetGenesisCheckpoint starts a header chain at the mainnet genesis block
+func MainnetGenesisCheckpoint() HeaderCheckpoint {
+	hash, _ := hex.DecodeString(mainnetGenesisHash)
+	return HeaderCheckpoint{Height: 0, Hash: reverseBytes(hash)}
+}
+
+// validate checks that the checkpoint can start a header chain
+func (c HeaderCheckpoint) validate() error {
+	if len(c.Hash) != 32 || c.Height%retargetInterval != 0 {
+		return ErrInvalidCheckpoint
+	}
+	return nil
+}
+
+// LocalExplorer serves block headers from a local header chain so that proofs
+// can be verified without network access. The chain starts at a trusted
+// checkpoint, the mainnet genesis block by default, and only grows by headers
+// that extend its tip with valid proof-of-work at the difficulty the
+// retarget rules require.
+type LocalExplorer struct {
+	mu         sync.RWMutex
+	headers    map[uint64]*BlockHeader
+	tip        uint64
+	checkpoint HeaderCheckpoint
+	powLimit   *big.Int
+}
+
+// NewLocalExplorer creates an empty local explorer starting at the mainnet
+// genesis block, using the mainnet PoW limit
+func NewLocalExplorer() *LocalExplorer {
+	e, _ := NewLocalExplorerWithCheckpoint(MainnetGenesisCheckpoint())
+	return e
+}
+
+// NewLocalExplorerWithCheckpoint creates an empty local explorer whose chain
+// starts at the given checkpoint
+func NewLocalExplorerWithCheckpoint(checkpoint HeaderCheckpoint) (*LocalExplorer, error) {
+	if err := checkpoint.validate(); err != nil {
+		return nil, err
+	}
+	limit, _ := compactToTarget(MainnetPowLimitBits)
+	return &LocalExplorer{
+		headers:    make(map[uint64]*BlockHeader),
+		checkpoint: checkpoint,
+		powLimit:   limit,
+	}, nil
+}
+
+// NewLocalExplorerFromFile creates a local explorer from a header file. The
+// file is either a JSON dump of bitcoind `getblockheader` results (an array or
+// a stream of objects) or raw concatenated 80-byte headers. The headers must
+// form a chain from the checkpoint, or from genesis if checkpoint is nil.
+func NewLocalExplorerFromFile(path string, checkpoint *HeaderCheckpoint) (*LocalExplorer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cp := MainnetGenesisCheckpoint()
+	if checkpoint != nil {
+		cp = *checkpoint
+	}
+	e, err := NewLocalExplorerWithCheckpoint(cp)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		_, err = e.ImportHeaderDump(bytes.NewReader(trimmed))
+	} else {
+		_, err = e.ImportRawHeaders(bytes.NewReader(data), cp.Height)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	return e, nil
+}
+
+// ImportRawHeaders imports concatenated raw 80-byte headers, the first of
+// which is at startHeight. It returns the number of headers imported.
+func (e *LocalExplorer) ImportRawHeaders(r io.Reader, startHeight uint64) (int, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+	if len(data)%blockHeaderSize != 0 {
+		return 0, fmt.Errorf("%w: %d bytes is not a multiple of %d", ErrInvalidHeader, len(data), blockHeaderSize)
+	}
+
+	count := 0
+	for offset := 0; offset < len(data); offset += blockHeaderSize {
+		header, err := parseBlockHeader(data[offset:offset+blockHeaderSize], startHeight+uint64(count))
+		if err != nil {
+			return count, err
+		}
+		if err := e.AddHeader(header); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// headerDumpEntry is a verbose bitcoind getblockheader result
+type headerDumpEntry struct {
+	Hash              string `json:"hash"`
+	Height            uint64 `json:"height"`
+	Version           int64  `json:"version"`
+	MerkleRoot        string `json:"merkleroot"`
+	Time              uint64 `json:"time"`
+	Nonce             uint32 `json:"nonce"`
+	Bits              string `json:"bits"`
+	PreviousBlockHash string `json:"previousblockhash"`
+}
+
+// ImportHeaderDump imports verbose bitcoind getblockheader results, given as a
+// JSON array or as a stream of JSON objects. It returns the number of headers
+// imported.
+func (e *LocalExplorer) ImportHeaderDump(r io.Reader) (int, error) {
+	br := bufio.NewReader(r)
+	dec := json.NewDecoder(br)
+
+	var entries []headerDumpEntry
+	if first, err := peekNonSpace(br); err == nil && first == '[' {
+		if err := dec.Decode(&entries); err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+		}
+	} else {
+		for {
+			var entry headerDumpEntry
+			err := dec.Decode(&entry)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return 0, fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	// Headers are added parent first
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Height < entries[j].Height })
+	for i, entry := range entries {
+		header, err := entry.toHeader()
+		if err != nil {
+			return i, err
+		}
+		if err := e.AddHeader(header); err != nil {
+			return i, err
+		}
+	}
+	return len(entries), nil
+}
+
+// toHeader converts a dump entry, whose hashes are in display byte order
+func (d *headerDumpEntry) toHeader() (*BlockHeader, error) {
+	decode := func(field, s string) ([]byte, error) {
+		if s == "" && field == "previousblockhash" {
+			return make([]byte, 32), nil // genesis
+		}
+		b, err := hex.DecodeString(s)
+		if err != nil || len(b) != 32 {
+			return nil, fmt.Errorf("%w: bad %s %q at height %d", ErrInvalidHeader, field, s, d.Height)
+		}
+		return reverseBytes(b), nil
+	}
+
+	hash, err := decode("hash", d.Hash)
+	if err != nil {
+		return nil, err
+	}
+	merkleRoot, err := decode("merkleroot", d.MerkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	prevHash, err := decode("previousblockhash", d.PreviousBlockHash)
+	if err != nil {
+		return nil, err
+	}
+	bits, err := strconv.ParseUint(d.Bits, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad bits %q at height %d", ErrInvalidHeader, d.Bits, d.Height)
+	}
+
+	return &BlockHeader{
+		Height:     d.Height,
+		Hash:       hash,
+		MerkleRoot: merkleRoot,
+		Timestamp:  d.Time,
+		Version:    uint32(d.Version),
+		PrevHash:   prevHash,
+		Nonce:      d.Nonce,
+		Bits:       uint32(bits),
+	}, nil
+}
+
+// AddHeader verifies and stores a header. The header hash is recomputed and
+// must match Hash if one is given, and must satisfy its own difficulty target.
+// The first header must be the checkpoint; every later one must extend the
+// tip and carry the difficulty the retarget rules give it. Headers already
+// stored are accepted again unchanged.
+func (e *LocalExplorer) AddHeader(h *BlockHeader) error {
+	if len(h.PrevHash) != 32 || len(h.MerkleRoot) != 32 {
+		return fmt.Errorf("%w: malformed header at height %d", ErrInvalidHeader, h.Height)
+	}
+
+	hash := computeBlockHash(h)
+	if len(h.Hash) > 0 && !bytes.Equal(h.Hash, hash) {
+		return fmt.Errorf("%w: hash mismatch at height %d", ErrInvalidHeader, h.Height)
+	}
+	if err := e.checkProofOfWork(hash, h.Bits); err != nil {
+		return fmt.Errorf("%w at height %d", err, h.Height)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if existing, ok := e.headers[h.Height]; ok {
+		if bytes.Equal(existing.Hash, hash) {
+			return nil
+		}
+		return fmt.Errorf("%w: conflicting header at height %d", ErrBrokenChain, h.Height)
+	}
+
+	if len(e.headers) == 0 {
+		if h.Height != e.checkpoint.Height || !bytes.Equal(hash, e.checkpoint.Hash) {
+			return fmt.Errorf("%w: height %d is not the checkpoint at height %d", ErrBrokenChain, h.Height, e.checkpoint.Height)
+		}
+	} else {
+		if h.Height != e.tip+1 {
+			return fmt.Errorf("%w: height %d leaves a gap after tip %d", ErrBrokenChain, h.Height, e.tip)
+		}
+		parent := e.headers[e.tip]
+		if !bytes.Equal(parent.Hash, h.PrevHash) {
+			return fmt.Errorf("%w: height %d does not extend its parent", ErrBrokenChain, h.Height)
+		}
+		bits, err := e.requiredBits(h.Height, parent)
+		if err != nil {
+			return err
+		}
+		if h.Bits != bits {
+			return fmt.Errorf("%w: bits %08x at height %d, want %08x", ErrBadDifficulty, h.Bits, h.Height, bits)
+		}
+	}
+
+	stored := *h
+	stored.Hash = hash
+	e.headers[h.Height] = &stored
+	e.tip = h.Height
+	return nil
+}
+
+// requiredBits returns the difficulty a header at height must carry on top
+// of parent. It only changes at retarget heights, where it follows the time
+// the previous interval took. The caller holds the lock.
+func (e *LocalExplorer) requiredBits(height uint64, parent *BlockHeader) (uint32, error) {
+	if height%retargetInterval != 0 {
+		return parent.Bits, nil
+	}
+	first, ok := e.headers[height-retargetInterval]
+	if !ok {
+		return 0, fmt.Errorf("%w: no header at height %d to retarget from", ErrBrokenChain, height-retargetInterval)
+	}
+	return retargetBits(parent.Bits, int64(parent.Timestamp)-int64(first.Timestamp), e.powLimit)
+}
+
+// retargetBits returns the difficulty following an interval with the given
+// bits that took timespan seconds, as Bitcoin Core computes it
+func retargetBits(bits uint32, timespan int64, powLimit *big.Int) (uint32, error) {
+	if timespan < retargetTimespan/4 {
+		timespan = retargetTimespan / 4
+	}
+	if timespan > retargetTimespan*4 {
+		timespan = retargetTimespan * 4
+	}
+	target, err := compactToTarget(bits)
+	if err != nil {
+		return 0, err
+	}
+	target.Mul(target, big.NewInt(timespan))
+	target.Div(target, big.NewInt(retargetTimespan))
+	if target.Cmp(powLimit) > 0 {
+		target.Set(powLimit)
+	}
+	return targetToCompact(target), nil
+}
+
+// checkProofOfWork checks a header hash (internal byte order) against the
+// target encoded in bits, which must not be easier than the PoW limit
+func (e *LocalExplorer) checkProofOfWork(hash []byte, bits uint32) error {
+	target, err := compactToTarget(bits)
+	if err != nil {
+		return err
+	}
+	if target.Cmp(e.powLimit) > 0 {
+		return fmt.Errorf("%w: target above pow limit", ErrInvalidPoW)
+	}
+	if new(big.Int).SetBytes(reverseBytes(hash)).Cmp(target) > 0 {
+		return ErrInvalidPoW
+	}
+	return nil
+}
+
+// Len returns the number of stored headers
+func (e *LocalExplorer) Len() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return len(e.headers)
+}
+
+// GetBlockHeader returns the block header for a given height
+func (e *LocalExplorer) GetBlockHeader(ctx context.Context, height uint64) (*BlockHeader, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	header, ok := e.headers[height]
+	if !ok {
+		return nil, ErrBlockNotFound
+	}
+	cpy := *header
+	return &cpy, nil
+}
+
+// GetBlockHash returns the block hash for a given height
+func (e *LocalExplorer) GetBlockHash(ctx context.Context, height uint64) ([]byte, error) {
+	header, err := e.GetBlockHeader(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	return header.Hash, nil
+}
+
+// GetTipHeight returns the height of the chain tip
+func (e *LocalExplorer) GetTipHeight(ctx context.Context) (uint64, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if len(e.headers) == 0 {
+		return 0, ErrBlockNotFound
+	}
+	return e.tip, nil
+}
+
+// VerifyMerkleRoot checks that the commitment equals the block's merkle root,
+// which is what an OpenTimestamps Bitcoin attestation commits to
+func (e *LocalExplorer) VerifyMerkleRoot(ctx context.Context, height uint64, commitment []byte) (bool, error) {
+	header, err := e.GetBlockHeader(ctx, height)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(commitment, header.MerkleRoot), nil
+}
+
+// compactToTarget decodes the compact difficulty encoding used in headers
+func compactToTarget(bits uint32) (*big.Int, error) {
+	exponent := uint(bits >> 24)
+	mantissa := int64(bits & 0x007fffff)
+	if bits&0x00800000 != 0 {
+		return nil, fmt.Errorf("%w: negative target", ErrInvalidPoW)
+	}
+
+	target := big.NewInt(mantissa)
+	if exponent <= 3 {
+		target.Rsh(target, 8*(3-exponent))
+	} else {
+		target.Lsh(target, 8*(exponent-3))
+	}
+	if target.Sign() == 0 {
+		return nil, fmt.Errorf("%w: zero target", ErrInvalidPoW)
+	}
+	return target, nil
+}
+
+// targetToCompact encodes a target in the compact form used in headers
+func targetToCompact(target *big.Int) uint32 {
+	size := uint32(len(target.Bytes()))
+	var mantissa uint32
+	if size <= 3 {
+		mantissa = uint32(target.Uint64()) << (8 * (3 - size))
+	} else {
+		mantissa = uint32(new(big.Int).Rsh(target, uint(8*(size-3))).Uint64())
+	}
+	// The sign bit must stay clear
+	if mantissa&0x00800000 != 0 {
+		mantissa >>= 8
+		size++
+	}
+	return size<<24 | mantissa
+}
+
+// peekNonSpace returns the first non-whitespace byte without consuming it
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, r.UnreadByte()
+	}
+}
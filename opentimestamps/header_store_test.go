@@ -0,0 +1,267 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package opentimestamps
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Mainnet blocks 0-2, as in testdata/headers.bin and testdata/headers.json
+var testBlockHashes = []string{
+	"000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f",
+	"00000000839a8e6886ab5951d76f411475428afc90947ee320161bbf18eb6048",
+	"000000006a625f06636b8bb6ac7b960a8d03705d1ace08b1a19da3fdcc99ddbd",
+}
+
+func readTestHeaders(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "headers.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestLocalExplorer_FromFile(t *testing.T) {
+	for _, name := range []string{"headers.bin", "headers.json"} {
+		t.Run(name, func(t *testing.T) {
+			e, err := NewLocalExplorerFromFile(filepath.Join("testdata", name), nil)
+			if err != nil {
+				t.Fatalf("NewLocalExplorerFromFile failed: %v", err)
+			}
+			if e.Len() != len(testBlockHashes) {
+				t.Fatalf("Len = %d, want %d", e.Len(), len(testBlockHashes))
+			}
+
+			for height, want := range testBlockHashes {
+				hash, err := e.GetBlockHash(context.Background(), uint64(height))
+				if err != nil {
+					t.Fatalf("GetBlockHash(%d) failed: %v", height, err)
+				}
+				if displayHash(hash) != want {
+					t.Errorf("hash at %d = %s, want %s", height, displayHash(hash), want)
+				}
+			}
+
+			header, err := e.GetBlockHeader(context.Background(), 2)
+			if err != nil {
+				t.Fatalf("GetBlockHeader failed: %v", err)
+			}
+			if header.Timestamp != 1231469744 {
+				t.Errorf("Timestamp = %d, want 1231469744", header.Timestamp)
+			}
+			if displayHash(header.MerkleRoot) != "9b0fc92260312ce44e74ef369f5c66bbb85848f2eddd5a7a1cde251e54ccfdd5" {
+				t.Errorf("unexpected merkle root %s", displayHash(header.MerkleRoot))
+			}
+		})
+	}
+}
+
+func TestLocalExplorer_HeaderDumpStream(t *testing.T) {
+	dump, err := os.ReadFile(filepath.Join("testdata", "headers.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Concatenated getblockheader outputs instead of an array
+	stream := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(string(dump)), "["), "]")
+	stream = strings.ReplaceAll(stream, "},\n", "}\n")
+
+	e := NewLocalExplorer()
+	n, err := e.ImportHeaderDump(strings.NewReader(stream))
+	if err != nil {
+		t.Fatalf("ImportHeaderDump failed: %v", err)
+	}
+	if n != len(testBlockHashes) {
+		t.Errorf("imported %d headers, want %d", n, len(testBlockHashes))
+	}
+}
+
+func TestLocalExplorer_RejectsBadProofOfWork(t *testing.T) {
+	raw := readTestHeaders(t)
+	header, err := parseBlockHeader(raw[:blockHeaderSize], 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header.Nonce++
+	header.Hash = nil
+
+	e := NewLocalExplorer()
+	if err := e.AddHeader(header); !errors.Is(err, ErrInvalidPoW) {
+		t.Errorf("expected ErrInvalidPoW, got %v", err)
+	}
+
+	// Targets easier than the PoW limit are rejected even if met
+	header.Bits = 0x207fffff
+	if err := e.AddHeader(header); !errors.Is(err, ErrInvalidPoW) {
+		t.Errorf("expected ErrInvalidPoW for easy target, got %v", err)
+	}
+}
+
+func TestLocalExplorer_RejectsHashMismatch(t *testing.T) {
+	raw := readTestHeaders(t)
+	header, _ := parseBlockHeader(raw[:blockHeaderSize], 0)
+	header.Hash = bytes.Repeat([]byte{0x01}, 32)
+
+	if err := NewLocalExplorer().AddHeader(header); !errors.Is(err, ErrInvalidHeader) {
+		t.Errorf("expected ErrInvalidHeader, got %v", err)
+	}
+}
+
+func TestLocalExplorer_RejectsBrokenLinkage(t *testing.T) {
+	raw := readTestHeaders(t)
+
+	// Block 2 claimed at height 1 does not extend the genesis block
+	e := NewLocalExplorer()
+	if _, err := e.ImportRawHeaders(bytes.NewReader(raw[:blockHeaderSize]), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.ImportRawHeaders(bytes.NewReader(raw[2*blockHeaderSize:]), 1); !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("expected ErrBrokenChain for wrong parent, got %v", err)
+	}
+
+	// The chain starts at the checkpoint and leaves no gaps
+	e = NewLocalExplorer()
+	if _, err := e.ImportRawHeaders(bytes.NewReader(raw[blockHeaderSize:]), 1); !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("expected ErrBrokenChain without genesis, got %v", err)
+	}
+	if _, err := e.ImportRawHeaders(bytes.NewReader(raw[:blockHeaderSize]), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.ImportRawHeaders(bytes.NewReader(raw[2*blockHeaderSize:]), 2); !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("expected ErrBrokenChain for a gap, got %v", err)
+	}
+	if tip, _ := e.GetTipHeight(context.Background()); tip != 0 {
+		t.Errorf("tip = %d after rejected headers, want 0", tip)
+	}
+
+	// Another checkpoint rejects the genesis block
+	genesis, _ := parseBlockHeader(raw[:blockHeaderSize], 0)
+	e, err := NewLocalExplorerWithCheckpoint(HeaderCheckpoint{Height: 0, Hash: bytes.Repeat([]byte{0x01}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.AddHeader(genesis); !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("expected ErrBrokenChain for another checkpoint, got %v", err)
+	}
+	if _, err := NewLocalExplorerWithCheckpoint(HeaderCheckpoint{Height: 1000, Hash: genesis.Hash}); !errors.Is(err, ErrInvalidCheckpoint) {
+		t.Errorf("expected ErrInvalidCheckpoint off a retarget height, got %v", err)
+	}
+
+	// Truncated input is not a header
+	if _, err := NewLocalExplorer().ImportRawHeaders(bytes.NewReader(raw[:79]), 0); !errors.Is(err, ErrInvalidHeader) {
+		t.Errorf("expected ErrInvalidHeader, got %v", err)
+	}
+}
+
+func TestLocalExplorer_RejectsIsolatedHeader(t *testing.T) {
+	e, err := NewLocalExplorerFromFile(filepath.Join("testdata", "headers.bin"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A header meeting its target, far above the tip, with a forged merkle
+	// root. The limit is lowered to the regtest one so it can be mined here.
+	e.powLimit, _ = compactToTarget(0x207fffff)
+	forged := &BlockHeader{
+		Height:     800000,
+		Version:    1,
+		PrevHash:   make([]byte, 32),
+		MerkleRoot: bytes.Repeat([]byte{0xab}, 32),
+		Timestamp:  1700000000,
+		Bits:       0x207fffff,
+	}
+	for ; ; forged.Nonce++ {
+		if new(big.Int).SetBytes(reverseBytes(computeBlockHash(forged))).Cmp(e.powLimit) <= 0 {
+			break
+		}
+	}
+	if err := e.AddHeader(forged); !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("expected ErrBrokenChain, got %v", err)
+	}
+	if tip, _ := e.GetTipHeight(context.Background()); tip != 2 {
+		t.Errorf("tip = %d, want 2", tip)
+	}
+}
+
+func TestRetargetBits(t *testing.T) {
+	limit, _ := compactToTarget(MainnetPowLimitBits)
+	tests := []struct {
+		name     string
+		bits     uint32
+		timespan int64
+		want     uint32
+	}{
+		// Mainnet block 32256, the first difficulty increase
+		{"block 32256", 0x1d00ffff, 1262152739 - 1261130161, 0x1d00d86a},
+		{"capped at the pow limit", 0x1d00ffff, 2 * retargetTimespan, 0x1d00ffff},
+		{"at most four times harder", 0x1d00ffff, 1, 0x1c3fffc0},
+		{"on schedule", 0x1b0404cb, retargetTimespan, 0x1b0404cb},
+	}
+	for _, tt := range tests {
+		got, err := retargetBits(tt.bits, tt.timespan, limit)
+		if err != nil {
+			t.Fatalf("%s: retargetBits failed: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: bits = %08x, want %08x", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestService_VerifyProofOffline(t *testing.T) {
+	explorer, err := NewLocalExplorerFromFile(filepath.Join("testdata", "headers.bin"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewService(ServiceConfig{Explorer: explorer})
+
+	header, _ := explorer.GetBlockHeader(context.Background(), 2)
+
+	// The attested commitment must equal the block's merkle root
+	var root [32]byte
+	copy(root[:], header.MerkleRoot)
+	ts := NewTimestamp(root)
+	ts.Root.AddAttestation(Attestation{Type: AttestationBitcoin, BTCBlockHeight: 2})
+	proof, err := ts.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := service.VerifyProof(context.Background(), proof)
+	if err != nil {
+		t.Fatalf("VerifyProof failed: %v", err)
+	}
+	if !result.Valid {
+		t.Fatalf("proof should be valid: %s", result.Message)
+	}
+	if result.BTCTimestamp != 1231469744 {
+		t.Errorf("BTCTimestamp = %d, want 1231469744", result.BTCTimestamp)
+	}
+
+	// A different commitment is rejected
+	wrong := NewTimestamp([32]byte{0x01})
+	wrong.Root.AddAttestation(Attestation{Type: AttestationBitcoin, BTCBlockHeight: 2})
+	result, err = service.Verify(context.Background(), wrong)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if result.Valid {
+		t.Error("proof with wrong commitment should be invalid")
+	}
+
+	// Unknown heights are reported as missing
+	missing := NewTimestamp(root)
+	missing.Root.AddAttestation(Attestation{Type: AttestationBitcoin, BTCBlockHeight: 3})
+	if _, err := service.Verify(context.Background(), missing); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("expected ErrBlockNotFound, got %v", err)
+	}
+}
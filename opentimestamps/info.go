@@ -0,0 +1,114 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+//
+// Attestation info extraction, equivalent to `ots info`.
+
+package opentimestamps
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Info returns the attestation info of the timestamp: the lowest Bitcoin
+// attestation and the transaction on its path, as reported by `ots info`
+func (t *Timestamp) Info() *AttestationInfo {
+	info := &AttestationInfo{}
+
+	node, att := t.bitcoinAttestation()
+	if att == nil {
+		return info
+	}
+	info.IsComplete = true
+	info.BTCBlockHeight = att.BTCBlockHeight
+
+	ops, _ := t.Root.pathTo(node)
+	msg := t.Digest
+	for _, op := range ops {
+		if txid, ok := bitcoinTxID(msg); ok {
+			info.BTCTxID = txid
+			break
+		}
+		next, err := ApplyOperation(msg, op)
+		if err != nil {
+			break
+		}
+		msg = next
+	}
+	return info
+}
+
+// bitcoinTxID returns the transaction id of msg if it is exactly one
+// serialized non-witness Bitcoin transaction
+func bitcoinTxID(msg []byte) (string, bool) {
+	if !isBitcoinTx(msg) {
+		return "", false
+	}
+	first := sha256.Sum256(msg)
+	hash := sha256.Sum256(first[:])
+
+	// Transaction ids are displayed in reversed byte order
+	for i, j := 0, len(hash)-1; i < j; i, j = i+1, j-1 {
+		hash[i], hash[j] = hash[j], hash[i]
+	}
+	return hex.EncodeToString(hash[:]), true
+}
+
+// isBitcoinTx reports whether msg deserializes as a transaction without
+// trailing data. Proof paths commit to txids, so witness data never appears.
+func isBitcoinTx(msg []byte) bool {
+	offset := 4 // version
+	if len(msg) < offset {
+		return false
+	}
+
+	// skip advances past n bytes, failing if msg is too short
+	skip := func(n uint64) bool {
+		if n > uint64(len(msg)-offset) {
+			return false
+		}
+		offset += int(n)
+		return true
+	}
+	count := func() (uint64, bool) {
+		n, size, err := ReadVarInt(msg, offset)
+		if err != nil {
+			return 0, false
+		}
+		offset += size
+		return n, true
+	}
+
+	inputs, ok := count()
+	if !ok || inputs == 0 {
+		return false
+	}
+	for i := uint64(0); i < inputs; i++ {
+		if !skip(36) { // prevout
+			return false
+		}
+		scriptLen, ok := count()
+		if !ok || !skip(scriptLen) || !skip(4) { // script, sequence
+			return false
+		}
+	}
+
+	outputs, ok := count()
+	if !ok || outputs == 0 {
+		return false
+	}
+	for i := uint64(0); i < outputs; i++ {
+		if !skip(8) { // value
+			return false
+		}
+		scriptLen, ok := count()
+		if !ok || !skip(scriptLen) {
+			return false
+		}
+	}
+
+	if !skip(4) { // lock time
+		return false
+	}
+	return offset == len(msg)
+}
@@ -9,6 +9,24 @@ import (
 	"os"
 )
 
+// Serialization markers of the commitment tree
+const (
+	// tagFork precedes every item of a node except the last one
+	tagFork byte = 0xff
+
+	// tagAttestation precedes an attestation
+	tagAttestation byte = 0x00
+)
+
+// Limits enforced by the reference implementation
+const (
+	maxRecursionDepth    = 256
+	maxMessageLength     = 4096
+	maxOpArgumentLength  = 4096
+	maxAttestationLength = 8192
+	maxCalendarURLLength = 1000
+)
+
 // ParseFile parses an OTS file from disk
 func ParseFile(filename string) (*Timestamp, error) {
 	data, err := os.ReadFile(filename)
@@ -32,27 +50,26 @@ func Parse(data []byte) (*Timestamp, error) {
 	offset := len(MagicHeader)
 
 	// Read version
-	version := data[offset]
-	offset++
+	version, n, err := ReadVarUint(data, offset)
+	if err != nil {
+		return nil, err
+	}
+	offset += n
 
 	if version != 1 {
 		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
 	}
 
 	// Read hash type
+	if offset >= len(data) {
+		return nil, ErrInvalidFormat
+	}
 	hashType := data[offset]
 	offset++
 
 	// Determine digest size based on hash type
-	var digestSize int
-	switch hashType {
-	case HashSHA256:
-		digestSize = 32
-	case HashSHA1:
-		digestSize = 20
-	case HashRIPEMD160:
-		digestSize = 20
-	default:
+	digestSize := digestLength(hashType)
+	if digestSize == 0 {
 		return nil, fmt.Errorf("unsupported hash type: 0x%02x", hashType)
 	}
 
@@ -64,148 +81,159 @@ func Parse(data []byte) (*Timestamp, error) {
 	copy(digest, data[offset:offset+digestSize])
 	offset += digestSize
 
-	ts := &Timestamp{
-		Version:      version,
-		HashType:     hashType,
-		Digest:       digest,
-		Operations:   make([]Operation, 0),
-		Attestations: make([]Attestation, 0),
+	// Parse the commitment tree
+	root, err := parseTimestampTree(data, &offset, maxRecursionDepth)
+	if err != nil {
+		return nil, err
+	}
+	if offset != len(data) {
+		return nil, fmt.Errorf("%w: %d trailing bytes at offset %d", ErrInvalidFormat, len(data)-offset, offset)
 	}
 
-	// Parse operations and attestations
-	err := parseTimestampTree(data, &offset, ts)
+	return &Timestamp{
+		Version:  byte(version),
+		HashType: hashType,
+		Digest:   digest,
+		Root:     root,
+	}, nil
+}
+
+// ParseNode parses a serialized commitment tree without file header, as
+// returned by calendar servers
+func ParseNode(data []byte) (*Node, error) {
+	offset := 0
+	node, err := parseTimestampTree(data, &offset, maxRecursionDepth)
 	if err != nil {
 		return nil, err
 	}
+	if offset != len(data) {
+		return nil, fmt.Errorf("%w: %d trailing bytes at offset %d", ErrInvalidFormat, len(data)-offset, offset)
+	}
+	return node, nil
+}
 
-	return ts, nil
+// digestLength returns the digest size of a hash tag, or 0 if unsupported
+func digestLength(hashType byte) int {
+	switch hashType {
+	case HashSHA256, OpKECCAK256:
+		return 32
+	case HashSHA1, HashRIPEMD160:
+		return 20
+	default:
+		return 0
+	}
 }
 
-// parseTimestampTree parses the timestamp operation tree
-func parseTimestampTree(data []byte, offset *int, ts *Timestamp) error {
-	for *offset < len(data) {
+// parseTimestampTree parses a commitment tree node and all its descendants.
+// Every item but the last one is preceded by a fork marker.
+func parseTimestampTree(data []byte, offset *int, depth int) (*Node, error) {
+	if depth <= 0 {
+		return nil, fmt.Errorf("%w: recursion limit exceeded at offset %d", ErrInvalidFormat, *offset)
+	}
+
+	node := &Node{}
+	for {
+		if *offset >= len(data) {
+			return nil, ErrInvalidFormat
+		}
 		tag := data[*offset]
 		*offset++
 
-		switch tag {
-		case OpAppend, OpPrepend:
-			// Read argument length
-			argLen, n, err := ReadVarInt(data, *offset)
-			if err != nil {
-				return err
-			}
-			*offset += n
-
-			// Read argument
-			if *offset+int(argLen) > len(data) {
-				return ErrInvalidFormat
-			}
-			arg := make([]byte, argLen)
-			copy(arg, data[*offset:*offset+int(argLen)])
-			*offset += int(argLen)
-
-			ts.Operations = append(ts.Operations, Operation{
-				Tag:      tag,
-				Argument: arg,
-			})
-
-		case OpReverse, OpSHA1, OpSHA256, OpRIPEMD160, OpKECCAK256:
-			ts.Operations = append(ts.Operations, Operation{
-				Tag: tag,
-			})
-
-		case 0x00:
-			// Fork point - for simplicity, we only follow first branch
-			// In a complete implementation, this would handle branching
-			continue
-
-		default:
-			// Check for attestation
-			if isAttestationTag(tag, data, *offset-1) {
-				att, n, err := parseAttestation(data, *offset-1)
-				if err != nil {
-					return err
-				}
-				ts.Attestations = append(ts.Attestations, att)
-				*offset = *offset - 1 + n
-				return nil // Attestation ends this branch
+		last := tag != tagFork
+		if !last {
+			if *offset >= len(data) {
+				return nil, ErrInvalidFormat
 			}
+			tag = data[*offset]
+			*offset++
+		}
 
-			// Unknown tag - might be end of operations
-			*offset--
-			return nil
+		if err := parseTreeItem(data, offset, tag, node, depth); err != nil {
+			return nil, err
+		}
+		if last {
+			return node, nil
 		}
 	}
-
-	return nil
 }
 
-// isAttestationTag checks if the current position is an attestation
-func isAttestationTag(tag byte, data []byte, offset int) bool {
-	if offset+8 > len(data) {
-		return false
+// parseTreeItem parses a single attestation or operation branch whose tag
+// has already been consumed
+func parseTreeItem(data []byte, offset *int, tag byte, node *Node, depth int) error {
+	if tag == tagAttestation {
+		att, n, err := parseAttestation(data, *offset)
+		if err != nil {
+			return err
+		}
+		*offset += n
+		node.Attestations = append(node.Attestations, att)
+		return nil
 	}
 
-	// Check for Bitcoin attestation magic
-	if bytes.Equal(data[offset:offset+8], BitcoinAttestationMagic) {
-		return true
-	}
+	op := Operation{Tag: tag}
+	switch tag {
+	case OpAppend, OpPrepend:
+		arg, n, err := readVarBytes(data, *offset, maxOpArgumentLength)
+		if err != nil {
+			return err
+		}
+		*offset += n
+		op.Argument = arg
 
-	// Check for Pending attestation magic
-	if bytes.Equal(data[offset:offset+8], PendingAttestationMagic) {
-		return true
+	case OpReverse, OpHexlify, OpSHA1, OpSHA256, OpRIPEMD160, OpKECCAK256:
+
+	default:
+		return fmt.Errorf("%w: unknown tag 0x%02x at offset %d", ErrInvalidOperation, tag, *offset-1)
 	}
 
-	return false
+	child, err := parseTimestampTree(data, offset, depth-1)
+	if err != nil {
+		return err
+	}
+	node.Branches = append(node.Branches, &Branch{Op: op, Node: child})
+	return nil
 }
 
-// parseAttestation parses an attestation
+// parseAttestation parses an attestation: an 8-byte tag followed by a
+// length-prefixed payload
 func parseAttestation(data []byte, offset int) (Attestation, int, error) {
 	if offset+8 > len(data) {
 		return Attestation{}, 0, ErrInvalidFormat
 	}
+	tag := data[offset : offset+8]
 
-	att := Attestation{}
-	consumed := 8 // Magic bytes
+	payload, n, err := readVarBytes(data, offset+8, maxAttestationLength)
+	if err != nil {
+		return Attestation{}, 0, err
+	}
+	consumed := 8 + n
 
-	if bytes.Equal(data[offset:offset+8], BitcoinAttestationMagic) {
+	att := Attestation{}
+	switch {
+	case bytes.Equal(tag, BitcoinAttestationMagic):
 		att.Type = AttestationBitcoin
 
-		// Read block height
-		if offset+8 >= len(data) {
-			return att, consumed, nil
-		}
-
-		height, n, err := ReadVarInt(data, offset+8)
-		if err != nil {
-			return att, consumed, nil
+		// Payload is the block height
+		height, n, err := ReadVarUint(payload, 0)
+		if err != nil || n != len(payload) {
+			return att, 0, fmt.Errorf("%w: bad bitcoin attestation at offset %d", ErrInvalidFormat, offset)
 		}
 		att.BTCBlockHeight = height
-		consumed += n
 
-	} else if bytes.Equal(data[offset:offset+8], PendingAttestationMagic) {
+	case bytes.Equal(tag, PendingAttestationMagic):
 		att.Type = AttestationPending
 
-		// Read calendar URL length
-		if offset+8 >= len(data) {
-			return att, consumed, nil
-		}
-
-		urlLen, n, err := ReadVarInt(data, offset+8)
-		if err != nil {
-			return att, consumed, nil
-		}
-		consumed += n
-
-		// Read calendar URL
-		if offset+consumed+int(urlLen) > len(data) {
-			return att, consumed, nil
+		// Payload is the length-prefixed calendar URL
+		url, n, err := readVarBytes(payload, 0, maxCalendarURLLength)
+		if err != nil || n != len(payload) {
+			return att, 0, fmt.Errorf("%w: bad pending attestation at offset %d", ErrInvalidFormat, offset)
 		}
-		att.CalendarURL = string(data[offset+consumed : offset+consumed+int(urlLen)])
-		consumed += int(urlLen)
+		att.CalendarURL = string(url)
 
-	} else {
+	default:
 		att.Type = AttestationUnknown
+		att.UnknownTag = append([]byte{}, tag...)
+		att.UnknownPayload = payload
 	}
 
 	return att, consumed, nil
@@ -213,13 +241,17 @@ func parseAttestation(data []byte, offset int) (Attestation, int, error) {
 
 // Serialize serializes a Timestamp to OTS file format
 func (t *Timestamp) Serialize() ([]byte, error) {
+	if t.Root == nil {
+		return nil, fmt.Errorf("%w: empty timestamp", ErrInvalidFormat)
+	}
+
 	var buf bytes.Buffer
 
 	// Write magic header
 	buf.Write(MagicHeader)
 
 	// Write version
-	buf.WriteByte(t.Version)
+	buf.Write(WriteVarUint(uint64(t.Version)))
 
 	// Write hash type
 	buf.WriteByte(t.HashType)
@@ -227,34 +259,77 @@ func (t *Timestamp) Serialize() ([]byte, error) {
 	// Write digest
 	buf.Write(t.Digest)
 
-	// Write operations
-	for _, op := range t.Operations {
-		buf.WriteByte(op.Tag)
+	// Write commitment tree
+	if err := t.Root.serialize(&buf); err != nil {
+		return nil, err
+	}
 
-		switch op.Tag {
-		case OpAppend, OpPrepend:
-			buf.Write(WriteVarInt(uint64(len(op.Argument))))
-			buf.Write(op.Argument)
-		// Other ops don't have arguments
+	return buf.Bytes(), nil
+}
+
+// Serialize serializes the commitment tree rooted at the node, without file
+// header
+func (n *Node) Serialize() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := n.serialize(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// serialize writes attestations first, then branches, prefixing every item
+// but the last one with a fork marker
+func (n *Node) serialize(buf *bytes.Buffer) error {
+	if n.IsEmpty() {
+		return fmt.Errorf("%w: empty timestamp", ErrInvalidFormat)
+	}
+
+	for i, att := range n.Attestations {
+		if i < len(n.Attestations)-1 || len(n.Branches) > 0 {
+			buf.WriteByte(tagFork)
+		}
+		buf.WriteByte(tagAttestation)
+		if err := serializeAttestation(buf, att); err != nil {
+			return err
 		}
 	}
 
-	// Write attestations
-	for _, att := range t.Attestations {
-		switch att.Type {
-		case AttestationBitcoin:
-			buf.Write(BitcoinAttestationMagic)
-			buf.Write(WriteVarInt(att.BTCBlockHeight))
-
-		case AttestationPending:
-			buf.Write(PendingAttestationMagic)
-			urlBytes := []byte(att.CalendarURL)
-			buf.Write(WriteVarInt(uint64(len(urlBytes))))
-			buf.Write(urlBytes)
+	for i, b := range n.Branches {
+		if i < len(n.Branches)-1 {
+			buf.WriteByte(tagFork)
+		}
+		buf.WriteByte(b.Op.Tag)
+		if b.Op.Tag == OpAppend || b.Op.Tag == OpPrepend {
+			buf.Write(writeVarBytes(b.Op.Argument))
+		}
+		if err := b.Node.serialize(buf); err != nil {
+			return err
 		}
 	}
 
-	return buf.Bytes(), nil
+	return nil
+}
+
+// serializeAttestation writes an attestation tag and its payload
+func serializeAttestation(buf *bytes.Buffer, att Attestation) error {
+	var payload []byte
+	switch att.Type {
+	case AttestationBitcoin:
+		payload = WriteVarUint(att.BTCBlockHeight)
+
+	case AttestationPending:
+		payload = writeVarBytes([]byte(att.CalendarURL))
+
+	default:
+		if len(att.UnknownTag) != 8 {
+			return fmt.Errorf("%w: attestation tag must be 8 bytes", ErrInvalidFormat)
+		}
+		payload = att.UnknownPayload
+	}
+
+	buf.Write(attestationTag(att))
+	buf.Write(writeVarBytes(payload))
+	return nil
 }
 
 // SaveFile saves a timestamp to a file
@@ -0,0 +1,286 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package opentimestamps
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("bad hex %q: %v", s, err)
+	}
+	return b
+}
+
+func TestParseSerialize_RoundTrip(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.ots"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no fixtures found")
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ts, err := Parse(data)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			out, err := ts.Serialize()
+			if err != nil {
+				t.Fatalf("Serialize failed: %v", err)
+			}
+
+			if !bytes.Equal(out, data) {
+				t.Errorf("round trip mismatch\n got %x\nwant %x", out, data)
+			}
+		})
+	}
+}
+
+func TestParse_PendingFixture(t *testing.T) {
+	ts, err := ParseFile(filepath.Join("testdata", "pending.ots"))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	emptyDigest := sha256.Sum256(nil)
+	if !bytes.Equal(ts.Digest, emptyDigest[:]) {
+		t.Errorf("Digest = %x, want %x", ts.Digest, emptyDigest)
+	}
+
+	if ts.IsComplete() {
+		t.Error("pending timestamp should not be complete")
+	}
+
+	pending := ts.GetPendingAttestations()
+	if len(pending) != 1 || pending[0].CalendarURL != "foobar" {
+		t.Fatalf("unexpected pending attestations: %+v", pending)
+	}
+
+	// The attestation sits on the root, so the commitment is the digest itself
+	commitment, err := ts.GetFinalDigest()
+	if err != nil {
+		t.Fatalf("GetFinalDigest failed: %v", err)
+	}
+	if !bytes.Equal(commitment, emptyDigest[:]) {
+		t.Errorf("commitment = %x, want %x", commitment, emptyDigest)
+	}
+}
+
+func TestParse_MultipleCalendarBranches(t *testing.T) {
+	ts, err := ParseFile(filepath.Join("testdata", "multi-calendar.ots"))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	pending := ts.GetPendingAttestations()
+	want := []string{
+		"https://finney.calendar.eternitywall.com",
+		"https://alice.btc.calendar.opentimestamps.org",
+		"https://bob.btc.calendar.opentimestamps.org",
+	}
+	if len(pending) != len(want) {
+		t.Fatalf("got %d pending attestations, want %d", len(pending), len(want))
+	}
+	for i, att := range pending {
+		if att.CalendarURL != want[i] {
+			t.Errorf("pending[%d] = %s, want %s", i, att.CalendarURL, want[i])
+		}
+	}
+
+	// append(nonce) -> sha256 -> fork into three calendar branches
+	if len(ts.Root.Branches) != 1 {
+		t.Fatalf("root should have 1 branch, got %d", len(ts.Root.Branches))
+	}
+	fork := ts.Root.Branches[0].Node.Branches[0].Node
+	if len(fork.Branches) != 3 {
+		t.Errorf("fork should have 3 branches, got %d", len(fork.Branches))
+	}
+}
+
+func TestParse_BitcoinBranch(t *testing.T) {
+	ts, err := ParseFile(filepath.Join("testdata", "bitcoin.ots"))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	if !ts.IsComplete() {
+		t.Fatal("timestamp should be complete")
+	}
+
+	att := ts.GetBitcoinAttestation()
+	if att == nil || att.BTCBlockHeight != 1 {
+		t.Fatalf("unexpected bitcoin attestation: %+v", att)
+	}
+
+	if len(ts.GetPendingAttestations()) != 2 {
+		t.Errorf("expected 2 pending attestations next to the bitcoin branch")
+	}
+
+	// Recompute the commitment along the bitcoin path by hand
+	sha := func(b []byte) []byte {
+		h := sha256.Sum256(b)
+		return h[:]
+	}
+	cat := func(parts ...[]byte) []byte {
+		return bytes.Join(parts, nil)
+	}
+	// The digest is committed to in the coinbase of mainnet block 1
+	msg := cat(mustHex(t, "01000000"+"01"+"0000000000000000000000000000000000000000000000000000000000000000"+"ffffffff"+
+		"07"+"04ffff001d0104"+"ffffffff"+"01"+"00f2052a01000000"+"43"+"4104"), ts.Digest)
+	msg = sha(sha(cat(msg, mustHex(t, "da7589379515d4e0a604f8141781e62294721166bf621e73a82cbf2342c858ee"+"ac"+"00000000"))))
+	if want := mustHex(t, "982051fd1e4ba744bbbe680e1fee14677ba1a3c3540bf7b1cdb606e857233e0e"); !bytes.Equal(msg, want) {
+		t.Fatalf("path does not reach the block 1 merkle root: %x", msg)
+	}
+
+	commitment, err := ts.GetFinalDigest()
+	if err != nil {
+		t.Fatalf("GetFinalDigest failed: %v", err)
+	}
+	if !bytes.Equal(commitment, msg) {
+		t.Errorf("commitment = %x, want %x", commitment, msg)
+	}
+}
+
+func TestNode_SerializeVectors(t *testing.T) {
+	// Vectors from the reference implementation's Timestamp tests
+	pendingItem := func(uri string) string {
+		return "00" + "83dfe30d2ef90c8e" + "07" + "06" + hex.EncodeToString([]byte(uri))
+	}
+
+	node := &Node{}
+	node.AddAttestation(Attestation{Type: AttestationPending, CalendarURL: "foobar"})
+
+	check := func(want string) {
+		t.Helper()
+		got, err := node.Serialize()
+		if err != nil {
+			t.Fatalf("Serialize failed: %v", err)
+		}
+		if hex.EncodeToString(got) != want {
+			t.Errorf("Serialize = %x, want %s", got, want)
+		}
+		parsed, err := ParseNode(got)
+		if err != nil {
+			t.Fatalf("ParseNode failed: %v", err)
+		}
+		again, _ := parsed.Serialize()
+		if !bytes.Equal(again, got) {
+			t.Errorf("re-serialized node mismatch: %x", again)
+		}
+	}
+
+	check(pendingItem("foobar"))
+
+	node.AddAttestation(Attestation{Type: AttestationPending, CalendarURL: "barfoo"})
+	check("ff" + pendingItem("barfoo") + pendingItem("foobar"))
+
+	node.AddAttestation(Attestation{Type: AttestationPending, CalendarURL: "foobaz"})
+	check("ff" + pendingItem("barfoo") + "ff" + pendingItem("foobar") + pendingItem("foobaz"))
+
+	// An empty child can't be serialized
+	child := node.Add(Operation{Tag: OpSHA256})
+	if _, err := node.Serialize(); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("expected ErrInvalidFormat for empty child, got %v", err)
+	}
+
+	child.AddAttestation(Attestation{Type: AttestationPending, CalendarURL: "deeper"})
+	check("ff" + pendingItem("barfoo") + "ff" + pendingItem("foobar") + "ff" + pendingItem("foobaz") +
+		"08" + pendingItem("deeper"))
+}
+
+func TestNode_MergeCanonicalOrder(t *testing.T) {
+	a := &Node{}
+	a.Add(Operation{Tag: OpPrepend, Argument: []byte{0x02}}).
+		AddAttestation(Attestation{Type: AttestationPending, CalendarURL: "https://b"})
+
+	b := &Node{}
+	b.Add(Operation{Tag: OpPrepend, Argument: []byte{0x01}}).
+		AddAttestation(Attestation{Type: AttestationPending, CalendarURL: "https://a"})
+	b.Add(Operation{Tag: OpPrepend, Argument: []byte{0x02}}).
+		AddAttestation(Attestation{Type: AttestationBitcoin, BTCBlockHeight: 100})
+
+	a.Merge(b)
+
+	if len(a.Branches) != 2 {
+		t.Fatalf("expected 2 branches after merge, got %d", len(a.Branches))
+	}
+	if a.Branches[0].Op.Argument[0] != 0x01 || a.Branches[1].Op.Argument[0] != 0x02 {
+		t.Error("branches not in canonical order")
+	}
+
+	// Bitcoin attestations sort before pending ones
+	merged := a.Branches[1].Node.Attestations
+	if len(merged) != 2 || merged[0].Type != AttestationBitcoin || merged[1].Type != AttestationPending {
+		t.Errorf("unexpected merged attestations: %+v", merged)
+	}
+
+	// Merging again must not duplicate anything
+	a.Merge(b)
+	if len(a.Branches) != 2 || len(a.Branches[1].Node.Attestations) != 2 {
+		t.Error("merge is not idempotent")
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	valid, err := os.ReadFile(filepath.Join("testdata", "bitcoin.ots"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unknownOp := append([]byte{}, valid[:len(MagicHeader)+2+32]...)
+	unknownOp = append(unknownOp, 0x42)
+
+	unknownAttestation := append([]byte{}, valid[:len(MagicHeader)+2+32]...)
+	unknownAttestation = append(unknownAttestation, mustHex(t, "00"+"0102030405060708"+"03"+"aabbcc")...)
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"bad magic", append([]byte{0x01}, valid[1:]...), ErrInvalidMagic},
+		{"truncated", valid[:len(valid)-1], ErrInvalidFormat},
+		{"trailing data", append(append([]byte{}, valid...), 0x00), ErrInvalidFormat},
+		{"unknown op", unknownOp, ErrInvalidOperation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// Unknown attestations are preserved rather than rejected
+	ts, err := Parse(unknownAttestation)
+	if err != nil {
+		t.Fatalf("Parse failed for unknown attestation: %v", err)
+	}
+	out, err := ts.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	if !bytes.Equal(out, unknownAttestation) {
+		t.Errorf("unknown attestation not round-tripped: %x", out)
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+//
+// Persistence of the service's pending timestamps.
+
+package opentimestamps
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// DefaultCompleteRetention is how long a Bitcoin-complete timestamp is kept
+// after confirmation before it is garbage-collected
+const DefaultCompleteRetention = 24 * time.Hour
+
+// PendingStore persists pending timestamps across restarts. It is implemented
+// by the module's storage.Store.
+type PendingStore interface {
+	SavePendingTimestamp(digest [32]byte, data []byte) error
+	DeletePendingTimestamp(digest [32]byte) error
+	IteratePendingTimestamps(fn func(digest [32]byte, data []byte) error) error
+}
+
+// pendingRecord is the stored form of a PendingTimestamp
+type pendingRecord struct {
+	Digest        []byte    `json:"digest"`
+	MerkleRoot    []byte    `json:"merkleRoot,omitempty"`
+	Proof         []byte    `json:"proof"`
+	CreatedAt     time.Time `json:"createdAt"`
+	ConfirmedAt   time.Time `json:"confirmedAt,omitempty"`
+	ContentHashes [][]byte  `json:"contentHashes,omitempty"`
+}
+
+// encodePending serializes a pending timestamp for storage
+func encodePending(p *PendingTimestamp) ([]byte, error) {
+	proof, err := p.Timestamp.Serialize()
+	if err != nil {
+		return nil, err
+	}
+
+	rec := pendingRecord{
+		Digest:      p.Digest[:],
+		Proof:       proof,
+		CreatedAt:   p.CreatedAt,
+		ConfirmedAt: p.ConfirmedAt,
+	}
+	if p.MerkleRoot != ([32]byte{}) {
+		rec.MerkleRoot = p.MerkleRoot[:]
+	}
+	for _, h := range p.ContentHashes {
+		h := h
+		rec.ContentHashes = append(rec.ContentHashes, h[:])
+	}
+	return json.Marshal(&rec)
+}
+
+// decodePending restores a pending timestamp from storage
+func decodePending(data []byte) (*PendingTimestamp, error) {
+	var rec pendingRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+	if len(rec.Digest) != 32 || (len(rec.MerkleRoot) != 0 && len(rec.MerkleRoot) != 32) {
+		return nil, fmt.Errorf("%w: bad pending record", ErrInvalidFormat)
+	}
+
+	ts, err := Parse(rec.Proof)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &PendingTimestamp{
+		Timestamp:   ts,
+		CreatedAt:   rec.CreatedAt,
+		ConfirmedAt: rec.ConfirmedAt,
+	}
+	copy(p.Digest[:], rec.Digest)
+	copy(p.MerkleRoot[:], rec.MerkleRoot)
+	for _, h := range rec.ContentHashes {
+		if len(h) != 32 {
+			return nil, fmt.Errorf("%w: bad content hash", ErrInvalidFormat)
+		}
+		var hash [32]byte
+		copy(hash[:], h)
+		p.ContentHashes = append(p.ContentHashes, hash)
+	}
+	return p, nil
+}
+
+// cloneTimestamp returns a deep copy of a timestamp
+func cloneTimestamp(ts *Timestamp) (*Timestamp, error) {
+	data, err := ts.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// persistPending writes a pending timestamp to the store, if one is configured.
+// Failures are logged: the in-memory entry stays authoritative. The caller
+// must not hold pendingMu.
+func (s *Service) persistPending(p *PendingTimestamp) {
+	if s.config.PendingStore == nil {
+		return
+	}
+	s.pendingMu.RLock()
+	data, err := encodePending(p)
+	s.pendingMu.RUnlock()
+	if err == nil {
+		err = s.config.PendingStore.SavePendingTimestamp(p.Digest, data)
+	}
+	if err != nil {
+		log.Warn("OTS: Failed to persist pending timestamp", "digest", DigestToHex(p.Digest[:]), "err", err)
+	}
+}
+
+// unpersistPending removes a pending timestamp from the store
+func (s *Service) unpersistPending(digest [32]byte) {
+	if s.config.PendingStore == nil {
+		return
+	}
+	if err := s.config.PendingStore.DeletePendingTimestamp(digest); err != nil {
+		log.Warn("OTS: Failed to delete pending timestamp", "digest", DigestToHex(digest[:]), "err", err)
+	}
+}
+
+// loadPending restores the pending set from the store. Corrupt records are
+// dropped so that they do not fail every start.
+func (s *Service) loadPending() error {
+	if s.config.PendingStore == nil {
+		return nil
+	}
+
+	var corrupt [][32]byte
+	loaded := make(map[string]*PendingTimestamp)
+	err := s.config.PendingStore.IteratePendingTimestamps(func(digest [32]byte, data []byte) error {
+		p, err := decodePending(data)
+		if err != nil || p.Digest != digest {
+			log.Warn("OTS: Dropping corrupt pending timestamp", "digest", DigestToHex(digest[:]), "err", err)
+			corrupt = append(corrupt, digest)
+			return nil
+		}
+		loaded[DigestToHex(digest[:])] = p
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load pending timestamps: %w", err)
+	}
+	for _, digest := range corrupt {
+		s.unpersistPending(digest)
+	}
+
+	s.pendingMu.Lock()
+	for key, p := range loaded {
+		s.pending[key] = p
+	}
+	s.pendingMu.Unlock()
+
+	if len(loaded) > 0 {
+		log.Info("OTS: Restored pending timestamps", "count", len(loaded))
+	}
+	return nil
+}
+
+// GarbageCollect removes Bitcoin-complete timestamps confirmed more than the
+// retention period ago and returns how many were removed
+func (s *Service) GarbageCollect() int {
+	retention := s.config.CompleteRetention
+	if retention <= 0 {
+		retention = DefaultCompleteRetention
+	}
+	cutoff := time.Now().Add(-retention)
+
+	var removed [][32]byte
+	s.pendingMu.Lock()
+	for key, p := range s.pending {
+		if p.ConfirmedAt.IsZero() || p.ConfirmedAt.After(cutoff) || !p.Timestamp.IsComplete() {
+			continue
+		}
+		delete(s.pending, key)
+		removed = append(removed, p.Digest)
+	}
+	s.pendingMu.Unlock()
+
+	for _, digest := range removed {
+		s.unpersistPending(digest)
+	}
+	if len(removed) > 0 {
+		log.Debug("OTS: Garbage-collected complete timestamps", "count", len(removed))
+	}
+	return len(removed)
+}
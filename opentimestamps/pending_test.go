@@ -0,0 +1,128 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package opentimestamps
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ots/storage"
+)
+
+func countStoredPending(t *testing.T, store PendingStore) int {
+	t.Helper()
+	n := 0
+	if err := store.IteratePendingTimestamps(func([32]byte, []byte) error {
+		n++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestService_PendingSurvivesRestart(t *testing.T) {
+	calendar := newTestCalendar(t, []byte{0x01})
+	defer calendar.Close()
+
+	store := storage.NewStoreWithDB(rawdb.NewMemoryDatabase())
+	config := ServiceConfig{
+		CalendarServers: []string{calendar.URL},
+		Timeout:         5 * time.Second,
+		PendingStore:    store,
+	}
+
+	service := NewService(config)
+	if err := service.Start(); err != nil {
+		t.Fatal(err)
+	}
+	digests := [][32]byte{{0x01}, {0x02}, {0x03}}
+	_, root, err := service.SubmitBatch(context.Background(), digests)
+	if err != nil {
+		t.Fatalf("SubmitBatch failed: %v", err)
+	}
+	proof, err := service.GetProof(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.Stop()
+
+	// A fresh service on the same store knows the batch
+	restarted := NewService(config)
+	if err := restarted.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer restarted.Stop()
+
+	if got := restarted.GetPendingDigests(); len(got) != 1 || got[0] != DigestToHex(root[:]) {
+		t.Fatalf("GetPendingDigests = %v, want [%x]", got, root)
+	}
+	restoredProof, err := restarted.GetProof(root)
+	if err != nil {
+		t.Fatalf("GetProof failed: %v", err)
+	}
+	if string(restoredProof) != string(proof) {
+		t.Error("restored proof differs from the submitted one")
+	}
+	ops, err := restarted.GetMerkleProof(root, digests[2])
+	if err != nil {
+		t.Fatalf("GetMerkleProof failed: %v", err)
+	}
+	if len(ops) == 0 {
+		t.Error("expected a merkle proof for a batch member")
+	}
+
+	restarted.RemovePending(root)
+	if n := countStoredPending(t, store); n != 0 {
+		t.Errorf("%d records left after RemovePending, want 0", n)
+	}
+}
+
+func TestService_GarbageCollectsCompleteTimestamps(t *testing.T) {
+	store := storage.NewStoreWithDB(rawdb.NewMemoryDatabase())
+
+	save := func(p *PendingTimestamp) {
+		data, err := encodePending(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SavePendingTimestamp(p.Digest, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	complete := func(digest [32]byte, confirmedAt time.Time) *PendingTimestamp {
+		ts := NewTimestamp(digest)
+		ts.Root.AddAttestation(Attestation{Type: AttestationBitcoin, BTCBlockHeight: 1})
+		return &PendingTimestamp{Digest: digest, Timestamp: ts, CreatedAt: confirmedAt, ConfirmedAt: confirmedAt}
+	}
+
+	pendingTs := NewTimestamp([32]byte{0x01})
+	pendingTs.Root.AddAttestation(Attestation{Type: AttestationPending, CalendarURL: "https://a.example"})
+	save(&PendingTimestamp{Digest: [32]byte{0x01}, Timestamp: pendingTs, CreatedAt: time.Now().Add(-72 * time.Hour)})
+	save(complete([32]byte{0x02}, time.Now().Add(-48*time.Hour)))
+	save(complete([32]byte{0x03}, time.Now().Add(-time.Hour)))
+	if err := store.SavePendingTimestamp([32]byte{0x04}, []byte("garbage")); err != nil {
+		t.Fatal(err)
+	}
+
+	service := NewService(ServiceConfig{PendingStore: store, CompleteRetention: 24 * time.Hour})
+	if err := service.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer service.Stop()
+
+	// The old complete and the corrupt record are gone, the rest is kept
+	if n := service.GetPendingCount(); n != 2 {
+		t.Errorf("GetPendingCount = %d, want 2", n)
+	}
+	if n := countStoredPending(t, store); n != 2 {
+		t.Errorf("%d stored records, want 2", n)
+	}
+	if _, err := service.GetProof([32]byte{0x02}); err == nil {
+		t.Error("expired complete timestamp should have been collected")
+	}
+}
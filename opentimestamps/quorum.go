@@ -0,0 +1,257 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+//
+// M-of-N quorum over several Bitcoin explorers.
+
+package opentimestamps
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	otsmetrics "github.com/ethereum/go-ethereum/ots/metrics"
+)
+
+var (
+	ErrInvalidQuorum        = errors.New("ots: quorum threshold must be between 1 and the number of explorers")
+	ErrQuorumNotReached     = errors.New("ots: not enough bitcoin explorers answered")
+	ErrExplorerDisagreement = errors.New("ots: bitcoin explorers disagree")
+)
+
+// NamedExplorer is a quorum member with a name used in logs and health reports
+type NamedExplorer struct {
+	Name     string
+	Explorer BitcoinExplorer
+}
+
+// QuorumExplorer queries several explorers and only answers when at least
+// threshold of them agree on the block hash and merkle root, so that no single
+// backend can forge a Bitcoin attestation.
+type QuorumExplorer struct {
+	backends  []*quorumBackend
+	threshold int
+
+	mu               sync.RWMutex
+	lastDisagreement time.Time
+}
+
+// quorumBackend tracks the health of a quorum member
+type quorumBackend struct {
+	NamedExplorer
+
+	healthy       bool
+	lastError     string
+	disagreements uint64
+}
+
+// QuorumStatus is a snapshot of the quorum health
+type QuorumStatus struct {
+	Threshold        int
+	Backends         []ExplorerStatus
+	LastDisagreement time.Time
+}
+
+// ExplorerStatus is the health of a single quorum member. A member is
+// unhealthy if its last answer failed or contradicted the quorum.
+type ExplorerStatus struct {
+	Name          string
+	Healthy       bool
+	LastError     string
+	Disagreements uint64
+}
+
+// NewQuorumExplorer creates an explorer requiring threshold of the given
+// explorers to agree
+func NewQuorumExplorer(threshold int, explorers ...NamedExplorer) (*QuorumExplorer, error) {
+	if threshold < 1 || threshold > len(explorers) {
+		return nil, fmt.Errorf("%w: %d of %d", ErrInvalidQuorum, threshold, len(explorers))
+	}
+
+	q := &QuorumExplorer{threshold: threshold}
+	for _, e := range explorers {
+		q.backends = append(q.backends, &quorumBackend{NamedExplorer: e, healthy: true})
+	}
+	return q, nil
+}
+
+// headerAnswer is one backend's answer to a header lookup
+type headerAnswer struct {
+	header *BlockHeader
+	err    error
+}
+
+// GetBlockHeader returns the block header agreed on by the quorum
+func (q *QuorumExplorer) GetBlockHeader(ctx context.Context, height uint64) (*BlockHeader, error) {
+	answers := make([]headerAnswer, len(q.backends))
+
+	var wg sync.WaitGroup
+	for i, b := range q.backends {
+		wg.Add(1)
+		go func(i int, b *quorumBackend) {
+			defer wg.Done()
+			header, err := b.Explorer.GetBlockHeader(ctx, height)
+			answers[i] = headerAnswer{header: header, err: err}
+		}(i, b)
+	}
+	wg.Wait()
+
+	// Group the answers by block hash and merkle root
+	groups := make(map[string][]int)
+	notFound := 0
+	for i, a := range answers {
+		if a.err != nil {
+			if errors.Is(a.err, ErrBlockNotFound) {
+				notFound++
+			}
+			continue
+		}
+		key := hex.EncodeToString(a.header.Hash) + hex.EncodeToString(a.header.MerkleRoot)
+		groups[key] = append(groups[key], i)
+	}
+
+	var agreed []int
+	quorums := 0
+	for _, members := range groups {
+		if len(members) >= q.threshold {
+			agreed = members
+			quorums++
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(groups) > 1 {
+		q.lastDisagreement = time.Now()
+	}
+	for i, a := range answers {
+		b := q.backends[i]
+		switch {
+		case a.err != nil:
+			b.healthy = false
+			b.lastError = a.err.Error()
+		case quorums == 1 && !containsIndex(agreed, i):
+			b.healthy = false
+			b.lastError = fmt.Sprintf("block %d: hash %s disagrees with quorum", height, displayHash(a.header.Hash))
+			b.disagreements++
+			otsmetrics.IncExplorerDisagreement()
+			log.Warn("OTS: Bitcoin explorer disagrees with quorum",
+				"explorer", b.Name,
+				"height", height,
+				"hash", displayHash(a.header.Hash),
+			)
+		case quorums != 1 && len(groups) > 1:
+			b.healthy = false
+			b.lastError = fmt.Sprintf("block %d: explorers disagree without quorum", height)
+		default:
+			b.healthy = true
+			b.lastError = ""
+		}
+	}
+
+	switch {
+	case quorums == 1:
+		cpy := *answers[agreed[0]].header
+		return &cpy, nil
+	case len(groups) > 1:
+		otsmetrics.IncExplorerDisagreement()
+		otsmetrics.IncExplorerQuorumFailure()
+		log.Warn("OTS: Bitcoin explorers disagree without quorum", "height", height, "answers", len(groups))
+		return nil, fmt.Errorf("%w: %d conflicting headers at height %d", ErrExplorerDisagreement, len(groups), height)
+	case notFound >= q.threshold:
+		return nil, ErrBlockNotFound
+	default:
+		otsmetrics.IncExplorerQuorumFailure()
+		return nil, fmt.Errorf("%w: block %d", ErrQuorumNotReached, height)
+	}
+}
+
+// GetBlockHash returns the block hash agreed on by the quorum
+func (q *QuorumExplorer) GetBlockHash(ctx context.Context, height uint64) ([]byte, error) {
+	header, err := q.GetBlockHeader(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	return header.Hash, nil
+}
+
+// VerifyMerkleRoot checks the commitment against the merkle root agreed on by
+// the quorum
+func (q *QuorumExplorer) VerifyMerkleRoot(ctx context.Context, height uint64, commitment []byte) (bool, error) {
+	header, err := q.GetBlockHeader(ctx, height)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(commitment, header.MerkleRoot), nil
+}
+
+// GetTipHeight returns the highest tip reached by at least threshold explorers.
+// Backends lag each other by a block or two, so differing tips are not
+// treated as disagreement.
+func (q *QuorumExplorer) GetTipHeight(ctx context.Context) (uint64, error) {
+	tips := make([]uint64, 0, len(q.backends))
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for _, b := range q.backends {
+		wg.Add(1)
+		go func(b *quorumBackend) {
+			defer wg.Done()
+			tip, err := b.Explorer.GetTipHeight(ctx)
+			if err != nil {
+				log.Debug("OTS: Bitcoin explorer tip query failed", "explorer", b.Name, "err", err)
+				return
+			}
+			mu.Lock()
+			tips = append(tips, tip)
+			mu.Unlock()
+		}(b)
+	}
+	wg.Wait()
+
+	if len(tips) < q.threshold {
+		otsmetrics.IncExplorerQuorumFailure()
+		return 0, fmt.Errorf("%w: %d of %d tips", ErrQuorumNotReached, len(tips), q.threshold)
+	}
+	sort.Slice(tips, func(i, j int) bool { return tips[i] > tips[j] })
+	return tips[q.threshold-1], nil
+}
+
+// Status returns a snapshot of the quorum health
+func (q *QuorumExplorer) Status() QuorumStatus {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	status := QuorumStatus{
+		Threshold:        q.threshold,
+		LastDisagreement: q.lastDisagreement,
+	}
+	for _, b := range q.backends {
+		status.Backends = append(status.Backends, ExplorerStatus{
+			Name:          b.Name,
+			Healthy:       b.healthy,
+			LastError:     b.lastError,
+			Disagreements: b.disagreements,
+		})
+	}
+	return status
+}
+
+// containsIndex reports whether idx is in list
+func containsIndex(list []int, idx int) bool {
+	for _, i := range list {
+		if i == idx {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package opentimestamps
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// forgingExplorer wraps an explorer and reports a forged merkle root
+type forgingExplorer struct {
+	BitcoinExplorer
+	merkleRoot []byte
+}
+
+func (f *forgingExplorer) GetBlockHeader(ctx context.Context, height uint64) (*BlockHeader, error) {
+	header, err := f.BitcoinExplorer.GetBlockHeader(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	header.MerkleRoot = f.merkleRoot
+	return header, nil
+}
+
+// downExplorer fails every request
+type downExplorer struct{}
+
+func (downExplorer) GetBlockHeader(context.Context, uint64) (*BlockHeader, error) {
+	return nil, ErrExplorerError
+}
+func (downExplorer) GetBlockHash(context.Context, uint64) ([]byte, error) {
+	return nil, ErrExplorerError
+}
+func (downExplorer) VerifyMerkleRoot(context.Context, uint64, []byte) (bool, error) {
+	return false, ErrExplorerError
+}
+func (downExplorer) GetTipHeight(context.Context) (uint64, error) { return 0, ErrExplorerError }
+
+func newTestLocalExplorer(t *testing.T) *LocalExplorer {
+	t.Helper()
+	e, err := NewLocalExplorerFromFile(filepath.Join("testdata", "headers.bin"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestQuorumExplorer_AgreementOutvotesForger(t *testing.T) {
+	honest := newTestLocalExplorer(t)
+	forged := make([]byte, 32)
+	forged[0] = 0x42
+
+	q, err := NewQuorumExplorer(2,
+		NamedExplorer{Name: "a", Explorer: honest},
+		NamedExplorer{Name: "b", Explorer: newTestLocalExplorer(t)},
+		NamedExplorer{Name: "evil", Explorer: &forgingExplorer{BitcoinExplorer: honest, merkleRoot: forged}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, _ := honest.GetBlockHeader(context.Background(), 2)
+	ok, err := q.VerifyMerkleRoot(context.Background(), 2, header.MerkleRoot)
+	if err != nil || !ok {
+		t.Fatalf("VerifyMerkleRoot = %v, %v; want true", ok, err)
+	}
+	ok, err = q.VerifyMerkleRoot(context.Background(), 2, forged)
+	if err != nil || ok {
+		t.Errorf("VerifyMerkleRoot for forged root = %v, %v; want false", ok, err)
+	}
+
+	status := q.Status()
+	for _, b := range status.Backends {
+		if b.Name == "evil" {
+			if b.Healthy || b.Disagreements != 2 {
+				t.Errorf("forger: Healthy = %v, Disagreements = %d; want false, 2", b.Healthy, b.Disagreements)
+			}
+		} else if !b.Healthy {
+			t.Errorf("%s should be healthy: %s", b.Name, b.LastError)
+		}
+	}
+	if status.LastDisagreement.IsZero() {
+		t.Error("LastDisagreement not recorded")
+	}
+}
+
+func TestQuorumExplorer_NoQuorum(t *testing.T) {
+	honest := newTestLocalExplorer(t)
+	forged := make([]byte, 32)
+
+	// One honest and one forged answer cannot reach 2-of-3
+	q, _ := NewQuorumExplorer(2,
+		NamedExplorer{Name: "honest", Explorer: honest},
+		NamedExplorer{Name: "evil", Explorer: &forgingExplorer{BitcoinExplorer: honest, merkleRoot: forged}},
+		NamedExplorer{Name: "down", Explorer: downExplorer{}},
+	)
+	if _, err := q.GetBlockHeader(context.Background(), 1); !errors.Is(err, ErrExplorerDisagreement) {
+		t.Errorf("expected ErrExplorerDisagreement, got %v", err)
+	}
+
+	// A single answer cannot reach 2-of-2 either
+	q, _ = NewQuorumExplorer(2,
+		NamedExplorer{Name: "honest", Explorer: honest},
+		NamedExplorer{Name: "down", Explorer: downExplorer{}},
+	)
+	if _, err := q.GetBlockHeader(context.Background(), 1); !errors.Is(err, ErrQuorumNotReached) {
+		t.Errorf("expected ErrQuorumNotReached, got %v", err)
+	}
+
+	// Heights unknown to the quorum are reported as missing
+	q, _ = NewQuorumExplorer(2,
+		NamedExplorer{Name: "a", Explorer: honest},
+		NamedExplorer{Name: "b", Explorer: newTestLocalExplorer(t)},
+	)
+	if _, err := q.GetBlockHeader(context.Background(), 3); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("expected ErrBlockNotFound, got %v", err)
+	}
+}
+
+func TestQuorumExplorer_TipHeight(t *testing.T) {
+	full := newTestLocalExplorer(t)
+	behind := NewLocalExplorer()
+	genesis, _ := full.GetBlockHeader(context.Background(), 0)
+	if err := behind.AddHeader(genesis); err != nil {
+		t.Fatal(err)
+	}
+
+	// The tip must be reached by two explorers
+	q, _ := NewQuorumExplorer(2,
+		NamedExplorer{Name: "full", Explorer: full},
+		NamedExplorer{Name: "behind", Explorer: behind},
+		NamedExplorer{Name: "down", Explorer: downExplorer{}},
+	)
+	tip, err := q.GetTipHeight(context.Background())
+	if err != nil || tip != 0 {
+		t.Errorf("GetTipHeight = %d, %v; want 0", tip, err)
+	}
+
+	q, _ = NewQuorumExplorer(3,
+		NamedExplorer{Name: "full", Explorer: full},
+		NamedExplorer{Name: "behind", Explorer: behind},
+		NamedExplorer{Name: "down", Explorer: downExplorer{}},
+	)
+	if _, err := q.GetTipHeight(context.Background()); !errors.Is(err, ErrQuorumNotReached) {
+		t.Errorf("expected ErrQuorumNotReached, got %v", err)
+	}
+}
+
+func TestQuorumExplorer_VerifierRequiresQuorum(t *testing.T) {
+	honest := newTestLocalExplorer(t)
+	header, _ := honest.GetBlockHeader(context.Background(), 2)
+
+	// Two colluding forgers outvote the honest explorer only if they agree on
+	// the forged root; the verifier must then reject the honest proof
+	forged := make([]byte, 32)
+	q, _ := NewQuorumExplorer(2,
+		NamedExplorer{Name: "honest", Explorer: honest},
+		NamedExplorer{Name: "evil1", Explorer: &forgingExplorer{BitcoinExplorer: honest, merkleRoot: forged}},
+		NamedExplorer{Name: "evil2", Explorer: &forgingExplorer{BitcoinExplorer: honest, merkleRoot: forged}},
+	)
+	var root [32]byte
+	copy(root[:], header.MerkleRoot)
+	ts := NewTimestamp(root)
+	ts.Root.AddAttestation(Attestation{Type: AttestationBitcoin, BTCBlockHeight: 2})
+
+	result, err := NewBitcoinVerifier(q, 0).VerifyAttestation(context.Background(), ts)
+	if err != nil {
+		t.Fatalf("VerifyAttestation failed: %v", err)
+	}
+	if result.Valid {
+		t.Error("proof should not verify against a forged quorum")
+	}
+
+	// With an honest majority the same proof is valid
+	q, _ = NewQuorumExplorer(2,
+		NamedExplorer{Name: "honest", Explorer: honest},
+		NamedExplorer{Name: "honest2", Explorer: newTestLocalExplorer(t)},
+		NamedExplorer{Name: "evil", Explorer: &forgingExplorer{BitcoinExplorer: honest, merkleRoot: forged}},
+	)
+	result, err = NewBitcoinVerifier(q, 1).VerifyAttestation(context.Background(), ts)
+	if err != nil {
+		t.Fatalf("VerifyAttestation failed: %v", err)
+	}
+	if !result.Valid {
+		t.Errorf("proof should verify with an honest quorum: %s", result.Message)
+	}
+}
+
+func TestNewQuorumExplorer_InvalidThreshold(t *testing.T) {
+	e := NamedExplorer{Name: "a", Explorer: downExplorer{}}
+	for _, threshold := range []int{0, 2} {
+		if _, err := NewQuorumExplorer(threshold, e); !errors.Is(err, ErrInvalidQuorum) {
+			t.Errorf("threshold %d: expected ErrInvalidQuorum, got %v", threshold, err)
+		}
+	}
+}
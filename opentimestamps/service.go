@@ -35,15 +35,47 @@ type ServiceConfig struct {
 
 	// UseTestnet uses Bitcoin testnet for verification
 	UseTestnet bool
+
+	// Explorer overrides the Blockstream explorer when set, e.g. with a
+	// LocalExplorer for offline verification
+	Explorer BitcoinExplorer
+
+	// MinCalendarResponses is how many calendars must accept a digest for a
+	// submission to succeed (default 1)
+	MinCalendarResponses int
+
+	// PendingStore persists pending timestamps across restarts when set
+	PendingStore PendingStore
+
+	// CompleteRetention is how long confirmed timestamps are kept before
+	// they are garbage-collected (default DefaultCompleteRetention)
+	CompleteRetention time.Duration
+
+	// AutoUpgrade runs a background loop that upgrades pending timestamps
+	AutoUpgrade bool
+
+	// UpgradeMinInterval and UpgradeMaxInterval bound the per-digest
+	// exponential backoff of the upgrade loop
+	UpgradeMinInterval time.Duration
+	UpgradeMaxInterval time.Duration
+
+	// OnConfirmed is called by the upgrade loop when a pending timestamp
+	// gets a verified Bitcoin attestation
+	OnConfirmed func(result *ConfirmationResult)
 }
 
 // DefaultServiceConfig returns default configuration
 func DefaultServiceConfig() ServiceConfig {
 	return ServiceConfig{
-		CalendarServers:  DefaultCalendarServers,
-		Timeout:          30 * time.Second,
-		BTCConfirmations: 6,
-		UseTestnet:       false,
+		CalendarServers:      DefaultCalendarServers,
+		Timeout:              30 * time.Second,
+		BTCConfirmations:     6,
+		UseTestnet:           false,
+		MinCalendarResponses: 1,
+		CompleteRetention:    DefaultCompleteRetention,
+		AutoUpgrade:          true,
+		UpgradeMinInterval:   DefaultUpgradeMinInterval,
+		UpgradeMaxInterval:   DefaultUpgradeMaxInterval,
 	}
 }
 
@@ -57,6 +89,10 @@ type Service struct {
 	pending   map[string]*PendingTimestamp
 	pendingMu sync.RWMutex
 
+	// Per-calendar results of the last submission
+	calendarResults   []CalendarResult
+	calendarResultsMu sync.RWMutex
+
 	// Running state
 	running bool
 	stopCh  chan struct{}
@@ -77,19 +113,29 @@ type PendingTimestamp struct {
 	// CreatedAt is when this was created
 	CreatedAt time.Time
 
+	// ConfirmedAt is when the Bitcoin attestation was verified, zero while pending
+	ConfirmedAt time.Time
+
 	// ContentHashes are the individual hashes in this batch
 	ContentHashes [][32]byte
+
+	// Upgrade loop backoff state
+	checks    int
+	nextCheck time.Time
 }
 
 // NewService creates a new OTS service
 func NewService(config ServiceConfig) *Service {
 	calendar := NewCalendarClient(config.CalendarServers, config.Timeout)
-
-	var explorer BitcoinExplorer
-	if config.UseTestnet {
-		explorer = NewBlockstreamTestnetExplorer(config.Timeout)
-	} else {
-		explorer = NewBlockstreamExplorer(config.Timeout)
+	calendar.SetMinResponses(config.MinCalendarResponses)
+
+	explorer := config.Explorer
+	if explorer == nil {
+		if config.UseTestnet {
+			explorer = NewBlockstreamTestnetExplorer(config.Timeout)
+		} else {
+			explorer = NewBlockstreamExplorer(config.Timeout)
+		}
 	}
 
 	verifier := NewBitcoinVerifier(explorer, config.BTCConfirmations)
@@ -109,10 +155,24 @@ func (s *Service) Start() error {
 		return nil
 	}
 
+	// Restore timestamps submitted before a restart
+	if err := s.loadPending(); err != nil {
+		return err
+	}
+	s.GarbageCollect()
+
 	s.running = true
+	s.stopCh = make(chan struct{})
+	if s.config.AutoUpgrade {
+		s.wg.Add(1)
+		go s.runUpgradeLoop(s.stopCh)
+	}
+
 	log.Info("OTS: Service started",
 		"calendars", len(s.config.CalendarServers),
 		"timeout", s.config.Timeout,
+		"pending", s.GetPendingCount(),
+		"autoUpgrade", s.config.AutoUpgrade,
 	)
 
 	return nil
@@ -138,19 +198,22 @@ func (s *Service) SubmitDigest(ctx context.Context, digest [32]byte) (*Timestamp
 		return nil, ErrServiceNotStarted
 	}
 
-	ts, err := s.calendar.Submit(ctx, digest)
+	ts, results, err := s.calendar.Submit(ctx, digest)
+	s.setCalendarResults(results)
 	if err != nil {
 		return nil, fmt.Errorf("failed to submit to calendar: %w", err)
 	}
 
 	// Store as pending
-	s.pendingMu.Lock()
-	s.pending[DigestToHex(digest[:])] = &PendingTimestamp{
+	pending := &PendingTimestamp{
 		Digest:    digest,
 		Timestamp: ts,
 		CreatedAt: time.Now(),
 	}
+	s.pendingMu.Lock()
+	s.pending[DigestToHex(digest[:])] = pending
 	s.pendingMu.Unlock()
+	s.persistPending(pending)
 
 	return ts, nil
 }
@@ -174,25 +237,45 @@ func (s *Service) SubmitBatch(ctx context.Context, digests [][32]byte) (*Timesta
 	)
 
 	// Submit the Merkle root
-	ts, err := s.calendar.Submit(ctx, merkleRoot)
+	ts, results, err := s.calendar.Submit(ctx, merkleRoot)
+	s.setCalendarResults(results)
 	if err != nil {
 		return nil, [32]byte{}, fmt.Errorf("failed to submit batch: %w", err)
 	}
 
 	// Store as pending with all content hashes
-	s.pendingMu.Lock()
-	s.pending[DigestToHex(merkleRoot[:])] = &PendingTimestamp{
+	pending := &PendingTimestamp{
 		Digest:        merkleRoot,
 		MerkleRoot:    merkleRoot,
 		Timestamp:     ts,
 		CreatedAt:     time.Now(),
 		ContentHashes: digests,
 	}
+	s.pendingMu.Lock()
+	s.pending[DigestToHex(merkleRoot[:])] = pending
 	s.pendingMu.Unlock()
+	s.persistPending(pending)
 
 	return ts, merkleRoot, nil
 }
 
+// setCalendarResults records the per-calendar results of a submission
+func (s *Service) setCalendarResults(results []CalendarResult) {
+	s.calendarResultsMu.Lock()
+	s.calendarResults = results
+	s.calendarResultsMu.Unlock()
+}
+
+// CalendarResults returns the per-calendar results of the last submission
+func (s *Service) CalendarResults() []CalendarResult {
+	s.calendarResultsMu.RLock()
+	defer s.calendarResultsMu.RUnlock()
+
+	results := make([]CalendarResult, len(s.calendarResults))
+	copy(results, s.calendarResults)
+	return results
+}
+
 // CheckConfirmation checks if a pending timestamp has been confirmed
 func (s *Service) CheckConfirmation(ctx context.Context, digest [32]byte) (*ConfirmationResult, error) {
 	if !s.running {
@@ -203,23 +286,34 @@ func (s *Service) CheckConfirmation(ctx context.Context, digest [32]byte) (*Conf
 
 	s.pendingMu.RLock()
 	pending, exists := s.pending[digestHex]
+	var current *Timestamp
+	var err error
+	if exists {
+		// Upgrade a copy, the stored tree is only replaced under the lock
+		current, err = cloneTimestamp(pending.Timestamp)
+	}
 	s.pendingMu.RUnlock()
 
+	if err != nil {
+		return nil, err
+	}
 	if !exists {
 		return &ConfirmationResult{
+			Digest:    digest,
 			Found:     false,
 			Confirmed: false,
 		}, nil
 	}
 
 	// Try to upgrade the timestamp
-	upgradedTs, err := s.calendar.UpgradeTimestamp(ctx, pending.Timestamp)
+	upgradedTs, err := s.calendar.UpgradeTimestamp(ctx, current)
 	if err == ErrNotConfirmed {
 		return &ConfirmationResult{
+			Digest:    digest,
 			Found:     true,
 			Confirmed: false,
 			Pending:   true,
-			Timestamp: pending.Timestamp,
+			Timestamp: current,
 		}, nil
 	}
 	if err != nil {
@@ -236,9 +330,14 @@ func (s *Service) CheckConfirmation(ctx context.Context, digest [32]byte) (*Conf
 		// Update stored timestamp
 		s.pendingMu.Lock()
 		pending.Timestamp = upgradedTs
+		if pending.ConfirmedAt.IsZero() {
+			pending.ConfirmedAt = time.Now()
+		}
 		s.pendingMu.Unlock()
+		s.persistPending(pending)
 
 		return &ConfirmationResult{
+			Digest:         digest,
 			Found:          true,
 			Confirmed:      true,
 			Pending:        false,
@@ -249,11 +348,20 @@ func (s *Service) CheckConfirmation(ctx context.Context, digest [32]byte) (*Conf
 		}, nil
 	}
 
+	// Keep the upgraded proof while the block gains depth
+	if verifyResult.InsufficientDepth {
+		s.pendingMu.Lock()
+		pending.Timestamp = upgradedTs
+		s.pendingMu.Unlock()
+		s.persistPending(pending)
+	}
+
 	return &ConfirmationResult{
+		Digest:    digest,
 		Found:     true,
 		Confirmed: false,
 		Pending:   true,
-		Timestamp: pending.Timestamp,
+		Timestamp: upgradedTs,
 	}, nil
 }
 
@@ -343,10 +451,14 @@ func (s *Service) RemovePending(digest [32]byte) {
 	s.pendingMu.Lock()
 	delete(s.pending, digestHex)
 	s.pendingMu.Unlock()
+	s.unpersistPending(digest)
 }
 
 // ConfirmationResult holds the result of a confirmation check
 type ConfirmationResult struct {
+	// Digest is the digest that was checked
+	Digest [32]byte
+
 	// Found indicates if the digest was found
 	Found bool
 
@@ -6,11 +6,17 @@
 package opentimestamps
 
 import (
+	"bytes"
+	"crypto/sha1"
 	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/ripemd160"
 )
 
 // OTS File Format Magic Header
@@ -59,7 +65,8 @@ var (
 	ErrInvalidOperation  = errors.New("ots: invalid operation")
 )
 
-// Timestamp represents an OpenTimestamps timestamp
+// Timestamp represents an OpenTimestamps detached timestamp: the file digest
+// together with the commitment tree proving when that digest existed
 type Timestamp struct {
 	// Version of the OTS file format (usually 1)
 	Version byte
@@ -70,11 +77,30 @@ type Timestamp struct {
 	// Digest is the original hash being timestamped
 	Digest []byte
 
-	// Operations are the timestamp operations to transform the digest
-	Operations []Operation
+	// Root is the commitment tree rooted at Digest
+	Root *Node
+}
 
-	// Attestations are the final attestations (Bitcoin or pending)
+// Node is a node of the commitment tree. The root commits to the file digest;
+// every other node commits to the result of applying its branch operation to
+// the message of its parent. A node may fork into several branches (one per
+// calendar server, or a Bitcoin branch next to pending ones) and may carry
+// attestations of its own.
+type Node struct {
+	// Attestations attest to the message committed by this node
 	Attestations []Attestation
+
+	// Branches lead to the child nodes derived from this node's message
+	Branches []*Branch
+}
+
+// Branch is an operation edge from a node to the child it produces
+type Branch struct {
+	// Op is applied to the parent's message
+	Op Operation
+
+	// Node is the child committing to the operation result
+	Node *Node
 }
 
 // Operation represents a timestamp operation
@@ -96,39 +122,172 @@ type Attestation struct {
 
 	// For Pending attestation - calendar URL
 	CalendarURL string
+
+	// For unknown attestations - raw tag and payload, kept for round-tripping
+	UnknownTag     []byte
+	UnknownPayload []byte
 }
 
 // NewTimestamp creates a new timestamp for a SHA256 digest
 func NewTimestamp(digest [32]byte) *Timestamp {
 	return &Timestamp{
-		Version:      1,
-		HashType:     HashSHA256,
-		Digest:       digest[:],
-		Operations:   make([]Operation, 0),
-		Attestations: make([]Attestation, 0),
+		Version:  1,
+		HashType: HashSHA256,
+		Digest:   digest[:],
+		Root:     &Node{},
+	}
+}
+
+// Add returns the child reached by op, creating it if the node has no such
+// branch yet. Branches are kept in canonical order (by tag, then argument).
+func (n *Node) Add(op Operation) *Node {
+	for i, b := range n.Branches {
+		switch c := compareOperations(op, b.Op); {
+		case c == 0:
+			return b.Node
+		case c < 0:
+			child := &Node{}
+			n.Branches = append(n.Branches[:i], append([]*Branch{{Op: op, Node: child}}, n.Branches[i:]...)...)
+			return child
+		}
 	}
+	child := &Node{}
+	n.Branches = append(n.Branches, &Branch{Op: op, Node: child})
+	return child
 }
 
-// AddOperation adds an operation to the timestamp
-func (t *Timestamp) AddOperation(op Operation) {
-	t.Operations = append(t.Operations, op)
+// AddAttestation adds an attestation to the node, ignoring duplicates.
+// Attestations are kept in canonical order (by tag, then payload).
+func (n *Node) AddAttestation(att Attestation) {
+	for i, existing := range n.Attestations {
+		switch c := compareAttestations(att, existing); {
+		case c == 0:
+			return
+		case c < 0:
+			n.Attestations = append(n.Attestations[:i], append([]Attestation{att}, n.Attestations[i:]...)...)
+			return
+		}
+	}
+	n.Attestations = append(n.Attestations, att)
+}
+
+// Merge merges all attestations and branches of other into the node. Both
+// nodes must commit to the same message.
+func (n *Node) Merge(other *Node) {
+	if other == nil {
+		return
+	}
+	for _, att := range other.Attestations {
+		n.AddAttestation(att)
+	}
+	for _, b := range other.Branches {
+		n.Add(b.Op).Merge(b.Node)
+	}
+}
+
+// IsEmpty returns true if the node has neither attestations nor branches
+func (n *Node) IsEmpty() bool {
+	return len(n.Attestations) == 0 && len(n.Branches) == 0
+}
+
+// each calls fn for every node of the tree in serialization order
+func (n *Node) each(fn func(node *Node)) {
+	fn(n)
+	for _, b := range n.Branches {
+		b.Node.each(fn)
+	}
 }
 
-// AddAttestation adds an attestation to the timestamp
-func (t *Timestamp) AddAttestation(att Attestation) {
-	t.Attestations = append(t.Attestations, att)
+// walk calls fn for every node of the tree together with the message the
+// node commits to, computing messages from msg as it descends
+func (n *Node) walk(msg []byte, fn func(msg []byte, node *Node) error) error {
+	return n.walkDepth(msg, 0, fn)
 }
 
-// GetFinalDigest computes the final digest after applying all operations
+func (n *Node) walkDepth(msg []byte, depth int, fn func(msg []byte, node *Node) error) error {
+	if err := fn(msg, n); err != nil {
+		return err
+	}
+	for _, b := range n.Branches {
+		next, err := ApplyOperation(msg, b.Op)
+		if err != nil {
+			return fmt.Errorf("operation at depth %d: %w", depth, err)
+		}
+		if err := b.Node.walkDepth(next, depth+1, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pathTo returns the operations leading from n to target, or false if target
+// is not part of the tree
+func (n *Node) pathTo(target *Node) ([]Operation, bool) {
+	if n == target {
+		return nil, true
+	}
+	for _, b := range n.Branches {
+		if ops, ok := b.Node.pathTo(target); ok {
+			return append([]Operation{b.Op}, ops...), true
+		}
+	}
+	return nil, false
+}
+
+// bitcoinAttestation returns the Bitcoin attestation with the lowest block
+// height and the node carrying it, or nil if the tree has none
+func (t *Timestamp) bitcoinAttestation() (*Node, *Attestation) {
+	var (
+		bestNode *Node
+		best     *Attestation
+	)
+	if t.Root == nil {
+		return nil, nil
+	}
+	t.Root.each(func(node *Node) {
+		for i := range node.Attestations {
+			att := &node.Attestations[i]
+			if att.Type != AttestationBitcoin {
+				continue
+			}
+			if best == nil || att.BTCBlockHeight < best.BTCBlockHeight {
+				bestNode, best = node, att
+			}
+		}
+	})
+	return bestNode, best
+}
+
+// GetFinalDigest computes the commitment the timestamp attests to: the message
+// of the node carrying the Bitcoin attestation, or of the first attested node
+// if the timestamp is still pending
 func (t *Timestamp) GetFinalDigest() ([]byte, error) {
+	if t.Root == nil {
+		return nil, ErrInvalidFormat
+	}
+
+	target, _ := t.bitcoinAttestation()
+	if target == nil {
+		t.Root.each(func(node *Node) {
+			if target == nil && len(node.Attestations) > 0 {
+				target = node
+			}
+		})
+	}
+	if target == nil {
+		return nil, fmt.Errorf("%w: timestamp has no attestations", ErrInvalidFormat)
+	}
+
+	ops, _ := t.Root.pathTo(target)
+
 	current := make([]byte, len(t.Digest))
 	copy(current, t.Digest)
 
-	for _, op := range t.Operations {
+	for i, op := range ops {
 		var err error
 		current, err = ApplyOperation(current, op)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("operation %d of %d: %w", i, len(ops), err)
 		}
 	}
 
@@ -137,68 +296,127 @@ func (t *Timestamp) GetFinalDigest() ([]byte, error) {
 
 // IsComplete returns true if the timestamp has a Bitcoin attestation
 func (t *Timestamp) IsComplete() bool {
-	for _, att := range t.Attestations {
-		if att.Type == AttestationBitcoin {
-			return true
-		}
-	}
-	return false
+	_, att := t.bitcoinAttestation()
+	return att != nil
 }
 
-// GetBitcoinAttestation returns the Bitcoin attestation if present
+// GetBitcoinAttestation returns the Bitcoin attestation if present. When
+// several branches reach Bitcoin, the one with the lowest block height wins.
 func (t *Timestamp) GetBitcoinAttestation() *Attestation {
-	for i := range t.Attestations {
-		if t.Attestations[i].Type == AttestationBitcoin {
-			return &t.Attestations[i]
-		}
-	}
-	return nil
+	_, att := t.bitcoinAttestation()
+	return att
 }
 
 // GetPendingAttestations returns all pending attestations
 func (t *Timestamp) GetPendingAttestations() []Attestation {
 	var pending []Attestation
-	for _, att := range t.Attestations {
-		if att.Type == AttestationPending {
-			pending = append(pending, att)
-		}
+	if t.Root == nil {
+		return pending
 	}
+	t.Root.each(func(node *Node) {
+		for _, att := range node.Attestations {
+			if att.Type == AttestationPending {
+				pending = append(pending, att)
+			}
+		}
+	})
 	return pending
 }
 
+// compareOperations orders operations by tag, then by argument
+func compareOperations(a, b Operation) int {
+	if a.Tag != b.Tag {
+		if a.Tag < b.Tag {
+			return -1
+		}
+		return 1
+	}
+	return bytes.Compare(a.Argument, b.Argument)
+}
+
+// compareAttestations orders attestations by tag, then by payload
+func compareAttestations(a, b Attestation) int {
+	if c := bytes.Compare(attestationTag(a), attestationTag(b)); c != 0 {
+		return c
+	}
+	switch a.Type {
+	case AttestationBitcoin:
+		switch {
+		case a.BTCBlockHeight < b.BTCBlockHeight:
+			return -1
+		case a.BTCBlockHeight > b.BTCBlockHeight:
+			return 1
+		}
+		return 0
+	case AttestationPending:
+		return strings.Compare(a.CalendarURL, b.CalendarURL)
+	default:
+		return bytes.Compare(a.UnknownPayload, b.UnknownPayload)
+	}
+}
+
+// attestationTag returns the 8-byte tag identifying the attestation type
+func attestationTag(att Attestation) []byte {
+	switch att.Type {
+	case AttestationBitcoin:
+		return BitcoinAttestationMagic
+	case AttestationPending:
+		return PendingAttestationMagic
+	default:
+		return att.UnknownTag
+	}
+}
+
 // ApplyOperation applies an operation to a digest
 func ApplyOperation(digest []byte, op Operation) ([]byte, error) {
+	var result []byte
+
 	switch op.Tag {
 	case OpAppend:
-		result := append(digest, op.Argument...)
-		return result, nil
+		result = make([]byte, 0, len(digest)+len(op.Argument))
+		result = append(append(result, digest...), op.Argument...)
 
 	case OpPrepend:
-		result := append(op.Argument, digest...)
-		return result, nil
+		result = make([]byte, 0, len(digest)+len(op.Argument))
+		result = append(append(result, op.Argument...), digest...)
 
 	case OpReverse:
-		result := make([]byte, len(digest))
+		result = make([]byte, len(digest))
 		for i, b := range digest {
 			result[len(digest)-1-i] = b
 		}
-		return result, nil
+
+	case OpHexlify:
+		// Result must stay within the message limit, so the input is halved
+		if len(digest) > maxMessageLength/2 {
+			return nil, fmt.Errorf("%w: hexlify input of %d bytes too long", ErrInvalidOperation, len(digest))
+		}
+		result = []byte(hex.EncodeToString(digest))
+
+	case OpSHA1:
+		hash := sha1.Sum(digest)
+		result = hash[:]
 
 	case OpSHA256:
 		hash := sha256.Sum256(digest)
-		return hash[:], nil
+		result = hash[:]
 
 	case OpRIPEMD160:
-		// Note: Would need golang.org/x/crypto/ripemd160
-		return nil, fmt.Errorf("RIPEMD160 not implemented")
+		hasher := ripemd160.New()
+		hasher.Write(digest)
+		result = hasher.Sum(nil)
 
-	case OpSHA1:
-		// Note: Would need crypto/sha1
-		return nil, fmt.Errorf("SHA1 not implemented")
+	case OpKECCAK256:
+		result = crypto.Keccak256(digest)
 
 	default:
-		return nil, fmt.Errorf("%w: 0x%02x", ErrInvalidOperation, op.Tag)
+		return nil, fmt.Errorf("%w: unknown tag 0x%02x", ErrInvalidOperation, op.Tag)
 	}
+
+	if len(result) > maxMessageLength {
+		return nil, fmt.Errorf("%w: result of 0x%02x exceeds %d bytes", ErrInvalidOperation, op.Tag, maxMessageLength)
+	}
+	return result, nil
 }
 
 // DigestToHex converts a digest to hex string
@@ -262,3 +480,56 @@ func WriteVarInt(n uint64) []byte {
 		return buf
 	}
 }
+
+// ReadVarUint reads an unsigned LEB128 integer, the varuint encoding used by
+// the OTS serialization format
+func ReadVarUint(data []byte, offset int) (uint64, int, error) {
+	var (
+		value uint64
+		shift uint
+	)
+	for i := offset; i < len(data); i++ {
+		b := data[i]
+		if shift >= 64 || (shift == 63 && b > 1) {
+			return 0, 0, fmt.Errorf("%w: varuint overflow", ErrInvalidFormat)
+		}
+		value |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return value, i - offset + 1, nil
+		}
+		shift += 7
+	}
+	return 0, 0, ErrInvalidFormat
+}
+
+// WriteVarUint writes an unsigned LEB128 integer
+func WriteVarUint(n uint64) []byte {
+	var buf []byte
+	for n >= 0x80 {
+		buf = append(buf, byte(n)|0x80)
+		n >>= 7
+	}
+	return append(buf, byte(n))
+}
+
+// readVarBytes reads a varuint length-prefixed byte string of at most maxLen bytes
+func readVarBytes(data []byte, offset int, maxLen int) ([]byte, int, error) {
+	length, n, err := ReadVarUint(data, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	if length > uint64(maxLen) {
+		return nil, 0, fmt.Errorf("%w: length %d exceeds limit %d", ErrInvalidFormat, length, maxLen)
+	}
+	if offset+n+int(length) > len(data) {
+		return nil, 0, ErrInvalidFormat
+	}
+	out := make([]byte, length)
+	copy(out, data[offset+n:offset+n+int(length)])
+	return out, n + int(length), nil
+}
+
+// writeVarBytes writes a varuint length-prefixed byte string
+func writeVarBytes(b []byte) []byte {
+	return append(WriteVarUint(uint64(len(b))), b...)
+}
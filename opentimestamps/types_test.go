@@ -0,0 +1,134 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package opentimestamps
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestApplyOperation(t *testing.T) {
+	// Vectors cross-checked against the reference implementation's op tests
+	tests := []struct {
+		name string
+		op   Operation
+		msg  string
+		want string
+	}{
+		{"append", Operation{Tag: OpAppend, Argument: []byte("suffix")}, "msg", hex.EncodeToString([]byte("msgsuffix"))},
+		{"prepend", Operation{Tag: OpPrepend, Argument: []byte("prefix")}, "msg", hex.EncodeToString([]byte("prefixmsg"))},
+		{"reverse", Operation{Tag: OpReverse}, "abc", hex.EncodeToString([]byte("cba"))},
+		{"hexlify", Operation{Tag: OpHexlify}, "\xde\xad\xbe\xef", hex.EncodeToString([]byte("deadbeef"))},
+		{"sha1 empty", Operation{Tag: OpSHA1}, "", "da39a3ee5e6b4b0d3255bfef95601890afd80709"},
+		{"sha1 abc", Operation{Tag: OpSHA1}, "abc", "a9993e364706816aba3e25717850c26c9cd0d89d"},
+		{"sha256 empty", Operation{Tag: OpSHA256}, "", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"ripemd160 empty", Operation{Tag: OpRIPEMD160}, "", "9c1185a5c5e9fc54612808977ee8f548b2258d31"},
+		{"ripemd160 abc", Operation{Tag: OpRIPEMD160}, "abc", "8eb208f7e05d987a9b044a8e98c6b087f15a0bfc"},
+		{"keccak256 empty", Operation{Tag: OpKECCAK256}, "", "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{"keccak256 abc", Operation{Tag: OpKECCAK256}, "abc", "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyOperation([]byte(tt.msg), tt.op)
+			if err != nil {
+				t.Fatalf("ApplyOperation failed: %v", err)
+			}
+			if hex.EncodeToString(got) != tt.want {
+				t.Errorf("ApplyOperation = %x, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyOperation_DoesNotAlias(t *testing.T) {
+	msg := make([]byte, 3, 16)
+	copy(msg, "msg")
+	arg := make([]byte, 6, 16)
+	copy(arg, "prefix")
+
+	if _, err := ApplyOperation(msg, Operation{Tag: OpAppend, Argument: []byte("xyz")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ApplyOperation([]byte("abc"), Operation{Tag: OpPrepend, Argument: arg}); err != nil {
+		t.Fatal(err)
+	}
+	if string(msg[:cap(msg)][3:6]) == "xyz" || string(arg[:cap(arg)][6:9]) == "abc" {
+		t.Error("operation wrote into its input's backing array")
+	}
+}
+
+func TestApplyOperation_Errors(t *testing.T) {
+	if _, err := ApplyOperation([]byte("msg"), Operation{Tag: 0x42}); !errors.Is(err, ErrInvalidOperation) {
+		t.Errorf("expected ErrInvalidOperation for unknown tag, got %v", err)
+	}
+
+	long := make([]byte, maxMessageLength/2+1)
+	if _, err := ApplyOperation(long, Operation{Tag: OpHexlify}); !errors.Is(err, ErrInvalidOperation) {
+		t.Errorf("expected ErrInvalidOperation for oversized hexlify, got %v", err)
+	}
+
+	full := make([]byte, maxMessageLength)
+	if _, err := ApplyOperation(full, Operation{Tag: OpAppend, Argument: []byte{0x00}}); !errors.Is(err, ErrInvalidOperation) {
+		t.Errorf("expected ErrInvalidOperation for oversized append, got %v", err)
+	}
+}
+
+func TestGetFinalDigest_RIPEMD160Path(t *testing.T) {
+	// Older calendar proofs route through RIPEMD160 before the Bitcoin branch
+	ts := NewTimestamp([32]byte{0x01})
+	ts.Root.
+		Add(Operation{Tag: OpRIPEMD160}).
+		Add(Operation{Tag: OpPrepend, Argument: []byte{0xaa}}).
+		Add(Operation{Tag: OpSHA256}).
+		AddAttestation(Attestation{Type: AttestationBitcoin, BTCBlockHeight: 400000})
+
+	got, err := ts.GetFinalDigest()
+	if err != nil {
+		t.Fatalf("GetFinalDigest failed: %v", err)
+	}
+
+	msg, _ := ApplyOperation(ts.Digest, Operation{Tag: OpRIPEMD160})
+	msg, _ = ApplyOperation(msg, Operation{Tag: OpPrepend, Argument: []byte{0xaa}})
+	msg, _ = ApplyOperation(msg, Operation{Tag: OpSHA256})
+	if !bytes.Equal(got, msg) {
+		t.Errorf("GetFinalDigest = %x, want %x", got, msg)
+	}
+}
+
+func TestGetFinalDigest_ReportsFailingOperation(t *testing.T) {
+	ts := NewTimestamp([32]byte{0x01})
+	ts.Root.
+		Add(Operation{Tag: OpSHA256}).
+		Add(Operation{Tag: 0x42}).
+		AddAttestation(Attestation{Type: AttestationBitcoin, BTCBlockHeight: 1})
+
+	_, err := ts.GetFinalDigest()
+	if !errors.Is(err, ErrInvalidOperation) {
+		t.Fatalf("expected ErrInvalidOperation, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "operation 1 of 2") {
+		t.Errorf("error should name the failing operation: %v", err)
+	}
+}
+
+func TestParse_UnknownTagReportsOffset(t *testing.T) {
+	data := append([]byte{}, MagicHeader...)
+	data = append(data, 0x01, HashSHA256)
+	data = append(data, make([]byte, 32)...)
+	data = append(data, OpSHA256, 0x42)
+	badOffset := len(data) - 1
+
+	_, err := Parse(data)
+	if !errors.Is(err, ErrInvalidOperation) {
+		t.Fatalf("expected ErrInvalidOperation, got %v", err)
+	}
+	if !strings.Contains(err.Error(), fmt.Sprintf("offset %d", badOffset)) {
+		t.Errorf("error should report the tag offset: %v", err)
+	}
+}
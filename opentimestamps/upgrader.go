@@ -0,0 +1,132 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+//
+// Background upgrade loop for pending timestamps.
+
+package opentimestamps
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Default upgrade backoff. Calendars aggregate into a Bitcoin transaction
+// within minutes to hours, so polling starts fast and slows down quickly.
+const (
+	DefaultUpgradeMinInterval = time.Minute
+	DefaultUpgradeMaxInterval = time.Hour
+)
+
+// upgradeBackoff returns the delay before the next upgrade check of a
+// timestamp that has already been checked the given number of times
+func upgradeBackoff(checks int, minDelay, maxDelay time.Duration) time.Duration {
+	delay := minDelay
+	for i := 0; i < checks && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// upgradeIntervals returns the configured backoff bounds
+func (s *Service) upgradeIntervals() (time.Duration, time.Duration) {
+	minDelay, maxDelay := s.config.UpgradeMinInterval, s.config.UpgradeMaxInterval
+	if minDelay <= 0 {
+		minDelay = DefaultUpgradeMinInterval
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultUpgradeMaxInterval
+	}
+	if maxDelay < minDelay {
+		maxDelay = minDelay
+	}
+	return minDelay, maxDelay
+}
+
+// runUpgradeLoop periodically upgrades due pending timestamps until Stop
+func (s *Service) runUpgradeLoop(stopCh <-chan struct{}) {
+	defer s.wg.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	minDelay, _ := s.upgradeIntervals()
+	tick := minDelay / 2
+	if tick > 10*time.Second {
+		tick = 10 * time.Second
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	log.Debug("OTS: Upgrade loop started", "minInterval", minDelay)
+	for {
+		select {
+		case <-stopCh:
+			log.Debug("OTS: Upgrade loop stopped")
+			return
+		case <-ticker.C:
+			s.upgradeDue(ctx)
+			s.GarbageCollect()
+		}
+	}
+}
+
+// upgradeDue checks every pending timestamp whose backoff has expired
+func (s *Service) upgradeDue(ctx context.Context) {
+	now := time.Now()
+	minDelay, maxDelay := s.upgradeIntervals()
+
+	// Fresh submissions are first checked one minimum interval after creation
+	var due [][32]byte
+	s.pendingMu.RLock()
+	for _, p := range s.pending {
+		next := p.nextCheck
+		if next.IsZero() {
+			next = p.CreatedAt.Add(minDelay)
+		}
+		if p.ConfirmedAt.IsZero() && !next.After(now) {
+			due = append(due, p.Digest)
+		}
+	}
+	s.pendingMu.RUnlock()
+
+	for _, digest := range due {
+		if ctx.Err() != nil {
+			return
+		}
+
+		result, err := s.CheckConfirmation(ctx, digest)
+		if err != nil {
+			log.Debug("OTS: Upgrade check failed", "digest", DigestToHex(digest[:]), "err", err)
+		}
+		if err == nil && result.Confirmed {
+			log.Info("OTS: Timestamp confirmed on Bitcoin",
+				"digest", DigestToHex(digest[:]),
+				"btcBlock", result.BTCBlockHeight,
+			)
+			if s.config.OnConfirmed != nil {
+				s.config.OnConfirmed(result)
+			}
+			continue
+		}
+
+		// Not confirmed yet, back off
+		s.pendingMu.Lock()
+		if p, ok := s.pending[DigestToHex(digest[:])]; ok {
+			p.nextCheck = time.Now().Add(upgradeBackoff(p.checks, minDelay, maxDelay))
+			p.checks++
+		}
+		s.pendingMu.Unlock()
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package opentimestamps
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUpgradeBackoff(t *testing.T) {
+	tests := []struct {
+		checks int
+		want   time.Duration
+	}{
+		{0, time.Minute},
+		{1, 2 * time.Minute},
+		{3, 8 * time.Minute},
+		{6, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := upgradeBackoff(tt.checks, time.Minute, time.Hour); got != tt.want {
+			t.Errorf("upgradeBackoff(%d) = %v, want %v", tt.checks, got, tt.want)
+		}
+	}
+}
+
+// acceptingExplorer accepts any commitment at a single block height
+type acceptingExplorer struct {
+	downExplorer
+	height uint64
+}
+
+func (e acceptingExplorer) GetBlockHeader(_ context.Context, height uint64) (*BlockHeader, error) {
+	if height != e.height {
+		return nil, ErrBlockNotFound
+	}
+	return &BlockHeader{Height: height, Hash: make([]byte, 32), Timestamp: 1700000000}, nil
+}
+
+func (e acceptingExplorer) VerifyMerkleRoot(_ context.Context, height uint64, _ []byte) (bool, error) {
+	return height == e.height, nil
+}
+
+// newUpgradingCalendar serves pending timestamps and, once confirmed is set,
+// Bitcoin-attested upgrades for them
+func newUpgradingCalendar(t *testing.T, confirmed *atomic.Bool, lookups *atomic.Int32) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var node *Node
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/digest":
+			node = &Node{}
+			node.Add(Operation{Tag: OpAppend, Argument: []byte{0x01}}).
+				Add(Operation{Tag: OpSHA256}).
+				AddAttestation(Attestation{Type: AttestationPending, CalendarURL: server.URL})
+		case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/timestamp/"):
+			lookups.Add(1)
+			if !confirmed.Load() {
+				http.NotFound(w, r)
+				return
+			}
+			node = &Node{}
+			node.AddAttestation(Attestation{Type: AttestationBitcoin, BTCBlockHeight: 800000})
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		data, err := node.Serialize()
+		if err != nil {
+			t.Errorf("serialize: %v", err)
+		}
+		w.Write(data)
+	}))
+	return server
+}
+
+func TestService_UpgradeLoopConfirms(t *testing.T) {
+	var confirmed atomic.Bool
+	var lookups atomic.Int32
+	calendar := newUpgradingCalendar(t, &confirmed, &lookups)
+	defer calendar.Close()
+
+	results := make(chan *ConfirmationResult, 1)
+	service := NewService(ServiceConfig{
+		CalendarServers:    []string{calendar.URL},
+		Timeout:            5 * time.Second,
+		Explorer:           acceptingExplorer{height: 800000},
+		AutoUpgrade:        true,
+		UpgradeMinInterval: 10 * time.Millisecond,
+		UpgradeMaxInterval: 40 * time.Millisecond,
+		OnConfirmed:        func(r *ConfirmationResult) { results <- r },
+	})
+	if err := service.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer service.Stop()
+
+	digest := [32]byte{0x07}
+	if _, err := service.SubmitDigest(context.Background(), digest); err != nil {
+		t.Fatalf("SubmitDigest failed: %v", err)
+	}
+
+	// The loop keeps polling while the calendar has nothing to offer
+	deadline := time.After(5 * time.Second)
+	for lookups.Load() < 2 {
+		select {
+		case <-deadline:
+			t.Fatal("upgrade loop did not poll the calendar")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	confirmed.Store(true)
+
+	select {
+	case r := <-results:
+		if r.Digest != digest || !r.Confirmed || r.BTCBlockHeight != 800000 {
+			t.Errorf("unexpected confirmation: %+v", r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no confirmation received")
+	}
+
+	// Confirmed timestamps are no longer polled
+	polled := lookups.Load()
+	time.Sleep(100 * time.Millisecond)
+	if n := lookups.Load(); n != polled {
+		t.Errorf("confirmed timestamp polled %d more times", n-polled)
+	}
+}
+
+func TestService_StopEndsUpgradeLoop(t *testing.T) {
+	service := NewService(ServiceConfig{
+		CalendarServers: []string{"http://127.0.0.1:1"},
+		Explorer:        downExplorer{},
+		AutoUpgrade:     true,
+	})
+	for i := 0; i < 2; i++ {
+		if err := service.Start(); err != nil {
+			t.Fatal(err)
+		}
+		done := make(chan struct{})
+		go func() {
+			service.Stop()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Stop did not return")
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+//
+// This file implements the OTS admin RPC API. Its methods change node state
+// and must only be exposed on authenticated or local endpoints.
+
+package rpc
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/ots/consensus"
+)
+
+// AdminAPI provides the OTS maintenance RPC methods
+type AdminAPI struct {
+	rebuilder Rebuilder
+}
+
+// Rebuilder defines the snapshot rebuild methods required from the OTS module
+type Rebuilder interface {
+	StartRebuild(fromBlock, toBlock uint64) error
+	StopRebuild() error
+	RebuildStatus() (*consensus.RebuildProgress, error)
+}
+
+// NewAdminAPI creates a new OTS admin RPC API
+func NewAdminAPI(rebuilder Rebuilder) *AdminAPI {
+	return &AdminAPI{rebuilder: rebuilder}
+}
+
+// RebuildSnapshots starts regenerating the OTS consensus snapshots of the
+// canonical blocks fromBlock..toBlock from headers and receipts. Calling it
+// again with the range of an interrupted rebuild resumes that rebuild.
+func (api *AdminAPI) RebuildSnapshots(ctx context.Context, fromBlock, toBlock uint64) (*RebuildStatusResult, error) {
+	if err := api.rebuilder.StartRebuild(fromBlock, toBlock); err != nil {
+		return nil, err
+	}
+	return api.RebuildStatus(ctx)
+}
+
+// StopRebuild stops the running snapshot rebuild. Its progress is kept.
+func (api *AdminAPI) StopRebuild(ctx context.Context) (*RebuildStatusResult, error) {
+	if err := api.rebuilder.StopRebuild(); err != nil {
+		return nil, err
+	}
+	return api.RebuildStatus(ctx)
+}
+
+// RebuildStatus returns the progress of the running or unfinished snapshot
+// rebuild
+func (api *AdminAPI) RebuildStatus(ctx context.Context) (*RebuildStatusResult, error) {
+	progress, err := api.rebuilder.RebuildStatus()
+	if err != nil {
+		return nil, err
+	}
+	if progress == nil {
+		return &RebuildStatusResult{Status: "idle"}, nil
+	}
+
+	result := &RebuildStatusResult{
+		From:   progress.From,
+		To:     progress.To,
+		Number: progress.Number,
+		Hash:   &progress.Hash,
+		Error:  progress.Error,
+	}
+	switch {
+	case progress.Running:
+		result.Status = "running"
+	case progress.Error != "":
+		result.Status = "failed"
+	default:
+		result.Status = "interrupted"
+	}
+	return result, nil
+}
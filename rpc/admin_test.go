@@ -0,0 +1,73 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package rpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ots/consensus"
+)
+
+// mockRebuilder implements Rebuilder for testing
+type mockRebuilder struct {
+	progress *consensus.RebuildProgress
+	err      error
+}
+
+func (r *mockRebuilder) StartRebuild(fromBlock, toBlock uint64) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.progress = &consensus.RebuildProgress{From: fromBlock, To: toBlock, Running: true}
+	return nil
+}
+
+func (r *mockRebuilder) StopRebuild() error {
+	if r.progress == nil || !r.progress.Running {
+		return consensus.ErrRebuildNotRunning
+	}
+	r.progress.Running = false
+	return nil
+}
+
+func (r *mockRebuilder) RebuildStatus() (*consensus.RebuildProgress, error) {
+	return r.progress, nil
+}
+
+func TestAdminAPI_Rebuild(t *testing.T) {
+	rebuilder := &mockRebuilder{}
+	api := NewAdminAPI(rebuilder)
+	ctx := context.Background()
+
+	if result, err := api.RebuildStatus(ctx); err != nil || result.Status != "idle" || result.Hash != nil {
+		t.Errorf("expected idle status, got %+v, %v", result, err)
+	}
+	if _, err := api.StopRebuild(ctx); err != consensus.ErrRebuildNotRunning {
+		t.Errorf("expected ErrRebuildNotRunning, got %v", err)
+	}
+
+	result, err := api.RebuildSnapshots(ctx, 100, 5000)
+	if err != nil {
+		t.Fatalf("RebuildSnapshots failed: %v", err)
+	}
+	if result.Status != "running" || result.From != 100 || result.To != 5000 {
+		t.Errorf("unexpected status after start: %+v", result)
+	}
+
+	result, err = api.StopRebuild(ctx)
+	if err != nil {
+		t.Fatalf("StopRebuild failed: %v", err)
+	}
+	if result.Status != "interrupted" {
+		t.Errorf("expected interrupted status, got %+v", result)
+	}
+
+	rebuilder.progress.Error = "missing chain data"
+	rebuilder.progress.Hash = common.HexToHash("0xaa")
+	if result, _ := api.RebuildStatus(ctx); result.Status != "failed" || result.Error != "missing chain data" || *result.Hash != rebuilder.progress.Hash {
+		t.Errorf("expected failed status, got %+v", result)
+	}
+}
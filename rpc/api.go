@@ -8,7 +8,9 @@ package rpc
 
 import (
 	"context"
+	"encoding/binary"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -17,14 +19,28 @@ import (
 	"github.com/ethereum/go-ethereum/ots"
 	otsmetrics "github.com/ethereum/go-ethereum/ots/metrics"
 	"github.com/ethereum/go-ethereum/ots/merkle"
+	"github.com/ethereum/go-ethereum/ots/opentimestamps"
 	"github.com/ethereum/go-ethereum/ots/storage"
 )
 
 var (
-	ErrModuleNotRunning = errors.New("OTS module not running")
-	ErrStorageNotReady  = errors.New("OTS storage not initialized")
-	ErrBatchNotFound    = errors.New("batch not found")
-	ErrRUIDNotFound     = errors.New("RUID not found")
+	ErrModuleNotRunning  = errors.New("OTS module not running")
+	ErrStorageNotReady   = errors.New("OTS storage not initialized")
+	ErrBatchNotFound     = errors.New("batch not found")
+	ErrRUIDNotFound      = errors.New("RUID not found")
+	ErrProofNotFound     = errors.New("OTS proof not found")
+	ErrProofHashMismatch = errors.New("OTS proof does not match its hash")
+	ErrProofNotCommitted = errors.New("proof hash not committed for root hash")
+	ErrInvalidCursor     = errors.New("invalid batch cursor")
+	ErrInvalidFilter     = errors.New("invalid batch filter")
+)
+
+const (
+	// defaultBatchPageSize is the number of batches listed per page by default
+	defaultBatchPageSize = 100
+
+	// maxBatchPageSize is the maximum number of batches listed per page
+	maxBatchPageSize = 1000
 )
 
 // API provides the OTS RPC methods
@@ -38,6 +54,9 @@ type ModuleInterface interface {
 	IsRunning() bool
 	Health() ots.HealthStatus
 	Config() *ots.Config
+	CalendarResults() []opentimestamps.CalendarResult
+	VerifyOTSProof(ctx context.Context, digest [32]byte, proof []byte) (*opentimestamps.AttestationInfo, error)
+	CommittedProofHash(rootHash common.Hash) (common.Hash, error)
 }
 
 // NewAPI creates a new OTS RPC API
@@ -86,6 +105,41 @@ func (api *API) Health(ctx context.Context) (*ots.HealthStatus, error) {
 	return &health, nil
 }
 
+// Calendars returns the status of each configured calendar server as seen by
+// the last submission. Servers not contacted yet are reported disconnected.
+func (api *API) Calendars(ctx context.Context) ([]*CalendarStatus, error) {
+	if api.module == nil || !api.module.IsRunning() {
+		return nil, ErrModuleNotRunning
+	}
+
+	var statuses []*CalendarStatus
+	byURL := make(map[string]*CalendarStatus)
+	for _, url := range api.module.Config().OTS.CalendarServers {
+		status := &CalendarStatus{URL: url}
+		byURL[url] = status
+		statuses = append(statuses, status)
+	}
+
+	// The client may fall back to its default servers, report those too
+	for _, r := range api.module.CalendarResults() {
+		status, ok := byURL[r.URL]
+		if !ok {
+			status = &CalendarStatus{URL: r.URL}
+			byURL[r.URL] = status
+			statuses = append(statuses, status)
+		}
+		status.Connected = r.OK()
+		status.LatencyMs = r.Latency.Milliseconds()
+		status.HTTPStatus = r.StatusCode
+		status.LastChecked = r.Time.Unix()
+		if r.Err != nil {
+			status.Error = r.Err.Error()
+		}
+	}
+
+	return statuses, nil
+}
+
 // GetBatch returns batch information by ID
 func (api *API) GetBatch(ctx context.Context, batchID string) (*BatchResult, error) {
 	if api.module == nil || !api.module.IsRunning() {
@@ -160,7 +214,7 @@ func (api *API) GetProof(ctx context.Context, ruidHex string, batchID string) (*
 	}
 
 	// Rebuild tree from RUIDs
-	tree, err := merkle.BuildFromRUIDs(meta.EventRUIDs)
+	tree, err := merkle.BuildWithScheme(merkle.Scheme(meta.MerkleScheme), meta.EventRUIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -203,18 +257,88 @@ func (api *API) GetPendingBatches(ctx context.Context) ([]*BatchSummary, error)
 
 	results := make([]*BatchSummary, len(metas))
 	for i, meta := range metas {
-		results[i] = &BatchSummary{
-			BatchID:    meta.BatchID,
-			StartBlock: meta.StartBlock,
-			EndBlock:   meta.EndBlock,
-			RUIDCount:  meta.RUIDCount,
-			Status:     getStatusString(attempts[i]),
-		}
+		results[i] = newBatchSummary(meta, attempts[i])
 	}
 
 	return results, nil
 }
 
+// ListBatches returns a page of the batches matching the filter, ordered by
+// start block and batch ID. The order is stable, so a page continues from
+// the cursor of the previous page even while new batches are created. A nil
+// filter lists all batches and a nil or zero limit uses the default page
+// size.
+func (api *API) ListBatches(ctx context.Context, filter *BatchFilter, cursor *string, limit *int) (*BatchPage, error) {
+	if api.module == nil || !api.module.IsRunning() {
+		return nil, ErrModuleNotRunning
+	}
+	if api.store == nil {
+		return nil, ErrStorageNotReady
+	}
+	if filter == nil {
+		filter = &BatchFilter{}
+	}
+
+	statuses, triggerType, err := parseBatchFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	var after *storage.BatchCursor
+	if cursor != nil && *cursor != "" {
+		if after, err = decodeBatchCursor(*cursor); err != nil {
+			return nil, err
+		}
+	}
+	size := defaultBatchPageSize
+	if limit != nil && *limit != 0 {
+		if *limit < 0 {
+			return nil, fmt.Errorf("%w: negative limit", ErrInvalidFilter)
+		}
+		size = *limit
+		if size > maxBatchPageSize {
+			size = maxBatchPageSize
+		}
+	}
+
+	// Collect one batch more than the page to know if another page follows
+	page := &BatchPage{Batches: []*BatchSummary{}}
+	var last *ots.BatchMeta
+	indexMgr := storage.NewIndexManager(api.store)
+	err = indexMgr.ListBatches(statuses, filter.FromBlock, filter.ToBlock, after, func(meta *ots.BatchMeta) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		if triggerType != nil && meta.TriggerType != *triggerType {
+			return true
+		}
+		created := meta.CreatedAt.Unix()
+		if (filter.FromCreatedAt != 0 && created < filter.FromCreatedAt) || (filter.ToCreatedAt != 0 && created > filter.ToCreatedAt) {
+			return true
+		}
+		attempt, _ := api.store.GetAttempt(meta.BatchID)
+		if filter.FromBTCHeight != 0 || filter.ToBTCHeight != 0 {
+			height := getUint64OrZero(attempt, func(a *ots.Attempt) uint64 { return a.BTCBlockHeight })
+			if height == 0 || height < filter.FromBTCHeight || (filter.ToBTCHeight != 0 && height > filter.ToBTCHeight) {
+				return true
+			}
+		}
+		if len(page.Batches) == size {
+			page.Next = encodeBatchCursor(last)
+			return false
+		}
+		page.Batches = append(page.Batches, newBatchSummary(meta, attempt))
+		last = meta
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
 // VerifyRUID verifies that a RUID is included in an anchored batch
 func (api *API) VerifyRUID(ctx context.Context, ruidHex string) (*VerifyResult, error) {
 	// Start verification timer
@@ -269,7 +393,7 @@ func (api *API) VerifyRUID(ctx context.Context, ruidHex string) (*VerifyResult,
 	}
 
 	// 4. Rebuild Merkle tree and verify proof
-	tree, err := merkle.BuildFromRUIDs(meta.EventRUIDs)
+	tree, err := merkle.BuildWithScheme(merkle.Scheme(meta.MerkleScheme), meta.EventRUIDs)
 	if err != nil {
 		otsmetrics.IncVerification(false)
 		return &VerifyResult{
@@ -328,6 +452,79 @@ func (api *API) VerifyRUID(ctx context.Context, ruidHex string) (*VerifyResult,
 
 // Helper functions
 
+func newBatchSummary(meta *ots.BatchMeta, attempt *ots.Attempt) *BatchSummary {
+	return &BatchSummary{
+		BatchID:        meta.BatchID,
+		StartBlock:     meta.StartBlock,
+		EndBlock:       meta.EndBlock,
+		RUIDCount:      meta.RUIDCount,
+		CreatedAt:      meta.CreatedAt.Unix(),
+		TriggerType:    meta.TriggerType.String(),
+		Status:         getStatusString(attempt),
+		BTCBlockHeight: getUint64OrZero(attempt, func(a *ots.Attempt) uint64 { return a.BTCBlockHeight }),
+	}
+}
+
+// parseBatchFilter checks the filter and resolves its status and trigger
+// type names
+func parseBatchFilter(filter *BatchFilter) ([]ots.BatchStatus, *ots.TriggerType, error) {
+	if filter.ToBlock != 0 && filter.FromBlock > filter.ToBlock {
+		return nil, nil, fmt.Errorf("%w: fromBlock %d after toBlock %d", ErrInvalidFilter, filter.FromBlock, filter.ToBlock)
+	}
+	if filter.ToBTCHeight != 0 && filter.FromBTCHeight > filter.ToBTCHeight {
+		return nil, nil, fmt.Errorf("%w: fromBtcHeight %d after toBtcHeight %d", ErrInvalidFilter, filter.FromBTCHeight, filter.ToBTCHeight)
+	}
+	if filter.ToCreatedAt != 0 && filter.FromCreatedAt > filter.ToCreatedAt {
+		return nil, nil, fmt.Errorf("%w: fromCreatedAt %d after toCreatedAt %d", ErrInvalidFilter, filter.FromCreatedAt, filter.ToCreatedAt)
+	}
+
+	var statuses []ots.BatchStatus
+	for _, name := range filter.Status {
+		status, ok := parseBatchStatus(name)
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: unknown status %q", ErrInvalidFilter, name)
+		}
+		statuses = append(statuses, status)
+	}
+
+	if filter.TriggerType == "" {
+		return statuses, nil, nil
+	}
+	for trigger := ots.TriggerTypeDaily; trigger <= ots.TriggerTypeManual; trigger++ {
+		if trigger.String() == filter.TriggerType {
+			return statuses, &trigger, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("%w: unknown trigger type %q", ErrInvalidFilter, filter.TriggerType)
+}
+
+func parseBatchStatus(name string) (ots.BatchStatus, bool) {
+	for status := ots.BatchStatusPending; status <= ots.BatchStatusFailed; status++ {
+		if status.String() == name {
+			return status, true
+		}
+	}
+	return 0, false
+}
+
+// encodeBatchCursor returns the opaque cursor of the page after the batch
+func encodeBatchCursor(meta *ots.BatchMeta) string {
+	buf := make([]byte, 8, 8+len(meta.BatchID))
+	binary.BigEndian.PutUint64(buf, meta.StartBlock)
+	return hexutil.Encode(append(buf, meta.BatchID...))
+}
+
+func decodeBatchCursor(cursor string) (*storage.BatchCursor, error) {
+	data, err := hexutil.Decode(cursor)
+	if err != nil || len(data) <= 8 {
+		return nil, ErrInvalidCursor
+	}
+	return &storage.BatchCursor{
+		StartBlock: binary.BigEndian.Uint64(data[:8]),
+		BatchID:    string(data[8:]),
+	}, nil
+}
+
 func getStatusString(attempt *ots.Attempt) string {
 	if attempt == nil {
 		return "unknown"
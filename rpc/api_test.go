@@ -5,6 +5,7 @@ package rpc
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb/memorydb"
 	"github.com/ethereum/go-ethereum/ots"
 	"github.com/ethereum/go-ethereum/ots/merkle"
+	"github.com/ethereum/go-ethereum/ots/opentimestamps"
 	"github.com/ethereum/go-ethereum/ots/storage"
 	"github.com/ethereum/go-ethereum/ots/types"
 )
@@ -26,7 +28,13 @@ func newTestStore() *storage.Store {
 
 // mockModule implements ModuleInterface for testing
 type mockModule struct {
-	running bool
+	running         bool
+	calendars       []string
+	calendarResults []opentimestamps.CalendarResult
+	proofPeers      []string
+	attestation     *opentimestamps.AttestationInfo
+	verifyErr       error
+	committed       map[common.Hash]common.Hash
 }
 
 func (m *mockModule) IsRunning() bool {
@@ -38,7 +46,23 @@ func (m *mockModule) Health() ots.HealthStatus {
 }
 
 func (m *mockModule) Config() *ots.Config {
-	return &ots.Config{Mode: ots.ModeWatcher}
+	return &ots.Config{Mode: ots.ModeWatcher, OTS: ots.OTSConfig{CalendarServers: m.calendars, ProofPeers: m.proofPeers}}
+}
+
+func (m *mockModule) CalendarResults() []opentimestamps.CalendarResult {
+	return m.calendarResults
+}
+
+func (m *mockModule) VerifyOTSProof(ctx context.Context, digest [32]byte, proof []byte) (*opentimestamps.AttestationInfo, error) {
+	return m.attestation, m.verifyErr
+}
+
+func (m *mockModule) CommittedProofHash(rootHash common.Hash) (common.Hash, error) {
+	proofHash, ok := m.committed[rootHash]
+	if !ok {
+		return common.Hash{}, ots.ErrProofNotCommitted
+	}
+	return proofHash, nil
 }
 
 func TestVerifyRUID_NotFound(t *testing.T) {
@@ -106,6 +130,14 @@ func TestVerifyRUID_PendingBatch(t *testing.T) {
 }
 
 func TestVerifyRUID_Confirmed(t *testing.T) {
+	// Batches recorded without a scheme are legacy batches; three RUIDs give
+	// an odd layer, where the schemes differ
+	for _, scheme := range []merkle.Scheme{merkle.SchemeLegacy, merkle.SchemeV1} {
+		testVerifyRUIDConfirmed(t, scheme)
+	}
+}
+
+func testVerifyRUIDConfirmed(t *testing.T, scheme merkle.Scheme) {
 	store := newTestStore()
 
 	// Create RUIDs
@@ -116,9 +148,9 @@ func TestVerifyRUID_Confirmed(t *testing.T) {
 	ruids := []common.Hash{ruid1, ruid2, ruid3}
 
 	// Build Merkle tree to get correct root using the merkle package
-	tree, err := merkle.BuildFromRUIDs(ruids)
+	tree, err := merkle.BuildWithScheme(scheme, ruids)
 	if err != nil {
-		t.Fatalf("BuildFromRUIDs failed: %v", err)
+		t.Fatalf("BuildWithScheme failed: %v", err)
 	}
 	rootHash := tree.Root()
 
@@ -129,6 +161,8 @@ func TestVerifyRUID_Confirmed(t *testing.T) {
 		RootHash:   rootHash,
 		EventRUIDs: ruids,
 		CreatedAt:  time.Now(),
+
+		MerkleScheme: uint8(scheme),
 	}
 
 	if err := store.SaveBatchMeta(meta); err != nil {
@@ -162,7 +196,7 @@ func TestVerifyRUID_Confirmed(t *testing.T) {
 
 	// Now should be verified successfully
 	if !result.Verified {
-		t.Errorf("expected Verified=true, got false. Message: %s", result.Message)
+		t.Errorf("scheme %d: expected Verified=true, got false. Message: %s", scheme, result.Message)
 	}
 
 	if result.BTCBlockHeight != 800000 {
@@ -219,3 +253,188 @@ func TestGetBatchByRUID(t *testing.T) {
 		t.Errorf("expected StartBlock=50, got %d", retrieved.StartBlock)
 	}
 }
+
+func TestCalendars(t *testing.T) {
+	now := time.Now()
+	module := &mockModule{
+		running:   true,
+		calendars: []string{"https://a.example", "https://b.example", "https://c.example"},
+		calendarResults: []opentimestamps.CalendarResult{
+			{URL: "https://a.example", StatusCode: 200, Latency: 120 * time.Millisecond, Time: now},
+			{URL: "https://b.example", StatusCode: 503, Latency: 40 * time.Millisecond, Time: now,
+				Err: errors.New("ots: calendar server error: 503 Service Unavailable")},
+		},
+	}
+	api := NewAPI(module, nil)
+
+	statuses, err := api.Calendars(context.Background())
+	if err != nil {
+		t.Fatalf("Calendars failed: %v", err)
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("expected 3 calendars, got %d", len(statuses))
+	}
+
+	a, b, c := statuses[0], statuses[1], statuses[2]
+	if !a.Connected || a.HTTPStatus != 200 || a.LatencyMs != 120 || a.Error != "" {
+		t.Errorf("unexpected status for a: %+v", a)
+	}
+	if b.Connected || b.HTTPStatus != 503 || b.Error == "" || b.LastChecked != now.Unix() {
+		t.Errorf("unexpected status for b: %+v", b)
+	}
+	if c.Connected || c.LastChecked != 0 {
+		t.Errorf("calendar never contacted should be disconnected: %+v", c)
+	}
+}
+
+// listBatchIDs lists the IDs of all batches matching filter page by page
+func listBatchIDs(t *testing.T, api *API, filter *BatchFilter, limit int) []string {
+	t.Helper()
+	var (
+		ids    []string
+		cursor string
+	)
+	for {
+		page, err := api.ListBatches(context.Background(), filter, &cursor, &limit)
+		if err != nil {
+			t.Fatalf("ListBatches failed: %v", err)
+		}
+		if len(page.Batches) > limit {
+			t.Fatalf("page of %d batches exceeds limit %d", len(page.Batches), limit)
+		}
+		for _, batch := range page.Batches {
+			ids = append(ids, batch.BatchID)
+		}
+		if page.Next == "" {
+			return ids
+		}
+		cursor = page.Next
+	}
+}
+
+func TestListBatches(t *testing.T) {
+	store := newTestStore()
+	api := NewAPI(&mockModule{running: true}, store)
+
+	created := time.Unix(1700000000, 0)
+	batches := []struct {
+		id         string
+		start, end uint64
+		trigger    types.TriggerType
+		created    int64
+		status     types.BatchStatus
+		btcHeight  uint64
+	}{
+		{"b1", 1, 10, types.TriggerTypeDaily, 0, types.BatchStatusAnchored, 800000},
+		{"b2", 11, 20, types.TriggerTypeFallback, 100, types.BatchStatusConfirmed, 800010},
+		{"a2", 11, 20, types.TriggerTypeManual, 300, types.BatchStatusPending, 0},
+		{"b3", 21, 30, types.TriggerTypeDaily, 200, types.BatchStatusPending, 0},
+	}
+	for i, b := range batches {
+		meta := &types.BatchMeta{
+			BatchID:     b.id,
+			StartBlock:  b.start,
+			EndBlock:    b.end,
+			OTSDigest:   [32]byte{byte(i + 1)},
+			TriggerType: b.trigger,
+			CreatedAt:   created.Add(time.Duration(b.created) * time.Second),
+		}
+		if err := store.SaveBatchMeta(meta); err != nil {
+			t.Fatalf("SaveBatchMeta failed: %v", err)
+		}
+		if err := store.SaveAttempt(&types.Attempt{BatchID: b.id, Status: b.status, BTCBlockHeight: b.btcHeight}); err != nil {
+			t.Fatalf("SaveAttempt failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter *BatchFilter
+		want   []string
+	}{
+		{"all", nil, []string{"b1", "a2", "b2", "b3"}},
+		{"status", &BatchFilter{Status: []string{"pending"}}, []string{"a2", "b3"}},
+		{"statuses", &BatchFilter{Status: []string{"anchored", "confirmed"}}, []string{"b1", "b2"}},
+		{"block range", &BatchFilter{FromBlock: 15, ToBlock: 22}, []string{"a2", "b2", "b3"}},
+		{"block range and status", &BatchFilter{Status: []string{"pending"}, FromBlock: 15, ToBlock: 20}, []string{"a2"}},
+		{"open block range", &BatchFilter{FromBlock: 20}, []string{"a2", "b2", "b3"}},
+		{"btc height", &BatchFilter{FromBTCHeight: 800005}, []string{"b2"}},
+		{"created at", &BatchFilter{FromCreatedAt: created.Unix() + 100, ToCreatedAt: created.Unix() + 200}, []string{"b2", "b3"}},
+		{"trigger type", &BatchFilter{TriggerType: "daily"}, []string{"b1", "b3"}},
+	}
+	for _, tt := range tests {
+		for _, limit := range []int{1, 2, 100} {
+			got := listBatchIDs(t, api, tt.filter, limit)
+			if len(got) != len(tt.want) {
+				t.Errorf("%s, limit %d: got %v, want %v", tt.name, limit, got, tt.want)
+				continue
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("%s, limit %d: got %v, want %v", tt.name, limit, got, tt.want)
+					break
+				}
+			}
+		}
+	}
+
+	// Batches created behind the cursor do not shift the following pages
+	limit := 2
+	page, err := api.ListBatches(context.Background(), nil, nil, &limit)
+	if err != nil || len(page.Batches) != 2 || page.Next == "" {
+		t.Fatalf("unexpected first page: %+v, %v", page, err)
+	}
+	if err := store.SaveBatchMeta(&types.BatchMeta{BatchID: "a1", StartBlock: 1, EndBlock: 10, OTSDigest: [32]byte{0xff}}); err != nil {
+		t.Fatalf("SaveBatchMeta failed: %v", err)
+	}
+	page, err = api.ListBatches(context.Background(), nil, &page.Next, &limit)
+	if err != nil || len(page.Batches) != 2 || page.Batches[0].BatchID != "b2" || page.Batches[1].BatchID != "b3" || page.Next != "" {
+		t.Errorf("unexpected second page: %+v, %v", page, err)
+	}
+
+	invalid := "0x1234"
+	if _, err := api.ListBatches(context.Background(), nil, &invalid, nil); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+	for _, filter := range []*BatchFilter{
+		{Status: []string{"unknown"}},
+		{TriggerType: "hourly"},
+		{FromBlock: 20, ToBlock: 10},
+	} {
+		if _, err := api.ListBatches(context.Background(), filter, nil, nil); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("%+v: expected ErrInvalidFilter, got %v", filter, err)
+		}
+	}
+}
+
+func TestListBatches_StartIndexBackfill(t *testing.T) {
+	db := rawdb.NewDatabase(memorydb.New())
+	store := storage.NewStoreWithDB(db)
+	for i, id := range []string{"b1", "b2", "b3"} {
+		meta := &types.BatchMeta{BatchID: id, StartBlock: uint64(i*10 + 1), EndBlock: uint64(i*10 + 10), OTSDigest: [32]byte{byte(i + 1)}}
+		if err := store.SaveBatchMeta(meta); err != nil {
+			t.Fatalf("SaveBatchMeta failed: %v", err)
+		}
+	}
+
+	// Drop the start block index, as in a database written before it existed
+	iter := db.NewIterator([]byte("bs:"), nil)
+	for iter.Next() {
+		if err := db.Delete(common.CopyBytes(iter.Key())); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+	}
+	iter.Release()
+	if err := db.Delete([]byte("mk:startIndex")); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if got := listBatchIDs(t, NewAPI(&mockModule{running: true}, store), nil, 100); len(got) != 0 {
+		t.Fatalf("listed %v without a start block index", got)
+	}
+
+	api := NewAPI(&mockModule{running: true}, storage.NewStoreWithDB(db))
+	got := listBatchIDs(t, api, nil, 2)
+	if len(got) != 3 || got[0] != "b1" || got[1] != "b2" || got[2] != "b3" {
+		t.Errorf("got %v after reopening, want [b1 b2 b3]", got)
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+//
+// This file implements retrieval of committed OTS proofs from the proof
+// sidecar store. Proofs missing locally are fetched from the configured proof
+// peers and accepted only if they hash to the on-chain commitment.
+
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/ots/opentimestamps"
+	"github.com/ethereum/go-ethereum/ots/storage"
+)
+
+// maxProofSize bounds proofs accepted from peers
+const maxProofSize = 1 << 20
+
+// GetProofSidecar returns the OTS proof with the given hash from the local
+// sidecar store. Peers call this to fetch proofs, so it never asks peers.
+func (api *API) GetProofSidecar(ctx context.Context, proofHash common.Hash) (hexutil.Bytes, error) {
+	if api.store == nil {
+		return nil, ErrStorageNotReady
+	}
+	proof, err := api.store.GetProofSidecar(proofHash)
+	if err != nil {
+		return nil, ErrProofNotFound
+	}
+	return proof, nil
+}
+
+// GetOTSProof retrieves the OTS proof committed on-chain for rootHash and
+// verifies it: the proof must hash to the committed proof hash, timestamp
+// rootHash, and carry a Bitcoin attestation that the OTS client accepts. A
+// proofHash given by the caller must match the commitment.
+func (api *API) GetOTSProof(ctx context.Context, rootHash common.Hash, proofHash *common.Hash) (*OTSProofResult, error) {
+	if api.module == nil || !api.module.IsRunning() {
+		return nil, ErrModuleNotRunning
+	}
+	if api.store == nil {
+		return nil, ErrStorageNotReady
+	}
+
+	committed, err := api.module.CommittedProofHash(rootHash)
+	if err != nil {
+		return nil, err
+	}
+	if proofHash != nil && *proofHash != committed {
+		return nil, fmt.Errorf("%w: root %s commits to %s", ErrProofNotCommitted, rootHash.Hex(), committed.Hex())
+	}
+
+	proof, source, err := api.findProof(ctx, committed)
+	if err != nil {
+		return nil, err
+	}
+	result := &OTSProofResult{
+		RootHash:  rootHash,
+		ProofHash: committed,
+		Proof:     proof,
+		Source:    source,
+	}
+
+	ts, err := opentimestamps.Parse(proof)
+	if err != nil {
+		result.Message = "invalid OTS proof: " + err.Error()
+		return result, nil
+	}
+	if !bytes.Equal(ts.Digest, rootHash[:]) {
+		result.Message = "proof does not timestamp the root hash"
+		return result, nil
+	}
+	if !ts.IsComplete() {
+		result.Message = "proof has no Bitcoin attestation"
+		return result, nil
+	}
+
+	info, err := api.module.VerifyOTSProof(ctx, rootHash, proof)
+	if err != nil {
+		result.Message = "Bitcoin verification failed: " + err.Error()
+		return result, nil
+	}
+	result.Verified = true
+	result.BTCBlockHeight = info.BTCBlockHeight
+	result.BTCTxID = info.BTCTxID
+	result.BTCTimestamp = info.BTCTimestamp
+	result.Message = "proof verified against Bitcoin"
+	return result, nil
+}
+
+// findProof returns the proof with the given hash and where it was found,
+// caching proofs fetched from peers in the local sidecar store
+func (api *API) findProof(ctx context.Context, proofHash common.Hash) ([]byte, string, error) {
+	proof, err := api.store.GetProofSidecar(proofHash)
+	if err == nil {
+		return proof, "local", nil
+	}
+
+	config := api.module.Config()
+	client := &http.Client{Timeout: config.OTS.Timeout}
+	for _, peer := range config.OTS.ProofPeers {
+		proof, err := fetchProofSidecar(ctx, client, peer, proofHash)
+		if err != nil {
+			log.Debug("OTS: Failed to fetch proof sidecar", "peer", peer, "proofHash", proofHash.Hex(), "err", err)
+			continue
+		}
+		if _, err := api.store.SaveProofSidecar(proof); err != nil {
+			log.Warn("OTS: Failed to cache proof sidecar", "proofHash", proofHash.Hex(), "err", err)
+		}
+		return proof, peer, nil
+	}
+	return nil, "", ErrProofNotFound
+}
+
+// fetchProofSidecar asks a peer for the proof with the given hash via
+// ots_getProofSidecar
+func fetchProofSidecar(ctx context.Context, client *http.Client, peer string, proofHash common.Hash) ([]byte, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "ots_getProofSidecar",
+		"params":  []interface{}{proofHash},
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, peer, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var reply struct {
+		Result hexutil.Bytes `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	// Hex encoding doubles the proof size
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 2*maxProofSize+1024)).Decode(&reply); err != nil {
+		return nil, err
+	}
+	if reply.Error != nil {
+		return nil, errors.New(reply.Error.Message)
+	}
+
+	// The sidecar is content-addressed, any peer's answer can be checked
+	if storage.ProofHash(reply.Result) != proofHash {
+		return nil, ErrProofHashMismatch
+	}
+	return reply.Result, nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ots"
+	"github.com/ethereum/go-ethereum/ots/opentimestamps"
+	"github.com/ethereum/go-ethereum/ots/storage"
+)
+
+// newTestProof returns a serialized proof of rootHash attested in a Bitcoin block
+func newTestProof(t *testing.T, rootHash common.Hash) []byte {
+	ts := opentimestamps.NewTimestamp(rootHash)
+	ts.Root.AddAttestation(opentimestamps.Attestation{Type: opentimestamps.AttestationBitcoin, BTCBlockHeight: 800000})
+	proof, err := ts.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	return proof
+}
+
+// newProofPeer serves ots_getProofSidecar from api. If tamper is set, the
+// proof is altered before it is returned.
+func newProofPeer(api *API, tamper bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Params []common.Hash `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Params) != 1 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		reply := map[string]interface{}{"jsonrpc": "2.0", "id": 1}
+		proof, err := api.GetProofSidecar(r.Context(), req.Params[0])
+		if err != nil {
+			reply["error"] = map[string]interface{}{"code": -32000, "message": err.Error()}
+		} else {
+			if tamper {
+				proof = append(proof, 0x00)
+			}
+			reply["result"] = proof
+		}
+		json.NewEncoder(w).Encode(reply)
+	}))
+}
+
+func TestGetOTSProof_Local(t *testing.T) {
+	rootHash := common.HexToHash("0xaa")
+	store := newTestStore()
+	proofHash, err := store.SaveProofSidecar(newTestProof(t, rootHash))
+	if err != nil {
+		t.Fatalf("SaveProofSidecar failed: %v", err)
+	}
+
+	otherRoot := common.HexToHash("0xbb")
+	module := &mockModule{
+		running:     true,
+		attestation: &opentimestamps.AttestationInfo{IsComplete: true, BTCBlockHeight: 800000, BTCTimestamp: 1700000000},
+		committed:   map[common.Hash]common.Hash{rootHash: proofHash, otherRoot: proofHash},
+	}
+	api := NewAPI(module, store)
+
+	// The committed proof is found without the caller naming it
+	result, err := api.GetOTSProof(context.Background(), rootHash, nil)
+	if err != nil {
+		t.Fatalf("GetOTSProof failed: %v", err)
+	}
+	if !result.Verified || result.Source != "local" || result.ProofHash != proofHash || result.BTCBlockHeight != 800000 || result.BTCTimestamp != 1700000000 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if result, err := api.GetOTSProof(context.Background(), rootHash, &proofHash); err != nil || !result.Verified {
+		t.Errorf("committed proof hash not accepted: %+v, %v", result, err)
+	}
+
+	// Proof hashes the chain did not commit to are refused
+	otherHash := common.HexToHash("0xcc")
+	if _, err := api.GetOTSProof(context.Background(), rootHash, &otherHash); !errors.Is(err, ErrProofNotCommitted) {
+		t.Errorf("expected ErrProofNotCommitted, got %v", err)
+	}
+	if _, err := api.GetOTSProof(context.Background(), common.HexToHash("0xdd"), nil); !errors.Is(err, ots.ErrProofNotCommitted) {
+		t.Errorf("expected ots.ErrProofNotCommitted for an unconfirmed root, got %v", err)
+	}
+
+	// A committed proof for another root is returned but not verified
+	result, err = api.GetOTSProof(context.Background(), otherRoot, nil)
+	if err != nil {
+		t.Fatalf("GetOTSProof failed: %v", err)
+	}
+	if result.Verified || result.Message != "proof does not timestamp the root hash" {
+		t.Errorf("proof for another root verified: %+v", result)
+	}
+
+	// Bitcoin verification failures are reported
+	module.verifyErr = opentimestamps.ErrVerifyFailed
+	if result, _ := api.GetOTSProof(context.Background(), rootHash, nil); result == nil || result.Verified {
+		t.Errorf("expected verification failure, got %+v", result)
+	}
+}
+
+func TestGetOTSProof_FromPeer(t *testing.T) {
+	rootHash := common.HexToHash("0xaa")
+	proof := newTestProof(t, rootHash)
+	proofHash := storage.ProofHash(proof)
+
+	peerStore := newTestStore()
+	if _, err := peerStore.SaveProofSidecar(proof); err != nil {
+		t.Fatalf("SaveProofSidecar failed: %v", err)
+	}
+	peerAPI := NewAPI(&mockModule{running: true}, peerStore)
+	tampering := newProofPeer(peerAPI, true)
+	defer tampering.Close()
+	honest := newProofPeer(peerAPI, false)
+	defer honest.Close()
+	empty := newProofPeer(NewAPI(&mockModule{running: true}, newTestStore()), false)
+	defer empty.Close()
+
+	store := newTestStore()
+	module := &mockModule{
+		running:     true,
+		proofPeers:  []string{empty.URL, tampering.URL, honest.URL},
+		attestation: &opentimestamps.AttestationInfo{IsComplete: true, BTCBlockHeight: 800000},
+		committed:   map[common.Hash]common.Hash{rootHash: proofHash, common.HexToHash("0xbb"): common.HexToHash("0xcc")},
+	}
+	api := NewAPI(module, store)
+
+	result, err := api.GetOTSProof(context.Background(), rootHash, nil)
+	if err != nil {
+		t.Fatalf("GetOTSProof failed: %v", err)
+	}
+	if !result.Verified || result.Source != honest.URL {
+		t.Errorf("expected proof verified from %s, got %+v", honest.URL, result)
+	}
+
+	// The fetched proof is cached and served to other peers
+	if cached, err := api.GetProofSidecar(context.Background(), proofHash); err != nil || storage.ProofHash(cached) != proofHash {
+		t.Errorf("proof not cached: %v", err)
+	}
+
+	// Unknown proofs are not found anywhere
+	if _, err := api.GetOTSProof(context.Background(), common.HexToHash("0xbb"), nil); !errors.Is(err, ErrProofNotFound) {
+		t.Errorf("expected ErrProofNotFound, got %v", err)
+	}
+}
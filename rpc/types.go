@@ -5,6 +5,7 @@ package rpc
 
 import (
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/ots/storage"
 )
 
@@ -35,11 +36,34 @@ type BatchResult struct {
 
 // BatchSummary represents a brief batch summary
 type BatchSummary struct {
-	BatchID    string `json:"batchId"`
-	StartBlock uint64 `json:"startBlock"`
-	EndBlock   uint64 `json:"endBlock"`
-	RUIDCount  uint32 `json:"ruidCount"`
-	Status     string `json:"status"`
+	BatchID        string `json:"batchId"`
+	StartBlock     uint64 `json:"startBlock"`
+	EndBlock       uint64 `json:"endBlock"`
+	RUIDCount      uint32 `json:"ruidCount"`
+	CreatedAt      int64  `json:"createdAt"`
+	TriggerType    string `json:"triggerType"`
+	Status         string `json:"status"`
+	BTCBlockHeight uint64 `json:"btcBlockHeight,omitempty"`
+}
+
+// BatchFilter selects the batches listed by ots_listBatches. Ranges are
+// inclusive and zero fields do not filter.
+type BatchFilter struct {
+	Status        []string `json:"status,omitempty"`
+	FromBlock     uint64   `json:"fromBlock,omitempty"`
+	ToBlock       uint64   `json:"toBlock,omitempty"`
+	FromBTCHeight uint64   `json:"fromBtcHeight,omitempty"`
+	ToBTCHeight   uint64   `json:"toBtcHeight,omitempty"`
+	FromCreatedAt int64    `json:"fromCreatedAt,omitempty"`
+	ToCreatedAt   int64    `json:"toCreatedAt,omitempty"`
+	TriggerType   string   `json:"triggerType,omitempty"`
+}
+
+// BatchPage represents a page of listed batches. Next is the cursor of the
+// following page and is empty on the last page.
+type BatchPage struct {
+	Batches []*BatchSummary `json:"batches"`
+	Next    string          `json:"next,omitempty"`
 }
 
 // ProofResult represents a Merkle proof with optional OTS proof
@@ -51,6 +75,19 @@ type ProofResult struct {
 	OTSProof    string      `json:"otsProof,omitempty"`
 }
 
+// OTSProofResult represents a committed OTS proof and its verification
+type OTSProofResult struct {
+	RootHash       common.Hash   `json:"rootHash"`
+	ProofHash      common.Hash   `json:"proofHash"`
+	Proof          hexutil.Bytes `json:"proof"`
+	Source         string        `json:"source"`
+	Verified       bool          `json:"verified"`
+	BTCBlockHeight uint64        `json:"btcBlockHeight,omitempty"`
+	BTCTxID        string        `json:"btcTxId,omitempty"`
+	BTCTimestamp   uint64        `json:"btcTimestamp,omitempty"`
+	Message        string        `json:"message,omitempty"`
+}
+
 // VerifyResult represents the result of RUID verification
 type VerifyResult struct {
 	RUID           string `json:"ruid"`
@@ -61,9 +98,23 @@ type VerifyResult struct {
 	Message        string `json:"message,omitempty"`
 }
 
-// CalendarStatus represents the status of a calendar server
+// CalendarStatus represents the status of a calendar server, as seen by the
+// last submission
 type CalendarStatus struct {
-	URL       string `json:"url"`
-	Connected bool   `json:"connected"`
-	LatencyMs int64  `json:"latencyMs,omitempty"`
+	URL         string `json:"url"`
+	Connected   bool   `json:"connected"`
+	LatencyMs   int64  `json:"latencyMs,omitempty"`
+	HTTPStatus  int    `json:"httpStatus,omitempty"`
+	Error       string `json:"error,omitempty"`
+	LastChecked int64  `json:"lastChecked,omitempty"`
+}
+
+// RebuildStatusResult represents the progress of an OTS snapshot rebuild
+type RebuildStatusResult struct {
+	Status string       `json:"status"`
+	From   uint64       `json:"from,omitempty"`
+	To     uint64       `json:"to,omitempty"`
+	Number uint64       `json:"number,omitempty"`
+	Hash   *common.Hash `json:"hash,omitempty"`
+	Error  string       `json:"error,omitempty"`
 }
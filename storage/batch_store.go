@@ -40,6 +40,12 @@ var (
 	// Block index: bi:{blockNumber}:{batchId} -> nil
 	prefixBlockIndex = []byte("bi:")
 
+	// Start block index: bs:{startBlock}:{batchId} -> nil
+	prefixStartIndex = []byte("bs:")
+
+	// Set once the start block index covers the batches stored before it
+	keyStartIndexed = []byte("mk:startIndex")
+
 	// Status index: si:{status}:{batchId} -> nil
 	prefixStatusIndex = []byte("si:")
 
@@ -48,6 +54,12 @@ var (
 
 	// RUID to BatchID: ri:{ruid} -> batchId
 	prefixRUIDIndex = []byte("ri:")
+
+	// Pending OTS timestamp: pt:{digest} -> service record
+	prefixPendingTimestamp = []byte("pt:")
+
+	// Proof sidecar: ps:{keccak256(proof)} -> upgraded OTS proof
+	prefixProofSidecar = []byte("ps:")
 )
 
 // Store provides storage operations for OTS batches
@@ -69,12 +81,47 @@ func NewStore(dataDir string, cacheSize int, writeBuffer int) (*Store, error) {
 	// Wrap with rawdb.NewDatabase to implement full ethdb.Database interface
 	db := rawdb.NewDatabase(ldb)
 
-	return &Store{db: db}, nil
+	return NewStoreWithDB(db), nil
 }
 
 // NewStoreWithDB creates a store with an existing database
 func NewStoreWithDB(db ethdb.Database) *Store {
-	return &Store{db: db}
+	s := &Store{db: db}
+	if err := s.indexStartBlocks(); err != nil {
+		log.Warn("OTS: Failed to index batch start blocks", "err", err)
+	}
+	return s
+}
+
+// indexStartBlocks adds the batches stored before the start block index
+// existed to it. It runs once per database.
+func (s *Store) indexStartBlocks() error {
+	if has, _ := s.db.Has(keyStartIndexed); has {
+		return nil
+	}
+	iter := s.db.NewIterator(prefixBatchMeta, nil)
+	defer iter.Release()
+
+	batch := s.db.NewBatch()
+	for iter.Next() {
+		var meta struct {
+			BatchID    string
+			StartBlock uint64
+		}
+		if err := json.Unmarshal(iter.Value(), &meta); err != nil {
+			continue
+		}
+		if err := batch.Put(makeStartIndexKey(meta.StartBlock, meta.BatchID), nil); err != nil {
+			return err
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if err := batch.Put(keyStartIndexed, nil); err != nil {
+		return err
+	}
+	return batch.Write()
 }
 
 // Close closes the storage
@@ -113,6 +160,11 @@ func (s *Store) SaveBatchMeta(meta *types.BatchMeta) error {
 		return err
 	}
 
+	// Save start block index entry
+	if err := batch.Put(makeStartIndexKey(meta.StartBlock, meta.BatchID), nil); err != nil {
+		return err
+	}
+
 	// Save block index entries
 	for block := meta.StartBlock; block <= meta.EndBlock; block++ {
 		blockKey := makeBlockIndexKey(block, meta.BatchID)
@@ -322,6 +374,27 @@ func (s *Store) GetBatchesInBlockRange(startBlock, endBlock uint64) ([]string, e
 	return batchIDs, nil
 }
 
+// iterateStartIndex calls fn with the start block index entries at or after
+// start block startBlock and batch ID startID, in key order, until fn returns
+// false. The store lock is not held while fn runs, so fn may read the store.
+func (s *Store) iterateStartIndex(startBlock uint64, startID string, fn func(startBlock uint64, batchID string) bool) error {
+	start := makeStartIndexKey(startBlock, startID)
+	iter := s.db.NewIterator(prefixStartIndex, start[len(prefixStartIndex):])
+	defer iter.Release()
+
+	for iter.Next() {
+		// Key format: bs:{startBlock}:{batchId}
+		key := iter.Key()[len(prefixStartIndex):]
+		if len(key) < 8 {
+			continue
+		}
+		if !fn(binary.BigEndian.Uint64(key[:8]), string(key[8:])) {
+			break
+		}
+	}
+	return iter.Error()
+}
+
 // Helper functions for key construction
 
 func makeBlockIndexKey(blockNumber uint64, batchID string) []byte {
@@ -335,6 +408,13 @@ func makeBlockIndexPrefix(blockNumber uint64) []byte {
 	return append(prefixBlockIndex, buf...)
 }
 
+func makeStartIndexKey(startBlock uint64, batchID string) []byte {
+	key := make([]byte, 0, len(prefixStartIndex)+8+len(batchID))
+	key = append(key, prefixStartIndex...)
+	key = binary.BigEndian.AppendUint64(key, startBlock)
+	return append(key, batchID...)
+}
+
 func makeStatusIndexKey(status types.BatchStatus, batchID string) []byte {
 	prefix := makeStatusIndexPrefix(status)
 	return append(prefix, []byte(batchID)...)
@@ -4,6 +4,8 @@
 package storage
 
 import (
+	"sort"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ots/types"
 )
@@ -88,6 +90,169 @@ func (im *IndexManager) GetConfirmedUnanchoredBatches() ([]string, error) {
 	return im.store.GetBatchesByStatus(types.BatchStatusConfirmed)
 }
 
+// MarkAnchored records that the batch covering startBlock..endBlock with the
+// given root was anchored on-chain by txHash in block, and returns its ID. It
+// returns ErrNotFound if no local batch matches. The reported flag is false if
+// the batch was already recorded as anchored, in which case only the anchor
+// location is updated.
+func (im *IndexManager) MarkAnchored(startBlock, endBlock uint64, rootHash, txHash common.Hash, block uint64) (string, bool, error) {
+	// Every block of a batch is indexed, so its first block finds it
+	batchIDs, err := im.store.GetBatchesInBlockRange(startBlock, startBlock)
+	if err != nil {
+		return "", false, err
+	}
+	for _, batchID := range batchIDs {
+		meta, err := im.store.GetBatchMeta(batchID)
+		if err != nil || meta.StartBlock != startBlock || meta.EndBlock != endBlock || meta.RootHash != rootHash {
+			continue
+		}
+		attempt, err := im.store.GetAttempt(batchID)
+		if err != nil {
+			return "", false, err
+		}
+		if attempt.Status == types.BatchStatusAnchored && attempt.AnchorTxHash == txHash && attempt.AnchorBlock == block {
+			return batchID, false, nil
+		}
+		changed := attempt.Status != types.BatchStatusAnchored
+		attempt.Status = types.BatchStatusAnchored
+		attempt.AnchorTxHash = txHash
+		attempt.AnchorBlock = block
+		return batchID, changed, im.store.SaveAttempt(attempt)
+	}
+	return "", false, ErrNotFound
+}
+
+// UnmarkAnchoredSince reverts the batches anchored in block or later, whose
+// anchors a reorg removed, to their status before anchoring, and returns
+// their IDs
+func (im *IndexManager) UnmarkAnchoredSince(block uint64) ([]string, error) {
+	batchIDs, err := im.store.GetBatchesByStatus(types.BatchStatusAnchored)
+	if err != nil {
+		return nil, err
+	}
+	var reverted []string
+	for _, batchID := range batchIDs {
+		attempt, err := im.store.GetAttempt(batchID)
+		if err != nil || attempt.AnchorBlock < block {
+			continue
+		}
+		// A batch anchored by another node may not be confirmed locally yet
+		attempt.Status = types.BatchStatusConfirmed
+		if attempt.BTCTxID == "" {
+			attempt.Status = types.BatchStatusSubmitted
+		}
+		attempt.AnchorTxHash = common.Hash{}
+		attempt.AnchorBlock = 0
+		if err := im.store.SaveAttempt(attempt); err != nil {
+			return reverted, err
+		}
+		reverted = append(reverted, batchID)
+	}
+	return reverted, nil
+}
+
+// BatchCursor is a position in the batch listing order, which sorts batches
+// by start block and then by batch ID. Both are immutable, so the order of
+// listed batches never changes.
+type BatchCursor struct {
+	StartBlock uint64
+	BatchID    string
+}
+
+// before reports whether the batch sorts before the cursor position or at it
+func (c *BatchCursor) before(meta *types.BatchMeta) bool {
+	if meta.StartBlock != c.StartBlock {
+		return meta.StartBlock < c.StartBlock
+	}
+	return meta.BatchID <= c.BatchID
+}
+
+// ListBatches calls fn with the batches overlapping fromBlock..toBlock in
+// listing order, starting after the cursor if one is given, until fn returns
+// false. A zero toBlock leaves the range open. If statuses are given only the
+// batches in one of them are listed. Batches are found through the start
+// block index, so a page only loads the batches it lists.
+func (im *IndexManager) ListBatches(statuses []types.BatchStatus, fromBlock, toBlock uint64, after *BatchCursor, fn func(*types.BatchMeta) bool) error {
+	listed := func(meta *types.BatchMeta) bool {
+		return after == nil || !after.before(meta)
+	}
+
+	// The status index only yields batch IDs, which filter the walk
+	var selected map[string]bool
+	if len(statuses) > 0 {
+		selected = make(map[string]bool)
+		for _, status := range statuses {
+			batchIDs, err := im.store.GetBatchesByStatus(status)
+			if err != nil {
+				return err
+			}
+			for _, batchID := range batchIDs {
+				selected[batchID] = true
+			}
+		}
+		if len(selected) == 0 {
+			return nil
+		}
+	}
+	wanted := func(batchID string) bool {
+		return selected == nil || selected[batchID]
+	}
+
+	// Batches that started before the range but reach into it come first
+	if after == nil || after.StartBlock < fromBlock {
+		batchIDs, err := im.store.GetBatchesInBlockRange(fromBlock, fromBlock)
+		if err != nil {
+			return err
+		}
+		var metas []*types.BatchMeta
+		for _, batchID := range batchIDs {
+			if !wanted(batchID) {
+				continue
+			}
+			meta, err := im.store.GetBatchMeta(batchID)
+			if err != nil || meta.StartBlock >= fromBlock || !listed(meta) {
+				continue
+			}
+			metas = append(metas, meta)
+		}
+		sortBatchMetas(metas)
+		for _, meta := range metas {
+			if !fn(meta) {
+				return nil
+			}
+		}
+	}
+
+	// Walk the start block index from the range start or the cursor
+	startBlock, startID := fromBlock, ""
+	if after != nil && after.StartBlock >= fromBlock {
+		startBlock, startID = after.StartBlock, after.BatchID+"\x00"
+	}
+	return im.store.iterateStartIndex(startBlock, startID, func(block uint64, batchID string) bool {
+		if toBlock != 0 && block > toBlock {
+			return false
+		}
+		if !wanted(batchID) {
+			return true
+		}
+		meta, err := im.store.GetBatchMeta(batchID)
+		if err != nil {
+			return true
+		}
+		return fn(meta)
+	})
+}
+
+// sortBatchMetas sorts batches into listing order
+func sortBatchMetas(metas []*types.BatchMeta) {
+	sort.Slice(metas, func(i, j int) bool {
+		if metas[i].StartBlock != metas[j].StartBlock {
+			return metas[i].StartBlock < metas[j].StartBlock
+		}
+		return metas[i].BatchID < metas[j].BatchID
+	})
+}
+
 // FindBatchForRUID finds which batch contains a given RUID
 // This requires scanning through batches since we don't store ruidToBatch on-chain
 func (im *IndexManager) FindBatchForRUID(ruid common.Hash, startBlock, endBlock uint64) (string, error) {
@@ -0,0 +1,48 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package storage
+
+// SavePendingTimestamp saves the OTS service record of a pending digest
+func (s *Store) SavePendingTimestamp(digest [32]byte, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := append(append([]byte{}, prefixPendingTimestamp...), digest[:]...)
+	return s.db.Put(key, data)
+}
+
+// DeletePendingTimestamp removes the record of a pending digest
+func (s *Store) DeletePendingTimestamp(digest [32]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := append(append([]byte{}, prefixPendingTimestamp...), digest[:]...)
+	return s.db.Delete(key)
+}
+
+// IteratePendingTimestamps calls fn for every pending digest record. Iteration
+// stops at the first error, which is returned. fn must not modify the store.
+func (s *Store) IteratePendingTimestamps(fn func(digest [32]byte, data []byte) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	iter := s.db.NewIterator(prefixPendingTimestamp, nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) != len(prefixPendingTimestamp)+32 {
+			return ErrCorrupted
+		}
+		var digest [32]byte
+		copy(digest[:], key[len(prefixPendingTimestamp):])
+
+		data := make([]byte, len(iter.Value()))
+		copy(data, iter.Value())
+		if err := fn(digest, data); err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
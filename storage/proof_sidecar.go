@@ -0,0 +1,48 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+//
+// This file implements the proof sidecar: upgraded OTS proofs stored under
+// their keccak256 hash, which otsConfirmed transactions commit to. Entries are
+// content-addressed, so a proof fetched from any peer can be checked against
+// the on-chain commitment.
+
+package storage
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ProofHash returns the sidecar key of an OTS proof
+func ProofHash(proof []byte) common.Hash {
+	return crypto.Keccak256Hash(proof)
+}
+
+// SaveProofSidecar stores an OTS proof under its hash and returns the hash
+func (s *Store) SaveProofSidecar(proof []byte) (common.Hash, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	proofHash := ProofHash(proof)
+	key := append(append([]byte{}, prefixProofSidecar...), proofHash[:]...)
+	if err := s.db.Put(key, proof); err != nil {
+		return common.Hash{}, err
+	}
+	return proofHash, nil
+}
+
+// GetProofSidecar retrieves the OTS proof with the given hash
+func (s *Store) GetProofSidecar(proofHash common.Hash) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key := append(append([]byte{}, prefixProofSidecar...), proofHash[:]...)
+	data, err := s.db.Get(key)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if ProofHash(data) != proofHash {
+		return nil, ErrCorrupted
+	}
+	return data, nil
+}
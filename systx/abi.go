@@ -0,0 +1,85 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+//
+// This file loads the CopyrightRegistry contract ABI that system transaction
+// calldata is encoded and decoded with. Supporting a new contract method only
+// needs its ABI entry and a signature constant here.
+
+package systx
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Signatures of the CopyrightRegistry methods called by system transactions,
+// and of the events the node reads back
+const (
+	anchorMethodSig             = "anchor(uint64,uint64,bytes32,bytes32,uint64)"
+	anchorBatchMethodSig        = "anchorBatch(uint64[],uint64[],bytes32[],bytes32[],uint64[])"
+	otsSubmittedMethodSig       = "otsSubmitted(bytes32,bytes32)"
+	otsConfirmedMethodSig       = "otsConfirmed(bytes32,uint64,bytes32,uint64,bytes32)"
+	otsConfirmedLegacyMethodSig = "otsConfirmed(bytes32,uint64,bytes32,uint64)"
+
+	anchoredEventSig = "Anchored(bytes32,uint64,uint64,uint64)"
+)
+
+//go:embed copyright_registry.abi.json
+var registryABIJSON []byte
+
+var (
+	// registryABI is the parsed CopyrightRegistry ABI
+	registryABI = mustParseABI(registryABIJSON)
+
+	anchorMethod             = registryMethod(anchorMethodSig)
+	anchorBatchMethod        = registryMethod(anchorBatchMethodSig)
+	otsSubmittedMethod       = registryMethod(otsSubmittedMethodSig)
+	otsConfirmedMethod       = registryMethod(otsConfirmedMethodSig)
+	otsConfirmedLegacyMethod = registryMethod(otsConfirmedLegacyMethodSig)
+
+	anchoredEvent = registryEvent(anchoredEventSig)
+)
+
+// mustParseABI parses the embedded contract ABI
+func mustParseABI(data []byte) abi.ABI {
+	parsed, err := abi.JSON(bytes.NewReader(data))
+	if err != nil {
+		panic(fmt.Sprintf("systx: invalid CopyrightRegistry ABI: %v", err))
+	}
+	return parsed
+}
+
+// registryMethod returns the CopyrightRegistry method with the given
+// signature. Overloaded methods are looked up by signature since the ABI
+// parser renames all but the first.
+func registryMethod(sig string) abi.Method {
+	for _, method := range registryABI.Methods {
+		if method.Sig == sig {
+			return method
+		}
+	}
+	panic(fmt.Sprintf("systx: CopyrightRegistry ABI has no method %s", sig))
+}
+
+// registryEvent returns the CopyrightRegistry event with the given signature
+func registryEvent(sig string) abi.Event {
+	for _, event := range registryABI.Events {
+		if event.Sig == sig {
+			return event
+		}
+	}
+	panic(fmt.Sprintf("systx: CopyrightRegistry ABI has no event %s", sig))
+}
+
+// packCall encodes a call of method with the given arguments
+func packCall(method abi.Method, args ...interface{}) ([]byte, error) {
+	packed, err := method.Inputs.Pack(args...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrBuildFailed, method.Sig, err)
+	}
+	return append(common.CopyBytes(method.ID), packed...), nil
+}
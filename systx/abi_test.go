@@ -0,0 +1,204 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package systx
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	otstypes "github.com/ethereum/go-ethereum/ots/types"
+)
+
+// TestGoldenCalldata pins the calldata of every system transaction kind
+// byte-for-byte, as produced by the hand-packed encoders the ABI replaced
+func TestGoldenCalldata(t *testing.T) {
+	builder := NewBuilder(common.HexToAddress("0x9000"))
+	mustTx := func(tx *types.Transaction, err error) []byte {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("build failed: %v", err)
+		}
+		return tx.Data()
+	}
+
+	tests := []struct {
+		name   string
+		encode func() ([]byte, error)
+		want   string
+	}{
+		{
+			name: "anchor",
+			encode: func() ([]byte, error) {
+				return builder.encodeCalldata(&otstypes.CandidateBatch{
+					BatchMeta: &otstypes.BatchMeta{
+						StartBlock: 100,
+						EndBlock:   200,
+						RootHash:   common.HexToHash("0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef"),
+					},
+					BTCTxID:      "deadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef",
+					BTCTimestamp: 1700000000,
+				})
+			},
+			want: "a0514efe" +
+				"0000000000000000000000000000000000000000000000000000000000000064" +
+				"00000000000000000000000000000000000000000000000000000000000000c8" +
+				"1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef" +
+				"deadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef" +
+				"000000000000000000000000000000000000000000000000000000006553f100",
+		},
+		{
+			name: "anchor empty batch",
+			encode: func() ([]byte, error) {
+				return builder.encodeCalldata(&otstypes.CandidateBatch{
+					BatchMeta: &otstypes.BatchMeta{StartBlock: 1, EndBlock: 100},
+				})
+			},
+			want: "a0514efe" +
+				"0000000000000000000000000000000000000000000000000000000000000001" +
+				"0000000000000000000000000000000000000000000000000000000000000064" +
+				"0000000000000000000000000000000000000000000000000000000000000000" +
+				"0000000000000000000000000000000000000000000000000000000000000000" +
+				"0000000000000000000000000000000000000000000000000000000000000000",
+		},
+		{
+			name: "anchor consensus",
+			encode: func() ([]byte, error) {
+				return mustTx(builder.BuildAnchorTx(&CandidateBatch{
+					StartBlock:   1001,
+					EndBlock:     2000,
+					RootHash:     common.HexToHash("0xaa"),
+					BTCTxID:      "0x00000000000000000002a7c4c1e48d76c5a37902165a270156b7a8d72728a054",
+					BTCTimestamp: 1700000123,
+				}, common.Address{}, 0, 0)), nil
+			},
+			want: "a0514efe" +
+				"00000000000000000000000000000000000000000000000000000000000003e9" +
+				"00000000000000000000000000000000000000000000000000000000000007d0" +
+				"00000000000000000000000000000000000000000000000000000000000000aa" +
+				"00000000000000000002a7c4c1e48d76c5a37902165a270156b7a8d72728a054" +
+				"000000000000000000000000000000000000000000000000000000006553f17b",
+		},
+		{
+			name: "anchorBatch",
+			encode: func() ([]byte, error) {
+				return mustTx(builder.BuildAnchorBatchTx(newAnchorBatch(2), common.Address{}, 0, 500000)), nil
+			},
+			want: "5f7f20b7" +
+				// Array offsets
+				"00000000000000000000000000000000000000000000000000000000000000a0" +
+				"0000000000000000000000000000000000000000000000000000000000000100" +
+				"0000000000000000000000000000000000000000000000000000000000000160" +
+				"00000000000000000000000000000000000000000000000000000000000001c0" +
+				"0000000000000000000000000000000000000000000000000000000000000220" +
+				// startBlocks
+				"0000000000000000000000000000000000000000000000000000000000000002" +
+				"0000000000000000000000000000000000000000000000000000000000000001" +
+				"0000000000000000000000000000000000000000000000000000000000000065" +
+				// endBlocks
+				"0000000000000000000000000000000000000000000000000000000000000002" +
+				"0000000000000000000000000000000000000000000000000000000000000064" +
+				"00000000000000000000000000000000000000000000000000000000000000c8" +
+				// batchRoots
+				"0000000000000000000000000000000000000000000000000000000000000002" +
+				"0000000000000000000000000000000000000000000000000000000000000001" +
+				"0000000000000000000000000000000000000000000000000000000000000002" +
+				// btcTxHashes
+				"0000000000000000000000000000000000000000000000000000000000000002" +
+				"0000000000000000000000000000000000000000000000000000000000000001" +
+				"0000000000000000000000000000000000000000000000000000000000000002" +
+				// btcTimestamps
+				"0000000000000000000000000000000000000000000000000000000000000002" +
+				"000000000000000000000000000000000000000000000000000000006553f100" +
+				"000000000000000000000000000000000000000000000000000000006553f101",
+		},
+		{
+			name: "otsSubmitted",
+			encode: func() ([]byte, error) {
+				return mustTx(builder.BuildOTSSubmittedTx(&OTSSubmittedParams{
+					RootHash:  common.HexToHash("0xaa"),
+					OTSDigest: [32]byte{0xbb, 0xcc},
+				}, common.Address{}, 0, 0)), nil
+			},
+			want: "9cb230ed" +
+				"00000000000000000000000000000000000000000000000000000000000000aa" +
+				"bbcc000000000000000000000000000000000000000000000000000000000000",
+		},
+		{
+			name: "otsConfirmed",
+			encode: func() ([]byte, error) {
+				return mustTx(builder.BuildOTSConfirmedTx(&OTSConfirmedParams{
+					RootHash:       common.HexToHash("0xaa"),
+					BTCBlockHeight: 800000,
+					BTCTxID:        [32]byte{0xbb},
+					BTCTimestamp:   1700000000,
+					ProofHash:      common.HexToHash("0xcc"),
+				}, common.Address{}, 0, 0)), nil
+			},
+			want: "e6afe963" +
+				"00000000000000000000000000000000000000000000000000000000000000aa" +
+				"00000000000000000000000000000000000000000000000000000000000c3500" +
+				"bb00000000000000000000000000000000000000000000000000000000000000" +
+				"000000000000000000000000000000000000000000000000000000006553f100" +
+				"00000000000000000000000000000000000000000000000000000000000000cc",
+		},
+		{
+			name: "otsConfirmed legacy",
+			encode: func() ([]byte, error) {
+				return mustTx(builder.BuildOTSConfirmedTx(&OTSConfirmedParams{
+					RootHash:       common.HexToHash("0xaa"),
+					BTCBlockHeight: 800000,
+					BTCTxID:        [32]byte{0xbb},
+					BTCTimestamp:   1700000000,
+				}, common.Address{}, 0, 0)), nil
+			},
+			want: "46bab705" +
+				"00000000000000000000000000000000000000000000000000000000000000aa" +
+				"00000000000000000000000000000000000000000000000000000000000c3500" +
+				"bb00000000000000000000000000000000000000000000000000000000000000" +
+				"000000000000000000000000000000000000000000000000000000006553f100",
+		},
+	}
+	for _, tt := range tests {
+		data, err := tt.encode()
+		if err != nil {
+			t.Fatalf("%s: encoding failed: %v", tt.name, err)
+		}
+		if want := common.FromHex(tt.want); !bytes.Equal(data, want) {
+			t.Errorf("%s: calldata mismatch\n got %x\nwant %x", tt.name, data, want)
+		}
+
+		// Every golden encoding decodes as a valid system call
+		if _, err := DecodeSystemCall(data); err != nil {
+			t.Errorf("%s: DecodeSystemCall failed: %v", tt.name, err)
+		}
+	}
+}
+
+func TestRegistryABI(t *testing.T) {
+	tests := []struct {
+		selector []byte
+		want     string
+	}{
+		{AnchorSelector, "a0514efe"},
+		{AnchorBatchSelector, "5f7f20b7"},
+		{OTSSubmittedSelector, "9cb230ed"},
+		{OTSConfirmedSelector, "e6afe963"},
+		{OTSConfirmedLegacySelector, "46bab705"},
+	}
+	for _, tt := range tests {
+		if !bytes.Equal(tt.selector, common.FromHex(tt.want)) {
+			t.Errorf("selector %x, want %s", tt.selector, tt.want)
+		}
+	}
+
+	// Encoding rejects arguments that do not match the ABI types
+	if _, err := packCall(otsSubmittedMethod, common.Hash{}); err == nil {
+		t.Error("expected error packing too few arguments")
+	}
+	if _, err := packCall(anchorMethod, "1", uint64(2), common.Hash{}, common.Hash{}, uint64(0)); err == nil {
+		t.Error("expected error packing a string as uint64")
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package systx
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	ErrNotAnchoredLog = errors.New("systx: not an Anchored event log")
+
+	// AnchoredEventTopic is the topic of Anchored event logs
+	AnchoredEventTopic = anchoredEvent.ID
+)
+
+// AnchoredEvent is an Anchored event of the CopyrightRegistry. The contract
+// emits one per anchored batch, for anchor and anchorBatch calls alike.
+//
+// event Anchored(bytes32 indexed rootHash, uint64 startBlock, uint64 endBlock, uint64 btcBlockHeight)
+type AnchoredEvent struct {
+	RootHash       common.Hash
+	StartBlock     uint64
+	EndBlock       uint64
+	BTCBlockHeight uint64
+
+	// TxHash and BlockNumber locate the anchoring system transaction
+	TxHash      common.Hash
+	BlockNumber uint64
+}
+
+// ParseAnchoredLog decodes an Anchored event log. The location fields of the
+// event are left to the caller.
+func ParseAnchoredLog(log *types.Log) (*AnchoredEvent, error) {
+	if len(log.Topics) != 2 || log.Topics[0] != anchoredEvent.ID {
+		return nil, ErrNotAnchoredLog
+	}
+	args, err := anchoredEvent.Inputs.NonIndexed().Unpack(log.Data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotAnchoredLog, err)
+	}
+	return &AnchoredEvent{
+		RootHash:       log.Topics[1],
+		StartBlock:     args[0].(uint64),
+		EndBlock:       args[1].(uint64),
+		BTCBlockHeight: args[2].(uint64),
+	}, nil
+}
+
+// AnchoredEvents returns the Anchored events the registry emitted in the
+// successful transactions of a block's receipts
+func AnchoredEvents(number uint64, receipts types.Receipts, registry common.Address) []*AnchoredEvent {
+	var events []*AnchoredEvent
+	for _, receipt := range receipts {
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			continue
+		}
+		for _, log := range receipt.Logs {
+			if log.Address != registry {
+				continue
+			}
+			event, err := ParseAnchoredLog(log)
+			if err != nil {
+				continue
+			}
+			event.TxHash, event.BlockNumber = receipt.TxHash, number
+			events = append(events, event)
+		}
+	}
+	return events
+}
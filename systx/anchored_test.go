@@ -0,0 +1,79 @@
+// Copyright 2024 The RMC Authors
+// This file is part of the RMC library.
+
+package systx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// anchoredLog returns an Anchored event log of the registry at address
+func anchoredLog(address common.Address, rootHash common.Hash, start, end, btcHeight uint64) *types.Log {
+	var data []byte
+	for _, n := range []uint64{start, end, btcHeight} {
+		data = append(data, uint64Word(n).Bytes()...)
+	}
+	return &types.Log{
+		Address: address,
+		Topics:  []common.Hash{AnchoredEventTopic, rootHash},
+		Data:    data,
+	}
+}
+
+func TestParseAnchoredLog(t *testing.T) {
+	if want := crypto.Keccak256Hash([]byte("Anchored(bytes32,uint64,uint64,uint64)")); AnchoredEventTopic != want {
+		t.Fatalf("AnchoredEventTopic = %x, want %x", AnchoredEventTopic, want)
+	}
+
+	event, err := ParseAnchoredLog(anchoredLog(testRegistry, testRootHash, 100, 199, 800000))
+	if err != nil {
+		t.Fatalf("ParseAnchoredLog failed: %v", err)
+	}
+	if event.RootHash != testRootHash || event.StartBlock != 100 || event.EndBlock != 199 || event.BTCBlockHeight != 800000 {
+		t.Errorf("unexpected event: %+v", event)
+	}
+
+	malformed := []*types.Log{
+		{Address: testRegistry, Topics: []common.Hash{{0x01}, testRootHash}, Data: make([]byte, 96)},
+		{Address: testRegistry, Topics: []common.Hash{AnchoredEventTopic}, Data: make([]byte, 96)},
+		{Address: testRegistry, Topics: []common.Hash{AnchoredEventTopic, testRootHash}, Data: make([]byte, 64)},
+	}
+	for i, log := range malformed {
+		if _, err := ParseAnchoredLog(log); !errors.Is(err, ErrNotAnchoredLog) {
+			t.Errorf("log %d: expected ErrNotAnchoredLog, got %v", i, err)
+		}
+	}
+}
+
+func TestAnchoredEvents(t *testing.T) {
+	receipts := types.Receipts{
+		{
+			Status: types.ReceiptStatusSuccessful,
+			TxHash: common.Hash{0x01},
+			Logs: []*types.Log{
+				anchoredLog(testRegistry, common.Hash{0xa1}, 1, 10, 800000),
+				anchoredLog(common.HexToAddress("0x9001"), common.Hash{0xa2}, 11, 20, 800000),
+				anchoredLog(testRegistry, common.Hash{0xa3}, 11, 20, 800001),
+			},
+		},
+		{
+			Status: types.ReceiptStatusFailed,
+			TxHash: common.Hash{0x02},
+			Logs:   []*types.Log{anchoredLog(testRegistry, common.Hash{0xa4}, 21, 30, 800002)},
+		},
+	}
+	events := AnchoredEvents(42, receipts, testRegistry)
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	for i, root := range []common.Hash{{0xa1}, {0xa3}} {
+		if events[i].RootHash != root || events[i].TxHash != (common.Hash{0x01}) || events[i].BlockNumber != 42 {
+			t.Errorf("event %d: unexpected %+v", i, events[i])
+		}
+	}
+}
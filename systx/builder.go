@@ -8,12 +8,11 @@ package systx
 
 import (
 	"errors"
+	"fmt"
 	"math/big"
 
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	otstypes "github.com/ethereum/go-ethereum/ots/types"
 )
@@ -22,22 +21,21 @@ var (
 	ErrInvalidCandidate = errors.New("systx: invalid candidate batch")
 	ErrRootMismatch     = errors.New("systx: root hash mismatch during validation")
 	ErrBuildFailed      = errors.New("systx: failed to build transaction")
+	ErrBatchGasLimit    = errors.New("systx: anchor batch exceeds the gas limit")
 )
 
-// anchor function signature
-// function anchor(
-//     uint64  startBlock,
-//     uint64  endBlock,
-//     bytes32 batchRoot,
-//     bytes32 btcTxHash,
-//     uint64  btcTimestamp
-// ) external onlyInit onlyCoinbase onlySystemTx;
-var anchorSig = crypto.Keccak256([]byte("anchor(uint64,uint64,bytes32,bytes32,uint64)"))[:4]
+// Gas costs of the anchor calls, see EstimateGas
+const (
+	anchorCallGas     = 30000       // Contract call overhead
+	anchorStateGas    = 5000        // lastAnchoredEndBlock and batchCount, written once per call
+	anchorRecordGas   = 20000       // New BatchRecord
+	anchorEventGas    = 5000        // Anchored event
+	anchorCalldataGas = 5 * 32 * 16 // Five calldata words, all bytes non-zero at worst
+)
 
 // Builder constructs system transactions for OTS anchoring
 type Builder struct {
 	contractAddress common.Address
-	contractABI     abi.ABI
 }
 
 // NewBuilder creates a new system transaction builder
@@ -96,55 +94,28 @@ func (b *Builder) BuildSystemTx(
 
 // encodeCalldata encodes the anchor function call
 func (b *Builder) encodeCalldata(candidate *otstypes.CandidateBatch) ([]byte, error) {
-	// Manual ABI encoding for anchor(uint64,uint64,bytes32,bytes32,uint64)
-	//
-	// Layout:
-	// - 4 bytes: function selector
-	// - 32 bytes: startBlock (uint64 padded to 32 bytes)
-	// - 32 bytes: endBlock (uint64 padded to 32 bytes)
-	// - 32 bytes: batchRoot (bytes32)
-	// - 32 bytes: btcTxHash (bytes32)
-	// - 32 bytes: btcTimestamp (uint64 padded to 32 bytes)
-
-	dataSize := 4 + 32*5
-	data := make([]byte, dataSize)
-
-	offset := 0
-
-	// Function selector
-	copy(data[offset:offset+4], anchorSig)
-	offset += 4
-
-	// startBlock (uint64)
-	startValue := new(big.Int).SetUint64(candidate.StartBlock)
-	copy(data[offset+32-len(startValue.Bytes()):offset+32], startValue.Bytes())
-	offset += 32
-
-	// endBlock (uint64)
-	endValue := new(big.Int).SetUint64(candidate.EndBlock)
-	copy(data[offset+32-len(endValue.Bytes()):offset+32], endValue.Bytes())
-	offset += 32
-
-	// batchRoot (bytes32) - can be 0 for empty batches
-	copy(data[offset:offset+32], candidate.RootHash[:])
-	offset += 32
-
-	// btcTxHash (bytes32) - convert BTCTxID string to bytes32
-	btcTxHash := btcTxIDToBytes32(candidate.BTCTxID)
-	copy(data[offset:offset+32], btcTxHash[:])
-	offset += 32
-
-	// btcTimestamp (uint64)
-	tsValue := new(big.Int).SetUint64(candidate.BTCTimestamp)
-	copy(data[offset+32-len(tsValue.Bytes()):offset+32], tsValue.Bytes())
-
-	return data, nil
+	return packAnchorCall(candidate.StartBlock, candidate.EndBlock, candidate.RootHash, candidate.BTCTxID, candidate.BTCTimestamp)
+}
+
+// packAnchorCall encodes the anchor function call
+//
+//	function anchor(
+//	    uint64  startBlock,
+//	    uint64  endBlock,
+//	    bytes32 batchRoot,
+//	    bytes32 btcTxHash,
+//	    uint64  btcTimestamp
+//	) external onlyInit onlyCoinbase onlySystemTx;
+//
+// Empty batches have a zero batchRoot, btcTxHash and btcTimestamp.
+func packAnchorCall(startBlock, endBlock uint64, batchRoot common.Hash, btcTxID string, btcTimestamp uint64) ([]byte, error) {
+	return packCall(anchorMethod, startBlock, endBlock, batchRoot, BTCTxIDToBytes32(btcTxID), btcTimestamp)
 }
 
-// btcTxIDToBytes32 converts a Bitcoin transaction ID (hex string) to bytes32.
+// BTCTxIDToBytes32 converts a Bitcoin transaction ID (hex string) to bytes32.
 // Bitcoin txids are 32 bytes displayed as 64 hex characters.
 // Returns zero bytes32 if the input is empty or invalid.
-func btcTxIDToBytes32(txid string) common.Hash {
+func BTCTxIDToBytes32(txid string) common.Hash {
 	if txid == "" {
 		return common.Hash{}
 	}
@@ -177,20 +148,87 @@ func (b *Builder) ValidateCandidate(candidate *otstypes.CandidateBatch, computed
 	return nil
 }
 
-// EstimateGas estimates the gas required for the system transaction.
-// The anchor function has fixed parameters (no dynamic arrays), so gas is predictable.
-func (b *Builder) EstimateGas() uint64 {
-	// Base cost: ~30,000 gas for contract call overhead
-	baseCost := uint64(30000)
+// EstimateGas estimates the gas required for a system transaction anchoring
+// the given number of batches. The call overhead and the anchor counters are
+// paid once, the batch record, event and calldata per batch.
+func (b *Builder) EstimateGas(batches int) uint64 {
+	if batches <= 0 {
+		return 0
+	}
+	perBatch := uint64(anchorRecordGas + anchorEventGas + anchorCalldataGas)
+	return anchorCallGas + anchorStateGas + uint64(batches)*perBatch
+}
+
+// MaxAnchorBatch returns the number of batches a system transaction with the
+// given gas limit can anchor
+func (b *Builder) MaxAnchorBatch(gasLimit uint64) int {
+	perBatch := uint64(anchorRecordGas + anchorEventGas + anchorCalldataGas)
+	if gasLimit < anchorCallGas+anchorStateGas+perBatch {
+		return 0
+	}
+	return int((gasLimit - anchorCallGas - anchorStateGas) / perBatch)
+}
+
+// BuildAnchorBatchTx constructs a system transaction anchoring several
+// candidate batches at once with
+// anchorBatch(startBlocks, endBlocks, batchRoots, btcTxHashes, btcTimestamps).
+// The candidates must cover ascending, non-overlapping block ranges and their
+// estimated gas must fit in gasLimit.
+func (b *Builder) BuildAnchorBatchTx(
+	candidates []*otstypes.CandidateBatch,
+	coinbase common.Address,
+	nonce uint64,
+	gasLimit uint64,
+) (*types.Transaction, error) {
+	if len(candidates) == 0 {
+		return nil, ErrInvalidCandidate
+	}
+	if gas := b.EstimateGas(len(candidates)); gas > gasLimit {
+		return nil, fmt.Errorf("%w: %d batches need %d gas, limit %d", ErrBatchGasLimit, len(candidates), gas, gasLimit)
+	}
 
-	// Storage cost: ~20,000 gas for new BatchRecord
-	storageCost := uint64(20000)
+	var (
+		startBlocks   = make([]uint64, len(candidates))
+		endBlocks     = make([]uint64, len(candidates))
+		batchRoots    = make([][32]byte, len(candidates))
+		btcTxHashes   = make([][32]byte, len(candidates))
+		btcTimestamps = make([]uint64, len(candidates))
+	)
+	for i, candidate := range candidates {
+		if candidate == nil || candidate.BatchMeta == nil {
+			return nil, ErrInvalidCandidate
+		}
+		if i > 0 && candidate.StartBlock <= candidates[i-1].EndBlock {
+			return nil, fmt.Errorf("%w: batch %s overlaps batch %s", ErrInvalidCandidate, candidate.BatchID, candidates[i-1].BatchID)
+		}
+		startBlocks[i] = candidate.StartBlock
+		endBlocks[i] = candidate.EndBlock
+		batchRoots[i] = candidate.RootHash
+		btcTxHashes[i] = BTCTxIDToBytes32(candidate.BTCTxID)
+		btcTimestamps[i] = candidate.BTCTimestamp
+	}
+	data, err := packCall(anchorBatchMethod, startBlocks, endBlocks, batchRoots, btcTxHashes, btcTimestamps)
+	if err != nil {
+		return nil, err
+	}
 
-	// State update: ~5,000 gas for lastAnchoredEndBlock and batchCount
-	stateUpdateCost := uint64(5000)
+	// Create transaction with zero gas price (system transaction)
+	tx := types.NewTransaction(
+		nonce,
+		b.contractAddress,
+		big.NewInt(0), // value = 0
+		gasLimit,
+		big.NewInt(0), // gasPrice = 0 (system transaction)
+		data,
+	)
 
-	// Event emission: ~5,000 gas
-	eventCost := uint64(5000)
+	log.Debug("OTS: Built anchor batch transaction",
+		"batches", len(candidates),
+		"txHash", tx.Hash().Hex(),
+		"startBlock", candidates[0].StartBlock,
+		"endBlock", candidates[len(candidates)-1].EndBlock,
+		"gasLimit", gasLimit,
+	)
 
-	return baseCost + storageCost + stateUpdateCost + eventCost
+	return tx, nil
 }
@@ -4,6 +4,8 @@
 package systx
 
 import (
+	"errors"
+	"fmt"
 	"math/big"
 	"testing"
 	"time"
@@ -13,18 +15,18 @@ import (
 	otstypes "github.com/ethereum/go-ethereum/ots/types"
 )
 
-func TestAnchorSig(t *testing.T) {
+func TestAnchorSelector(t *testing.T) {
 	// Verify the anchor function selector matches Solidity's
 	// anchor(uint64,uint64,bytes32,bytes32,uint64)
 	expectedSig := crypto.Keccak256([]byte("anchor(uint64,uint64,bytes32,bytes32,uint64)"))[:4]
 
-	if len(anchorSig) != 4 {
-		t.Fatalf("anchorSig should be 4 bytes, got %d", len(anchorSig))
+	if len(AnchorSelector) != 4 {
+		t.Fatalf("AnchorSelector should be 4 bytes, got %d", len(AnchorSelector))
 	}
 
 	for i := 0; i < 4; i++ {
-		if anchorSig[i] != expectedSig[i] {
-			t.Errorf("anchorSig[%d] = %x, want %x", i, anchorSig[i], expectedSig[i])
+		if AnchorSelector[i] != expectedSig[i] {
+			t.Errorf("AnchorSelector[%d] = %x, want %x", i, AnchorSelector[i], expectedSig[i])
 		}
 	}
 }
@@ -59,9 +61,9 @@ func TestBtcTxIDToBytes32(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := btcTxIDToBytes32(tt.input)
+			result := BTCTxIDToBytes32(tt.input)
 			if result != tt.expected {
-				t.Errorf("btcTxIDToBytes32(%q) = %s, want %s", tt.input, result.Hex(), tt.expected.Hex())
+				t.Errorf("BTCTxIDToBytes32(%q) = %s, want %s", tt.input, result.Hex(), tt.expected.Hex())
 			}
 		})
 	}
@@ -94,8 +96,8 @@ func TestEncodeCalldata(t *testing.T) {
 	}
 
 	// Verify function selector
-	if data[0] != anchorSig[0] || data[1] != anchorSig[1] ||
-		data[2] != anchorSig[2] || data[3] != anchorSig[3] {
+	if data[0] != AnchorSelector[0] || data[1] != AnchorSelector[1] ||
+		data[2] != AnchorSelector[2] || data[3] != AnchorSelector[3] {
 		t.Error("function selector mismatch")
 	}
 
@@ -251,7 +253,7 @@ func TestBuildSystemTx_NilBatchMeta(t *testing.T) {
 func TestEstimateGas(t *testing.T) {
 	builder := NewBuilder(common.HexToAddress("0x9000"))
 
-	gas := builder.EstimateGas()
+	gas := builder.EstimateGas(1)
 
 	// Should be a reasonable estimate (> 50000 for contract call + storage)
 	if gas < 50000 {
@@ -262,6 +264,82 @@ func TestEstimateGas(t *testing.T) {
 	if gas > 200000 {
 		t.Errorf("estimated gas %d seems too high", gas)
 	}
+
+	// Batching pays the call overhead once
+	if batched := builder.EstimateGas(10); batched >= 10*gas || batched <= gas {
+		t.Errorf("estimated gas for 10 batches %d, for one %d", batched, gas)
+	}
+
+	// MaxAnchorBatch is the largest batch count within the gas limit
+	for _, gasLimit := range []uint64{0, gas - 1, gas, 500000, 10000000} {
+		n := builder.MaxAnchorBatch(gasLimit)
+		if n > 0 && builder.EstimateGas(n) > gasLimit || builder.EstimateGas(n+1) <= gasLimit {
+			t.Errorf("gas limit %d: MaxAnchorBatch = %d", gasLimit, n)
+		}
+	}
+}
+
+// newAnchorBatch returns n confirmed candidates covering consecutive 100
+// block ranges
+func newAnchorBatch(n int) []*otstypes.CandidateBatch {
+	candidates := make([]*otstypes.CandidateBatch, n)
+	for i := range candidates {
+		candidates[i] = &otstypes.CandidateBatch{
+			BatchMeta: &otstypes.BatchMeta{
+				BatchID:    fmt.Sprintf("batch-%d", i),
+				StartBlock: uint64(100*i + 1),
+				EndBlock:   uint64(100 * (i + 1)),
+				RootHash:   common.BigToHash(big.NewInt(int64(i + 1))),
+			},
+			BTCTxID:      fmt.Sprintf("%064x", i+1),
+			BTCTimestamp: 1700000000 + uint64(i),
+		}
+	}
+	return candidates
+}
+
+func TestBuildAnchorBatchTx(t *testing.T) {
+	builder := NewBuilder(common.HexToAddress("0x9000"))
+	candidates := newAnchorBatch(3)
+
+	tx, err := builder.BuildAnchorBatchTx(candidates, common.Address{}, 7, 500000)
+	if err != nil {
+		t.Fatalf("BuildAnchorBatchTx failed: %v", err)
+	}
+	if !IsAnchorBatchTx(tx) || !IsOTSSystemTx(tx) || IsAnchorTx(tx) {
+		t.Error("anchorBatch transaction not recognized")
+	}
+	if tx.Nonce() != 7 || tx.Gas() != 500000 || tx.GasPrice().Sign() != 0 {
+		t.Errorf("unexpected transaction fields: nonce %d gas %d gasPrice %v", tx.Nonce(), tx.Gas(), tx.GasPrice())
+	}
+
+	decoded, err := DecodeAnchorBatchCalldata(tx.Data())
+	if err != nil {
+		t.Fatalf("DecodeAnchorBatchCalldata failed: %v", err)
+	}
+	if len(decoded) != len(candidates) {
+		t.Fatalf("decoded %d batches, want %d", len(decoded), len(candidates))
+	}
+	for i, batch := range decoded {
+		want := candidates[i]
+		if batch.StartBlock != want.StartBlock || batch.EndBlock != want.EndBlock || batch.RootHash != want.RootHash ||
+			batch.BTCTxHash != BTCTxIDToBytes32(want.BTCTxID) || batch.BTCTimestamp != want.BTCTimestamp {
+			t.Errorf("batch %d decoded as %+v", i, batch)
+		}
+	}
+
+	// Batches beyond the gas limit, overlapping or missing are rejected
+	if _, err := builder.BuildAnchorBatchTx(newAnchorBatch(3), common.Address{}, 0, builder.EstimateGas(2)); !errors.Is(err, ErrBatchGasLimit) {
+		t.Errorf("expected ErrBatchGasLimit, got %v", err)
+	}
+	overlapping := newAnchorBatch(2)
+	overlapping[1].StartBlock = overlapping[0].EndBlock
+	if _, err := builder.BuildAnchorBatchTx(overlapping, common.Address{}, 0, 500000); !errors.Is(err, ErrInvalidCandidate) {
+		t.Errorf("expected ErrInvalidCandidate for overlapping batches, got %v", err)
+	}
+	if _, err := builder.BuildAnchorBatchTx(nil, common.Address{}, 0, 500000); !errors.Is(err, ErrInvalidCandidate) {
+		t.Errorf("expected ErrInvalidCandidate for no batches, got %v", err)
+	}
 }
 
 func TestValidateCandidate(t *testing.T) {
@@ -293,3 +371,40 @@ func TestValidateCandidate(t *testing.T) {
 		t.Errorf("expected ErrRootMismatch, got %v", err)
 	}
 }
+
+func TestOTSConfirmedTx_Encodings(t *testing.T) {
+	builder := NewBuilder(common.HexToAddress("0x9000"))
+	params := &OTSConfirmedParams{
+		RootHash:       common.HexToHash("0xaa"),
+		BTCBlockHeight: 800000,
+		BTCTxID:        [32]byte{0xbb},
+		BTCTimestamp:   1700000000,
+	}
+
+	// Without a proof commitment the legacy encoding is used
+	for _, proofHash := range []common.Hash{{}, common.HexToHash("0xcc")} {
+		params.ProofHash = proofHash
+		tx, err := builder.BuildOTSConfirmedTx(params, common.Address{}, 0, 100000)
+		if err != nil {
+			t.Fatalf("BuildOTSConfirmedTx failed: %v", err)
+		}
+		selector, size := OTSConfirmedSelector, 4+32*5
+		if proofHash == (common.Hash{}) {
+			selector, size = OTSConfirmedLegacySelector, 4+32*4
+		}
+		if !matchSelector(tx.Data(), selector) || len(tx.Data()) != size {
+			t.Errorf("proofHash %x: selector %x size %d", proofHash, tx.Data()[:4], len(tx.Data()))
+		}
+		if !IsOTSConfirmedTx(tx) || ValidateOTSConfirmedTx(tx, common.HexToAddress("0x9000")) != nil {
+			t.Errorf("proofHash %x: not accepted as otsConfirmed", proofHash)
+		}
+
+		decoded, err := DecodeOTSConfirmedTx(tx)
+		if err != nil {
+			t.Fatalf("DecodeOTSConfirmedTx failed: %v", err)
+		}
+		if *decoded != *params {
+			t.Errorf("decoded %+v, want %+v", decoded, params)
+		}
+	}
+}
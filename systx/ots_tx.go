@@ -10,9 +10,9 @@ import (
 	"errors"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 )
 
@@ -21,13 +21,20 @@ var (
 
 	// Function selectors for OTS system transactions
 	// otsSubmitted(bytes32 rootHash, bytes32 otsDigest)
-	OTSSubmittedSelector = crypto.Keccak256([]byte("otsSubmitted(bytes32,bytes32)"))[:4]
+	OTSSubmittedSelector = otsSubmittedMethod.ID
+
+	// otsConfirmed(bytes32 rootHash, uint64 btcBlockHeight, bytes32 btcTxID, uint64 btcTimestamp, bytes32 proofHash)
+	OTSConfirmedSelector = otsConfirmedMethod.ID
 
 	// otsConfirmed(bytes32 rootHash, uint64 btcBlockHeight, bytes32 btcTxID, uint64 btcTimestamp)
-	OTSConfirmedSelector = crypto.Keccak256([]byte("otsConfirmed(bytes32,uint64,bytes32,uint64)"))[:4]
+	// Used when no proof is committed, and accepted from older blocks
+	OTSConfirmedLegacySelector = otsConfirmedLegacyMethod.ID
 
 	// anchor(uint64 startBlock, uint64 endBlock, bytes32 batchRoot, bytes32 btcTxHash, uint64 btcTimestamp)
-	anchorSelector = crypto.Keccak256([]byte("anchor(uint64,uint64,bytes32,bytes32,uint64)"))[:4]
+	AnchorSelector = anchorMethod.ID
+
+	// anchorBatch(uint64[] startBlocks, uint64[] endBlocks, bytes32[] batchRoots, bytes32[] btcTxHashes, uint64[] btcTimestamps)
+	AnchorBatchSelector = anchorBatchMethod.ID
 )
 
 // CandidateBatch contains batch data for anchor transaction (local definition to avoid circular imports)
@@ -53,6 +60,10 @@ type OTSConfirmedParams struct {
 	BTCBlockHeight uint64
 	BTCTxID        [32]byte
 	BTCTimestamp   uint64
+
+	// ProofHash is the keccak256 hash of the upgraded .ots proof, published in
+	// the proof sidecar store. Zero if no proof is committed.
+	ProofHash common.Hash
 }
 
 // BuildOTSSubmittedTx builds an otsSubmitted system transaction
@@ -61,11 +72,10 @@ func (b *Builder) BuildOTSSubmittedTx(params *OTSSubmittedParams, coinbase commo
 		return nil, ErrInvalidOTSTx
 	}
 
-	// Build calldata: selector + rootHash + otsDigest
-	calldata := make([]byte, 4+32+32)
-	copy(calldata[0:4], OTSSubmittedSelector)
-	copy(calldata[4:36], params.RootHash[:])
-	copy(calldata[36:68], params.OTSDigest[:])
+	calldata, err := packCall(otsSubmittedMethod, params.RootHash, params.OTSDigest)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create transaction with zero gas price (system transaction)
 	tx := types.NewTransaction(
@@ -86,22 +96,19 @@ func (b *Builder) BuildOTSConfirmedTx(params *OTSConfirmedParams, coinbase commo
 		return nil, ErrInvalidOTSTx
 	}
 
-	// Build calldata: selector + rootHash + btcBlockHeight + btcTxID + btcTimestamp
-	// Each parameter is padded to 32 bytes (ABI encoding)
-	calldata := make([]byte, 4+32+32+32+32)
-	copy(calldata[0:4], OTSConfirmedSelector)
-	copy(calldata[4:36], params.RootHash[:])
-
-	// btcBlockHeight (uint64 -> bytes32, right-aligned)
-	btcBlockHeightBytes := common.BigToHash(big.NewInt(int64(params.BTCBlockHeight)))
-	copy(calldata[36:68], btcBlockHeightBytes[:])
-
-	// btcTxID (bytes32)
-	copy(calldata[68:100], params.BTCTxID[:])
-
-	// btcTimestamp (uint64 -> bytes32, right-aligned)
-	btcTimestampBytes := common.BigToHash(big.NewInt(int64(params.BTCTimestamp)))
-	copy(calldata[100:132], btcTimestampBytes[:])
+	// Without a committed proof the legacy encoding is used
+	var (
+		calldata []byte
+		err      error
+	)
+	if params.ProofHash == (common.Hash{}) {
+		calldata, err = packCall(otsConfirmedLegacyMethod, params.RootHash, params.BTCBlockHeight, params.BTCTxID, params.BTCTimestamp)
+	} else {
+		calldata, err = packCall(otsConfirmedMethod, params.RootHash, params.BTCBlockHeight, params.BTCTxID, params.BTCTimestamp, params.ProofHash)
+	}
+	if err != nil {
+		return nil, err
+	}
 
 	// Create transaction with zero gas price (system transaction)
 	tx := types.NewTransaction(
@@ -122,39 +129,10 @@ func (b *Builder) BuildAnchorTx(candidate *CandidateBatch, coinbase common.Addre
 		return nil, ErrInvalidOTSTx
 	}
 
-	// Build calldata using the same encoding as the original anchor function
-	// anchor(uint64,uint64,bytes32,bytes32,uint64)
-	dataSize := 4 + 32*5
-	calldata := make([]byte, dataSize)
-
-	offset := 0
-
-	// Function selector
-	copy(calldata[offset:offset+4], anchorSelector[:])
-	offset += 4
-
-	// startBlock (uint64)
-	startValue := new(big.Int).SetUint64(candidate.StartBlock)
-	copy(calldata[offset+32-len(startValue.Bytes()):offset+32], startValue.Bytes())
-	offset += 32
-
-	// endBlock (uint64)
-	endValue := new(big.Int).SetUint64(candidate.EndBlock)
-	copy(calldata[offset+32-len(endValue.Bytes()):offset+32], endValue.Bytes())
-	offset += 32
-
-	// batchRoot (bytes32)
-	copy(calldata[offset:offset+32], candidate.RootHash[:])
-	offset += 32
-
-	// btcTxHash (bytes32)
-	btcTxHash := btcTxIDToBytes32Local(candidate.BTCTxID)
-	copy(calldata[offset:offset+32], btcTxHash[:])
-	offset += 32
-
-	// btcTimestamp (uint64)
-	tsValue := new(big.Int).SetUint64(candidate.BTCTimestamp)
-	copy(calldata[offset+32-len(tsValue.Bytes()):offset+32], tsValue.Bytes())
+	calldata, err := packAnchorCall(candidate.StartBlock, candidate.EndBlock, candidate.RootHash, candidate.BTCTxID, candidate.BTCTimestamp)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create transaction with zero gas price (system transaction)
 	tx := types.NewTransaction(
@@ -176,61 +154,46 @@ func (b *Builder) BuildAnchorTx(candidate *CandidateBatch, coinbase common.Addre
 	return tx, nil
 }
 
-// btcTxIDToBytes32Local converts a Bitcoin transaction ID to bytes32
-func btcTxIDToBytes32Local(txid string) common.Hash {
-	if txid == "" {
-		return common.Hash{}
+// DecodeOTSSubmittedTx decodes an otsSubmitted transaction, with the checks
+// of DecodeSystemCall
+func DecodeOTSSubmittedTx(tx *types.Transaction) (*OTSSubmittedParams, error) {
+	if !IsOTSSubmittedTx(tx) {
+		return nil, &CalldataError{Err: ErrUnknownSelector}
 	}
-	if len(txid) >= 2 && txid[:2] == "0x" {
-		txid = txid[2:]
+	call, err := decodeOTSSubmittedCall(tx.Data())
+	if err != nil {
+		return nil, err
 	}
-	return common.HexToHash(txid)
+	return call.Submitted, nil
 }
 
-// DecodeOTSSubmittedTx decodes an otsSubmitted transaction
-func DecodeOTSSubmittedTx(tx *types.Transaction) (*OTSSubmittedParams, error) {
-	data := tx.Data()
-	if len(data) < 68 {
-		return nil, ErrInvalidOTSTx
+// DecodeOTSConfirmedTx decodes an otsConfirmed transaction of either
+// overload, with the checks of DecodeSystemCall
+func DecodeOTSConfirmedTx(tx *types.Transaction) (*OTSConfirmedParams, error) {
+	method, ok := otsConfirmedMethodOf(tx.Data())
+	if !ok {
+		return nil, &CalldataError{Err: ErrUnknownSelector}
 	}
-
-	// Check selector
-	if !matchSelector(data[:4], OTSSubmittedSelector) {
-		return nil, ErrInvalidOTSTx
+	call, err := decodeOTSConfirmedCall(method, tx.Data())
+	if err != nil {
+		return nil, err
 	}
-
-	params := &OTSSubmittedParams{}
-	copy(params.RootHash[:], data[4:36])
-	copy(params.OTSDigest[:], data[36:68])
-
-	return params, nil
+	return call.Confirmed, nil
 }
 
-// DecodeOTSConfirmedTx decodes an otsConfirmed transaction
-func DecodeOTSConfirmedTx(tx *types.Transaction) (*OTSConfirmedParams, error) {
-	data := tx.Data()
-	if len(data) < 132 {
-		return nil, ErrInvalidOTSTx
+// otsConfirmedMethodOf returns the otsConfirmed overload selected by data,
+// and false if data is not an otsConfirmed call
+func otsConfirmedMethodOf(data []byte) (abi.Method, bool) {
+	if len(data) < 4 {
+		return abi.Method{}, false
 	}
-
-	// Check selector
-	if !matchSelector(data[:4], OTSConfirmedSelector) {
-		return nil, ErrInvalidOTSTx
+	switch {
+	case matchSelector(data[:4], OTSConfirmedSelector):
+		return otsConfirmedMethod, true
+	case matchSelector(data[:4], OTSConfirmedLegacySelector):
+		return otsConfirmedLegacyMethod, true
 	}
-
-	params := &OTSConfirmedParams{}
-	copy(params.RootHash[:], data[4:36])
-
-	// btcBlockHeight
-	params.BTCBlockHeight = common.BytesToHash(data[36:68]).Big().Uint64()
-
-	// btcTxID
-	copy(params.BTCTxID[:], data[68:100])
-
-	// btcTimestamp
-	params.BTCTimestamp = common.BytesToHash(data[100:132]).Big().Uint64()
-
-	return params, nil
+	return abi.Method{}, false
 }
 
 // IsOTSSubmittedTx checks if a transaction is an otsSubmitted system transaction
@@ -244,16 +207,13 @@ func IsOTSSubmittedTx(tx *types.Transaction) bool {
 
 // IsOTSConfirmedTx checks if a transaction is an otsConfirmed system transaction
 func IsOTSConfirmedTx(tx *types.Transaction) bool {
-	data := tx.Data()
-	if len(data) < 4 {
-		return false
-	}
-	return matchSelector(data[:4], OTSConfirmedSelector)
+	_, ok := otsConfirmedMethodOf(tx.Data())
+	return ok
 }
 
 // IsOTSSystemTx checks if a transaction is any OTS system transaction
 func IsOTSSystemTx(tx *types.Transaction) bool {
-	return IsOTSSubmittedTx(tx) || IsOTSConfirmedTx(tx) || IsAnchorTx(tx)
+	return IsOTSSubmittedTx(tx) || IsOTSConfirmedTx(tx) || IsAnchorTx(tx) || IsAnchorBatchTx(tx)
 }
 
 // IsAnchorTx checks if a transaction is an anchor system transaction
@@ -262,7 +222,16 @@ func IsAnchorTx(tx *types.Transaction) bool {
 	if len(data) < 4 {
 		return false
 	}
-	return matchSelector(data[:4], anchorSelector[:])
+	return matchSelector(data[:4], AnchorSelector)
+}
+
+// IsAnchorBatchTx checks if a transaction is an anchorBatch system transaction
+func IsAnchorBatchTx(tx *types.Transaction) bool {
+	data := tx.Data()
+	if len(data) < 4 {
+		return false
+	}
+	return matchSelector(data[:4], AnchorBatchSelector)
 }
 
 // DecodedAnchorCalldata represents decoded anchor parameters
@@ -275,44 +244,30 @@ type DecodedAnchorCalldata struct {
 	BTCTimestamp   uint64
 }
 
-// DecodeCalldata decodes anchor calldata from transaction data
+// DecodeCalldata decodes anchor calldata from transaction data, with the
+// checks of DecodeSystemCall
 func DecodeCalldata(data []byte) (*DecodedAnchorCalldata, error) {
-	// Minimum size: 4 (selector) + 32*5 (5 fixed params)
-	if len(data) < 4+32*5 {
-		return nil, ErrInvalidOTSTx
+	if len(data) < 4 || !matchSelector(data[:4], AnchorSelector) {
+		return nil, &CalldataError{Err: ErrUnknownSelector}
+	}
+	call, err := decodeAnchorCall(data)
+	if err != nil {
+		return nil, err
 	}
+	return call.Anchor, nil
+}
 
-	// Skip function selector
-	offset := 4
-
-	// startBlock (uint64)
-	startBlock := new(big.Int).SetBytes(data[offset : offset+32]).Uint64()
-	offset += 32
-
-	// endBlock (uint64)
-	endBlock := new(big.Int).SetBytes(data[offset : offset+32]).Uint64()
-	offset += 32
-
-	// batchRoot (bytes32)
-	var rootHash common.Hash
-	copy(rootHash[:], data[offset:offset+32])
-	offset += 32
-
-	// btcTxHash (bytes32)
-	var btcTxHash common.Hash
-	copy(btcTxHash[:], data[offset:offset+32])
-	offset += 32
-
-	// btcTimestamp (uint64)
-	btcTimestamp := new(big.Int).SetBytes(data[offset : offset+32]).Uint64()
-
-	return &DecodedAnchorCalldata{
-		StartBlock:   startBlock,
-		EndBlock:     endBlock,
-		RootHash:     rootHash,
-		BTCTxHash:    btcTxHash,
-		BTCTimestamp: btcTimestamp,
-	}, nil
+// DecodeAnchorBatchCalldata decodes anchorBatch calldata into the anchored
+// batches, in calldata order, with the checks of DecodeSystemCall
+func DecodeAnchorBatchCalldata(data []byte) ([]*DecodedAnchorCalldata, error) {
+	if len(data) < 4 || !matchSelector(data[:4], AnchorBatchSelector) {
+		return nil, &CalldataError{Err: ErrUnknownSelector}
+	}
+	call, err := decodeAnchorBatchCall(data)
+	if err != nil {
+		return nil, err
+	}
+	return call.AnchorBatch, nil
 }
 
 // matchSelector compares two byte slices for selector matching
@@ -351,7 +306,7 @@ func ValidateOTSSubmittedTx(tx *types.Transaction, contractAddr common.Address)
 	}
 
 	// Check data length
-	if len(tx.Data()) < 68 {
+	if len(tx.Data()) < 4+32*len(otsSubmittedMethod.Inputs) {
 		return ErrInvalidOTSTx
 	}
 
@@ -371,7 +326,7 @@ func ValidateOTSConfirmedTx(tx *types.Transaction, contractAddr common.Address)
 	}
 
 	// Check data length
-	if len(tx.Data()) < 132 {
+	if method, _ := otsConfirmedMethodOf(tx.Data()); len(tx.Data()) < 4+32*len(method.Inputs) {
 		return ErrInvalidOTSTx
 	}
 
@@ -4,9 +4,12 @@
 package systx
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
@@ -14,108 +17,298 @@ import (
 
 var (
 	ErrNotSystemTx      = errors.New("systx: not a system transaction (gasPrice != 0)")
+	ErrInvalidValue     = errors.New("systx: system transaction transfers value")
 	ErrInvalidSender    = errors.New("systx: sender is not coinbase")
 	ErrInvalidRecipient = errors.New("systx: invalid recipient address")
 	ErrInvalidCalldata  = errors.New("systx: invalid calldata")
+
+	// Causes of a CalldataError, which also matches ErrInvalidCalldata
+	ErrUnknownSelector = errors.New("systx: unknown system transaction selector")
+	ErrCalldataLength  = errors.New("systx: calldata length does not match the method")
+	ErrMalformedWord   = errors.New("systx: malformed ABI word")
+	ErrInvalidArgument = errors.New("systx: invalid argument")
+)
+
+// System transaction method names
+const (
+	MethodAnchor       = "anchor"
+	MethodAnchorBatch  = "anchorBatch"
+	MethodOTSSubmitted = "otsSubmitted"
+	MethodOTSConfirmed = "otsConfirmed"
 )
 
+// CalldataError reports which part of a system transaction call is invalid
+type CalldataError struct {
+	Method string // Method name, empty if the selector is unknown
+	Field  string // Argument name, empty if the calldata as a whole is invalid
+	Err    error  // ErrUnknownSelector, ErrCalldataLength, ErrMalformedWord or ErrInvalidArgument
+}
+
+func (e *CalldataError) Error() string {
+	switch {
+	case e.Method == "":
+		return e.Err.Error()
+	case e.Field == "":
+		return fmt.Sprintf("%v: %s", e.Err, e.Method)
+	}
+	return fmt.Sprintf("%v: %s.%s", e.Err, e.Method, e.Field)
+}
+
+// Unwrap returns the cause and ErrInvalidCalldata
+func (e *CalldataError) Unwrap() []error {
+	return []error{e.Err, ErrInvalidCalldata}
+}
+
 // Validator validates system transactions
 type Validator struct {
 	contractAddress common.Address
+	signer          types.Signer
 }
 
-// NewValidator creates a new system transaction validator
-func NewValidator(contractAddress common.Address) *Validator {
+// NewValidator creates a new system transaction validator. The signer of the
+// chain recovers transaction senders.
+func NewValidator(contractAddress common.Address, signer types.Signer) *Validator {
 	return &Validator{
 		contractAddress: contractAddress,
+		signer:          signer,
 	}
 }
 
 // ValidateSystemTx validates that a transaction is a valid OTS system transaction.
 // Checks:
-// 1. gasPrice == 0
-// 2. sender == coinbase
-// 3. to == CopyrightRegistry contract
-// 4. calldata starts with anchor selector
+//  1. gasPrice == 0 and value == 0
+//  2. to == CopyrightRegistry contract
+//  3. calldata is a well-formed anchor, anchorBatch, otsSubmitted or
+//     otsConfirmed call,
+//     see DecodeSystemCall
+//  4. sender == coinbase
 func (v *Validator) ValidateSystemTx(tx *types.Transaction, coinbase common.Address) error {
 	// Check gasPrice == 0
-	if tx.GasPrice().Cmp(big.NewInt(0)) != 0 {
+	if tx.GasPrice().Sign() != 0 {
 		return ErrNotSystemTx
 	}
+	if tx.Value().Sign() != 0 {
+		return ErrInvalidValue
+	}
 
 	// Check recipient
 	if tx.To() == nil || *tx.To() != v.contractAddress {
 		return ErrInvalidRecipient
 	}
 
-	// Check calldata has valid selector
-	data := tx.Data()
-	if len(data) < 4 {
-		return ErrInvalidCalldata
+	// Check calldata
+	call, err := DecodeSystemCall(tx.Data())
+	if err != nil {
+		return err
 	}
 
-	// Verify function selector matches anchor(uint64,uint64,bytes32,bytes32,uint64)
-	if data[0] != anchorSig[0] ||
-		data[1] != anchorSig[1] ||
-		data[2] != anchorSig[2] ||
-		data[3] != anchorSig[3] {
-		return ErrInvalidCalldata
+	// Check sender last, recovering it is the most expensive check
+	from, err := types.Sender(v.signer, tx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSender, err)
+	}
+	if from != coinbase {
+		return fmt.Errorf("%w: sent by %s, coinbase %s", ErrInvalidSender, from.Hex(), coinbase.Hex())
 	}
 
 	log.Debug("OTS: System transaction validated",
 		"txHash", tx.Hash().Hex(),
+		"method", call.Method,
 		"to", tx.To().Hex(),
 	)
 
 	return nil
 }
 
-// DecodeCalldata decodes the anchor calldata
-// anchor(uint64 startBlock, uint64 endBlock, bytes32 batchRoot, bytes32 btcTxHash, uint64 btcTimestamp)
-func (v *Validator) DecodeCalldata(data []byte) (*DecodedCalldata, error) {
-	// Minimum size: 4 (selector) + 32*5 (5 fixed params)
-	if len(data) < 4+32*5 {
-		return nil, ErrInvalidCalldata
-	}
-
-	// Skip function selector
-	offset := 4
-
-	// startBlock (uint64)
-	startBlock := new(big.Int).SetBytes(data[offset : offset+32]).Uint64()
-	offset += 32
-
-	// endBlock (uint64)
-	endBlock := new(big.Int).SetBytes(data[offset : offset+32]).Uint64()
-	offset += 32
-
-	// batchRoot (bytes32)
-	var batchRoot common.Hash
-	copy(batchRoot[:], data[offset:offset+32])
-	offset += 32
-
-	// btcTxHash (bytes32)
-	var btcTxHash common.Hash
-	copy(btcTxHash[:], data[offset:offset+32])
-	offset += 32
-
-	// btcTimestamp (uint64)
-	btcTimestamp := new(big.Int).SetBytes(data[offset : offset+32]).Uint64()
-
-	return &DecodedCalldata{
-		StartBlock:   startBlock,
-		EndBlock:     endBlock,
-		BatchRoot:    batchRoot,
-		BTCTxHash:    btcTxHash,
-		BTCTimestamp: btcTimestamp,
-	}, nil
+// SystemCall is a decoded OTS system transaction call. The parameters of
+// Method are set, the others are nil.
+type SystemCall struct {
+	Method      string
+	Anchor      *DecodedAnchorCalldata
+	AnchorBatch []*DecodedAnchorCalldata
+	Submitted   *OTSSubmittedParams
+	Confirmed   *OTSConfirmedParams
+}
+
+// DecodeSystemCall decodes the calldata of an anchor, anchorBatch,
+// otsSubmitted or otsConfirmed call. The calldata must be the canonical ABI
+// encoding of the method's arguments, uint64 words must be zero-padded, and
+// the arguments must be usable: block ranges ordered, and digests of set root
+// hashes, proof hashes and Bitcoin heights set. Empty batches have a zero
+// root. Errors are CalldataErrors naming the failed field.
+func DecodeSystemCall(data []byte) (*SystemCall, error) {
+	if len(data) < 4 {
+		return nil, &CalldataError{Err: ErrUnknownSelector}
+	}
+
+	switch selector := data[:4]; {
+	case matchSelector(selector, AnchorSelector):
+		return decodeAnchorCall(data)
+	case matchSelector(selector, AnchorBatchSelector):
+		return decodeAnchorBatchCall(data)
+	case matchSelector(selector, OTSSubmittedSelector):
+		return decodeOTSSubmittedCall(data)
+	case matchSelector(selector, OTSConfirmedSelector):
+		return decodeOTSConfirmedCall(otsConfirmedMethod, data)
+	case matchSelector(selector, OTSConfirmedLegacySelector):
+		return decodeOTSConfirmedCall(otsConfirmedLegacyMethod, data)
+	}
+	return nil, &CalldataError{Err: ErrUnknownSelector}
+}
+
+// decodeAnchorCall decodes anchor(uint64,uint64,bytes32,bytes32,uint64)
+func decodeAnchorCall(data []byte) (*SystemCall, error) {
+	args, err := unpackSystemCall(anchorMethod, data)
+	if err != nil {
+		return nil, err
+	}
+	anchor := &DecodedAnchorCalldata{
+		StartBlock:   args[0].(uint64),
+		EndBlock:     args[1].(uint64),
+		RootHash:     args[2].([32]byte),
+		BTCTxHash:    args[3].([32]byte),
+		BTCTimestamp: args[4].(uint64),
+	}
+
+	// Empty batches have a zero root and no Bitcoin data, but a range
+	if anchor.StartBlock == 0 {
+		return nil, invalidArgument(MethodAnchor, "startBlock")
+	}
+	if anchor.EndBlock < anchor.StartBlock {
+		return nil, invalidArgument(MethodAnchor, "endBlock")
+	}
+	return &SystemCall{Method: MethodAnchor, Anchor: anchor}, nil
+}
+
+// decodeAnchorBatchCall decodes
+// anchorBatch(uint64[],uint64[],bytes32[],bytes32[],uint64[]). The arrays
+// hold one entry per batch, the batches ascending and not overlapping.
+func decodeAnchorBatchCall(data []byte) (*SystemCall, error) {
+	method := anchorBatchMethod
+	if len(data) < 4+32*len(method.Inputs) {
+		return nil, &CalldataError{Method: MethodAnchorBatch, Err: ErrCalldataLength}
+	}
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil, &CalldataError{Method: MethodAnchorBatch, Err: fmt.Errorf("%w: %v", ErrMalformedWord, err)}
+	}
+
+	// Only the canonical encoding is accepted, which rules out trailing data,
+	// unusual array offsets and dirty padding
+	canonical, err := packCall(method, args...)
+	switch {
+	case err != nil:
+		return nil, &CalldataError{Method: MethodAnchorBatch, Err: fmt.Errorf("%w: %v", ErrMalformedWord, err)}
+	case len(canonical) != len(data):
+		return nil, &CalldataError{Method: MethodAnchorBatch, Err: ErrCalldataLength}
+	case !bytes.Equal(canonical, data):
+		return nil, &CalldataError{Method: MethodAnchorBatch, Err: ErrMalformedWord}
+	}
+
+	var (
+		startBlocks   = args[0].([]uint64)
+		endBlocks     = args[1].([]uint64)
+		batchRoots    = args[2].([][32]byte)
+		btcTxHashes   = args[3].([][32]byte)
+		btcTimestamps = args[4].([]uint64)
+	)
+	if len(startBlocks) == 0 {
+		return nil, invalidArgument(MethodAnchorBatch, "startBlocks")
+	}
+	for i, n := range []int{len(endBlocks), len(batchRoots), len(btcTxHashes), len(btcTimestamps)} {
+		if n != len(startBlocks) {
+			return nil, invalidArgument(MethodAnchorBatch, method.Inputs[i+1].Name)
+		}
+	}
+
+	batches := make([]*DecodedAnchorCalldata, len(startBlocks))
+	for i := range batches {
+		batch := &DecodedAnchorCalldata{
+			StartBlock:   startBlocks[i],
+			EndBlock:     endBlocks[i],
+			RootHash:     batchRoots[i],
+			BTCTxHash:    btcTxHashes[i],
+			BTCTimestamp: btcTimestamps[i],
+		}
+		switch {
+		case batch.StartBlock == 0:
+			return nil, invalidArgument(MethodAnchorBatch, fmt.Sprintf("startBlocks[%d]", i))
+		case i > 0 && batch.StartBlock <= batches[i-1].EndBlock:
+			return nil, invalidArgument(MethodAnchorBatch, fmt.Sprintf("startBlocks[%d]", i))
+		case batch.EndBlock < batch.StartBlock:
+			return nil, invalidArgument(MethodAnchorBatch, fmt.Sprintf("endBlocks[%d]", i))
+		}
+		batches[i] = batch
+	}
+	return &SystemCall{Method: MethodAnchorBatch, AnchorBatch: batches}, nil
+}
+
+// decodeOTSSubmittedCall decodes otsSubmitted(bytes32,bytes32)
+func decodeOTSSubmittedCall(data []byte) (*SystemCall, error) {
+	args, err := unpackSystemCall(otsSubmittedMethod, data)
+	if err != nil {
+		return nil, err
+	}
+	params := &OTSSubmittedParams{
+		RootHash:  args[0].([32]byte),
+		OTSDigest: args[1].([32]byte),
+	}
+	// Empty batches have a zero root, which the native client stamps as is
+	if params.OTSDigest == ([32]byte{}) && params.RootHash != (common.Hash{}) {
+		return nil, invalidArgument(MethodOTSSubmitted, "otsDigest")
+	}
+	return &SystemCall{Method: MethodOTSSubmitted, Submitted: params}, nil
+}
+
+// decodeOTSConfirmedCall decodes a call of either otsConfirmed overload
+func decodeOTSConfirmedCall(method abi.Method, data []byte) (*SystemCall, error) {
+	args, err := unpackSystemCall(method, data)
+	if err != nil {
+		return nil, err
+	}
+	params := &OTSConfirmedParams{
+		RootHash:       args[0].([32]byte),
+		BTCBlockHeight: args[1].(uint64),
+		BTCTxID:        args[2].([32]byte),
+		BTCTimestamp:   args[3].(uint64),
+	}
+	if len(args) > 4 {
+		// A committed proof has a hash, no commitment uses the legacy call
+		if params.ProofHash = args[4].([32]byte); params.ProofHash == (common.Hash{}) {
+			return nil, invalidArgument(MethodOTSConfirmed, "proofHash")
+		}
+	}
+
+	// Empty batches have a zero root, but are confirmed like any other
+	if params.BTCBlockHeight == 0 {
+		return nil, invalidArgument(MethodOTSConfirmed, "btcBlockHeight")
+	}
+	return &SystemCall{Method: MethodOTSConfirmed, Confirmed: params}, nil
+}
+
+// unpackSystemCall decodes the arguments of a call of method. Unlike the ABI
+// decoder it requires data to hold exactly the method's static arguments and
+// uint64 words to be zero-padded.
+func unpackSystemCall(method abi.Method, data []byte) ([]interface{}, error) {
+	if len(data) != 4+32*len(method.Inputs) {
+		return nil, &CalldataError{Method: method.RawName, Err: ErrCalldataLength}
+	}
+	for i, input := range method.Inputs {
+		if input.Type.String() != "uint64" {
+			continue
+		}
+		if word := data[4+32*i : 4+32*(i+1)]; new(big.Int).SetBytes(word).BitLen() > 64 {
+			return nil, &CalldataError{Method: method.RawName, Field: input.Name, Err: ErrMalformedWord}
+		}
+	}
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil, &CalldataError{Method: method.RawName, Err: fmt.Errorf("%w: %v", ErrMalformedWord, err)}
+	}
+	return args, nil
 }
 
-// DecodedCalldata represents decoded anchor parameters
-type DecodedCalldata struct {
-	StartBlock   uint64
-	EndBlock     uint64
-	BatchRoot    common.Hash
-	BTCTxHash    common.Hash
-	BTCTimestamp uint64
+// invalidArgument returns the error for an unusable argument
+func invalidArgument(method, field string) error {
+	return &CalldataError{Method: method, Field: field, Err: ErrInvalidArgument}
 }
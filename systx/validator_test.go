@@ -4,19 +4,80 @@
 package systx
 
 import (
+	"crypto/ecdsa"
+	"errors"
 	"math/big"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	otstypes "github.com/ethereum/go-ethereum/ots/types"
 )
 
+var (
+	testSigner     = types.LatestSignerForChainID(big.NewInt(1337))
+	testKey, _     = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testCoinbase   = crypto.PubkeyToAddress(testKey.PublicKey)
+	testRegistry   = common.HexToAddress("0x9000")
+	testRootHash   = common.HexToHash("0xabcd")
+	testBTCTxID    = common.HexToHash("0xbeef")
+	testProofHash  = common.HexToHash("0xf00d")
+	testOTSDigest  = common.Hash{0x01}
+	testBTCHeight  = uint64(800000)
+	testBTCTime    = uint64(1700000000)
+	testBatchStart = uint64(1)
+	testBatchEnd   = uint64(100)
+)
+
+// signedSystemTx returns a system transaction calling the registry with data,
+// signed by key
+func signedSystemTx(t *testing.T, key *ecdsa.PrivateKey, data []byte) *types.Transaction {
+	t.Helper()
+	tx, err := types.SignTx(types.NewTransaction(0, testRegistry, big.NewInt(0), 100000, big.NewInt(0), data), testSigner, key)
+	if err != nil {
+		t.Fatalf("SignTx failed: %v", err)
+	}
+	return tx
+}
+
+// callData returns the calldata of selector with the given ABI words
+func callData(selector []byte, words ...common.Hash) []byte {
+	data := append([]byte{}, selector...)
+	for _, word := range words {
+		data = append(data, word[:]...)
+	}
+	return data
+}
+
+// anchorBatchData returns anchorBatch calldata for batches with the given
+// ranges
+func anchorBatchData(t *testing.T, startBlocks, endBlocks []uint64, roots int) []byte {
+	t.Helper()
+	var (
+		batchRoots    = make([][32]byte, roots)
+		btcTxHashes   = make([][32]byte, len(startBlocks))
+		btcTimestamps = make([]uint64, len(startBlocks))
+	)
+	for i := range batchRoots {
+		batchRoots[i] = testRootHash
+	}
+	data, err := packCall(anchorBatchMethod, startBlocks, endBlocks, batchRoots, btcTxHashes, btcTimestamps)
+	if err != nil {
+		t.Fatalf("packCall failed: %v", err)
+	}
+	return data
+}
+
+// uint64Word returns n as an ABI word
+func uint64Word(n uint64) common.Hash {
+	return common.BigToHash(new(big.Int).SetUint64(n))
+}
+
 func TestDecodeCalldata(t *testing.T) {
 	contractAddr := common.HexToAddress("0x9000")
 	builder := NewBuilder(contractAddr)
-	validator := NewValidator(contractAddr)
 
 	// Build a valid calldata
 	candidate := &otstypes.CandidateBatch{
@@ -37,7 +98,7 @@ func TestDecodeCalldata(t *testing.T) {
 	}
 
 	// Decode the calldata
-	decoded, err := validator.DecodeCalldata(data)
+	decoded, err := DecodeCalldata(data)
 	if err != nil {
 		t.Fatalf("DecodeCalldata failed: %v", err)
 	}
@@ -52,8 +113,8 @@ func TestDecodeCalldata(t *testing.T) {
 	}
 
 	expectedRoot := common.HexToHash("0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef")
-	if decoded.BatchRoot != expectedRoot {
-		t.Errorf("BatchRoot = %s, want %s", decoded.BatchRoot.Hex(), expectedRoot.Hex())
+	if decoded.RootHash != expectedRoot {
+		t.Errorf("RootHash = %s, want %s", decoded.RootHash.Hex(), expectedRoot.Hex())
 	}
 
 	expectedTxHash := common.HexToHash("0xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
@@ -67,14 +128,12 @@ func TestDecodeCalldata(t *testing.T) {
 }
 
 func TestDecodeCalldata_TooShort(t *testing.T) {
-	validator := NewValidator(common.HexToAddress("0x9000"))
-
 	// Too short calldata (less than 4 + 32*5 = 164 bytes)
 	shortData := make([]byte, 100)
-	copy(shortData[:4], anchorSig)
+	copy(shortData[:4], AnchorSelector)
 
-	_, err := validator.DecodeCalldata(shortData)
-	if err != ErrInvalidCalldata {
+	_, err := DecodeCalldata(shortData)
+	if !errors.Is(err, ErrInvalidCalldata) {
 		t.Errorf("expected ErrInvalidCalldata for short data, got %v", err)
 	}
 }
@@ -82,7 +141,6 @@ func TestDecodeCalldata_TooShort(t *testing.T) {
 func TestDecodeCalldata_EmptyBatch(t *testing.T) {
 	contractAddr := common.HexToAddress("0x9000")
 	builder := NewBuilder(contractAddr)
-	validator := NewValidator(contractAddr)
 
 	// Empty batch
 	candidate := &otstypes.CandidateBatch{
@@ -102,13 +160,13 @@ func TestDecodeCalldata_EmptyBatch(t *testing.T) {
 		t.Fatalf("encodeCalldata failed: %v", err)
 	}
 
-	decoded, err := validator.DecodeCalldata(data)
+	decoded, err := DecodeCalldata(data)
 	if err != nil {
 		t.Fatalf("DecodeCalldata failed: %v", err)
 	}
 
-	if decoded.BatchRoot != (common.Hash{}) {
-		t.Errorf("BatchRoot should be zero, got %s", decoded.BatchRoot.Hex())
+	if decoded.RootHash != (common.Hash{}) {
+		t.Errorf("RootHash should be zero, got %s", decoded.RootHash.Hex())
 	}
 
 	if decoded.BTCTxHash != (common.Hash{}) {
@@ -122,8 +180,7 @@ func TestDecodeCalldata_EmptyBatch(t *testing.T) {
 
 func TestValidateSystemTx(t *testing.T) {
 	contractAddr := common.HexToAddress("0x9000")
-	validator := NewValidator(contractAddr)
-	coinbase := common.HexToAddress("0x1234")
+	validator := NewValidator(contractAddr, testSigner)
 
 	// Build valid calldata
 	builder := NewBuilder(contractAddr)
@@ -141,25 +198,34 @@ func TestValidateSystemTx(t *testing.T) {
 
 	data, _ := builder.encodeCalldata(candidate)
 
-	// Create a valid system transaction
-	tx := types.NewTransaction(
-		0,              // nonce
-		contractAddr,   // to
-		big.NewInt(0),  // value
-		100000,         // gas
-		big.NewInt(0),  // gasPrice = 0 (system tx)
-		data,
-	)
+	// Create a valid system transaction, signed by the coinbase
+	tx := signedSystemTx(t, testKey, data)
 
-	err := validator.ValidateSystemTx(tx, coinbase)
+	err := validator.ValidateSystemTx(tx, testCoinbase)
 	if err != nil {
 		t.Errorf("ValidateSystemTx failed for valid tx: %v", err)
 	}
+
+	// The other system transaction kinds validate as well
+	for _, data := range [][]byte{
+		callData(OTSSubmittedSelector, testRootHash, testOTSDigest),
+		callData(OTSConfirmedSelector, testRootHash, uint64Word(testBTCHeight), testBTCTxID, uint64Word(testBTCTime), testProofHash),
+		callData(OTSConfirmedLegacySelector, testRootHash, uint64Word(testBTCHeight), testBTCTxID, uint64Word(testBTCTime)),
+		anchorBatchData(t, []uint64{1, 101}, []uint64{100, 200}, 2),
+
+		// Empty batches are stamped and confirmed under the zero root
+		callData(OTSSubmittedSelector, common.Hash{}, common.Hash{}),
+		callData(OTSConfirmedSelector, common.Hash{}, uint64Word(testBTCHeight), testBTCTxID, uint64Word(testBTCTime), testProofHash),
+	} {
+		if err := validator.ValidateSystemTx(signedSystemTx(t, testKey, data), testCoinbase); err != nil {
+			t.Errorf("ValidateSystemTx failed for %x: %v", data[:4], err)
+		}
+	}
 }
 
 func TestValidateSystemTx_NonZeroGasPrice(t *testing.T) {
 	contractAddr := common.HexToAddress("0x9000")
-	validator := NewValidator(contractAddr)
+	validator := NewValidator(contractAddr, testSigner)
 	coinbase := common.HexToAddress("0x1234")
 
 	// Transaction with non-zero gas price
@@ -169,7 +235,7 @@ func TestValidateSystemTx_NonZeroGasPrice(t *testing.T) {
 		big.NewInt(0),
 		100000,
 		big.NewInt(1), // Non-zero gasPrice
-		anchorSig,
+		AnchorSelector,
 	)
 
 	err := validator.ValidateSystemTx(tx, coinbase)
@@ -180,7 +246,7 @@ func TestValidateSystemTx_NonZeroGasPrice(t *testing.T) {
 
 func TestValidateSystemTx_WrongRecipient(t *testing.T) {
 	contractAddr := common.HexToAddress("0x9000")
-	validator := NewValidator(contractAddr)
+	validator := NewValidator(contractAddr, testSigner)
 	coinbase := common.HexToAddress("0x1234")
 
 	wrongAddr := common.HexToAddress("0x9001")
@@ -190,7 +256,7 @@ func TestValidateSystemTx_WrongRecipient(t *testing.T) {
 		big.NewInt(0),
 		100000,
 		big.NewInt(0),
-		anchorSig,
+		AnchorSelector,
 	)
 
 	err := validator.ValidateSystemTx(tx, coinbase)
@@ -201,7 +267,7 @@ func TestValidateSystemTx_WrongRecipient(t *testing.T) {
 
 func TestValidateSystemTx_ShortCalldata(t *testing.T) {
 	contractAddr := common.HexToAddress("0x9000")
-	validator := NewValidator(contractAddr)
+	validator := NewValidator(contractAddr, testSigner)
 	coinbase := common.HexToAddress("0x1234")
 
 	// Calldata too short (less than 4 bytes)
@@ -215,14 +281,14 @@ func TestValidateSystemTx_ShortCalldata(t *testing.T) {
 	)
 
 	err := validator.ValidateSystemTx(tx, coinbase)
-	if err != ErrInvalidCalldata {
+	if !errors.Is(err, ErrInvalidCalldata) {
 		t.Errorf("expected ErrInvalidCalldata, got %v", err)
 	}
 }
 
 func TestValidateSystemTx_WrongSelector(t *testing.T) {
 	contractAddr := common.HexToAddress("0x9000")
-	validator := NewValidator(contractAddr)
+	validator := NewValidator(contractAddr, testSigner)
 	coinbase := common.HexToAddress("0x1234")
 
 	// Wrong function selector
@@ -237,7 +303,85 @@ func TestValidateSystemTx_WrongSelector(t *testing.T) {
 	)
 
 	err := validator.ValidateSystemTx(tx, coinbase)
-	if err != ErrInvalidCalldata {
+	if !errors.Is(err, ErrInvalidCalldata) {
 		t.Errorf("expected ErrInvalidCalldata, got %v", err)
 	}
 }
+
+func TestValidateSystemTx_Malformed(t *testing.T) {
+	validator := NewValidator(testRegistry, testSigner)
+	otherKey, _ := crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+
+	anchor := func(start, end common.Hash) []byte {
+		return callData(AnchorSelector, start, end, testRootHash, testBTCTxID, uint64Word(testBTCTime))
+	}
+	confirmed := func(height common.Hash, proofHash common.Hash) []byte {
+		return callData(OTSConfirmedSelector, testRootHash, height, testBTCTxID, uint64Word(testBTCTime), proofHash)
+	}
+	dirty := uint64Word(testBTCHeight)
+	dirty[0] = 0x01
+	batch := anchorBatchData(t, []uint64{1, 101}, []uint64{100, 200}, 2)
+	dirtyBatch := common.CopyBytes(batch)
+	dirtyBatch[4+32*6] = 0x01 // High byte of startBlocks[0]
+
+	tests := []struct {
+		name   string
+		tx     *types.Transaction
+		err    error
+		method string
+		field  string
+	}{
+		{"unknown selector", signedSystemTx(t, testKey, callData([]byte{0xde, 0xad, 0xbe, 0xef}, testRootHash)), ErrUnknownSelector, "", ""},
+		{"anchor short", signedSystemTx(t, testKey, anchor(uint64Word(1), uint64Word(2))[:163]), ErrCalldataLength, MethodAnchor, ""},
+		{"anchor trailing word", signedSystemTx(t, testKey, append(anchor(uint64Word(1), uint64Word(2)), testRootHash[:]...)), ErrCalldataLength, MethodAnchor, ""},
+		{"anchor dirty startBlock", signedSystemTx(t, testKey, anchor(dirty, uint64Word(2))), ErrMalformedWord, MethodAnchor, "startBlock"},
+		{"anchor dirty endBlock", signedSystemTx(t, testKey, anchor(uint64Word(1), dirty)), ErrMalformedWord, MethodAnchor, "endBlock"},
+		{"anchor dirty btcTimestamp", signedSystemTx(t, testKey, callData(AnchorSelector, uint64Word(1), uint64Word(2), testRootHash, testBTCTxID, dirty)), ErrMalformedWord, MethodAnchor, "btcTimestamp"},
+		{"anchor zero startBlock", signedSystemTx(t, testKey, anchor(uint64Word(0), uint64Word(2))), ErrInvalidArgument, MethodAnchor, "startBlock"},
+		{"anchor reversed range", signedSystemTx(t, testKey, anchor(uint64Word(testBatchEnd), uint64Word(testBatchStart))), ErrInvalidArgument, MethodAnchor, "endBlock"},
+		{"otsSubmitted short", signedSystemTx(t, testKey, callData(OTSSubmittedSelector, testRootHash)), ErrCalldataLength, MethodOTSSubmitted, ""},
+		{"otsSubmitted trailing word", signedSystemTx(t, testKey, callData(OTSSubmittedSelector, testRootHash, testOTSDigest, testRootHash)), ErrCalldataLength, MethodOTSSubmitted, ""},
+		{"otsSubmitted zero otsDigest", signedSystemTx(t, testKey, callData(OTSSubmittedSelector, testRootHash, common.Hash{})), ErrInvalidArgument, MethodOTSSubmitted, "otsDigest"},
+		{"otsConfirmed short", signedSystemTx(t, testKey, confirmed(uint64Word(testBTCHeight), testProofHash)[:132]), ErrCalldataLength, MethodOTSConfirmed, ""},
+		{"otsConfirmed trailing word", signedSystemTx(t, testKey, append(confirmed(uint64Word(testBTCHeight), testProofHash), testRootHash[:]...)), ErrCalldataLength, MethodOTSConfirmed, ""},
+		{"otsConfirmed legacy trailing word", signedSystemTx(t, testKey, callData(OTSConfirmedLegacySelector, testRootHash, uint64Word(testBTCHeight), testBTCTxID, uint64Word(testBTCTime), testProofHash)), ErrCalldataLength, MethodOTSConfirmed, ""},
+		{"otsConfirmed dirty btcBlockHeight", signedSystemTx(t, testKey, confirmed(dirty, testProofHash)), ErrMalformedWord, MethodOTSConfirmed, "btcBlockHeight"},
+		{"otsConfirmed dirty btcTimestamp", signedSystemTx(t, testKey, callData(OTSConfirmedSelector, testRootHash, uint64Word(testBTCHeight), testBTCTxID, dirty, testProofHash)), ErrMalformedWord, MethodOTSConfirmed, "btcTimestamp"},
+		{"otsConfirmed zero btcBlockHeight", signedSystemTx(t, testKey, confirmed(uint64Word(0), testProofHash)), ErrInvalidArgument, MethodOTSConfirmed, "btcBlockHeight"},
+		{"otsConfirmed zero proofHash", signedSystemTx(t, testKey, confirmed(uint64Word(testBTCHeight), common.Hash{})), ErrInvalidArgument, MethodOTSConfirmed, "proofHash"},
+		{"anchorBatch short", signedSystemTx(t, testKey, batch[:4+32*4]), ErrCalldataLength, MethodAnchorBatch, ""},
+		{"anchorBatch truncated array", signedSystemTx(t, testKey, batch[:len(batch)-32]), ErrMalformedWord, MethodAnchorBatch, ""},
+		{"anchorBatch trailing word", signedSystemTx(t, testKey, append(common.CopyBytes(batch), testRootHash[:]...)), ErrCalldataLength, MethodAnchorBatch, ""},
+		{"anchorBatch dirty startBlocks", signedSystemTx(t, testKey, dirtyBatch), ErrMalformedWord, MethodAnchorBatch, ""},
+		{"anchorBatch empty", signedSystemTx(t, testKey, anchorBatchData(t, nil, nil, 0)), ErrInvalidArgument, MethodAnchorBatch, "startBlocks"},
+		{"anchorBatch missing endBlocks", signedSystemTx(t, testKey, anchorBatchData(t, []uint64{1, 101}, []uint64{100}, 2)), ErrInvalidArgument, MethodAnchorBatch, "endBlocks"},
+		{"anchorBatch missing batchRoots", signedSystemTx(t, testKey, anchorBatchData(t, []uint64{1, 101}, []uint64{100, 200}, 1)), ErrInvalidArgument, MethodAnchorBatch, "batchRoots"},
+		{"anchorBatch zero startBlock", signedSystemTx(t, testKey, anchorBatchData(t, []uint64{0, 101}, []uint64{100, 200}, 2)), ErrInvalidArgument, MethodAnchorBatch, "startBlocks[0]"},
+		{"anchorBatch reversed range", signedSystemTx(t, testKey, anchorBatchData(t, []uint64{1, 201}, []uint64{100, 200}, 2)), ErrInvalidArgument, MethodAnchorBatch, "endBlocks[1]"},
+		{"anchorBatch overlapping", signedSystemTx(t, testKey, anchorBatchData(t, []uint64{1, 100}, []uint64{100, 200}, 2)), ErrInvalidArgument, MethodAnchorBatch, "startBlocks[1]"},
+		{"anchorBatch unordered", signedSystemTx(t, testKey, anchorBatchData(t, []uint64{101, 1}, []uint64{200, 100}, 2)), ErrInvalidArgument, MethodAnchorBatch, "startBlocks[1]"},
+		{"wrong sender", signedSystemTx(t, otherKey, anchor(uint64Word(1), uint64Word(2))), ErrInvalidSender, "", ""},
+		{"unsigned", types.NewTransaction(0, testRegistry, big.NewInt(0), 100000, big.NewInt(0), anchor(uint64Word(1), uint64Word(2))), ErrInvalidSender, "", ""},
+		{"value", types.NewTransaction(0, testRegistry, big.NewInt(1), 100000, big.NewInt(0), anchor(uint64Word(1), uint64Word(2))), ErrInvalidValue, "", ""},
+	}
+	for _, tt := range tests {
+		err := validator.ValidateSystemTx(tt.tx, testCoinbase)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+			continue
+		}
+		var callErr *CalldataError
+		if !errors.As(err, &callErr) {
+			if tt.method != "" {
+				t.Errorf("%s: expected a CalldataError, got %v", tt.name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrInvalidCalldata) {
+			t.Errorf("%s: CalldataError does not match ErrInvalidCalldata", tt.name)
+		}
+		if callErr.Method != tt.method || callErr.Field != tt.field {
+			t.Errorf("%s: failed field %s.%s, want %s.%s", tt.name, callErr.Method, callErr.Field, tt.method, tt.field)
+		}
+	}
+}
@@ -240,9 +240,8 @@ func TestIntegration_SystemTxBuilding(t *testing.T) {
 		t.Errorf("expected nonce %d, got %d", nonce, tx.Nonce())
 	}
 
-	// Validate the transaction using validator
-	validator := systx.NewValidator(contractAddr)
-	decoded, err := validator.DecodeCalldata(tx.Data())
+	// Decode the calldata
+	decoded, err := systx.DecodeCalldata(tx.Data())
 	if err != nil {
 		t.Fatalf("DecodeCalldata failed: %v", err)
 	}
@@ -255,8 +254,8 @@ func TestIntegration_SystemTxBuilding(t *testing.T) {
 		t.Errorf("decoded EndBlock mismatch: expected %d, got %d", meta.EndBlock, decoded.EndBlock)
 	}
 
-	if decoded.BatchRoot != meta.RootHash {
-		t.Error("decoded BatchRoot mismatch")
+	if decoded.RootHash != meta.RootHash {
+		t.Error("decoded RootHash mismatch")
 	}
 
 	if decoded.BTCTimestamp != candidate.BTCTimestamp {
@@ -546,6 +545,75 @@ func TestIntegration_BatchStatusTransitions(t *testing.T) {
 	}
 }
 
+// TestIntegration_MarkAnchored tests recording and reverting on-chain anchors
+func TestIntegration_MarkAnchored(t *testing.T) {
+	memdb := memorydb.New()
+	db := rawdb.NewDatabase(memdb)
+	store := storage.NewStoreWithDB(db)
+	indexMgr := storage.NewIndexManager(store)
+
+	batchID := "anchor-test-batch"
+	meta := &types.BatchMeta{
+		BatchID:    batchID,
+		StartBlock: 1,
+		EndBlock:   100,
+		RootHash:   common.HexToHash("0x1234"),
+		EventRUIDs: []common.Hash{common.HexToHash("0xaaaa")},
+		CreatedAt:  time.Now(),
+	}
+	if err := store.SaveBatchMeta(meta); err != nil {
+		t.Fatalf("SaveBatchMeta failed: %v", err)
+	}
+	if err := store.SaveAttempt(&types.Attempt{BatchID: batchID, Status: types.BatchStatusConfirmed, BTCTxID: "beef"}); err != nil {
+		t.Fatalf("SaveAttempt failed: %v", err)
+	}
+
+	// An anchor of a different range or root does not match the batch
+	txHash := common.HexToHash("0x5555")
+	if _, _, err := indexMgr.MarkAnchored(1, 99, meta.RootHash, txHash, 120); err != storage.ErrNotFound {
+		t.Errorf("expected ErrNotFound for wrong range, got %v", err)
+	}
+	if _, _, err := indexMgr.MarkAnchored(1, 100, common.HexToHash("0x4321"), txHash, 120); err != storage.ErrNotFound {
+		t.Errorf("expected ErrNotFound for wrong root, got %v", err)
+	}
+
+	// Only the first sighting of the anchor reports a change
+	for i, want := range []bool{true, false} {
+		id, changed, err := indexMgr.MarkAnchored(1, 100, meta.RootHash, txHash, 120)
+		if err != nil || id != batchID || changed != want {
+			t.Fatalf("MarkAnchored %d = %s, %v, %v; want %s, %v", i, id, changed, err, batchID, want)
+		}
+	}
+	attempt, err := store.GetAttempt(batchID)
+	if err != nil {
+		t.Fatalf("GetAttempt failed: %v", err)
+	}
+	if attempt.Status != types.BatchStatusAnchored || attempt.AnchorTxHash != txHash || attempt.AnchorBlock != 120 {
+		t.Errorf("anchor not recorded: %+v", attempt)
+	}
+	if ids, err := indexMgr.GetConfirmedUnanchoredBatches(); err != nil || len(ids) != 0 {
+		t.Errorf("anchored batch still waiting for anchoring: %v, %v", ids, err)
+	}
+
+	// Reorgs above the anchor keep it, reorgs below revert it
+	if ids, err := indexMgr.UnmarkAnchoredSince(121); err != nil || len(ids) != 0 {
+		t.Errorf("UnmarkAnchoredSince(121) = %v, %v; want nothing reverted", ids, err)
+	}
+	if ids, err := indexMgr.UnmarkAnchoredSince(120); err != nil || len(ids) != 1 || ids[0] != batchID {
+		t.Errorf("UnmarkAnchoredSince(120) = %v, %v; want [%s]", ids, err, batchID)
+	}
+	attempt, err = store.GetAttempt(batchID)
+	if err != nil {
+		t.Fatalf("GetAttempt failed: %v", err)
+	}
+	if attempt.Status != types.BatchStatusConfirmed || attempt.AnchorTxHash != (common.Hash{}) || attempt.AnchorBlock != 0 {
+		t.Errorf("anchor not reverted: %+v", attempt)
+	}
+	if ids, err := indexMgr.GetConfirmedUnanchoredBatches(); err != nil || len(ids) != 1 {
+		t.Errorf("reverted batch not waiting for anchoring: %v, %v", ids, err)
+	}
+}
+
 // TestIntegration_MultipleRUIDsInBatch tests handling of multiple RUIDs
 func TestIntegration_MultipleRUIDsInBatch(t *testing.T) {
 	memdb := memorydb.New()
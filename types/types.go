@@ -94,6 +94,10 @@ type BatchMeta struct {
 	// EventRUIDs is the ordered list of RUIDs (sorted by SortKey)
 	EventRUIDs []common.Hash
 
+	// MerkleScheme is the merkle.Scheme RootHash was built with. Batches
+	// stored before schemes were recorded have zero, the legacy scheme.
+	MerkleScheme uint8
+
 	// CreatedAt is when the batch was created
 	CreatedAt time.Time
 
@@ -130,6 +134,9 @@ type Attempt struct {
 	// BTCTimestamp is the Bitcoin block timestamp
 	BTCTimestamp uint64
 
+	// ProofHash is the proof sidecar key of the upgraded OTS proof
+	ProofHash common.Hash
+
 	// AnchorTxHash is the RMC system transaction hash
 	AnchorTxHash common.Hash
 